@@ -0,0 +1,172 @@
+/*
+Package cmd commands for nimbus
+Copyright © 2019 Alexander Zillion <alex@alexzillion.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/azillion/nimbus/convert"
+	"github.com/azillion/nimbus/gfs"
+	"github.com/azillion/nimbus/grib2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertTo          string
+	convertOutput      string
+	convertPerVariable bool
+	convertRegion      string
+	convertGzip        bool
+	convertSkipMissing bool
+	convertStack       bool
+	convertStride      int
+	convertCells       bool
+	convertVariables   []string
+	convertLevels      []string
+	convertHours       []int
+	convertChunks      []int
+	convertCompression string
+	convertPerMessage  bool
+	convertPacking     string
+	convertBits        int
+	convertDecimal     int
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert [grib2 files...]",
+	Short: "Convert GRIB2 files to other formats.",
+	Long: `Decode the fields of GRIB2 files and write them to another format,
+either all to one output or to one output per variable named after it.
+
+--to picks the format and -o only names the output, - being stdout. Formats
+streamed like ndjson are written to stdout when no output is given, so
+--to ndjson -o - and --to ndjson both pipe NDJSON into tools like jq.
+
+NetCDF files hold a single valid time unless --stack is given. Fields of
+several valid times are then written to one file per time, named after the
+output with the time added: -o out.nc writes out.2024010200.nc,
+out.2024010203.nc and so on, and no out.nc.
+
+GRIB2 outputs hold every field as a message of its own, cropped to the
+region. Values are packed with --packing in --bits bits after scaling them by
+10^--decimal-scale, without --bits they keep the scale factors of the input
+and do not change.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := convert.LookupFormat(convertTo)
+		if err != nil {
+			logrus.Fatalf("%v, must be one of: %s", err, strings.Join(convert.FormatNames(), ", "))
+		}
+		if err = checkOutputName(convertOutput); err != nil {
+			logrus.Fatal(err)
+		}
+		c := &convert.Converter{
+			Format:      format,
+			Output:      convertOutput,
+			PerVariable: convertPerVariable,
+			Options: convert.Options{
+				SkipMissing: convertSkipMissing,
+				Gzip:        convertGzip,
+				Stack:       convertStack,
+				Stride:      convertStride,
+				Cells:       convertCells,
+				Chunks:      convertChunks,
+				Compression: convertCompression,
+				PerMessage:  convertPerMessage,
+				Encode: grib2.EncodeOptions{
+					Bits:         convertBits,
+					DecimalScale: convertDecimal,
+				},
+			},
+		}
+		c.Options.Encode.Template, err = convert.PackingTemplate(convertPacking)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if len(convertVariables) > 0 || len(convertLevels) > 0 || len(convertHours) > 0 {
+			c.Filter = convert.Select(convertVariables, convertLevels, convertHours)
+		}
+		if convertRegion != "" {
+			c.Options.Region, err = gfs.ParseRegion(convertRegion)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+		}
+		if c.Output == "" {
+			c.Output = c.DefaultOutput()
+		}
+		if err = c.CheckOutputs(args...); err != nil {
+			logrus.Fatal(err)
+		}
+
+		for _, fileName := range args {
+			err = c.ConvertFile(fileName)
+			if err != nil {
+				break
+			}
+			logrus.Debugf("converted %s", fileName)
+		}
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringVar(&convertTo, "to", "csv", "output format, one of: "+strings.Join(convert.FormatNames(), ", "))
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "output file, - for stdout, or folder with --per-variable, {date}, {cycle}, {variable}, {level} and {fh} are replaced by those of each field")
+	convertCmd.Flags().BoolVar(&convertPerVariable, "per-variable", false, "write one output per variable")
+	convertCmd.Flags().StringVar(&convertRegion, "region", "", "only write points in leftlon,rightlon,toplat,bottomlat")
+	convertCmd.Flags().BoolVar(&convertGzip, "gzip", false, "gzip compress the output, or the pages of parquet files")
+	convertCmd.Flags().BoolVar(&convertSkipMissing, "skip-missing", false, "leave out points without a value")
+	convertCmd.Flags().StringSliceVar(&convertVariables, "var", nil, "only write these variables, e.g. TMP,UGRD")
+	convertCmd.Flags().StringArrayVar(&convertLevels, "level", nil, "only write this level, e.g. \"2 m above ground\", can be repeated")
+	convertCmd.Flags().IntSliceVar(&convertHours, "fhour", nil, "only write these forecast hours")
+	convertCmd.Flags().IntVar(&convertStride, "stride", 1, "only write every Nth grid point along each axis")
+	convertCmd.Flags().BoolVar(&convertCells, "cells", false, "write grid cells as polygons instead of points (geojson)")
+	convertCmd.Flags().BoolVar(&convertPerMessage, "per-message", false, "write a record per message holding all its values instead of one per point (ndjson)")
+	convertCmd.Flags().IntSliceVar(&convertChunks, "chunks", nil, "chunk sizes along time, rows and columns (zarr), e.g. 24,128,128")
+	convertCmd.Flags().StringVar(&convertCompression, "compression", "", "chunk compression and level (zarr): zlib, gzip or none, e.g. zlib:5")
+	convertCmd.Flags().StringVar(&convertPacking, "packing", "simple", "packing of the values (grib2): simple, complex or complex-spatial")
+	convertCmd.Flags().IntVar(&convertBits, "bits", 0, "bits per packed value (grib2), 0 keeps the scale factors and reference of the input so its values are unchanged")
+	convertCmd.Flags().IntVar(&convertDecimal, "decimal-scale", 0, "decimal scale factor of the values packed in --bits (grib2), values are multiplied by 10^N")
+	convertCmd.Flags().BoolVar(&convertStack, "stack", false, "stack every valid time in one output along its time dimension (netcdf), without it several valid times are written to out.YYYYMMDDHH.nc for -o out.nc")
+}
+
+// checkOutputName refuses an output named like a format, -o picks the
+// format of inspect but names the output of convert, so -o ndjson would
+// otherwise write CSV to a file called ndjson
+func checkOutputName(output string) error {
+	if _, err := convert.LookupFormat(output); err == nil {
+		return fmt.Errorf("-o %s names the output file, not its format: use --to %s, with -o - for stdout", output, strings.ToLower(output))
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckOutputName(t *testing.T) {
+	for _, output := range []string{"ndjson", "CSV", "GeoJSON"} {
+		err := checkOutputName(output)
+		if err == nil || !strings.Contains(err.Error(), "--to "+strings.ToLower(output)) {
+			t.Errorf("-o %s: %v", output, err)
+		}
+	}
+	for _, output := range []string{"", "-", "out.ndjson", "ndjson/", "{variable}.csv"} {
+		if err := checkOutputName(output); err != nil {
+			t.Errorf("-o %s: %v", output, err)
+		}
+	}
+}
@@ -34,11 +34,15 @@ import (
 	"github.com/spf13/viper"
 )
 
-var outputFolder string
+var (
+	outputFolder string
+	writeIndex   bool
+)
 
 var defaultParams = &gfs.Params{
 	RepositoryType: gfs.NCEPRepoType,
-	Resolution:     gfs.OneDegree,
+	// leave the resolution empty so the data source picks its default grid
+	Resolution: "",
 	DateRange: gfs.DateRange{
 		Start: time.Now().AddDate(0, 0, -8),
 		End:   time.Now(),
@@ -49,35 +53,23 @@ var defaultParams = &gfs.Params{
 
 // getCmd represents the get command
 var getCmd = &cobra.Command{
-	Use:   "get [config file]",
-	Short: "Get files from NOMADS.",
-	Long:  `Download files from NOMADS`,
-	Args:  cobra.ExactArgs(1),
-	PreRun: func(cmd *cobra.Command, args []string) {
-		if debug {
-			logrus.SetLevel(logrus.DebugLevel)
-		}
-		if len(args) < 1 {
-			logrus.Fatal("requires a config file")
-		}
-		if util.FileExists(args[0]) {
-			cfgFile = args[0]
-			initConfig()
-		} else {
-			logrus.Fatal("config file does not exist or is unreadable: %s", args[0])
-		}
-	},
+	Use:    "get [config file]",
+	Short:  "Get files from NOMADS.",
+	Long:   `Download files from NOMADS`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: loadConfigFile,
 	Run: func(cmd *cobra.Command, args []string) {
 		dataSource := viper.GetString("data_source")
 		logrus.Debug(dataSource)
-		if strings.EqualFold(dataSource, "gfs") {
-			err := handleGFSDataSource()
+		if _, err := gfs.LookupModel(dataSource); err == nil {
+			err := handleDataSource()
 			if err != nil {
 				logrus.Fatal(err)
 			}
 			return
 		}
 		logrus.Infof("no valid config file provided: %s", cfgFile)
+		logrus.Infof("data_source must be one of: %s", strings.Join(gfs.ModelNames(), ", "))
 	},
 }
 
@@ -85,6 +77,24 @@ func init() {
 	rootCmd.AddCommand(getCmd)
 	getCmd.Flags().StringVarP(&outputFolder, "output-folder", "o", "", "output folder (default is working directory)")
 	viper.BindPFlag("output_folder", getCmd.Flags().Lookup("output-folder"))
+	getCmd.Flags().BoolVar(&writeIndex, "index", false, "write a wgrib2 style .idx file next to every downloaded file")
+	viper.BindPFlag("write_index", getCmd.Flags().Lookup("index"))
+}
+
+// loadConfigFile reads the config file given as the first argument
+func loadConfigFile(cmd *cobra.Command, args []string) {
+	if debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+	if len(args) < 1 {
+		logrus.Fatal("requires a config file")
+	}
+	if util.FileExists(args[0]) {
+		cfgFile = args[0]
+		initConfig()
+	} else {
+		logrus.Fatalf("config file does not exist or is unreadable: %s", args[0])
+	}
 }
 
 func parseConfigFile() (*gfs.Params, error) {
@@ -95,6 +105,12 @@ func parseConfigFile() (*gfs.Params, error) {
 		return nil, err
 	}
 
+	// the latest cycle is resolved by probing the repositories later on
+	if gfs.IsLatest(viper.GetString("date_range")) {
+		logrus.Debug(params)
+		return &params, nil
+	}
+
 	// parse the date values to strings
 	var dateRangeStrings gfs.DateRangeStrings
 	err = viper.UnmarshalKey("date_range", &dateRangeStrings)
@@ -109,7 +125,7 @@ func parseConfigFile() (*gfs.Params, error) {
 	return &params, nil
 }
 
-func handleGFSDataSource() error {
+func handleDataSource() error {
 	// parse the config file
 	params, err := parseConfigFile()
 	if err != nil {
@@ -117,11 +133,17 @@ func handleGFSDataSource() error {
 	}
 	logrus.Debug("parsed the config file")
 
-	// create a new gfs service
-	gfsService := gfs.NewService(params)
-	logrus.Debug("created a new GFS service")
+	if gfs.IsLatest(viper.GetString("date_range")) {
+		cycle, err := gfs.ResolveLatest(params, time.Now(), gfs.DefaultLookback)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("latest %s cycle is %s", params.DataSource, cycle.Format("2006-01-02 15Z"))
+	}
 
-	gfsService.GetFiles()
+	// create a new service for the data source
+	service := gfs.NewService(params)
+	logrus.Debugf("created a new %s service", params.DataSource)
 
-	return nil
+	return service.GetFiles()
 }
@@ -0,0 +1,60 @@
+/*
+Package cmd commands for nimbus
+Copyright © 2019 Alexander Zillion <alex@alexzillion.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/azillion/nimbus/gfs"
+	"github.com/spf13/cobra"
+)
+
+// indexCmd represents the index command
+var indexCmd = &cobra.Command{
+	Use:   "index [grib2 files...]",
+	Short: "Write .idx files for GRIB2 files.",
+	Long: `Write the inventory of every GRIB2 file to <file>.idx in the short
+format of wgrib2 that NOMADS serves, e.g.
+
+  1:0:d=2019061800:UGRD:10 m above ground:1 hour fcst:`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var failed int
+		for _, fileName := range args {
+			err := gfs.WriteIndexFile(fileName)
+			if err != nil {
+				logrus.Warnf("failed to index %s: %v", fileName, err)
+				failed++
+				continue
+			}
+			logrus.Debugf("indexed %s", fileName)
+		}
+		if failed > 0 {
+			logrus.Fatalf("failed to index %d of %d files", failed, len(args))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+}
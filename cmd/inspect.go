@@ -0,0 +1,305 @@
+/*
+Package cmd commands for nimbus
+Copyright © 2019 Alexander Zillion <alex@alexzillion.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/azillion/nimbus/grib2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectOutput string
+	inspectStats  bool
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [grib2 file]",
+	Short: "List the messages of a GRIB2 file.",
+	Long: `List every message of a GRIB2 file with its offset, length, reference
+time, forecast time, variable, level, grid shape, packing and the minimum,
+maximum and mean of its values. Messages holding several fields are listed
+once per field, numbered message.field like wgrib2. --stats=false skips
+unpacking the values for a quicker listing of large files.
+
+Fields with templates nimbus cannot decode are still listed with what can be
+read of them, their statistics are shown as "-".
+
+-o picks the format of the listing, which only describes the fields: -o
+ndjson writes a line per field. Their values are written by convert, e.g.
+nimbus convert --to ndjson -o - file.grb2.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if inspectOutput != "text" && inspectOutput != "json" && inspectOutput != "ndjson" {
+			logrus.Fatalf("output must be text, json or ndjson: %s", inspectOutput)
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer f.Close()
+
+		if inspectOutput == "ndjson" {
+			err = writeInventoryNDJSON(os.Stdout, grib2.NewScanner(f), inspectStats)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			return
+		}
+		entries, err := inventory(grib2.NewScanner(f), inspectStats)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if inspectOutput == "json" {
+			err = writeInventoryJSON(os.Stdout, entries)
+		} else {
+			err = writeInventory(os.Stdout, entries, inspectStats)
+			for _, e := range entries {
+				if e.Error != "" {
+					logrus.Warnf("message %s: %s", e.Message, e.Error)
+				}
+			}
+		}
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().StringVarP(&inspectOutput, "output", "o", "text", "format of the listing, text, json or ndjson, the values of fields are written by convert --to")
+	inspectCmd.Flags().BoolVarP(&inspectStats, "stats", "s", true, "unpack every field for the minimum, maximum and mean of its values")
+}
+
+// inventoryEntry describes one field of a GRIB2 file. Whatever cannot be
+// read of the field is left empty and Error says why, the statistics are
+// only set when asked for and some grid points have a value
+type inventoryEntry struct {
+	Message         string    `json:"message"`
+	Offset          int64     `json:"offset"`
+	Length          int64     `json:"length"`
+	RefTime         time.Time `json:"ref_time"`
+	Forecast        string    `json:"forecast"`
+	ForecastHour    *float64  `json:"forecast_hour"`
+	Variable        string    `json:"variable"`
+	Description     string    `json:"description"`
+	Unit            string    `json:"unit"`
+	Level           string    `json:"level"`
+	Ni              int       `json:"ni"`
+	Nj              int       `json:"nj"`
+	GridTemplate    int       `json:"grid_template"`
+	ProductTemplate int       `json:"product_template"`
+	PackingTemplate int       `json:"packing_template"`
+	Points          int       `json:"points"`
+	Packed          int       `json:"packed"`
+	Missing         *int      `json:"missing"`
+	Min             *float64  `json:"min"`
+	Max             *float64  `json:"max"`
+	Mean            *float64  `json:"mean"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// inventory describes every field of the messages a scanner reads
+func inventory(s *grib2.Scanner, stats bool) ([]inventoryEntry, error) {
+	var entries []inventoryEntry
+	err := eachInventoryEntry(s, stats, func(e *inventoryEntry) error {
+		entries = append(entries, *e)
+		return nil
+	})
+	return entries, err
+}
+
+// eachInventoryEntry calls fn with the description of every field of the
+// messages a scanner reads, as they are read
+func eachInventoryEntry(s *grib2.Scanner, stats bool, fn func(e *inventoryEntry) error) error {
+	for n := 1; s.Scan(); n++ {
+		m := s.Message()
+		for k, f := range m.Fields {
+			e := describeField(f, stats)
+			e.Message = strconv.Itoa(n)
+			if len(m.Fields) > 1 {
+				e.Message += "." + strconv.Itoa(k+1)
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Err()
+}
+
+// describeField summarises a field from its definition sections, the values
+// are only unpacked for their statistics
+func describeField(f *grib2.Field, stats bool) *inventoryEntry {
+	param := f.Parameter()
+	e := &inventoryEntry{
+		Offset:          f.Message.Offset,
+		Length:          f.Message.Length,
+		RefTime:         f.RefTime(),
+		Forecast:        "-",
+		Variable:        param.Name,
+		Description:     param.Description,
+		Unit:            param.Unit,
+		Level:           "-",
+		Ni:              f.Grid.Ni,
+		Nj:              f.Grid.Nj,
+		GridTemplate:    f.Grid.TemplateNumber,
+		ProductTemplate: f.Product.TemplateNumber,
+		PackingTemplate: f.Representation.TemplateNumber,
+		Points:          f.NumPoints(),
+		Packed:          f.Representation.NumValues,
+	}
+	fail := func(err error) {
+		if e.Error == "" {
+			e.Error = err.Error()
+		}
+	}
+	if err := f.Err(); err != nil {
+		fail(err)
+	}
+
+	if f.Product.Err() == nil {
+		e.Level = f.Level()
+		forecast, hour, err := describeForecast(f)
+		if err != nil {
+			fail(err)
+		} else {
+			e.Forecast, e.ForecastHour = forecast, &hour
+		}
+	}
+	if !stats || f.Err() != nil {
+		return e
+	}
+
+	values, err := f.Values()
+	if err != nil {
+		fail(err)
+		return e
+	}
+	missing := 0
+	min, max, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, v := range values {
+		x := float64(v)
+		if math.IsNaN(x) {
+			missing++
+			continue
+		}
+		min = math.Min(min, x)
+		max = math.Max(max, x)
+		sum += x
+	}
+	e.Missing = &missing
+	if present := len(values) - missing; present > 0 {
+		mean := sum / float64(present)
+		e.Min, e.Max, e.Mean = &min, &max, &mean
+	}
+	return e
+}
+
+// describeForecast formats the forecast time of a field in hours, "6" for a
+// forecast and "0-6 acc" for an accumulation over the first 6 hours, and
+// returns the hours from the reference time to the valid time
+func describeForecast(f *grib2.Field) (string, float64, error) {
+	verfTime, err := f.VerfTime()
+	if err != nil {
+		return "", 0, err
+	}
+	hour := verfTime.Sub(f.RefTime()).Hours()
+	if !f.Product.HasInterval {
+		return formatHours(hour), hour, nil
+	}
+
+	start, err := f.Product.ForecastHours()
+	if err != nil {
+		return "", 0, err
+	}
+	process := grib2.StatisticalProcessName(f.Product.StatisticalProcess)
+	return formatHours(start) + "-" + formatHours(hour) + " " + process, hour, nil
+}
+
+func formatHours(h float64) string {
+	return strconv.FormatFloat(h, 'g', -1, 64)
+}
+
+// writeInventory prints the inventory as an aligned table, with the
+// statistics of the fields when stats is set
+func writeInventory(w io.Writer, entries []inventoryEntry, stats bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	header := "MSG\tOFFSET\tLENGTH\tREF TIME\tFCST\tVARIABLE\tLEVEL\tGRID\tPACKING"
+	if stats {
+		header += "\tMIN\tMAX\tMEAN"
+	}
+	fmt.Fprintln(tw, header)
+	for _, e := range entries {
+		shape := "-"
+		if e.Ni > 0 && e.Nj > 0 {
+			shape = fmt.Sprintf("%dx%d", e.Ni, e.Nj)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s (3.%d)\t5.%d",
+			e.Message, e.Offset, e.Length, e.RefTime.Format("2006-01-02 15Z"),
+			e.Forecast, e.Variable, e.Level, shape, e.GridTemplate, e.PackingTemplate)
+		if stats {
+			fmt.Fprintf(tw, "\t%s\t%s\t%s", formatStat(e.Min), formatStat(e.Max), formatStat(e.Mean))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// writeInventoryJSON prints the inventory as a JSON array
+func writeInventoryJSON(w io.Writer, entries []inventoryEntry) error {
+	if entries == nil {
+		entries = []inventoryEntry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// writeInventoryNDJSON prints the description of every field as a line of
+// JSON, each line as soon as its field is read
+func writeInventoryNDJSON(w io.Writer, s *grib2.Scanner, stats bool) error {
+	enc := json.NewEncoder(w)
+	return eachInventoryEntry(s, stats, func(e *inventoryEntry) error {
+		return enc.Encode(e)
+	})
+}
+
+func formatStat(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*v, 'g', 6, 64)
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azillion/nimbus/grib2"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("..", "grib2", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// splitMessage splits a single message into its sections, the indicator and
+// end section left out
+func splitMessage(m []byte) [][]byte {
+	var s [][]byte
+	for pos := 16; pos < len(m)-4; {
+		length := int(binary.BigEndian.Uint32(m[pos:]))
+		s = append(s, append([]byte(nil), m[pos:pos+length]...))
+		pos += length
+	}
+	return s
+}
+
+// joinMessage builds a message of discipline 0 from its sections
+func joinMessage(sections [][]byte) []byte {
+	m := []byte{'G', 'R', 'I', 'B', 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0}
+	for _, s := range sections {
+		m = append(m, s...)
+	}
+	m = append(m, "7777"...)
+	binary.BigEndian.PutUint64(m[8:], uint64(len(m)))
+	return m
+}
+
+// accumulation turns the product definition of a message into template 4.8,
+// an accumulation from the forecast time over hours hours
+func accumulation(m []byte, hours int) []byte {
+	s := splitMessage(m)
+	product := append(s[2][:34:34], make([]byte, 24)...)
+	binary.BigEndian.PutUint32(product, 58)
+	binary.BigEndian.PutUint16(product[7:], 8)
+	// the reference time of section 1 plus the forecast time and interval
+	copy(product[34:], s[0][12:19])
+	forecast := int(binary.BigEndian.Uint32(product[18:]))
+	product[38] += byte(forecast + hours)
+	product[41] = 1
+	product[46] = 1
+	product[47] = 2
+	product[48] = 1
+	binary.BigEndian.PutUint32(product[49:], uint32(hours))
+	product[53] = 1
+	s[2] = product
+	return joinMessage(s)
+}
+
+func TestInventory(t *testing.T) {
+	gfs := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")
+	ugrd, vgrd := gfs[:17378], gfs[17378:]
+
+	// a field of an unsupported packing follows an accumulation
+	unsupported := append([]byte(nil), ugrd...)
+	s := splitMessage(unsupported)
+	binary.BigEndian.PutUint16(unsupported[16+len(s[0])+len(s[1])+len(s[2])+9:], 50)
+	var file []byte
+	file = append(file, ugrd...)
+	file = append(file, accumulation(vgrd, 6)...)
+	file = append(file, unsupported...)
+
+	entries, err := inventory(grib2.NewScanner(bytes.NewReader(file)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("%d entries, want 3", len(entries))
+	}
+
+	e := entries[0]
+	if e.Message != "1" || e.Offset != 0 || e.Length != 17378 || e.Variable != "UGRD" ||
+		e.Level != "10 m above ground" || e.Forecast != "1" || *e.ForecastHour != 1 ||
+		e.Ni != 117 || e.Nj != 98 || e.PackingTemplate != 0 || e.Error != "" {
+		t.Errorf("entry 1 = %+v", e)
+	}
+	if e.Min == nil || *e.Min != -13.498762130737305 || *e.Max != 12.861237525939941 || *e.Missing != 0 {
+		t.Errorf("entry 1 statistics %v %v %v", e.Min, e.Max, e.Missing)
+	}
+
+	// accumulations read like wgrib2's "1-7 hour acc fcst"
+	if e := entries[1]; e.Forecast != "1-7 acc" || *e.ForecastHour != 7 || e.Variable != "VGRD" || e.Min == nil {
+		t.Errorf("entry 2 = %+v", e)
+	}
+
+	e = entries[2]
+	if e.Offset != int64(len(file)-len(unsupported)) || e.Length != 17378 || e.Variable != "UGRD" ||
+		e.Forecast != "1" || e.PackingTemplate != 50 || e.Packed != 117*98 {
+		t.Errorf("entry 3 = %+v", e)
+	}
+	if e.Min != nil || e.Max != nil || e.Mean != nil || e.Missing != nil ||
+		!strings.Contains(e.Error, "data representation template 5.50") {
+		t.Errorf("entry 3 statistics %v %v %v, error %q", e.Min, e.Max, e.Mean, e.Error)
+	}
+
+	var text bytes.Buffer
+	if err = writeInventory(&text, entries, true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("%d lines, want 4:\n%s", len(lines), text.String())
+	}
+	if fields := strings.Fields(lines[3]); fields[len(fields)-1] != "-" || fields[len(fields)-3] != "-" {
+		t.Errorf("statistics of the unsupported field: %s", lines[3])
+	}
+	if !strings.Contains(lines[2], "1-7 acc") || !strings.Contains(lines[1], "117x98 (3.0)") {
+		t.Errorf("table:\n%s", text.String())
+	}
+}
+
+func TestInventoryWithoutStats(t *testing.T) {
+	gfs := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")
+	entries, err := inventory(grib2.NewScanner(bytes.NewReader(gfs)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Min != nil || e.Missing != nil || e.Error != "" {
+			t.Errorf("message %s unpacked with --stats=false: %+v", e.Message, e)
+		}
+	}
+
+	var out bytes.Buffer
+	if err = writeInventoryJSON(&out, entries); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err = json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1]["variable"] != "VGRD" || decoded[1]["offset"] != 17378.0 || decoded[1]["min"] != nil {
+		t.Errorf("JSON inventory %v", decoded)
+	}
+	if header := strings.SplitN(inventoryTable(t, entries), "\n", 2)[0]; strings.Contains(header, "MIN") {
+		t.Errorf("statistics columns with --stats=false: %s", header)
+	}
+}
+
+func inventoryTable(t *testing.T, entries []inventoryEntry) string {
+	var out bytes.Buffer
+	if err := writeInventory(&out, entries, false); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
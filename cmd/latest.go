@@ -0,0 +1,61 @@
+/*
+Package cmd commands for nimbus
+Copyright © 2019 Alexander Zillion <alex@alexzillion.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/azillion/nimbus/gfs"
+	"github.com/spf13/cobra"
+)
+
+var lookback time.Duration
+
+// latestCmd represents the latest command
+var latestCmd = &cobra.Command{
+	Use:   "latest [config file]",
+	Short: "Find the latest fully published cycle.",
+	Long: `Probe the repositories of a config file for the newest cycle that has
+every requested forecast hour published and print it as YYYYMMDDHH.`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: loadConfigFile,
+	Run: func(cmd *cobra.Command, args []string) {
+		params, err := parseConfigFile()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		cycle, err := gfs.NewService(params).LatestCycle(time.Now(), lookback)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		fmt.Println(cycle.Format("2006010215"))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(latestCmd)
+	latestCmd.Flags().DurationVar(&lookback, "lookback", gfs.DefaultLookback, "how far back to search for a published cycle")
+}
@@ -0,0 +1,140 @@
+/*
+Package cmd commands for nimbus
+Copyright © 2019 Alexander Zillion <alex@alexzillion.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/azillion/nimbus/convert"
+	"github.com/azillion/nimbus/gfs"
+	"github.com/azillion/nimbus/grib2"
+	"github.com/azillion/nimbus/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renderOutput     string
+	renderVariables  []string
+	renderLevels     []string
+	renderHours      []int
+	renderPalette    string
+	renderMin        float64
+	renderMax        float64
+	renderCoastlines bool
+	renderLegend     bool
+	renderScale      int
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render [grib2 file]",
+	Short: "Draw a field of a GRIB2 file to a PNG image.",
+	Long: `Draw the first field of a GRIB2 file matching --var, --level and
+--fhour to a PNG image, north up, titled with its variable, level and valid
+time and with a colorbar of its values.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := render.Options{
+			Coastlines: renderCoastlines,
+			Legend:     renderLegend,
+			Scale:      renderScale,
+		}
+		if renderPalette != "" {
+			var err error
+			opts.Palette, err = render.LookupPalette(renderPalette)
+			if err != nil {
+				logrus.Fatalf("%v, must be one of: %s", err, strings.Join(render.PaletteNames(), ", "))
+			}
+		}
+		if cmd.Flags().Changed("min") {
+			opts.Min = &renderMin
+		}
+		if cmd.Flags().Changed("max") {
+			opts.Max = &renderMax
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer f.Close()
+		field, err := firstField(grib2.NewScanner(f), convert.Select(renderVariables, renderLevels, renderHours))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		img, err := render.Render(field, opts)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		var w io.Writer = os.Stdout
+		if renderOutput != "-" {
+			out, err := os.Create(renderOutput)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			defer out.Close()
+			w = out
+		}
+		if err = png.Encode(w, img); err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Debugf("rendered %s %s to %s", field.Name, field.Level, renderOutput)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.Flags().StringVarP(&renderOutput, "output", "o", "nimbus.png", "output file, - for stdout")
+	renderCmd.Flags().StringSliceVar(&renderVariables, "var", nil, "draw this variable, e.g. TMP")
+	renderCmd.Flags().StringArrayVar(&renderLevels, "level", nil, "draw this level, e.g. \"2 m above ground\"")
+	renderCmd.Flags().IntSliceVar(&renderHours, "fhour", nil, "draw this forecast hour")
+	renderCmd.Flags().StringVar(&renderPalette, "palette", "", "palette, one of: "+strings.Join(render.PaletteNames(), ", ")+" (default picked by variable)")
+	renderCmd.Flags().Float64Var(&renderMin, "min", 0, "value at the low end of the palette, lower values are clamped (default the smallest value)")
+	renderCmd.Flags().Float64Var(&renderMax, "max", 0, "value at the high end of the palette, higher values are clamped (default the largest value)")
+	renderCmd.Flags().BoolVar(&renderCoastlines, "coastlines", false, "draw coastlines over the field")
+	renderCmd.Flags().BoolVar(&renderLegend, "legend", true, "draw a colorbar under the field")
+	renderCmd.Flags().IntVar(&renderScale, "scale", 0, "pixels per grid point (default about 800 pixels wide)")
+}
+
+// firstField decodes the first field a scanner reads that match returns true
+// for
+func firstField(s *grib2.Scanner, match func(*grib2.Field) bool) (*gfs.Field, error) {
+	for s.Scan() {
+		for _, f := range s.Message().Fields {
+			if match(f) {
+				return gfs.NewField(f)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no field matches")
+}
@@ -0,0 +1,364 @@
+package convert
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// Arrow metadata version, message headers and types of Schema.fbs and
+// Message.fbs
+const (
+	arrowV5 = 4
+
+	arrowSchema          = 1
+	arrowDictionaryBatch = 2
+	arrowRecordBatch     = 3
+
+	arrowInt           = 2
+	arrowFloatingPoint = 3
+	arrowUtf8          = 5
+	arrowTimestamp     = 10
+
+	arrowSingle      = 1
+	arrowMicrosecond = 2
+)
+
+// arrowMagic starts and ends Arrow files
+const arrowMagic = "ARROW1"
+
+// arrowWriter writes the long format of the Parquet output as Arrow IPC,
+// a record batch per field, either as a file, also known as Feather v2, or
+// as a stream. Variables and levels are dictionary encoded, the values new
+// to a dictionary are sent as a delta before the batch using them. Streams
+// are flushed after each batch, so a reader of a pipe or of the growing
+// file gets whole fields as they are decoded. Files also list their
+// dictionaries and batches in a footer
+type arrowWriter struct {
+	file   *textFile
+	opts   Options
+	stream bool
+	// offset is the position of the next message in the file
+	offset int64
+	// dicts are the variables and the levels written so far
+	dicts        [2]*arrowDictionary
+	dictBlocks   []arrowBlock
+	recordBlocks []arrowBlock
+}
+
+// arrowDictionary is a dictionary of strings, written holds how many were
+// sent already
+type arrowDictionary struct {
+	values  []string
+	index   map[string]int32
+	written int
+}
+
+// arrowBlock locates a message of a file
+type arrowBlock struct {
+	offset     int64
+	metaLength int32
+	bodyLength int64
+}
+
+func createArrowFile(path string, opts Options) (Writer, error) {
+	return createArrow(path, opts, false)
+}
+
+func createArrowStream(path string, opts Options) (Writer, error) {
+	return createArrow(path, opts, true)
+}
+
+func createArrow(path string, opts Options, stream bool) (Writer, error) {
+	if opts.Gzip {
+		return nil, errors.New("arrow: only LZ4 and ZSTD compress Arrow IPC, which are not supported")
+	}
+	file, err := createTextFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	w := &arrowWriter{file: file, opts: opts, stream: stream}
+	for k := range w.dicts {
+		w.dicts[k] = &arrowDictionary{index: map[string]int32{}}
+	}
+	if !stream {
+		if err = w.write([]byte(arrowMagic + "\x00\x00")); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if _, err = w.message(arrowSchema, arrowSchemaTable(), nil); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *arrowWriter) WriteField(f *gfs.Field) error {
+	var lats, lons, values []float32
+	nulls := 0
+	err := eachPoint(f, w.opts, func(i, j int, lat, lon float64, v float32) error {
+		lats = append(lats, float32(lat))
+		lons = append(lons, float32(lon))
+		values = append(values, v)
+		if math.IsNaN(float64(v)) {
+			nulls++
+		}
+		return nil
+	})
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	variable := w.dicts[0].add(f.Name)
+	level := w.dicts[1].add(f.Level)
+	for id, d := range w.dicts {
+		if err = w.sendDictionary(int64(id), d); err != nil {
+			return err
+		}
+	}
+
+	n := len(values)
+	body := &arrowBody{}
+	repeat := func(size int, v uint64) {
+		b := make([]byte, size*n)
+		for k := 0; k < n; k++ {
+			if size == 8 {
+				binary.LittleEndian.PutUint64(b[8*k:], v)
+			} else {
+				binary.LittleEndian.PutUint32(b[4*k:], uint32(v))
+			}
+		}
+		body.column(n, 0, nil, b)
+	}
+	floats := func(v []float32) []byte {
+		b := make([]byte, 4*len(v))
+		for k, x := range v {
+			binary.LittleEndian.PutUint32(b[4*k:], math.Float32bits(x))
+		}
+		return b
+	}
+	repeat(8, uint64(f.RefTime.UnixNano()/1000))
+	repeat(8, uint64(f.VerfTime.UnixNano()/1000))
+	repeat(4, uint64(int32(f.VerfTime.Sub(f.RefTime).Hours())))
+	repeat(4, uint64(variable))
+	repeat(4, uint64(level))
+	body.column(n, 0, nil, floats(lats))
+	body.column(n, 0, nil, floats(lons))
+	// missing values are null, and 0 rather than NaN
+	var validity []byte
+	if nulls > 0 {
+		validity = make([]byte, (n+7)/8)
+		for k, v := range values {
+			if math.IsNaN(float64(v)) {
+				values[k] = 0
+			} else {
+				validity[k/8] |= 1 << uint(k%8)
+			}
+		}
+	}
+	body.column(n, nulls, validity, floats(values))
+
+	block, err := w.message(arrowRecordBatch, body.recordBatch(n), body.data)
+	if err != nil {
+		return err
+	}
+	w.recordBlocks = append(w.recordBlocks, block)
+	if w.stream {
+		return w.file.Flush()
+	}
+	return nil
+}
+
+// sendDictionary writes the values of a dictionary that were not written,
+// as a delta of those that were. Files get every dictionary, even an empty
+// one when they hold no batch
+func (w *arrowWriter) sendDictionary(id int64, d *arrowDictionary) error {
+	if d.written == len(d.values) && (w.stream || d.written > 0) {
+		return nil
+	}
+	values := d.values[d.written:]
+	offsets := make([]byte, 0, 4*(len(values)+1))
+	var data []byte
+	offsets = appendUint32(offsets, 0)
+	for _, s := range values {
+		data = append(data, s...)
+		offsets = appendUint32(offsets, uint32(len(data)))
+	}
+	body := &arrowBody{}
+	body.column(len(values), 0, nil, offsets, data)
+
+	batch := &fbTable{}
+	batch.int64(0, id)
+	batch.offset(1, body.recordBatch(len(values)))
+	batch.bool(2, d.written > 0)
+	block, err := w.message(arrowDictionaryBatch, batch, body.data)
+	if err != nil {
+		return err
+	}
+	w.dictBlocks = append(w.dictBlocks, block)
+	d.written = len(d.values)
+	return nil
+}
+
+// Close ends a stream, or writes the footer of a file
+func (w *arrowWriter) Close() error {
+	err := w.closeMessages()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (w *arrowWriter) closeMessages() error {
+	if !w.stream {
+		for id, d := range w.dicts {
+			if err := w.sendDictionary(int64(id), d); err != nil {
+				return err
+			}
+		}
+	}
+	// the end of stream marker
+	if err := w.write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}); err != nil || w.stream {
+		return err
+	}
+
+	footer := &fbTable{}
+	footer.int16(0, arrowV5)
+	footer.offset(1, arrowSchemaTable())
+	footer.offset(2, arrowBlocks(w.dictBlocks))
+	footer.offset(3, arrowBlocks(w.recordBlocks))
+	b := fbFinish(footer)
+	b = appendUint32(b, uint32(len(b)))
+	return w.write(append(b, arrowMagic...))
+}
+
+// message writes an encapsulated message, its metadata padded to 8 bytes
+// and its body
+func (w *arrowWriter) message(typ uint8, header *fbTable, body []byte) (arrowBlock, error) {
+	m := &fbTable{}
+	m.int16(0, arrowV5)
+	m.uint8(1, typ)
+	m.offset(2, header)
+	m.int64(3, int64(len(body)))
+	meta := fbFinish(m)
+
+	block := arrowBlock{offset: w.offset, metaLength: int32(8 + len(meta)), bodyLength: int64(len(body))}
+	b := append([]byte{0xff, 0xff, 0xff, 0xff}, appendUint32(nil, uint32(len(meta)))...)
+	if err := w.write(append(b, meta...)); err != nil {
+		return block, err
+	}
+	return block, w.write(body)
+}
+
+func (w *arrowWriter) write(b []byte) error {
+	n, err := w.file.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+func (d *arrowDictionary) add(s string) int32 {
+	k, ok := d.index[s]
+	if !ok {
+		k = int32(len(d.values))
+		d.values = append(d.values, s)
+		d.index[s] = k
+	}
+	return k
+}
+
+// arrowBody is the body of a record batch, its buffers each padded to 8
+// bytes, and the nodes and buffers locating its columns
+type arrowBody struct {
+	data    []byte
+	nodes   []byte
+	buffers []byte
+	n       int
+}
+
+// column adds a column of n values, its validity bitmap, nil when no value
+// is null, and its other buffers
+func (a *arrowBody) column(n, nulls int, validity []byte, buffers ...[]byte) {
+	a.nodes = appendUint64(appendUint64(a.nodes, uint64(n)), uint64(nulls))
+	for _, b := range append([][]byte{validity}, buffers...) {
+		a.buffers = appendUint64(appendUint64(a.buffers, uint64(len(a.data))), uint64(len(b)))
+		a.data = append(a.data, b...)
+		for len(a.data)%8 != 0 {
+			a.data = append(a.data, 0)
+		}
+		a.n++
+	}
+}
+
+func (a *arrowBody) recordBatch(length int) *fbTable {
+	t := &fbTable{}
+	t.int64(0, int64(length))
+	t.offset(1, fbStructs{len(a.nodes) / 16, a.nodes})
+	t.offset(2, fbStructs{a.n, a.buffers})
+	return t
+}
+
+func arrowBlocks(blocks []arrowBlock) fbStructs {
+	var b []byte
+	for _, block := range blocks {
+		b = appendUint64(b, uint64(block.offset))
+		b = appendUint32(b, uint32(block.metaLength))
+		b = appendUint32(b, 0)
+		b = appendUint64(b, uint64(block.bodyLength))
+	}
+	return fbStructs{len(blocks), b}
+}
+
+// arrowSchemaTable describes the columns, in the order of the batches
+func arrowSchemaTable() *fbTable {
+	timestamp := func() *fbTable {
+		t := &fbTable{}
+		t.int16(0, arrowMicrosecond)
+		t.offset(1, fbString("UTC"))
+		return t
+	}
+	int32Type := func() *fbTable {
+		t := &fbTable{}
+		t.int32(0, 32)
+		t.bool(1, true)
+		return t
+	}
+	float32Type := func() *fbTable {
+		t := &fbTable{}
+		t.int16(0, arrowSingle)
+		return t
+	}
+	field := func(name string, nullable bool, typ uint8, t *fbTable) *fbTable {
+		f := &fbTable{}
+		f.offset(0, fbString(name))
+		f.bool(1, nullable)
+		f.uint8(2, typ)
+		f.offset(3, t)
+		f.offset(5, fbTables{})
+		return f
+	}
+	dictionary := func(name string, id int64) *fbTable {
+		f := field(name, false, arrowUtf8, &fbTable{})
+		d := &fbTable{}
+		d.int64(0, id)
+		d.offset(1, int32Type())
+		d.bool(2, false)
+		f.offset(4, d)
+		return f
+	}
+
+	s := &fbTable{}
+	s.int16(0, 0) // little endian
+	s.offset(1, fbTables{
+		field("ref_time", false, arrowTimestamp, timestamp()),
+		field("valid_time", false, arrowTimestamp, timestamp()),
+		field("fh", false, arrowInt, int32Type()),
+		dictionary("variable", 0),
+		dictionary("level", 1),
+		field("lat", false, arrowFloatingPoint, float32Type()),
+		field("lon", false, arrowFloatingPoint, float32Type()),
+		field("value", true, arrowFloatingPoint, float32Type()),
+	})
+	return s
+}
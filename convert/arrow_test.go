@@ -0,0 +1,545 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// fbRef is a FlatBuffers table read back, its position in b
+type fbRef struct {
+	t   *testing.T
+	b   []byte
+	pos int
+}
+
+func fbRoot(t *testing.T, b []byte) fbRef {
+	return fbRef{t, b, int(binary.LittleEndian.Uint32(b))}
+}
+
+// field is the position of a field, 0 when it is absent
+func (r fbRef) field(id int) int {
+	vtable := r.pos - int(int32(binary.LittleEndian.Uint32(r.b[r.pos:])))
+	if 4+2*id >= int(binary.LittleEndian.Uint16(r.b[vtable:])) {
+		return 0
+	}
+	if off := int(binary.LittleEndian.Uint16(r.b[vtable+4+2*id:])); off != 0 {
+		return r.pos + off
+	}
+	return 0
+}
+
+func (r fbRef) uint8(id int) uint8 {
+	if p := r.field(id); p != 0 {
+		return r.b[p]
+	}
+	return 0
+}
+
+func (r fbRef) int16(id int) int16 {
+	if p := r.field(id); p != 0 {
+		return int16(binary.LittleEndian.Uint16(r.b[p:]))
+	}
+	return 0
+}
+
+func (r fbRef) int32(id int) int32 {
+	if p := r.field(id); p != 0 {
+		return int32(binary.LittleEndian.Uint32(r.b[p:]))
+	}
+	return 0
+}
+
+func (r fbRef) int64(id int) int64 {
+	if p := r.field(id); p != 0 {
+		return int64(binary.LittleEndian.Uint64(r.b[p:]))
+	}
+	return 0
+}
+
+// target follows the offset of a field
+func (r fbRef) target(id int) int {
+	p := r.field(id)
+	if p == 0 {
+		r.t.Fatalf("no field %d in the table at %d", id, r.pos)
+	}
+	return p + int(binary.LittleEndian.Uint32(r.b[p:]))
+}
+
+func (r fbRef) table(id int) fbRef {
+	return fbRef{r.t, r.b, r.target(id)}
+}
+
+func (r fbRef) string(id int) string {
+	p := r.target(id)
+	n := int(binary.LittleEndian.Uint32(r.b[p:]))
+	if r.b[p+4+n] != 0 {
+		r.t.Errorf("string at %d is not terminated", p)
+	}
+	return string(r.b[p+4 : p+4+n])
+}
+
+// tables reads a vector of tables
+func (r fbRef) tables(id int) []fbRef {
+	p := r.target(id)
+	tables := make([]fbRef, binary.LittleEndian.Uint32(r.b[p:]))
+	for k := range tables {
+		slot := p + 4 + 4*k
+		tables[k] = fbRef{r.t, r.b, slot + int(binary.LittleEndian.Uint32(r.b[slot:]))}
+	}
+	return tables
+}
+
+// structs reads a vector of structs of 8 byte fields
+func (r fbRef) structs(id, size int) [][]int64 {
+	p := r.target(id)
+	if (p+4)%8 != 0 {
+		r.t.Errorf("structs at %d are not aligned", p+4)
+	}
+	structs := make([][]int64, binary.LittleEndian.Uint32(r.b[p:]))
+	for k := range structs {
+		for f := 0; f < size; f += 8 {
+			structs[k] = append(structs[k], int64(binary.LittleEndian.Uint64(r.b[p+4+size*k+f:])))
+		}
+	}
+	return structs
+}
+
+// arrowMessage is an encapsulated message read back
+type arrowMessage struct {
+	typ    uint8
+	header fbRef
+	body   []byte
+	// length is the length of the message with its body
+	length int
+}
+
+func readArrowMessage(t *testing.T, b []byte) arrowMessage {
+	if !bytes.Equal(b[:4], []byte{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatalf("continuation % x", b[:4])
+	}
+	n := int(binary.LittleEndian.Uint32(b[4:]))
+	if n == 0 || n%8 != 0 {
+		t.Fatalf("metadata of %d octets", n)
+	}
+	m := fbRoot(t, b[8:8+n])
+	if m.int16(0) != arrowV5 {
+		t.Errorf("version %d", m.int16(0))
+	}
+	length := int(m.int64(3))
+	if length%8 != 0 {
+		t.Errorf("body of %d octets", length)
+	}
+	return arrowMessage{m.uint8(1), m.table(2), b[8+n : 8+n+length], 8 + n + length}
+}
+
+// buffer is the buffer k of a record batch
+func (m arrowMessage) buffer(k int) []byte {
+	buffer := m.header.structs(2, 16)[k]
+	return m.body[buffer[0] : buffer[0]+buffer[1]]
+}
+
+// strings decodes the dictionary of a dictionary batch
+func (m arrowMessage) strings() []string {
+	offsets := m.buffer(1)
+	data := m.buffer(2)
+	var s []string
+	for k := 4; k < len(offsets); k += 4 {
+		s = append(s, string(data[binary.LittleEndian.Uint32(offsets[k-4:]):binary.LittleEndian.Uint32(offsets[k:])]))
+	}
+	return s
+}
+
+// checkArrowSchema compares a schema with the columns of the Parquet long
+// format
+func checkArrowSchema(t *testing.T, s fbRef) {
+	if s.int16(0) != 0 {
+		t.Errorf("endianness %d", s.int16(0))
+	}
+	want := []struct {
+		name     string
+		nullable bool
+		typ      uint8
+		dict     int64
+	}{
+		{"ref_time", false, arrowTimestamp, -1},
+		{"valid_time", false, arrowTimestamp, -1},
+		{"fh", false, arrowInt, -1},
+		{"variable", false, arrowUtf8, 0},
+		{"level", false, arrowUtf8, 1},
+		{"lat", false, arrowFloatingPoint, -1},
+		{"lon", false, arrowFloatingPoint, -1},
+		{"value", true, arrowFloatingPoint, -1},
+	}
+	fields := s.tables(1)
+	if len(fields) != len(want) {
+		t.Fatalf("%d fields", len(fields))
+	}
+	for k, w := range want {
+		f := fields[k]
+		if f.string(0) != w.name || (f.uint8(1) == 1) != w.nullable || f.uint8(2) != w.typ || len(f.tables(5)) != 0 {
+			t.Errorf("field %d: %q, nullable %d, type %d", k, f.string(0), f.uint8(1), f.uint8(2))
+		}
+		typ := f.table(3)
+		switch w.typ {
+		case arrowTimestamp:
+			if typ.int16(0) != arrowMicrosecond || typ.string(1) != "UTC" {
+				t.Errorf("field %d: unit %d, time zone %q", k, typ.int16(0), typ.string(1))
+			}
+		case arrowInt:
+			if typ.int32(0) != 32 || typ.uint8(1) != 1 {
+				t.Errorf("field %d: %d bits, signed %d", k, typ.int32(0), typ.uint8(1))
+			}
+		case arrowFloatingPoint:
+			if typ.int16(0) != arrowSingle {
+				t.Errorf("field %d: precision %d", k, typ.int16(0))
+			}
+		}
+		if w.dict < 0 {
+			if f.field(4) != 0 {
+				t.Errorf("field %d is dictionary encoded", k)
+			}
+			continue
+		}
+		d := f.table(4)
+		index := d.table(1)
+		if d.int64(0) != w.dict || index.int32(0) != 32 || index.uint8(1) != 1 || d.uint8(2) != 0 {
+			t.Errorf("field %d: dictionary %d of int%d", k, d.int64(0), index.int32(0))
+		}
+	}
+}
+
+// checkArrowBatch compares a record batch with the points of a field
+func checkArrowBatch(t *testing.T, m arrowMessage, f *gfs.Field, opts Options, variable, level int32) {
+	var lats, lons, values []float32
+	eachPoint(f, opts, func(i, j int, lat, lon float64, v float32) error {
+		lats = append(lats, float32(lat))
+		lons = append(lons, float32(lon))
+		values = append(values, v)
+		return nil
+	})
+	n := len(values)
+	if m.typ != arrowRecordBatch || m.header.int64(0) != int64(n) {
+		t.Fatalf("message %d of %d rows, want %d", m.typ, m.header.int64(0), n)
+	}
+	nodes := m.header.structs(1, 16)
+	if len(nodes) != 8 || len(m.header.structs(2, 16)) != 16 {
+		t.Fatalf("%d nodes", len(nodes))
+	}
+	nulls := 0
+	for _, v := range values {
+		if math.IsNaN(float64(v)) {
+			nulls++
+		}
+	}
+	for k, node := range nodes {
+		if node[0] != int64(n) || (k < 7 && node[1] != 0) || (k == 7 && node[1] != int64(nulls)) {
+			t.Errorf("node %d: %v", k, node)
+		}
+	}
+
+	column := func(k, size int) []byte {
+		b := m.buffer(2*k + 1)
+		if len(b) != size*n {
+			t.Fatalf("column %d of %d octets", k, len(b))
+		}
+		return b
+	}
+	ref := column(0, 8)
+	valid := column(1, 8)
+	fh := column(2, 4)
+	for r := 0; r < n; r++ {
+		if int64(binary.LittleEndian.Uint64(ref[8*r:])) != f.RefTime.UnixNano()/1000 ||
+			int64(binary.LittleEndian.Uint64(valid[8*r:])) != f.VerfTime.UnixNano()/1000 ||
+			int32(binary.LittleEndian.Uint32(fh[4*r:])) != int32(f.VerfTime.Sub(f.RefTime).Hours()) {
+			t.Fatalf("row %d: times %x, %x, %x", r, ref[8*r:8*r+8], valid[8*r:8*r+8], fh[4*r:4*r+4])
+		}
+	}
+	for k, want := range []int32{variable, level} {
+		b := column(3+k, 4)
+		for r := 0; r < n; r++ {
+			if got := int32(binary.LittleEndian.Uint32(b[4*r:])); got != want {
+				t.Fatalf("column %d, row %d: index %d, want %d", 3+k, r, got, want)
+			}
+		}
+	}
+
+	validity := m.buffer(14)
+	if nulls == 0 && len(validity) != 0 {
+		t.Errorf("validity of %d octets without nulls", len(validity))
+	}
+	for k, want := range [][]float32{lats, lons, values} {
+		b := column(5+k, 4)
+		for r, w := range want {
+			got := math.Float32frombits(binary.LittleEndian.Uint32(b[4*r:]))
+			if math.IsNaN(float64(w)) {
+				if got != 0 || validity[r/8]&(1<<uint(r%8)) != 0 {
+					t.Fatalf("column %d, row %d: %v, a valid missing value", 5+k, r, got)
+				}
+				continue
+			}
+			if got != w {
+				t.Fatalf("column %d, row %d: %v, want %v", 5+k, r, got, w)
+			}
+			if k == 2 && nulls > 0 && validity[r/8]&(1<<uint(r%8)) == 0 {
+				t.Fatalf("row %d: %v is null", r, got)
+			}
+		}
+	}
+}
+
+func TestArrowFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Region: region}
+	c := &Converter{
+		Format:  lookupFormat(t, "arrow"),
+		Options: opts,
+		Output:  filepath.Join(dir, "out.arrow"),
+	}
+	convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+	data, err := ioutil.ReadFile(c.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the magic padded to 8 bytes, the footer, its length and the magic
+	if string(data[:8]) != "ARROW1\x00\x00" || string(data[len(data)-6:]) != "ARROW1" {
+		t.Fatalf("magic %q and %q", data[:8], data[len(data)-6:])
+	}
+	length := int(binary.LittleEndian.Uint32(data[len(data)-10:]))
+	start := len(data) - 10 - length
+	if length%8 != 0 || !bytes.Equal(data[start-8:start], []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}) {
+		t.Fatalf("footer of %d octets after % x", length, data[start-8:start])
+	}
+	footer := fbRoot(t, data[start:start+length])
+	if footer.int16(0) != arrowV5 {
+		t.Errorf("version %d", footer.int16(0))
+	}
+	checkArrowSchema(t, footer.table(1))
+
+	schema := readArrowMessage(t, data[8:])
+	if schema.typ != arrowSchema {
+		t.Fatalf("first message %d", schema.typ)
+	}
+	checkArrowSchema(t, schema.header)
+
+	// the blocks locate the messages, which follow each other, the values
+	// of the dictionaries are sent before the batch using them. UGRD and
+	// the level come with the first batch, VGRD as a delta with the second
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	dicts := footer.structs(2, 24)
+	batches := footer.structs(3, 24)
+	if len(dicts) != 3 || len(batches) != len(fields) {
+		t.Fatalf("%d dictionaries, %d record batches", len(dicts), len(batches))
+	}
+	offset := int64(8 + schema.length)
+	for k, block := range [][]int64{dicts[0], dicts[1], batches[0], dicts[2], batches[1]} {
+		m := readArrowMessage(t, data[block[0]:])
+		if block[0] != offset || block[1] != int64(m.length-len(m.body)) || block[2] != int64(len(m.body)) {
+			t.Errorf("block %d: %v", k, block)
+		}
+		offset += int64(m.length)
+	}
+	if offset != int64(start-8) {
+		t.Errorf("the messages end at %d", offset)
+	}
+
+	for g := range fields {
+		checkArrowBatch(t, readArrowMessage(t, data[batches[g][0]:]), &fields[g], opts, int32(g), 0)
+	}
+	want := []struct {
+		id     int64
+		delta  bool
+		values []string
+	}{
+		{0, false, []string{fields[0].Name}},
+		{1, false, []string{fields[0].Level}},
+		{0, true, []string{fields[1].Name}},
+	}
+	for k, block := range dicts {
+		m := readArrowMessage(t, data[block[0]:])
+		w := want[k]
+		if m.typ != arrowDictionaryBatch || m.header.int64(0) != w.id || (m.header.uint8(2) == 1) != w.delta {
+			t.Fatalf("dictionary batch %d: message %d, id %d, delta %d", k, m.typ, m.header.int64(0), m.header.uint8(2))
+		}
+		batch := arrowMessage{body: m.body, header: m.header.table(1)}
+		if got := batch.strings(); len(got) != len(w.values) || got[0] != w.values[0] {
+			t.Errorf("dictionary batch %d: %q, want %q", k, got, w.values)
+		}
+	}
+}
+
+// TestArrowReader reads the file and the stream back with the Arrow
+// library, which checks their layout against the specification
+func TestArrowReader(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	for _, format := range []string{"arrow", "arrow-stream"} {
+		c := &Converter{
+			Format:  lookupFormat(t, format),
+			Options: Options{Region: region},
+			Output:  filepath.Join(dir, "out."+format),
+		}
+		convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+		f, err := os.Open(c.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var batches []arrow.RecordBatch
+		if format == "arrow" {
+			r, err := ipc.NewFileReader(f)
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			for k := 0; k < r.NumRecords(); k++ {
+				batch, err := r.RecordBatchAt(k)
+				if err != nil {
+					t.Fatalf("%s: batch %d: %v", format, k, err)
+				}
+				batches = append(batches, batch)
+			}
+			r.Close()
+		} else {
+			r, err := ipc.NewReader(f)
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			for r.Next() {
+				batch := r.RecordBatch()
+				batch.Retain()
+				batches = append(batches, batch)
+			}
+			if r.Err() != nil {
+				t.Fatalf("%s: %v", format, r.Err())
+			}
+			r.Release()
+		}
+		f.Close()
+
+		if len(batches) != len(fields) {
+			t.Fatalf("%s: %d batches", format, len(batches))
+		}
+		for k, batch := range batches {
+			schema := batch.Schema()
+			if schema.NumFields() != 8 || schema.Field(3).Name != "variable" || schema.Field(7).Name != "value" {
+				t.Fatalf("%s: schema %v", format, schema)
+			}
+			// the points of the region, every other quarter degree
+			if batch.NumRows() != 45 {
+				t.Errorf("%s batch %d: %d rows", format, k, batch.NumRows())
+			}
+			variable := batch.Column(3).(*array.Dictionary)
+			level := batch.Column(4).(*array.Dictionary)
+			name := variable.Dictionary().(*array.String).Value(variable.GetValueIndex(0))
+			lev := level.Dictionary().(*array.String).Value(level.GetValueIndex(0))
+			if name != fields[k].Name || lev != fields[k].Level {
+				t.Errorf("%s batch %d: %s at %s, want %s at %s", format, k, name, lev, fields[k].Name, fields[k].Level)
+			}
+			lats := batch.Column(5).(*array.Float32)
+			lons := batch.Column(6).(*array.Float32)
+			values := batch.Column(7).(*array.Float32)
+			for r := 0; r < int(batch.NumRows()); r++ {
+				want, ok := fields[k].Nearest(float64(lats.Value(r)), float64(lons.Value(r)))
+				if !ok || values.Value(r) != want {
+					t.Errorf("%s batch %d row %d: %v, want %v", format, k, r, values.Value(r), want)
+					break
+				}
+			}
+			batch.Release()
+		}
+	}
+}
+
+func TestArrowStream(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	c := &Converter{
+		Format: lookupFormat(t, "arrow-stream"),
+		Output: filepath.Join(dir, "out.arrows"),
+	}
+	convert(t, c, "complex.grb2")
+	data, err := ioutil.ReadFile(c.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no magic, the dictionaries before the batches using them, new
+	// variables as deltas and the end of stream marker
+	fields := readFields(t, "complex.grb2")
+	var batches []arrowMessage
+	var types []uint8
+	variables := map[string]int32{}
+	levels := map[string]int32{}
+	offset := 0
+	for offset < len(data)-8 {
+		m := readArrowMessage(t, data[offset:])
+		offset += m.length
+		types = append(types, m.typ)
+		switch m.typ {
+		case arrowSchema:
+			checkArrowSchema(t, m.header)
+		case arrowDictionaryBatch:
+			dict := variables
+			if m.header.int64(0) == 1 {
+				dict = levels
+			}
+			if (m.header.uint8(2) == 1) != (len(dict) > 0) {
+				t.Errorf("dictionary %d: delta %d after %d values", m.header.int64(0), m.header.uint8(2), len(dict))
+			}
+			for _, s := range (arrowMessage{body: m.body, header: m.header.table(1)}).strings() {
+				dict[s] = int32(len(dict))
+			}
+		case arrowRecordBatch:
+			f := &fields[len(batches)]
+			variable, ok := variables[f.Name]
+			level, ok2 := levels[f.Level]
+			if !ok || !ok2 {
+				t.Fatalf("batch %d before the dictionary of %q at %q", len(batches), f.Name, f.Level)
+			}
+			checkArrowBatch(t, m, f, Options{}, variable, level)
+			batches = append(batches, m)
+		}
+	}
+	if types[0] != arrowSchema || len(batches) != len(fields) {
+		t.Errorf("messages %v", types)
+	}
+	if offset != len(data)-8 || !bytes.Equal(data[offset:], []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}) {
+		t.Errorf("stream ends with % x at %d", data[offset:], offset)
+	}
+
+	// the fourth and fifth fields have missing values
+	for g, nulls := range []int64{0, 0, 0, 690, 1271} {
+		if got := batches[g].header.structs(1, 16)[7][1]; got != nulls {
+			t.Errorf("batch %d: %d nulls, want %d", g, got, nulls)
+		}
+	}
+}
+
+func TestArrowGzip(t *testing.T) {
+	if _, err := createArrowFile(filepath.Join("testdata", "unused.arrow"), Options{Gzip: true}); err == nil {
+		t.Error("gzip accepted")
+	}
+}
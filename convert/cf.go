@@ -0,0 +1,114 @@
+package convert
+
+import (
+	"strconv"
+	"strings"
+)
+
+// standardNames are the CF standard names of the NCEP variables that have
+// one, other variables only get a long_name
+var standardNames = map[string]string{
+	"ABSV":  "atmosphere_absolute_vorticity",
+	"ALBDO": "surface_albedo",
+	"APCP":  "precipitation_amount",
+	"CAPE":  "atmosphere_convective_available_potential_energy",
+	"CIN":   "atmosphere_convective_inhibition",
+	"CLWMR": "mass_fraction_of_cloud_liquid_water_in_air",
+	"DLWRF": "surface_downwelling_longwave_flux_in_air",
+	"DPT":   "dew_point_temperature",
+	"DSWRF": "surface_downwelling_shortwave_flux_in_air",
+	"GUST":  "wind_speed_of_gust",
+	"HGT":   "geopotential_height",
+	"HPBL":  "atmosphere_boundary_layer_thickness",
+	"ICEC":  "sea_ice_area_fraction",
+	"LAND":  "land_binary_mask",
+	"LHTFL": "surface_upward_latent_heat_flux",
+	"MSLET": "air_pressure_at_mean_sea_level",
+	"O3MR":  "mass_fraction_of_ozone_in_air",
+	"POT":   "air_potential_temperature",
+	"PRATE": "precipitation_flux",
+	"PRES":  "air_pressure",
+	"PRMSL": "air_pressure_at_mean_sea_level",
+	"PWAT":  "atmosphere_mass_content_of_water_vapor",
+	"RH":    "relative_humidity",
+	"SHTFL": "surface_upward_sensible_heat_flux",
+	"SNOD":  "surface_snow_thickness",
+	"SPFH":  "specific_humidity",
+	"TCDC":  "cloud_area_fraction",
+	"TMAX":  "air_temperature",
+	"TMIN":  "air_temperature",
+	"TMP":   "air_temperature",
+	"TSOIL": "soil_temperature",
+	"UGRD":  "eastward_wind",
+	"VGRD":  "northward_wind",
+	"VIS":   "visibility_in_air",
+	"VVEL":  "lagrangian_tendency_of_air_pressure",
+	"WEASD": "surface_snow_amount",
+	"WDIR":  "wind_from_direction",
+	"WIND":  "wind_speed",
+}
+
+// cfUnits turns a unit of the parameter tables into a UDUNITS one,
+// dimensionless units are 1
+func cfUnits(unit string) string {
+	switch unit {
+	case "numeric", "proportion", "non-dim", "fraction", "Numeric", "Proportion":
+		return "1"
+	case "unknown", "":
+		return ""
+	}
+	return unit
+}
+
+// levelAxis describes the coordinate of a fixed surface type of code table
+// 4.5, levels are stored with the values of grib2.Surface
+type levelAxis struct {
+	name         string
+	longName     string
+	units        string
+	standardName string
+	positive     string
+}
+
+var levelAxes = map[int]levelAxis{
+	100: {"isobaric", "isobaric surface", "Pa", "air_pressure", "down"},
+	102: {"altitude_above_msl", "specified altitude above mean sea level", "m", "altitude", "up"},
+	103: {"height_above_ground", "specified height above ground", "m", "height", "up"},
+	104: {"sigma", "sigma level", "1", "", "down"},
+	105: {"hybrid", "hybrid level", "1", "", "down"},
+	106: {"depth_below_surface", "depth below land surface", "m", "depth", "down"},
+	107: {"isentropic", "isentropic level", "K", "air_potential_temperature", "up"},
+	108: {"pressure_above_ground", "level at specified pressure difference from ground", "Pa", "", "up"},
+	160: {"depth_below_sea", "depth below sea level", "m", "depth", "down"},
+}
+
+// lookupLevelAxis returns the coordinate of a fixed surface type, unknown
+// types are named by their code
+func lookupLevelAxis(surfaceType int) levelAxis {
+	if a, ok := levelAxes[surfaceType]; ok {
+		return a
+	}
+	code := strconv.Itoa(surfaceType)
+	return levelAxis{name: "level_type_" + code, longName: "fixed surface type " + code}
+}
+
+// variableName makes a name safe for formats that restrict them, letters,
+// digits and underscores are kept, "." becomes "p" and "-" becomes "_"
+func variableName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == '.':
+			b.WriteByte('p')
+		case r == '-':
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
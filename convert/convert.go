@@ -0,0 +1,356 @@
+/*
+Package convert writes decoded GRIB2 fields to other file formats.
+
+Fields are read from GRIB2 files one message at a time and handed to the
+Writer of a Format, so a file is never held in memory as a whole.
+*/
+package convert
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/azillion/nimbus/gfs"
+	"github.com/azillion/nimbus/grib2"
+)
+
+// Options change what is written
+type Options struct {
+	// Region, when set, leaves out the points outside it
+	Region *gfs.Region
+	// SkipMissing leaves out points without a value
+	SkipMissing bool
+	// Stride decimates the points, only every Stride-th point along each
+	// axis of the grid is written when it is above 1. Gridded formats
+	// count from the first row and column of the region
+	Stride int
+	// Cells writes grid cells as polygons rather than points, for formats
+	// with geometries
+	Cells bool
+	// PerMessage writes a record per field holding the values of all its
+	// points rather than a record per point, for formats of records
+	PerMessage bool
+	// Gzip compresses the output of text formats
+	Gzip bool
+	// Compression is the compressor of the chunks of array stores and its
+	// level, like zlib:5, or none
+	Compression string
+	// Chunks are the chunk sizes of array stores along time, rows and
+	// columns, sizes that are not given or 0 are the defaults of the format
+	Chunks []int
+	// Stack writes fields of several valid times to one output along its
+	// time dimension. Otherwise formats with a time dimension write one
+	// output per valid time when there are several, with the time added to
+	// the name: out.nc becomes out.2024010200.nc, out.2024010203.nc, ...
+	Stack bool
+	// Encode packs the fields of GRIB2 outputs
+	Encode grib2.EncodeOptions
+}
+
+// Writer writes fields to one output
+type Writer interface {
+	WriteField(f *gfs.Field) error
+	// Close finishes the output, it must be called for it to be complete
+	Close() error
+}
+
+// Format is an output format
+type Format struct {
+	Name string
+	// Extension is added to the names of the outputs
+	Extension string
+	// Compresses is set for formats that compress their own data for
+	// Gzip, their outputs keep the extension
+	Compresses bool
+	// Output names the outputs of formats that hold a single field, with
+	// the placeholders of Converter.Output
+	Output string
+	// Stream is set for formats written to stdout when no output is given
+	Stream bool
+	// Create starts a new output at path
+	Create func(path string, opts Options) (Writer, error)
+}
+
+var formats = map[string]*Format{
+	"arrow":        {Name: "arrow", Extension: ".arrow", Create: createArrowFile},
+	"arrow-stream": {Name: "arrow-stream", Extension: ".arrows", Stream: true, Create: createArrowStream},
+	"csv":          {Name: "csv", Extension: ".csv", Create: createCSV},
+	"geojson":      {Name: "geojson", Extension: ".geojson", Create: createGeoJSON},
+	"geotiff":      {Name: "geotiff", Extension: ".tif", Compresses: true, Output: geoTIFFOutput, Create: createGeoTIFF},
+	"grib2":        {Name: "grib2", Extension: ".grb2", Create: createGRIB2},
+	"ndjson":       {Name: "ndjson", Extension: ".ndjson", Stream: true, Create: createNDJSON},
+	"netcdf":       {Name: "netcdf", Extension: ".nc", Create: createNetCDF},
+	"parquet":      {Name: "parquet", Extension: ".parquet", Compresses: true, Create: createParquet},
+	"sqlite":       {Name: "sqlite", Extension: ".sqlite", Create: createSQLite},
+	"zarr":         {Name: "zarr", Extension: ".zarr", Compresses: true, Create: createZarr},
+}
+
+// LookupFormat returns the format with the name
+func LookupFormat(name string) (*Format, error) {
+	f, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format: %s", name)
+	}
+	return f, nil
+}
+
+// FormatNames returns the names of all formats
+func FormatNames() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Converter writes the fields of GRIB2 files to a single output, or to one
+// output per variable
+type Converter struct {
+	Format  *Format
+	Options Options
+	// Output is the path of the output, or the folder of the outputs when
+	// PerVariable is set. The placeholders {date} and {cycle} are replaced
+	// by the date and hour of the reference time of each field, {variable},
+	// {level} and {fh} by its variable, level and forecast hour, so outputs
+	// can be partitioned like lake/date={date}/cycle={cycle}/gfs.parquet
+	Output      string
+	PerVariable bool
+	// Filter, when set, skips the fields it returns false for
+	Filter func(*grib2.Field) bool
+
+	writers map[string]Writer
+	// order keeps the writers in the order they were created for Close
+	order []string
+}
+
+// ConvertFile writes every field of a GRIB2 file
+func (c *Converter) ConvertFile(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := grib2.NewScanner(f)
+	for s.Scan() {
+		for _, field := range s.Message().Fields {
+			if c.Filter != nil && !c.Filter(field) {
+				continue
+			}
+			decoded, err := gfs.NewField(field)
+			if err != nil {
+				return fmt.Errorf("%s: %v", fileName, err)
+			}
+			w, err := c.writer(decoded)
+			if err != nil {
+				return err
+			}
+			if mw, ok := w.(messageWriter); ok {
+				err = mw.WriteMessageField(field, decoded)
+			} else {
+				err = w.WriteField(decoded)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return s.Err()
+}
+
+// CheckOutputs returns an error, before anything is written, when formats
+// holding a single field would write two fields of the files to one output,
+// such as every field to -o out.tif
+func (c *Converter) CheckOutputs(fileNames ...string) error {
+	if c.Format.Output == "" {
+		return nil
+	}
+	seen := make(map[string]string)
+	for _, fileName := range fileNames {
+		f, err := os.Open(fileName)
+		if err != nil {
+			return err
+		}
+		s := grib2.NewScanner(f)
+		for s.Scan() {
+			for _, field := range s.Message().Fields {
+				if c.Filter != nil && !c.Filter(field) {
+					continue
+				}
+				verfTime, err := field.VerfTime()
+				if err != nil {
+					f.Close()
+					return fmt.Errorf("%s: %v", fileName, err)
+				}
+				// the names of outputs only need the metadata of fields
+				meta := &gfs.Field{RefTime: field.RefTime(), VerfTime: verfTime, Name: field.Parameter().Name, Level: field.Level()}
+				path := c.outputPath(meta)
+				name := meta.Name + " " + meta.Level + " " + field.ForecastTime()
+				if other, ok := seen[path]; ok {
+					f.Close()
+					return fmt.Errorf("%s holds one field, %s and %s would both be written to it, name the outputs by {variable}, {level} and {fh} or select one field", path, other, name)
+				}
+				seen[path] = name
+			}
+		}
+		f.Close()
+		if err := s.Err(); err != nil {
+			return fmt.Errorf("%s: %v", fileName, err)
+		}
+	}
+	return nil
+}
+
+// outputPath is the path of the output of a field
+func (c *Converter) outputPath(f *gfs.Field) string {
+	path := outputPath(c.Output, f)
+	if c.PerVariable {
+		name := f.Name
+		if c.Format.Output != "" {
+			name = outputPath(c.Format.Output, f)
+		}
+		path = filepath.Join(path, name+c.extension())
+	}
+	return path
+}
+
+// writer returns the output a field is written to, creating it on first use
+func (c *Converter) writer(f *gfs.Field) (Writer, error) {
+	path := c.outputPath(f)
+	if w, ok := c.writers[path]; ok {
+		return w, nil
+	}
+
+	if path != "-" {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	w, err := c.Format.Create(path, c.Options)
+	if err != nil {
+		return nil, err
+	}
+	if c.writers == nil {
+		c.writers = make(map[string]Writer)
+	}
+	c.writers[path] = w
+	c.order = append(c.order, path)
+	return w, nil
+}
+
+// outputPath replaces the placeholders of an output path for a field
+func outputPath(path string, f *gfs.Field) string {
+	if !strings.Contains(path, "{") {
+		return path
+	}
+	t := f.RefTime.UTC()
+	return strings.NewReplacer(
+		"{date}", t.Format("20060102"),
+		"{cycle}", t.Format("15"),
+		"{variable}", f.Name,
+		"{level}", strings.NewReplacer(" ", "_", "/", "_").Replace(f.Level),
+		"{fh}", fmt.Sprintf("%03d", int(f.VerfTime.Sub(f.RefTime).Hours())),
+	).Replace(path)
+}
+
+// extension is the extension of the outputs, including compression
+func (c *Converter) extension() string {
+	if c.Options.Gzip && !c.Format.Compresses {
+		return c.Format.Extension + ".gz"
+	}
+	return c.Format.Extension
+}
+
+// DefaultOutput is the output used when none is given, a file in the
+// working directory, stdout for formats streamed to it, files named after
+// their field for formats holding one, or the working directory itself for
+// PerVariable
+func (c *Converter) DefaultOutput() string {
+	if c.PerVariable {
+		return "."
+	}
+	if c.Format.Stream {
+		return "-"
+	}
+	if c.Format.Output != "" {
+		return c.Format.Output + c.extension()
+	}
+	return "nimbus" + c.extension()
+}
+
+// Close finishes every output and returns the first error
+func (c *Converter) Close() error {
+	var first error
+	for _, path := range c.order {
+		err := c.writers[path].Close()
+		if err != nil && first == nil {
+			first = fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return first
+}
+
+// eachPoint calls fn with every point of a field that is in the region of the
+// options and kept by their stride, in the order the grid is scanned
+func eachPoint(f *gfs.Field, opts Options, fn func(i, j int, lat, lon float64, v float32) error) error {
+	for n, v := range f.Values {
+		if opts.SkipMissing && math.IsNaN(float64(v)) {
+			continue
+		}
+		i, j := f.Grid.Point(n)
+		if opts.Stride > 1 && (i%opts.Stride != 0 || j%opts.Stride != 0) {
+			continue
+		}
+		lat, lon := f.Grid.LatLon(i, j)
+		if opts.Region != nil && !opts.Region.Contains(lat, lon) {
+			continue
+		}
+		if err := fn(i, j, lat, lon, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Select returns a filter of the fields of some variables, levels and
+// forecast hours, empty lists select everything. Forecast hours are the
+// hours from the reference time to the valid time
+func Select(variables, levels []string, hours []int) func(*grib2.Field) bool {
+	return func(f *grib2.Field) bool {
+		if len(variables) > 0 && !containsString(variables, f.Parameter().Name) {
+			return false
+		}
+		if len(levels) > 0 && !containsString(levels, f.Level()) {
+			return false
+		}
+		if len(hours) > 0 {
+			verfTime, err := f.VerfTime()
+			if err != nil {
+				return false
+			}
+			hour := verfTime.Sub(f.RefTime()).Hours()
+			for _, h := range hours {
+				if float64(h) == hour {
+					return true
+				}
+			}
+			return false
+		}
+		return true
+	}
+}
+
+func containsString(s []string, v string) bool {
+	for _, x := range s {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
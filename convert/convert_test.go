@@ -0,0 +1,57 @@
+package convert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// fixture is the path of a GRIB2 file in the testdata of the grib2 package
+func fixture(name string) string {
+	return filepath.Join("..", "grib2", "testdata", name)
+}
+
+// tempDir creates a directory and returns it with a function removing it
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// convert writes the fields of GRIB2 files in testdata with a format
+func convert(t *testing.T, c *Converter, names ...string) {
+	for _, name := range names {
+		if err := c.ConvertFile(fixture(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lookupFormat(t *testing.T, name string) *Format {
+	f, err := LookupFormat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readFields decodes a GRIB2 file in testdata
+func readFields(t *testing.T, name string) []gfs.Field {
+	data, err := ioutil.ReadFile(fixture(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := gfs.ReadFields(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
@@ -0,0 +1,67 @@
+package convert
+
+import (
+	"encoding/csv"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// csvHeader are the columns of the long format, one row per grid point
+var csvHeader = []string{"ref_time", "valid_time", "variable", "level", "lat", "lon", "value"}
+
+// csvWriter writes fields as rows of a CSV file, rows are written as the
+// points are visited so only one field is held in memory. Missing values are
+// empty
+type csvWriter struct {
+	file *textFile
+	csv  *csv.Writer
+	opts Options
+	row  []string
+}
+
+func createCSV(path string, opts Options) (Writer, error) {
+	file, err := createTextFile(path, opts.Gzip)
+	if err != nil {
+		return nil, err
+	}
+	w := &csvWriter{file: file, csv: csv.NewWriter(file), opts: opts, row: make([]string, len(csvHeader))}
+	err = w.csv.Write(csvHeader)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *csvWriter) WriteField(f *gfs.Field) error {
+	w.row[0] = f.RefTime.Format(time.RFC3339)
+	w.row[1] = f.VerfTime.Format(time.RFC3339)
+	w.row[2] = f.Name
+	w.row[3] = f.Level
+	err := eachPoint(f, w.opts, func(i, j int, lat, lon float64, v float32) error {
+		w.row[4] = strconv.FormatFloat(lat, 'f', -1, 64)
+		w.row[5] = strconv.FormatFloat(lon, 'f', -1, 64)
+		w.row[6] = ""
+		if !math.IsNaN(float64(v)) {
+			w.row[6] = strconv.FormatFloat(float64(v), 'g', -1, 32)
+		}
+		return w.csv.Write(w.row)
+	})
+	if err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
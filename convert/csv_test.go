@@ -0,0 +1,151 @@
+package convert
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/azillion/nimbus/gfs"
+	"github.com/azillion/nimbus/grib2"
+)
+
+func readCSV(t *testing.T, path string) [][]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = csv.NewReader(gz)
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != "ref_time,valid_time,variable,level,lat,lon,value" {
+		t.Fatalf("%s: header %v", path, rows)
+	}
+	return rows[1:]
+}
+
+func TestCSVRegion(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// the region spans the prime meridian, 359 to 1E
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{
+		Format:  lookupFormat(t, "csv"),
+		Options: Options{Region: region},
+		Output:  filepath.Join(dir, "out.csv"),
+	}
+	convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	rows := readCSV(t, c.Output)
+	// 5 rows of 9 points for each of the 2 fields
+	if len(rows) != 2*5*9 {
+		t.Fatalf("%d rows, want %d", len(rows), 2*5*9)
+	}
+	for k, row := range rows {
+		f := fields[k/45]
+		if row[0] != "2017-07-20T00:00:00Z" || row[1] != "2017-07-20T01:00:00Z" || row[2] != f.Name || row[3] != "10 m above ground" {
+			t.Fatalf("row %d: %v", k, row)
+		}
+		lat, err1 := strconv.ParseFloat(row[4], 64)
+		lon, err2 := strconv.ParseFloat(row[5], 64)
+		v, err3 := strconv.ParseFloat(row[6], 32)
+		if err1 != nil || err2 != nil || err3 != nil {
+			t.Fatalf("row %d: %v", k, row)
+		}
+		if lat < 36 || lat > 37 || lon > 1 && lon < 359 {
+			t.Errorf("row %d at %v, %v is outside the region", k, lat, lon)
+		}
+		if want, ok := f.Nearest(lat, lon); !ok || float32(v) != want {
+			t.Errorf("row %d: %s at %v, %v = %v, want %v", k, f.Name, lat, lon, v, want)
+		}
+	}
+	// the grid is scanned northwards from 350E
+	if rows[0][4] != "36" || rows[0][5] != "359" || rows[44][4] != "37" || rows[44][5] != "1" {
+		t.Errorf("first row %v, last row of the field %v", rows[0], rows[44])
+	}
+}
+
+func TestCSVPerVariable(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	c := &Converter{
+		Format:      lookupFormat(t, "csv"),
+		Options:     Options{Gzip: true},
+		Output:      dir,
+		PerVariable: true,
+	}
+	convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	for _, f := range fields {
+		rows := readCSV(t, filepath.Join(dir, f.Name+".csv.gz"))
+		if len(rows) != len(f.Values) {
+			t.Fatalf("%s: %d rows, want %d", f.Name, len(rows), len(f.Values))
+		}
+		for n, row := range rows {
+			if row[2] != f.Name {
+				t.Fatalf("%s: row %d of %s", f.Name, n, row[2])
+			}
+			if v, _ := strconv.ParseFloat(row[6], 32); float32(v) != f.Values[n] {
+				t.Fatalf("%s: row %d value %s, want %v", f.Name, n, row[6], f.Values[n])
+			}
+		}
+	}
+}
+
+func TestCSVMissing(t *testing.T) {
+	// the fourth field of complex.grb2 has 690 missing values
+	for _, skip := range []bool{false, true} {
+		dir, cleanup := tempDir(t)
+		c := &Converter{
+			Format:  lookupFormat(t, "csv"),
+			Options: Options{SkipMissing: skip},
+			Output:  filepath.Join(dir, "out.csv"),
+			Filter: func() func(*grib2.Field) bool {
+				n := 0
+				return func(*grib2.Field) bool {
+					n++
+					return n == 4
+				}
+			}(),
+		}
+		convert(t, c, "complex.grb2")
+		rows := readCSV(t, c.Output)
+		cleanup()
+
+		empty := 0
+		for _, row := range rows {
+			if row[6] == "" {
+				empty++
+			} else if v, err := strconv.ParseFloat(row[6], 64); err != nil || math.IsNaN(v) {
+				t.Errorf("value %q", row[6])
+			}
+		}
+		if want := 117*98 - 690; skip && (len(rows) != want || empty != 0) {
+			t.Errorf("skipping missing values: %d rows, %d empty, want %d", len(rows), empty, want)
+		}
+		if !skip && (len(rows) != 117*98 || empty != 690) {
+			t.Errorf("%d rows, %d empty, want %d and 690", len(rows), empty, 117*98)
+		}
+	}
+}
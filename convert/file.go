@@ -0,0 +1,53 @@
+package convert
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// textFile is a buffered output file, gzip compressed when asked for. The
+// path - is stdout
+type textFile struct {
+	*bufio.Writer
+	file *os.File
+	gz   *gzip.Writer
+}
+
+func createTextFile(path string, compress bool) (*textFile, error) {
+	f := os.Stdout
+	if path != "-" {
+		var err error
+		f, err = os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	t := &textFile{file: f}
+	var w io.Writer = f
+	if compress {
+		t.gz = gzip.NewWriter(f)
+		w = t.gz
+	}
+	t.Writer = bufio.NewWriterSize(w, 64<<10)
+	return t, nil
+}
+
+// Close flushes the buffer and the compressor and closes the file, stdout is
+// left open
+func (t *textFile) Close() error {
+	err := t.Flush()
+	if t.gz != nil {
+		if gzErr := t.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if t.file == os.Stdout {
+		return err
+	}
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
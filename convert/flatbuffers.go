@@ -0,0 +1,160 @@
+package convert
+
+import "encoding/binary"
+
+// fbTable is a FlatBuffers table, the encoding of the metadata of Arrow IPC
+// messages. Tables are built as trees and laid out front to back when the
+// buffer is finished: each table follows its vtable and the tables, strings
+// and vectors it refers to follow it, so every offset points forward
+type fbTable struct {
+	fields []fbField
+}
+
+type fbField struct {
+	id int
+	// inline holds scalars and structs, aligned to align
+	inline []byte
+	align  int
+	// child is the object of an offset field
+	child fbObject
+}
+
+// fbObject is what an offset can point to, write lays it out at the end of
+// the buffer and returns where the offset has to point
+type fbObject interface {
+	write(b *fbBuffer) int
+}
+
+// fbString is a string
+type fbString string
+
+// fbTables is a vector of tables
+type fbTables []*fbTable
+
+// fbStructs is a vector of n structs of 8 byte fields
+type fbStructs struct {
+	n    int
+	data []byte
+}
+
+func (t *fbTable) scalar(id int, v []byte) {
+	t.fields = append(t.fields, fbField{id: id, inline: v, align: len(v)})
+}
+
+func (t *fbTable) bool(id int, v bool) {
+	if v {
+		t.scalar(id, []byte{1})
+	} else {
+		t.scalar(id, []byte{0})
+	}
+}
+
+func (t *fbTable) uint8(id int, v uint8) {
+	t.scalar(id, []byte{v})
+}
+
+func (t *fbTable) int16(id int, v int16) {
+	t.scalar(id, []byte{byte(v), byte(v >> 8)})
+}
+
+func (t *fbTable) int32(id int, v int32) {
+	t.scalar(id, appendUint32(nil, uint32(v)))
+}
+
+func (t *fbTable) int64(id int, v int64) {
+	t.scalar(id, appendUint64(nil, uint64(v)))
+}
+
+func (t *fbTable) offset(id int, child fbObject) {
+	t.fields = append(t.fields, fbField{id: id, child: child})
+}
+
+// fbFinish lays out a buffer with root as its root table, padded to 8 bytes
+func fbFinish(root *fbTable) []byte {
+	b := &fbBuffer{buf: make([]byte, 4)}
+	pos := root.write(b)
+	binary.LittleEndian.PutUint32(b.buf, uint32(pos))
+	b.pad(8, 0)
+	return b.buf
+}
+
+type fbBuffer struct {
+	buf []byte
+}
+
+// pad appends zeros until the position after the next n bytes is aligned
+func (b *fbBuffer) pad(align, n int) {
+	for (len(b.buf)+n)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (t *fbTable) write(b *fbBuffer) int {
+	n := 0
+	for _, f := range t.fields {
+		if f.id >= n {
+			n = f.id + 1
+		}
+	}
+	b.pad(2, 0)
+	vtable := len(b.buf)
+	b.buf = append(b.buf, make([]byte, 4+2*n)...)
+
+	b.pad(4, 0)
+	start := len(b.buf)
+	b.buf = append(b.buf, 0, 0, 0, 0)
+	slots := make([]int, len(t.fields))
+	for k, f := range t.fields {
+		if f.child != nil {
+			b.pad(4, 0)
+			slots[k] = len(b.buf)
+			b.buf = append(b.buf, 0, 0, 0, 0)
+		} else {
+			b.pad(f.align, 0)
+			slots[k] = len(b.buf)
+			b.buf = append(b.buf, f.inline...)
+		}
+		binary.LittleEndian.PutUint16(b.buf[vtable+4+2*f.id:], uint16(slots[k]-start))
+	}
+	binary.LittleEndian.PutUint16(b.buf[vtable:], uint16(4+2*n))
+	binary.LittleEndian.PutUint16(b.buf[vtable+2:], uint16(len(b.buf)-start))
+	binary.LittleEndian.PutUint32(b.buf[start:], uint32(start-vtable))
+
+	for k, f := range t.fields {
+		if f.child != nil {
+			pos := f.child.write(b)
+			binary.LittleEndian.PutUint32(b.buf[slots[k]:], uint32(pos-slots[k]))
+		}
+	}
+	return start
+}
+
+func (s fbString) write(b *fbBuffer) int {
+	b.pad(4, 0)
+	pos := len(b.buf)
+	b.buf = appendUint32(b.buf, uint32(len(s)))
+	b.buf = append(append(b.buf, s...), 0)
+	return pos
+}
+
+func (v fbTables) write(b *fbBuffer) int {
+	b.pad(4, 0)
+	pos := len(b.buf)
+	b.buf = appendUint32(b.buf, uint32(len(v)))
+	b.buf = append(b.buf, make([]byte, 4*len(v))...)
+	for k, t := range v {
+		slot := pos + 4 + 4*k
+		child := t.write(b)
+		binary.LittleEndian.PutUint32(b.buf[slot:], uint32(child-slot))
+	}
+	return pos
+}
+
+func (v fbStructs) write(b *fbBuffer) int {
+	// the structs are aligned, the length before them
+	b.pad(8, 4)
+	pos := len(b.buf)
+	b.buf = appendUint32(b.buf, uint32(v.n))
+	b.buf = append(b.buf, v.data...)
+	return pos
+}
@@ -0,0 +1,149 @@
+package convert
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// geoJSONWriter writes fields as a GeoJSON FeatureCollection, one point or
+// grid cell polygon per grid point. Features are written as the points are
+// visited, the collection is closed by Close. Longitudes are in [-180, 180)
+// as RFC 7946 asks
+type geoJSONWriter struct {
+	file  *textFile
+	opts  Options
+	first bool
+	buf   []byte
+}
+
+func createGeoJSON(path string, opts Options) (Writer, error) {
+	file, err := createTextFile(path, opts.Gzip)
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.WriteString(`{"type":"FeatureCollection","features":[`)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &geoJSONWriter{file: file, opts: opts, first: true}, nil
+}
+
+func (w *geoJSONWriter) WriteField(f *gfs.Field) error {
+	// the properties every feature of the field shares, ahead of its value
+	shared, err := json.Marshal(map[string]string{
+		"variable":   f.Name,
+		"level":      f.Level,
+		"valid_time": f.VerfTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	shared = shared[:len(shared)-1]
+
+	stride := 1.0
+	if w.opts.Stride > 1 {
+		stride = float64(w.opts.Stride)
+	}
+	return eachPoint(f, w.opts, func(i, j int, lat, lon float64, v float32) error {
+		b := w.buf[:0]
+		if !w.first {
+			b = append(b, ',')
+		}
+		w.first = false
+
+		b = append(b, `{"type":"Feature","geometry":`...)
+		if w.opts.Cells {
+			b = appendCell(b, f, float64(i), float64(j), stride, lon)
+		} else {
+			b = append(b, `{"type":"Point","coordinates":`...)
+			b = appendPosition(b, lat, lon)
+			b = append(b, '}')
+		}
+		b = append(b, `,"properties":`...)
+		b = append(b, shared...)
+		b = append(b, `,"value":`...)
+		b = appendJSONValue(b, v)
+		b = append(b, "}}"...)
+		w.buf = b
+		_, err := w.file.Write(b)
+		return err
+	})
+}
+
+// appendCell appends the polygon of the cell around point (i, j), its corners
+// are halfway to the next points. The corners are kept within 180 degrees of
+// the point so cells across the antimeridian do not wrap around the globe
+func appendCell(b []byte, f *gfs.Field, i, j, size, lon float64) []byte {
+	h := size / 2
+	corners := [4][2]float64{{i - h, j - h}, {i + h, j - h}, {i + h, j + h}, {i - h, j + h}}
+	var ring [5][2]float64
+	center := geoJSONLon(lon)
+	for k, c := range corners {
+		lat, lon := f.Grid.LatLonAt(c[0], c[1])
+		lat = math.Max(-90, math.Min(90, lat))
+		lon = geoJSONLon(lon)
+		if lon-center > 180 {
+			lon -= 360
+		} else if lon-center < -180 {
+			lon += 360
+		}
+		ring[k] = [2]float64{lon, lat}
+	}
+	ring[4] = ring[0]
+
+	// exterior rings are counterclockwise, the order of the corners depends
+	// on the directions the grid is scanned in
+	area := 0.0
+	for k := 0; k < 4; k++ {
+		area += ring[k][0]*ring[k+1][1] - ring[k+1][0]*ring[k][1]
+	}
+	if area < 0 {
+		ring[1], ring[3] = ring[3], ring[1]
+	}
+
+	b = append(b, `{"type":"Polygon","coordinates":[[`...)
+	for k, p := range ring {
+		if k > 0 {
+			b = append(b, ',')
+		}
+		b = appendCoordinates(b, p[0], p[1])
+	}
+	return append(b, "]]}"...)
+}
+
+// appendPosition appends a GeoJSON position, longitude first
+func appendPosition(b []byte, lat, lon float64) []byte {
+	return appendCoordinates(b, geoJSONLon(lon), lat)
+}
+
+// appendCoordinates appends x and y rounded to a micro degree, about 0.1 m
+func appendCoordinates(b []byte, x, y float64) []byte {
+	b = append(b, '[')
+	b = strconv.AppendFloat(b, math.Round(x*1e6)/1e6, 'f', -1, 64)
+	b = append(b, ',')
+	b = strconv.AppendFloat(b, math.Round(y*1e6)/1e6, 'f', -1, 64)
+	return append(b, ']')
+}
+
+// geoJSONLon puts a longitude in [-180, 180)
+func geoJSONLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
+
+func (w *geoJSONWriter) Close() error {
+	_, err := w.file.WriteString("]}\n")
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
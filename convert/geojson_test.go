@@ -0,0 +1,142 @@
+package convert
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+type featureCollection struct {
+	Type     string
+	Features []struct {
+		Type     string
+		Geometry struct {
+			Type        string
+			Coordinates json.RawMessage
+		}
+		Properties struct {
+			Variable  string
+			Level     string
+			ValidTime string `json:"valid_time"`
+			Value     *float64
+		}
+	}
+}
+
+func readGeoJSON(t *testing.T, c *Converter) *featureCollection {
+	f, err := os.Open(c.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var fc featureCollection
+	if err = json.NewDecoder(f).Decode(&fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Type != "FeatureCollection" {
+		t.Fatalf("type %q", fc.Type)
+	}
+	return &fc
+}
+
+func TestGeoJSONPoints(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// the region spans the prime meridian, every other point of the grid
+	// is kept: columns 36 to 44 and rows 2 and 4
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{
+		Format:  lookupFormat(t, "geojson"),
+		Options: Options{Region: region, Stride: 2},
+		Output:  filepath.Join(dir, "out.geojson"),
+	}
+	convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	fc := readGeoJSON(t, c)
+	if len(fc.Features) != 2*2*5 {
+		t.Fatalf("%d features, want %d", len(fc.Features), 2*2*5)
+	}
+	for k, feature := range fc.Features {
+		f := fields[k/10]
+		p := feature.Properties
+		if feature.Type != "Feature" || feature.Geometry.Type != "Point" || p.Variable != f.Name || p.Level != "10 m above ground" || p.ValidTime != "2017-07-20T01:00:00Z" {
+			t.Fatalf("feature %d: %+v", k, feature)
+		}
+		var position [2]float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &position); err != nil {
+			t.Fatal(err)
+		}
+		lon, lat := position[0], position[1]
+		if lon < -1 || lon > 1 || (lat != 36.25 && lat != 36.75) || math.Mod(lon, 0.5) != 0 {
+			t.Errorf("feature %d at %v, %v", k, lon, lat)
+		}
+		if want, ok := f.Nearest(lat, lon); !ok || p.Value == nil || float32(*p.Value) != want {
+			t.Errorf("feature %d: %s at %v, %v = %v, want %v", k, f.Name, lat, lon, p.Value, want)
+		}
+	}
+}
+
+func TestGeoJSONCells(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{
+		Format:  lookupFormat(t, "geojson"),
+		Options: Options{Region: region, Cells: true},
+		Output:  filepath.Join(dir, "out.geojson"),
+	}
+	convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+
+	fc := readGeoJSON(t, c)
+	if len(fc.Features) != 2*5*9 {
+		t.Fatalf("%d features, want %d", len(fc.Features), 2*5*9)
+	}
+	for k, feature := range fc.Features {
+		if feature.Geometry.Type != "Polygon" {
+			t.Fatalf("feature %d: %s", k, feature.Geometry.Type)
+		}
+		var rings [][][2]float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &rings); err != nil {
+			t.Fatal(err)
+		}
+		if len(rings) != 1 || len(rings[0]) != 5 || rings[0][0] != rings[0][4] {
+			t.Fatalf("feature %d: rings %v", k, rings)
+		}
+		// a counterclockwise square of a quarter degree, without a jump
+		// across the antimeridian
+		ring := rings[0]
+		area := 0.0
+		for n := 0; n < 4; n++ {
+			area += ring[n][0]*ring[n+1][1] - ring[n+1][0]*ring[n][1]
+			if d := math.Abs(ring[n+1][0]-ring[n][0]) + math.Abs(ring[n+1][1]-ring[n][1]); math.Abs(d-0.25) > 1e-9 {
+				t.Errorf("feature %d: side %v to %v", k, ring[n], ring[n+1])
+			}
+		}
+		if math.Abs(area/2-0.0625) > 1e-9 {
+			t.Errorf("feature %d: area %v of %v", k, area/2, ring)
+		}
+	}
+}
+
+func TestGeoJSONLon(t *testing.T) {
+	for _, c := range []struct{ lon, want float64 }{
+		{0, 0}, {179.75, 179.75}, {180, -180}, {350, -10}, {359.75, -0.25}, {360, 0}, {-190, 170}, {-180, -180},
+	} {
+		if got := geoJSONLon(c.lon); got != c.want {
+			t.Errorf("geoJSONLon(%v) = %v, want %v", c.lon, got, c.want)
+		}
+	}
+}
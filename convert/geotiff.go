@@ -0,0 +1,294 @@
+package convert
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// geoTIFFOutput names the GeoTIFF files, one per field
+const geoTIFFOutput = "{variable}_{level}_{date}{cycle}_f{fh}"
+
+// TIFF field types and tags, the GeoTIFF ones and those of GDAL
+const (
+	tiffASCII  = 2
+	tiffShort  = 3
+	tiffLong   = 4
+	tiffDouble = 12
+
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPlanarConfig    = 284
+	tagSampleFormat    = 339
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagGeoKeyDirectory = 34735
+	tagGDALMetadata    = 42112
+	tagGDALNoData      = 42113
+
+	tiffCompressionNone = 1
+	tiffDeflate         = 8
+
+	// tiffStripSize is the size strips are kept under, uncompressed
+	tiffStripSize = 64 << 10
+)
+
+// geoKeys are the GeoKeyDirectory of a WGS 84 latitude/longitude raster,
+// EPSG:4326 in degrees with the values covering their cells
+var geoKeys = []uint16{
+	1, 1, 0, 4,
+	1024, 0, 1, 2, // GTModelTypeGeoKey, geographic
+	1025, 0, 1, 1, // GTRasterTypeGeoKey, pixel is area
+	2048, 0, 1, 4326, // GeographicTypeGeoKey, WGS 84
+	2054, 0, 1, 9102, // GeogAngularUnitsGeoKey, degree
+}
+
+// geoTIFFWriter writes a field to a single band float32 GeoTIFF, north up
+// with longitudes in [-180, 180). Missing values are NaN, the NoData value
+// of the file
+type geoTIFFWriter struct {
+	path    string
+	opts    Options
+	written bool
+}
+
+func createGeoTIFF(path string, opts Options) (Writer, error) {
+	return &geoTIFFWriter{path: path, opts: opts}, nil
+}
+
+func (w *geoTIFFWriter) WriteField(f *gfs.Field) error {
+	if w.written {
+		return fmt.Errorf("geotiff: %s holds one field, name the files by {variable}, {level} and {fh}", w.path)
+	}
+	w.written = true
+	if !f.Grid.Regular() {
+		return fmt.Errorf("geotiff: %s %s is not on a latitude/longitude grid", f.Name, f.Level)
+	}
+	win, err := newWindow(f.Grid, w.opts)
+	if err != nil {
+		return err
+	}
+
+	// global grids start at the column closest to 180 west, windows of
+	// other grids are continuous already
+	if w.opts.Region == nil && global(f.Grid) {
+		first, west := 0, math.Inf(1)
+		for k, i := range win.is {
+			_, lon := f.Grid.LatLon(i, 0)
+			if lon = geoJSONLon(lon); lon < west {
+				first, west = k, lon
+			}
+		}
+		win.is = append(win.is[first:], win.is[:first]...)
+	}
+	lats, lons := win.axes()
+	// only the first longitude places the raster, the others follow it
+	west := geoJSONLon(lons[0])
+	// rows go from north to south
+	if lats[0] < lats[len(lats)-1] {
+		for a, b := 0, len(win.js)-1; a < b; a, b = a+1, b-1 {
+			win.js[a], win.js[b] = win.js[b], win.js[a]
+			lats[a], lats[b] = lats[b], lats[a]
+		}
+	}
+
+	stride := 1.0
+	if w.opts.Stride > 1 {
+		stride = float64(w.opts.Stride)
+	}
+	lat0, lon0 := f.Grid.LatLon(0, 0)
+	lat1, _ := f.Grid.LatLon(0, 1)
+	_, lon1 := f.Grid.LatLon(1, 0)
+	dx := math.Abs(geoJSONLon(lon1-lon0)) * stride
+	dy := math.Abs(lat1-lat0) * stride
+
+	file := &tiffFile{width: len(win.is), height: len(win.js), compress: w.opts.Gzip}
+	err = file.setData(win.values(f))
+	if err != nil {
+		return err
+	}
+	file.doubles(tagModelPixelScale, dx, dy, 0)
+	file.doubles(tagModelTiepoint, 0, 0, 0, west-dx/2, lats[0]+dy/2, 0)
+	file.shorts(tagGeoKeyDirectory, geoKeys...)
+	file.ascii(tagGDALMetadata, gdalMetadata(f))
+	file.ascii(tagGDALNoData, "nan")
+	return ioutil.WriteFile(w.path, file.bytes(), 0644)
+}
+
+func (w *geoTIFFWriter) Close() error {
+	return nil
+}
+
+// gdalMetadata describes a field to GDAL, which shows the items in QGIS
+func gdalMetadata(f *gfs.Field) string {
+	var b bytes.Buffer
+	item := func(name, value, extra string) {
+		b.WriteString(`<Item name="` + name + `"` + extra + `>`)
+		xml.EscapeText(&b, []byte(value))
+		b.WriteString("</Item>")
+	}
+	b.WriteString("<GDALMetadata>")
+	item("variable", f.Name, "")
+	item("level", f.Level, "")
+	item("units", f.Unit, "")
+	item("ref_time", f.RefTime.Format(time.RFC3339), "")
+	item("valid_time", f.VerfTime.Format(time.RFC3339), "")
+	item("forecast_hour", strconv.Itoa(int(f.VerfTime.Sub(f.RefTime).Hours())), "")
+	item("DESCRIPTION", f.Description+", "+f.Level, ` sample="0" role="description"`)
+	item("UNITTYPE", f.Unit, ` sample="0" role="unittype"`)
+	b.WriteString("</GDALMetadata>")
+	return b.String()
+}
+
+// tiffFile is a little endian TIFF of one float32 band in strips, built in
+// memory: the header, the strips and the directory of its tags
+type tiffFile struct {
+	width, height int
+	compress      bool
+	strips        [][]byte
+	rowsPerStrip  int
+	entries       []tiffEntry
+}
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count int
+	data  []byte
+}
+
+// setData splits the values, row by row, into strips of about 64 KB
+func (t *tiffFile) setData(values []float32) error {
+	t.rowsPerStrip = tiffStripSize / (4 * t.width)
+	if t.rowsPerStrip < 1 {
+		t.rowsPerStrip = 1
+	}
+	if t.rowsPerStrip > t.height {
+		t.rowsPerStrip = t.height
+	}
+	n := t.rowsPerStrip * t.width
+	for start := 0; start < len(values); start += n {
+		end := start + n
+		if end > len(values) {
+			end = len(values)
+		}
+		strip := make([]byte, 4*(end-start))
+		for k, v := range values[start:end] {
+			binary.LittleEndian.PutUint32(strip[4*k:], math.Float32bits(v))
+		}
+		if t.compress {
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			if _, err := zw.Write(strip); err != nil {
+				return err
+			}
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			strip = buf.Bytes()
+		}
+		t.strips = append(t.strips, strip)
+	}
+	return nil
+}
+
+func (t *tiffFile) shorts(tag uint16, values ...uint16) {
+	data := make([]byte, 2*len(values))
+	for k, v := range values {
+		binary.LittleEndian.PutUint16(data[2*k:], v)
+	}
+	t.entries = append(t.entries, tiffEntry{tag, tiffShort, len(values), data})
+}
+
+func (t *tiffFile) longs(tag uint16, values ...uint32) {
+	data := make([]byte, 4*len(values))
+	for k, v := range values {
+		binary.LittleEndian.PutUint32(data[4*k:], v)
+	}
+	t.entries = append(t.entries, tiffEntry{tag, tiffLong, len(values), data})
+}
+
+func (t *tiffFile) doubles(tag uint16, values ...float64) {
+	data := make([]byte, 8*len(values))
+	for k, v := range values {
+		binary.LittleEndian.PutUint64(data[8*k:], math.Float64bits(v))
+	}
+	t.entries = append(t.entries, tiffEntry{tag, tiffDouble, len(values), data})
+}
+
+func (t *tiffFile) ascii(tag uint16, s string) {
+	data := append([]byte(s), 0)
+	t.entries = append(t.entries, tiffEntry{tag, tiffASCII, len(data), data})
+}
+
+// bytes returns the file, the directory follows the strips
+func (t *tiffFile) bytes() []byte {
+	b := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+	offsets := make([]uint32, len(t.strips))
+	counts := make([]uint32, len(t.strips))
+	for k, strip := range t.strips {
+		offsets[k], counts[k] = uint32(len(b)), uint32(len(strip))
+		b = append(b, strip...)
+	}
+	if len(b)%2 == 1 {
+		b = append(b, 0)
+	}
+
+	compression := uint16(tiffCompressionNone)
+	if t.compress {
+		compression = tiffDeflate
+	}
+	t.longs(tagImageWidth, uint32(t.width))
+	t.longs(tagImageLength, uint32(t.height))
+	t.shorts(tagBitsPerSample, 32)
+	t.shorts(tagCompression, compression)
+	t.shorts(tagPhotometric, 1)
+	t.longs(tagStripOffsets, offsets...)
+	t.shorts(tagSamplesPerPixel, 1)
+	t.longs(tagRowsPerStrip, uint32(t.rowsPerStrip))
+	t.longs(tagStripByteCounts, counts...)
+	t.shorts(tagPlanarConfig, 1)
+	t.shorts(tagSampleFormat, 3)
+	sort.Slice(t.entries, func(a, b int) bool { return t.entries[a].tag < t.entries[b].tag })
+
+	// the directory, values of more than 4 bytes follow it
+	ifd := len(b)
+	binary.LittleEndian.PutUint32(b[4:], uint32(ifd))
+	extra := ifd + 2 + 12*len(t.entries) + 4
+	var values []byte
+	b = append(b, byte(len(t.entries)), byte(len(t.entries)>>8))
+	for _, e := range t.entries {
+		entry := make([]byte, 12)
+		binary.LittleEndian.PutUint16(entry, e.tag)
+		binary.LittleEndian.PutUint16(entry[2:], e.typ)
+		binary.LittleEndian.PutUint32(entry[4:], uint32(e.count))
+		if len(e.data) <= 4 {
+			copy(entry[8:], e.data)
+		} else {
+			binary.LittleEndian.PutUint32(entry[8:], uint32(extra+len(values)))
+			values = append(values, e.data...)
+			if len(values)%2 == 1 {
+				values = append(values, 0)
+			}
+		}
+		b = append(b, entry...)
+	}
+	b = append(b, 0, 0, 0, 0)
+	return append(b, values...)
+}
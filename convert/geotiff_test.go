@@ -0,0 +1,245 @@
+package convert
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// tiffImage is a GeoTIFF read back: the values of its directory entries,
+// as integers or doubles, and its uncompressed samples
+type tiffImage struct {
+	ints    map[uint16][]int
+	doubles map[uint16][]float64
+	ascii   map[uint16]string
+	samples []float32
+}
+
+func readTIFF(t *testing.T, path string) *tiffImage {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:4]) != "II*\x00" {
+		t.Fatalf("header %q", data[:4])
+	}
+	img := &tiffImage{ints: make(map[uint16][]int), doubles: make(map[uint16][]float64), ascii: make(map[uint16]string)}
+	ifd := int(binary.LittleEndian.Uint32(data[4:]))
+	n := int(binary.LittleEndian.Uint16(data[ifd:]))
+	last := uint16(0)
+	for k := 0; k < n; k++ {
+		e := data[ifd+2+12*k:]
+		tag, typ, count := binary.LittleEndian.Uint16(e), binary.LittleEndian.Uint16(e[2:]), int(binary.LittleEndian.Uint32(e[4:]))
+		if tag <= last {
+			t.Errorf("tag %d after %d", tag, last)
+		}
+		last = tag
+		size := map[uint16]int{tiffASCII: 1, tiffShort: 2, tiffLong: 4, tiffDouble: 8}[typ]
+		value := e[8:12]
+		if size*count > 4 {
+			value = data[binary.LittleEndian.Uint32(e[8:]):]
+		}
+		for c := 0; c < count; c++ {
+			switch typ {
+			case tiffShort:
+				img.ints[tag] = append(img.ints[tag], int(binary.LittleEndian.Uint16(value[2*c:])))
+			case tiffLong:
+				img.ints[tag] = append(img.ints[tag], int(binary.LittleEndian.Uint32(value[4*c:])))
+			case tiffDouble:
+				img.doubles[tag] = append(img.doubles[tag], math.Float64frombits(binary.LittleEndian.Uint64(value[8*c:])))
+			}
+		}
+		if typ == tiffASCII {
+			img.ascii[tag] = string(value[:count-1])
+		}
+	}
+	if binary.LittleEndian.Uint32(data[ifd+2+12*n:]) != 0 {
+		t.Errorf("more than one directory")
+	}
+
+	if img.ints[tagCompression][0] == tiffCompressionNone {
+		for k, offset := range img.ints[tagStripOffsets] {
+			strip := data[offset : offset+img.ints[tagStripByteCounts][k]]
+			for s := 0; s < len(strip); s += 4 {
+				img.samples = append(img.samples, math.Float32frombits(binary.LittleEndian.Uint32(strip[s:])))
+			}
+		}
+	}
+	return img
+}
+
+// checkGeoTIFF checks the tags every GeoTIFF has and its size
+func checkGeoTIFF(t *testing.T, img *tiffImage, width, height int) {
+	if img.ints[tagImageWidth][0] != width || img.ints[tagImageLength][0] != height {
+		t.Errorf("%dx%d, want %dx%d", img.ints[tagImageWidth][0], img.ints[tagImageLength][0], width, height)
+	}
+	if img.ints[tagBitsPerSample][0] != 32 || img.ints[tagSampleFormat][0] != 3 || img.ints[tagSamplesPerPixel][0] != 1 {
+		t.Errorf("samples %v", img.ints)
+	}
+	keys := make([]int, len(geoKeys))
+	for k, v := range geoKeys {
+		keys[k] = int(v)
+	}
+	if !reflect.DeepEqual(img.ints[tagGeoKeyDirectory], keys) || img.ascii[tagGDALNoData] != "nan" {
+		t.Errorf("geokeys %v, no data %q", img.ints[tagGeoKeyDirectory], img.ascii[tagGDALNoData])
+	}
+	if img.samples != nil && len(img.samples) != width*height {
+		t.Errorf("%d samples", len(img.samples))
+	}
+}
+
+func TestGeoTIFFRegion(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// every other point of the region across the prime meridian, from its
+	// edges: longitudes -1 to 1 and latitudes 36 to 37
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{
+		Format:  lookupFormat(t, "geotiff"),
+		Options: Options{Region: region, Stride: 2},
+		Output:  filepath.Join(dir, "{variable}.tif"),
+	}
+	convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+
+	for _, f := range readFields(t, "gfs.t00z.pgrb2.0p25.f001") {
+		img := readTIFF(t, filepath.Join(dir, f.Name+".tif"))
+		checkGeoTIFF(t, img, 5, 3)
+		if want := []float64{0.5, 0.5, 0}; !reflect.DeepEqual(img.doubles[tagModelPixelScale], want) {
+			t.Errorf("%s: pixel scale %v", f.Name, img.doubles[tagModelPixelScale])
+		}
+		if want := []float64{0, 0, 0, -1.25, 37.25, 0}; !reflect.DeepEqual(img.doubles[tagModelTiepoint], want) {
+			t.Errorf("%s: tiepoint %v", f.Name, img.doubles[tagModelTiepoint])
+		}
+		// rows from north to south
+		for k, v := range img.samples {
+			lat, lon := 37-0.5*float64(k/5), -1+0.5*float64(k%5)
+			if want, ok := f.Nearest(lat, lon); !ok || v != want {
+				t.Errorf("%s: sample %d at %v, %v = %v, want %v", f.Name, k, lat, lon, v, want)
+			}
+		}
+	}
+}
+
+func TestGeoTIFFOneField(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	file := fixture("gfs.t00z.pgrb2.0p25.f001")
+	c := &Converter{Format: lookupFormat(t, "geotiff"), Output: filepath.Join(dir, "single.tif")}
+	err := c.CheckOutputs(file)
+	if err == nil || !strings.Contains(err.Error(), "holds one field") {
+		t.Errorf("two fields for single.tif: %v", err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("%d files written", len(entries))
+	}
+
+	c.Filter = Select([]string{"UGRD"}, nil, nil)
+	if err = c.CheckOutputs(file); err != nil {
+		t.Errorf("one field for single.tif: %v", err)
+	}
+	c.Filter = nil
+	c.Output = filepath.Join(dir, "{variable}.tif")
+	if err = c.CheckOutputs(file); err != nil {
+		t.Errorf("a file per variable: %v", err)
+	}
+	c.Output = filepath.Join(dir, "{fh}.tif")
+	if err = c.CheckOutputs(file); err == nil {
+		t.Error("two fields of one forecast hour for {fh}.tif")
+	}
+}
+
+// globalFields returns the fields of the GFS fixture on a global grid of
+// 117 columns from 0E, the values are those of the regional grid
+func globalFields(t *testing.T) []gfs.Field {
+	data, err := ioutil.ReadFile(fixture("gfs.t00z.pgrb2.0p25.f001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for m := 0; m < len(data); m += int(binary.BigEndian.Uint64(data[m+8:])) {
+		for s := m + 16; ; s += int(binary.BigEndian.Uint32(data[s:])) {
+			if data[s+4] != 3 {
+				continue
+			}
+			section := data[s:]
+			di := uint32(3076923)
+			binary.BigEndian.PutUint32(section[50:], 0)
+			binary.BigEndian.PutUint32(section[59:], 116*di)
+			binary.BigEndian.PutUint32(section[63:], di)
+			break
+		}
+	}
+	fields, err := gfs.ReadFields(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+func TestGeoTIFFGlobal(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	f := globalFields(t)[0]
+	if !global(f.Grid) {
+		t.Fatal("the grid is not global")
+	}
+	path := filepath.Join(dir, "out.tif")
+	w, err := createGeoTIFF(path, Options{Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteField(&f); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteField(&f); err == nil {
+		t.Error("wrote a second field to one file")
+	}
+
+	// the raster starts at the column closest to 180 west, 181.54E, and
+	// covers the globe
+	img := readTIFF(t, path)
+	checkGeoTIFF(t, img, 117, 98)
+	if img.ints[tagCompression][0] != tiffDeflate {
+		t.Errorf("compression %v", img.ints[tagCompression])
+	}
+	scale, tie := img.doubles[tagModelPixelScale], img.doubles[tagModelTiepoint]
+	if math.Abs(scale[0]-3.076923) > 1e-6 || scale[1] != 0.25 {
+		t.Errorf("pixel scale %v", scale)
+	}
+	if math.Abs(tie[3]+180) > 1e-4 || tie[4] != 60.125 {
+		t.Errorf("tiepoint %v", tie)
+	}
+}
+
+func TestDecimate(t *testing.T) {
+	for _, c := range []struct {
+		s      []int
+		stride int
+		want   []int
+	}{
+		{[]int{0, 1, 2, 3, 4}, 1, []int{0, 1, 2, 3, 4}},
+		{[]int{0, 1, 2, 3, 4}, 2, []int{0, 2, 4}},
+		{[]int{3, 4, 5, 6, 7, 8}, 2, []int{3, 5, 7}},
+		// across the first column of a global grid of 7 columns
+		{[]int{5, 6, 0, 1, 2}, 2, []int{5, 0, 2}},
+		{[]int{5, 6, 0, 1, 2}, 9, []int{5}},
+	} {
+		if got := decimate(append([]int(nil), c.s...), c.stride); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("decimate(%v, %d) = %v, want %v", c.s, c.stride, got, c.want)
+		}
+	}
+}
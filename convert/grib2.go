@@ -0,0 +1,101 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/azillion/nimbus/gfs"
+	"github.com/azillion/nimbus/grib2"
+)
+
+// packings are the data representation templates GRIB2 outputs can be
+// packed with, by the names of the --packing flag
+var packings = map[string]int{
+	"simple":          0,
+	"complex":         2,
+	"complex-spatial": 3,
+}
+
+// PackingTemplate returns the data representation template of a packing
+// name: simple (5.0), complex (5.2) or complex-spatial (5.3)
+func PackingTemplate(name string) (int, error) {
+	template, ok := packings[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown packing: %s, must be simple, complex or complex-spatial", name)
+	}
+	return template, nil
+}
+
+// messageWriter is implemented by writers that need the GRIB2 field a field
+// was decoded from, the Converter hands it to them instead of WriteField
+type messageWriter interface {
+	WriteMessageField(src *grib2.Field, f *gfs.Field) error
+}
+
+// grib2Writer writes every field as a message of its own, with the sections
+// 1 and 4 of the message it was read from. With a region, the grid is
+// cropped to the smallest block of columns and rows holding it and section 3
+// is rewritten for it
+type grib2Writer struct {
+	file *textFile
+	opts Options
+}
+
+func createGRIB2(path string, opts Options) (Writer, error) {
+	file, err := createTextFile(path, opts.Gzip)
+	if err != nil {
+		return nil, err
+	}
+	return &grib2Writer{file: file, opts: opts}, nil
+}
+
+func (w *grib2Writer) WriteField(f *gfs.Field) error {
+	return fmt.Errorf("grib2: %s was not read from a GRIB2 message", f.Name)
+}
+
+func (w *grib2Writer) WriteMessageField(src *grib2.Field, f *gfs.Field) error {
+	grid, values := src.Grid, f.Values
+	if w.opts.Region != nil {
+		i0, j0, ni, nj, err := regionBlock(f, w.opts.Region)
+		if err != nil {
+			return err
+		}
+		grid, values, err = src.Grid.Crop(values, i0, j0, ni, nj)
+		if err != nil {
+			return err
+		}
+	}
+	return grib2.Encode(w.file, src, grid, values, w.opts.Encode)
+}
+
+func (w *grib2Writer) Close() error {
+	return w.file.Close()
+}
+
+// regionBlock returns the first column and row and the size of the smallest
+// block of a grid holding every point in a region
+func regionBlock(f *gfs.Field, r *gfs.Region) (i0, j0, ni, nj int, err error) {
+	imin, jmin, imax, jmax := f.Grid.Ni, f.Grid.Nj, -1, -1
+	for n := range f.Values {
+		i, j := f.Grid.Point(n)
+		if !r.Contains(f.Grid.LatLon(i, j)) {
+			continue
+		}
+		if i < imin {
+			imin = i
+		}
+		if i > imax {
+			imax = i
+		}
+		if j < jmin {
+			jmin = j
+		}
+		if j > jmax {
+			jmax = j
+		}
+	}
+	if imax < 0 {
+		return 0, 0, 0, 0, fmt.Errorf("grib2: no point of %s is in the region", f.Name)
+	}
+	return imin, jmin, imax - imin + 1, jmax - jmin + 1, nil
+}
@@ -0,0 +1,105 @@
+package convert
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/azillion/nimbus/gfs"
+	"github.com/azillion/nimbus/grib2"
+)
+
+func TestGRIB2(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// the region spans the prime meridian, 359 to 1E
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		packing string
+		opts    Options
+		// tolerance is how far values may move, 0 for none
+		tolerance float64
+	}{
+		{"simple", Options{}, 0},
+		{"complex-spatial", Options{Region: region}, 0},
+		{"complex", Options{Region: region, Encode: grib2.EncodeOptions{Bits: 12}}, 0.01},
+		{"simple", Options{Encode: grib2.EncodeOptions{Bits: 8, DecimalScale: 1}}, 0.2},
+	}
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	for k, test := range tests {
+		template, err := PackingTemplate(test.packing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.opts.Encode.Template = template
+		c := &Converter{
+			Format:  lookupFormat(t, "grib2"),
+			Options: test.opts,
+			Output:  filepath.Join(dir, "out.grb2"),
+		}
+		convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+
+		data, err := ioutil.ReadFile(c.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages, err := grib2.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != len(fields) {
+			t.Fatalf("test %d: %d messages, want %d", k, len(messages), len(fields))
+		}
+		for n, m := range messages {
+			got, err := gfs.NewField(m.Fields[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := fields[n]
+			if m.Fields[0].Representation.TemplateNumber != template {
+				t.Errorf("test %d: %s packed with template 5.%d", k, got.Name, m.Fields[0].Representation.TemplateNumber)
+			}
+			if got.Name != want.Name || got.Level != want.Level || !got.RefTime.Equal(want.RefTime) || !got.VerfTime.Equal(want.VerfTime) {
+				t.Errorf("test %d: %s %s at %s is not %s %s at %s", k, got.Name, got.Level, got.VerfTime, want.Name, want.Level, want.VerfTime)
+			}
+			// 5 rows of 9 points in the region
+			ni, nj := want.Grid.Ni, want.Grid.Nj
+			if test.opts.Region != nil {
+				ni, nj = 9, 5
+			}
+			if got.Grid.Ni != ni || got.Grid.Nj != nj {
+				t.Errorf("test %d: %s is %dx%d, want %dx%d", k, got.Name, got.Grid.Ni, got.Grid.Nj, ni, nj)
+			}
+			for p, v := range got.Values {
+				lat, lon := got.Grid.LatLon(got.Grid.Point(p))
+				if test.opts.Region != nil && !test.opts.Region.Contains(lat, lon) {
+					t.Errorf("test %d: %s point %v, %v is outside the region", k, got.Name, lat, lon)
+					break
+				}
+				w, ok := want.Nearest(lat, lon)
+				if !ok || math.Abs(float64(v-w)) > test.tolerance+1e-6 {
+					t.Errorf("test %d: %s at %v, %v = %v, want %v", k, got.Name, lat, lon, v, w)
+					break
+				}
+			}
+		}
+	}
+
+	if _, err := PackingTemplate("jpeg2000"); err == nil {
+		t.Error("packed with JPEG 2000")
+	}
+	c := &Converter{
+		Format:  lookupFormat(t, "grib2"),
+		Options: Options{Region: &gfs.Region{LeftLon: 100, RightLon: 110, TopLat: 10, BottomLat: 0}},
+		Output:  filepath.Join(dir, "empty.grb2"),
+	}
+	if err := c.ConvertFile(fixture("gfs.t00z.pgrb2.0p25.f001")); err == nil {
+		t.Error("wrote a region outside the grid")
+	}
+	c.Close()
+}
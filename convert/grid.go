@@ -0,0 +1,212 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/azillion/nimbus/gfs"
+	"github.com/azillion/nimbus/grib2"
+)
+
+// window is the block of a grid gridded formats write, the columns is and
+// rows js of the grid in the order they are written. Columns of a global
+// latitude/longitude grid wrap, so a region across the first column of the
+// grid is written as one block
+type window struct {
+	grid   *grib2.Grid
+	is, js []int
+}
+
+// newWindow returns the smallest block of a grid holding every point of the
+// region of the options, or the whole grid without a region, decimated by
+// their stride
+func newWindow(g *grib2.Grid, opts Options) (*window, error) {
+	w := &window{grid: g}
+	r := opts.Region
+	if r == nil {
+		w.is, w.js = decimate(span(0, g.Ni-1), opts.Stride), decimate(span(0, g.Nj-1), opts.Stride)
+		return w, nil
+	}
+
+	cols := make([]bool, g.Ni)
+	rows := make([]bool, g.Nj)
+	if g.Rectilinear() {
+		// test the axes apart, a point lies in the region when its
+		// column and row both do
+		lat, _ := g.LatLon(0, 0)
+		inside := math.Max(float64(r.BottomLat), math.Min(float64(r.TopLat), lat))
+		for i := range cols {
+			_, lon := g.LatLon(i, 0)
+			cols[i] = r.Contains(inside, lon)
+		}
+		// any longitude of the region does for the rows
+		lon := float64(r.LeftLon)
+		for j := range rows {
+			lat, _ := g.LatLon(0, j)
+			rows[j] = r.Contains(lat, lon)
+		}
+	} else {
+		for j := 0; j < g.Nj; j++ {
+			for i := 0; i < g.Ni; i++ {
+				lat, lon := g.LatLon(i, j)
+				if r.Contains(lat, lon) {
+					cols[i], rows[j] = true, true
+				}
+			}
+		}
+	}
+
+	w.js = decimate(bounds(rows, false), opts.Stride)
+	w.is = decimate(bounds(cols, g.Rectilinear() && global(g)), opts.Stride)
+	if len(w.is) == 0 || len(w.js) == 0 {
+		return nil, fmt.Errorf("no grid points in the region")
+	}
+	return w, nil
+}
+
+// decimate keeps every stride-th index of a window from its first, so the
+// spacing of the points is the same across the first column of a global
+// grid and a region keeps the points on its edge
+func decimate(s []int, stride int) []int {
+	if stride <= 1 {
+		return s
+	}
+	kept := s[:0]
+	for k := 0; k < len(s); k += stride {
+		kept = append(kept, s[k])
+	}
+	return kept
+}
+
+// span returns the integers from a to b
+func span(a, b int) []int {
+	s := make([]int, 0, b-a+1)
+	for k := a; k <= b; k++ {
+		s = append(s, k)
+	}
+	return s
+}
+
+// bounds returns the shortest run of indexes covering every set one, runs
+// wrap around the end when cyclic is set
+func bounds(set []bool, cyclic bool) []int {
+	first, last := -1, -1
+	for k, ok := range set {
+		if ok {
+			if first < 0 {
+				first = k
+			}
+			last = k
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	if !cyclic {
+		return span(first, last)
+	}
+
+	// start after the longest run of unset indexes, going around
+	n := len(set)
+	start, longest, gap := first, n-1-last+first, 0
+	for k := first; k <= last; k++ {
+		if !set[k] {
+			gap++
+			continue
+		}
+		if gap > longest {
+			start, longest = k, gap
+		}
+		gap = 0
+	}
+	s := make([]int, 0, n-longest)
+	for k := 0; k < n-longest; k++ {
+		s = append(s, (start+k)%n)
+	}
+	return s
+}
+
+// global reports whether the columns of a rectilinear grid go around the
+// globe, so the column after the last is the first
+func global(g *grib2.Grid) bool {
+	_, first := g.LatLon(0, 0)
+	_, next := g.LatLon(1, 0)
+	_, after := g.LatLon(g.Ni, 0)
+	step := math.Abs(next - first)
+	d := math.Abs(after - first)
+	return math.Min(d, 360-d) < step/2
+}
+
+// sameGrid reports whether a grid has the points of the grid of the window,
+// fields of different messages have their own Grid
+func (w *window) sameGrid(g *grib2.Grid) bool {
+	if g == w.grid {
+		return true
+	}
+	if g.Ni != w.grid.Ni || g.Nj != w.grid.Nj || g.ScanningMode != w.grid.ScanningMode || g.Rectilinear() != w.grid.Rectilinear() {
+		return false
+	}
+	for _, p := range [][2]int{{0, 0}, {g.Ni - 1, 0}, {0, g.Nj - 1}, {g.Ni - 1, g.Nj - 1}} {
+		lat1, lon1 := g.LatLon(p[0], p[1])
+		lat2, lon2 := w.grid.LatLon(p[0], p[1])
+		if math.Abs(lat1-lat2) > 1e-6 || math.Abs(lon1-lon2) > 1e-6 {
+			return false
+		}
+	}
+	return true
+}
+
+// values returns the values of a field in the window, row by row
+func (w *window) values(f *gfs.Field) []float32 {
+	values := make([]float32, 0, len(w.is)*len(w.js))
+	for _, j := range w.js {
+		for _, i := range w.is {
+			values = append(values, f.At(i, j))
+		}
+	}
+	return values
+}
+
+// axes returns the latitudes of the rows and longitudes of the columns of a
+// window on a rectilinear grid. Longitudes change steadily across the
+// columns, so a window across the first column of a global grid has
+// negative longitudes west of it
+func (w *window) axes() (lats, lons []float64) {
+	for _, j := range w.js {
+		lat, _ := w.grid.LatLon(0, j)
+		lats = append(lats, lat)
+	}
+	over := false
+	for k, i := range w.is {
+		_, lon := w.grid.LatLon(i, 0)
+		if k > 0 {
+			for lon-lons[k-1] > 180 {
+				lon -= 360
+			}
+			for lon-lons[k-1] < -180 {
+				lon += 360
+			}
+		}
+		over = over || lon >= 360
+		lons = append(lons, lon)
+	}
+	if over {
+		for k := range lons {
+			lons[k] -= 360
+		}
+	}
+	return lats, lons
+}
+
+// latLons returns the latitude and longitude of every point of a window, row
+// by row
+func (w *window) latLons() (lats, lons []float64) {
+	for _, j := range w.js {
+		for _, i := range w.is {
+			lat, lon := w.grid.LatLon(i, j)
+			lats = append(lats, lat)
+			lons = append(lons, lon)
+		}
+	}
+	return lats, lons
+}
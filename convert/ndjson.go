@@ -0,0 +1,126 @@
+package convert
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// ndjsonWriter writes newline delimited JSON, an object per point or, with
+// PerMessage, an object per field holding the coordinates and values of its
+// points. Keys are always in the same order so outputs can be diffed, and
+// missing values are null. Objects per field are flushed once written, so a
+// reader of a pipe gets whole fields as they are decoded
+type ndjsonWriter struct {
+	file *textFile
+	opts Options
+	buf  []byte
+}
+
+func createNDJSON(path string, opts Options) (Writer, error) {
+	file, err := createTextFile(path, opts.Gzip)
+	if err != nil {
+		return nil, err
+	}
+	return &ndjsonWriter{file: file, opts: opts}, nil
+}
+
+func (w *ndjsonWriter) WriteField(f *gfs.Field) error {
+	// the keys every object of the field starts with
+	b := append(w.buf[:0], `{"ref_time":`...)
+	b = appendJSONString(b, f.RefTime.Format(time.RFC3339))
+	b = append(b, `,"valid_time":`...)
+	b = appendJSONString(b, f.VerfTime.Format(time.RFC3339))
+	b = append(b, `,"fh":`...)
+	b = strconv.AppendFloat(b, f.VerfTime.Sub(f.RefTime).Hours(), 'f', -1, 64)
+	b = append(b, `,"variable":`...)
+	b = appendJSONString(b, f.Name)
+	b = append(b, `,"level":`...)
+	b = appendJSONString(b, f.Level)
+
+	if w.opts.PerMessage {
+		b = append(b, `,"description":`...)
+		b = appendJSONString(b, f.Description)
+		b = append(b, `,"unit":`...)
+		b = appendJSONString(b, f.Unit)
+		var lats, lons []float64
+		var values []float32
+		err := eachPoint(f, w.opts, func(i, j int, lat, lon float64, v float32) error {
+			lats = append(lats, lat)
+			lons = append(lons, lon)
+			values = append(values, v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		b = append(b, `,"points":`...)
+		b = strconv.AppendInt(b, int64(len(values)), 10)
+		b = append(b, `,"lat":[`...)
+		for k, lat := range lats {
+			if k > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendFloat(b, lat, 'f', -1, 64)
+		}
+		b = append(b, `],"lon":[`...)
+		for k, lon := range lons {
+			if k > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendFloat(b, lon, 'f', -1, 64)
+		}
+		b = append(b, `],"values":[`...)
+		for k, v := range values {
+			if k > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONValue(b, v)
+		}
+		b = append(b, "]}\n"...)
+		w.buf = b
+		if _, err = w.file.Write(b); err != nil {
+			return err
+		}
+		return w.file.Flush()
+	}
+
+	prefix := len(b)
+	err := eachPoint(f, w.opts, func(i, j int, lat, lon float64, v float32) error {
+		b = append(b[:prefix], `,"lat":`...)
+		b = strconv.AppendFloat(b, lat, 'f', -1, 64)
+		b = append(b, `,"lon":`...)
+		b = strconv.AppendFloat(b, lon, 'f', -1, 64)
+		b = append(b, `,"value":`...)
+		b = appendJSONValue(b, v)
+		b = append(b, "}\n"...)
+		_, err := w.file.Write(b)
+		return err
+	})
+	w.buf = b
+	if err != nil {
+		return err
+	}
+	return w.file.Flush()
+}
+
+// appendJSONString appends a quoted JSON string
+func appendJSONString(b []byte, s string) []byte {
+	quoted, _ := json.Marshal(s)
+	return append(b, quoted...)
+}
+
+// appendJSONValue appends a value, null when it is missing
+func appendJSONValue(b []byte, v float32) []byte {
+	if math.IsNaN(float64(v)) {
+		return append(b, "null"...)
+	}
+	return strconv.AppendFloat(b, float64(v), 'g', -1, 32)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.file.Close()
+}
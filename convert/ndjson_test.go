@@ -0,0 +1,176 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azillion/nimbus/grib2"
+)
+
+func TestNDJSONPoints(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	var outputs [2][]byte
+	for k := range outputs {
+		c := &Converter{
+			Format: lookupFormat(t, "ndjson"),
+			Output: filepath.Join(dir, "out.ndjson"),
+		}
+		convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+		data, err := ioutil.ReadFile(c.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs[k] = data
+	}
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Error("the outputs of the same file differ")
+	}
+
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	lines := strings.Split(strings.TrimSuffix(string(outputs[0]), "\n"), "\n")
+	if len(lines) != 2*len(fields[0].Values) {
+		t.Fatalf("%d lines", len(lines))
+	}
+	// the keys are in a fixed order, the first point is the south west
+	// corner of the grid
+	want := `{"ref_time":"2017-07-20T00:00:00Z","valid_time":"2017-07-20T01:00:00Z","fh":1,"variable":"UGRD","level":"10 m above ground","lat":35.75,"lon":350,"value":`
+	if !strings.HasPrefix(lines[0], want) {
+		t.Errorf("first line\n%s\nwant\n%s...", lines[0], want)
+	}
+	for k, line := range lines {
+		var point struct {
+			Variable string
+			Lat, Lon float64
+			Value    *float32
+		}
+		if err := json.Unmarshal([]byte(line), &point); err != nil {
+			t.Fatalf("line %d: %v", k, err)
+		}
+		f := fields[k/len(fields[0].Values)]
+		v := f.Values[k%len(f.Values)]
+		if point.Variable != f.Name || point.Value == nil || *point.Value != v {
+			t.Fatalf("line %d: %s", k, line)
+		}
+	}
+}
+
+func TestNDJSONPerMessage(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// the fourth field of complex.grb2 has 690 missing values
+	c := &Converter{
+		Format:  lookupFormat(t, "ndjson"),
+		Options: Options{PerMessage: true},
+		Output:  filepath.Join(dir, "out.ndjson"),
+		Filter: func() func(*grib2.Field) bool {
+			n := 0
+			return func(*grib2.Field) bool {
+				n++
+				return n == 4
+			}
+		}(),
+	}
+	convert(t, c, "complex.grb2")
+
+	data, err := ioutil.ReadFile(c.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(data, []byte("\n")) != 1 {
+		t.Fatalf("%d lines", bytes.Count(data, []byte("\n")))
+	}
+	var message struct {
+		Points   int
+		Lat, Lon []float64
+		Values   []*float64
+	}
+	if err = json.Unmarshal(data, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Points != 117*98 || len(message.Lat) != message.Points || len(message.Lon) != message.Points || len(message.Values) != message.Points {
+		t.Fatalf("%d points, %d latitudes, %d longitudes, %d values", message.Points, len(message.Lat), len(message.Lon), len(message.Values))
+	}
+	missing := 0
+	for _, v := range message.Values {
+		if v == nil {
+			missing++
+		} else if math.IsNaN(*v) {
+			t.Fatal("NaN value")
+		}
+	}
+	if missing != 690 {
+		t.Errorf("%d missing values, want 690", missing)
+	}
+	keys := []string{`{"ref_time":`, `,"valid_time":`, `,"fh":`, `,"variable":`, `,"level":`, `,"description":`, `,"unit":`, `,"points":`, `,"lat":[`, `],"lon":[`, `],"values":[`}
+	at := 0
+	for _, key := range keys {
+		n := bytes.Index(data[at:], []byte(key))
+		if n < 0 {
+			t.Fatalf("%s missing or out of order", key)
+		}
+		at += n
+	}
+}
+
+func TestNDJSONStream(t *testing.T) {
+	r, pipe, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	stdout := os.Stdout
+	os.Stdout = pipe
+	defer func() { os.Stdout = stdout }()
+	w, err := createNDJSON("-", Options{PerMessage: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each field is on the pipe once written, before the output is closed
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	errs := make(chan error, 1)
+	next := make(chan bool)
+	go func() {
+		for k := range fields {
+			if err := w.WriteField(&fields[k]); err != nil {
+				errs <- err
+				return
+			}
+			<-next
+		}
+		errs <- w.Close()
+	}()
+	lines := bufio.NewReaderSize(r, 1<<20)
+	for _, f := range fields {
+		line, err := lines.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(line, []byte(`"variable":"`+f.Name+`"`)) {
+			t.Errorf("line of %q", line[:100])
+		}
+		next <- true
+	}
+	if err = <-errs; err != nil {
+		t.Fatal(err)
+	}
+	// stdout is left open
+	if _, err = pipe.Write([]byte("end\n")); err != nil {
+		t.Fatal(err)
+	}
+	pipe.Close()
+	rest, _ := ioutil.ReadAll(lines)
+	if string(rest) != "end\n" {
+		t.Errorf("after the fields %q", rest)
+	}
+}
@@ -0,0 +1,583 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// ncFill is the default fill value of NetCDF floats, NC_FILL_FLOAT
+const ncFill float32 = 9.9692099683868690e+36
+
+// external types of the NetCDF classic format
+const (
+	ncChar   = 2
+	ncFloat  = 5
+	ncDouble = 6
+)
+
+// tags of the lists of a NetCDF header
+const (
+	ncDimensionTag = 0x0a
+	ncVariableTag  = 0x0b
+	ncAttributeTag = 0x0c
+)
+
+// ncRecord is a field spooled by the NetCDF writer, offset locates its values
+type ncRecord struct {
+	field  gfs.Field
+	offset int64
+}
+
+// netCDFWriter writes fields to a NetCDF classic file with CF-1.8 metadata.
+// The header of the format lists every variable before their data, so
+// fields are spooled to a temporary file and the NetCDF file is only written
+// on Close, when all variables, times and levels are known
+type netCDFWriter struct {
+	path    string
+	opts    Options
+	spool   *os.File
+	size    int64
+	window  *window
+	records []ncRecord
+}
+
+func createNetCDF(path string, opts Options) (Writer, error) {
+	if opts.Gzip {
+		return nil, fmt.Errorf("netcdf output cannot be gzip compressed")
+	}
+	spool, err := ioutil.TempFile("", "nimbus-netcdf-")
+	if err != nil {
+		return nil, err
+	}
+	return &netCDFWriter{path: path, opts: opts, spool: spool}, nil
+}
+
+func (w *netCDFWriter) WriteField(f *gfs.Field) error {
+	if w.window == nil {
+		win, err := newWindow(f.Grid, w.opts)
+		if err != nil {
+			return err
+		}
+		w.window = win
+	} else if !w.window.sameGrid(f.Grid) {
+		return fmt.Errorf("netcdf: %s %s is on another grid than the fields before it, write one variable per file", f.Name, f.Level)
+	}
+
+	values := w.window.values(f)
+	buf := make([]byte, 4*len(values))
+	for k, v := range values {
+		if math.IsNaN(float64(v)) {
+			v = ncFill
+		}
+		binary.BigEndian.PutUint32(buf[4*k:], math.Float32bits(v))
+	}
+	if _, err := w.spool.WriteAt(buf, w.size); err != nil {
+		return err
+	}
+
+	record := ncRecord{field: *f, offset: w.size}
+	record.field.Values = nil
+	w.records = append(w.records, record)
+	w.size += int64(len(buf))
+	return nil
+}
+
+// Close writes the NetCDF file, or a file per valid time unless the options
+// stack them
+func (w *netCDFWriter) Close() error {
+	defer os.Remove(w.spool.Name())
+	defer w.spool.Close()
+
+	// a region outside the grid leaves nothing to write, WriteField
+	// returned why
+	if len(w.records) == 0 {
+		return nil
+	}
+	if w.opts.Stack {
+		return w.write(w.path, w.records)
+	}
+	var times []time.Time
+	byTime := make(map[time.Time][]ncRecord)
+	for _, r := range w.records {
+		t := r.field.VerfTime
+		if _, ok := byTime[t]; !ok {
+			times = append(times, t)
+		}
+		byTime[t] = append(byTime[t], r)
+	}
+	if len(times) == 1 {
+		return w.write(w.path, w.records)
+	}
+	for _, t := range times {
+		path := strings.TrimSuffix(w.path, ".nc") + "." + t.Format("2006010215") + ".nc"
+		if err := w.write(path, byTime[t]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ncGroup is a data variable, the fields of one variable on one kind of
+// level. Fields on several levels of the same surface type share a level
+// dimension
+type ncGroup struct {
+	name        string
+	surfaceType int
+	// level is set for fields that are not stacked on a level dimension
+	level   string
+	levels  []float64
+	records []ncRecord
+}
+
+// groupKey groups the fields of a variable, fields on a single surface with a
+// value are grouped by surface type and others by their level
+type groupKey struct {
+	name        string
+	surfaceType int
+	level       string
+}
+
+func newGroupKey(f *gfs.Field) groupKey {
+	if f.SecondSurface.Type == 255 && f.FirstSurface.HasValue {
+		return groupKey{name: f.Name, surfaceType: f.FirstSurface.Type}
+	}
+	return groupKey{name: f.Name, surfaceType: -1, level: f.Level}
+}
+
+// write writes one NetCDF file holding records
+func (w *netCDFWriter) write(path string, records []ncRecord) error {
+	var file ncFile
+	file.attr("Conventions", "CF-1.8")
+	file.attr("source", "nimbus")
+	file.attr("history", time.Now().UTC().Format("2006-01-02T15:04:05Z")+" nimbus convert")
+
+	// the time axis, in hours since the earliest reference time
+	var times []time.Time
+	timeIndex := make(map[time.Time]int)
+	base := records[0].field.RefTime
+	sameRef := true
+	for _, r := range records {
+		if r.field.RefTime.Before(base) {
+			base = r.field.RefTime
+		}
+		sameRef = sameRef && r.field.RefTime.Equal(records[0].field.RefTime)
+		if _, ok := timeIndex[r.field.VerfTime]; !ok {
+			timeIndex[r.field.VerfTime] = 0
+			times = append(times, r.field.VerfTime)
+		}
+	}
+	sort.Slice(times, func(a, b int) bool { return times[a].Before(times[b]) })
+	hours := make([]float64, len(times))
+	for k, t := range times {
+		timeIndex[t] = k
+		hours[k] = t.Sub(base).Hours()
+	}
+	timeUnits := "hours since " + base.Format("2006-01-02 15:04:05")
+	timeDim := file.dim("time", len(times))
+	v := file.variable("time", ncDouble, []int{timeDim}, doubles(hours))
+	v.attr("standard_name", "time")
+	v.attr("long_name", "verification time")
+	v.attr("units", timeUnits)
+	v.attr("calendar", "standard")
+	v.attr("axis", "T")
+	if sameRef {
+		v := file.variable("reference_time", ncDouble, nil, doubles([]float64{0}))
+		v.attr("standard_name", "forecast_reference_time")
+		v.attr("units", timeUnits)
+		v.attr("calendar", "standard")
+	}
+
+	// the horizontal axes
+	win := w.window
+	var gridDims []int
+	coordinates := ""
+	if win.grid.Rectilinear() {
+		lats, lons := win.axes()
+		latDim := file.dim("latitude", len(lats))
+		lonDim := file.dim("longitude", len(lons))
+		gridDims = []int{latDim, lonDim}
+		v := file.variable("latitude", ncDouble, []int{latDim}, doubles(lats))
+		v.attr("standard_name", "latitude")
+		v.attr("long_name", "latitude")
+		v.attr("units", "degrees_north")
+		v.attr("axis", "Y")
+		v = file.variable("longitude", ncDouble, []int{lonDim}, doubles(lons))
+		v.attr("standard_name", "longitude")
+		v.attr("long_name", "longitude")
+		v.attr("units", "degrees_east")
+		v.attr("axis", "X")
+	} else {
+		lats, lons := win.latLons()
+		yDim := file.dim("y", len(win.js))
+		xDim := file.dim("x", len(win.is))
+		gridDims = []int{yDim, xDim}
+		v := file.variable("latitude", ncDouble, gridDims, doubles(lats))
+		v.attr("standard_name", "latitude")
+		v.attr("long_name", "latitude")
+		v.attr("units", "degrees_north")
+		v = file.variable("longitude", ncDouble, gridDims, doubles(lons))
+		v.attr("standard_name", "longitude")
+		v.attr("long_name", "longitude")
+		v.attr("units", "degrees_east")
+		coordinates = "latitude longitude"
+	}
+
+	// the data variables, in the order their first fields were written
+	var groups []*ncGroup
+	byKey := make(map[groupKey]*ncGroup)
+	for _, r := range records {
+		key := newGroupKey(&r.field)
+		g, ok := byKey[key]
+		if !ok {
+			g = &ncGroup{name: key.name, surfaceType: key.surfaceType, level: key.level}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.records = append(g.records, r)
+		if key.surfaceType >= 0 && !containsFloat(g.levels, r.field.FirstSurface.Value) {
+			g.levels = append(g.levels, r.field.FirstSurface.Value)
+		}
+	}
+
+	names := make(map[string]bool)
+	levelDims := make(map[string]int)
+	points := len(win.is) * len(win.js)
+	for _, g := range groups {
+		dims := []int{timeDim}
+		var axis levelAxis
+		if len(g.levels) > 1 {
+			sort.Float64s(g.levels)
+			axis = lookupLevelAxis(g.surfaceType)
+			key := fmt.Sprint(g.surfaceType, g.levels)
+			dim, ok := levelDims[key]
+			if !ok {
+				name := "level"
+				if len(levelDims) > 0 {
+					name = fmt.Sprintf("level_%d", len(levelDims)+1)
+				}
+				dim = file.dim(name, len(g.levels))
+				levelDims[key] = dim
+				v := file.variable(name, ncDouble, []int{dim}, doubles(g.levels))
+				v.attr("long_name", axis.longName)
+				if axis.standardName != "" {
+					v.attr("standard_name", axis.standardName)
+				}
+				if axis.units != "" {
+					v.attr("units", axis.units)
+				}
+				if axis.positive != "" {
+					v.attr("positive", axis.positive)
+				}
+				v.attr("axis", "Z")
+			}
+			dims = append(dims, dim)
+		} else {
+			g.level = g.records[0].field.Level
+			g.levels = nil
+		}
+		dims = append(dims, gridDims...)
+
+		name := variableName(g.name + "_" + g.level)
+		if g.levels != nil {
+			name = variableName(g.name + "_" + axis.name)
+		}
+		base := name
+		for k := 2; names[name]; k++ {
+			name = fmt.Sprintf("%s_%d", base, k)
+		}
+		names[name] = true
+
+		first := g.records[0].field
+		v := file.variable(name, ncFloat, dims, w.groupData(g, timeIndex, len(times), points))
+		v.attr("long_name", first.Description)
+		if units := cfUnits(first.Unit); units != "" {
+			v.attr("units", units)
+		}
+		if sn, ok := standardNames[first.Name]; ok {
+			v.attr("standard_name", sn)
+		}
+		if g.levels == nil {
+			v.attr("level", g.level)
+		}
+		v.attr("_FillValue", ncFill)
+		if coordinates != "" {
+			v.attr("coordinates", coordinates)
+		}
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriterSize(out, 1<<20)
+	err = file.writeTo(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// groupData writes the values of a data variable from the spool, time by
+// time and level by level, with fill values where no field was written
+func (w *netCDFWriter) groupData(g *ncGroup, timeIndex map[time.Time]int, numTimes, points int) ncData {
+	numLevels := 1
+	if g.levels != nil {
+		numLevels = len(g.levels)
+	}
+	offsets := make([]int64, numTimes*numLevels)
+	for k := range offsets {
+		offsets[k] = -1
+	}
+	for _, r := range g.records {
+		level := 0
+		if g.levels != nil {
+			level = sort.SearchFloat64s(g.levels, r.field.FirstSurface.Value)
+		}
+		offsets[timeIndex[r.field.VerfTime]*numLevels+level] = r.offset
+	}
+
+	size := int64(points) * 4
+	return ncData{
+		size: size * int64(len(offsets)),
+		write: func(out io.Writer) error {
+			buf := make([]byte, size)
+			fill := make([]byte, size)
+			for k := 0; k < points; k++ {
+				binary.BigEndian.PutUint32(fill[4*k:], math.Float32bits(ncFill))
+			}
+			for _, offset := range offsets {
+				block := fill
+				if offset >= 0 {
+					if _, err := w.spool.ReadAt(buf, offset); err != nil {
+						return err
+					}
+					block = buf
+				}
+				if _, err := out.Write(block); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func containsFloat(s []float64, v float64) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ncFile is the header of a NetCDF classic file, variables write their data
+// after it in the order they were added
+type ncFile struct {
+	dims  []ncDim
+	attrs []ncAttr
+	vars  []*ncVar
+}
+
+type ncDim struct {
+	name   string
+	length int
+}
+
+// ncAttr is an attribute, its value is a string, a float32 or a []float64
+type ncAttr struct {
+	name  string
+	value interface{}
+}
+
+type ncVar struct {
+	name  string
+	typ   int
+	dims  []int
+	attrs []ncAttr
+	data  ncData
+}
+
+// ncData is the data of a variable, size octets written by write
+type ncData struct {
+	size  int64
+	write func(io.Writer) error
+}
+
+func doubles(values []float64) ncData {
+	return ncData{
+		size: int64(len(values)) * 8,
+		write: func(w io.Writer) error {
+			return binary.Write(w, binary.BigEndian, values)
+		},
+	}
+}
+
+// dim adds a dimension and returns its id
+func (f *ncFile) dim(name string, length int) int {
+	f.dims = append(f.dims, ncDim{name, length})
+	return len(f.dims) - 1
+}
+
+// attr adds a global attribute
+func (f *ncFile) attr(name string, value interface{}) {
+	f.attrs = append(f.attrs, ncAttr{name, value})
+}
+
+func (f *ncFile) variable(name string, typ int, dims []int, data ncData) *ncVar {
+	v := &ncVar{name: name, typ: typ, dims: dims, data: data}
+	f.vars = append(f.vars, v)
+	return v
+}
+
+func (v *ncVar) attr(name string, value interface{}) {
+	v.attrs = append(v.attrs, ncAttr{name, value})
+}
+
+// writeTo writes the header and data. The classic format is used when every
+// offset fits in 32 bits, the 64-bit offset format otherwise
+func (f *ncFile) writeTo(w io.Writer) error {
+	version := byte(1)
+	header := f.header(version, nil)
+	end := int64(len(header))
+	for _, v := range f.vars {
+		end += pad4(v.data.size)
+	}
+	if end > math.MaxInt32 {
+		version = 2
+		header = f.header(version, nil)
+	}
+
+	begins := make([]int64, len(f.vars))
+	offset := int64(len(header))
+	for k, v := range f.vars {
+		if v.data.size >= math.MaxUint32-3 && k != len(f.vars)-1 {
+			return fmt.Errorf("netcdf: variable %s is too large for the classic format", v.name)
+		}
+		begins[k] = offset
+		offset += pad4(v.data.size)
+	}
+
+	if _, err := w.Write(f.header(version, begins)); err != nil {
+		return err
+	}
+	for _, v := range f.vars {
+		if err := v.data.write(w); err != nil {
+			return err
+		}
+		if n := pad4(v.data.size) - v.data.size; n > 0 {
+			if _, err := w.Write(make([]byte, n)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// header encodes the header, begins are the offsets of the data of the
+// variables and only change the values written, not the size
+func (f *ncFile) header(version byte, begins []int64) []byte {
+	var b bytes.Buffer
+	put := func(v uint32) {
+		binary.Write(&b, binary.BigEndian, v)
+	}
+	name := func(s string) {
+		put(uint32(len(s)))
+		b.WriteString(s)
+		b.Write(make([]byte, pad4(int64(len(s)))-int64(len(s))))
+	}
+	attrs := func(attrs []ncAttr) {
+		if len(attrs) == 0 {
+			put(0)
+			put(0)
+			return
+		}
+		put(ncAttributeTag)
+		put(uint32(len(attrs)))
+		for _, a := range attrs {
+			name(a.name)
+			switch v := a.value.(type) {
+			case string:
+				put(ncChar)
+				name(v)
+			case float32:
+				put(ncFloat)
+				put(1)
+				put(math.Float32bits(v))
+			case []float64:
+				put(ncDouble)
+				put(uint32(len(v)))
+				binary.Write(&b, binary.BigEndian, v)
+			}
+		}
+	}
+
+	b.WriteString("CDF")
+	b.WriteByte(version)
+	// no record dimension, so no records
+	put(0)
+
+	if len(f.dims) == 0 {
+		put(0)
+		put(0)
+	} else {
+		put(ncDimensionTag)
+		put(uint32(len(f.dims)))
+		for _, d := range f.dims {
+			name(d.name)
+			put(uint32(d.length))
+		}
+	}
+	attrs(f.attrs)
+
+	if len(f.vars) == 0 {
+		put(0)
+		put(0)
+		return b.Bytes()
+	}
+	put(ncVariableTag)
+	put(uint32(len(f.vars)))
+	for k, v := range f.vars {
+		name(v.name)
+		put(uint32(len(v.dims)))
+		for _, d := range v.dims {
+			put(uint32(d))
+		}
+		attrs(v.attrs)
+		put(uint32(v.typ))
+		size := pad4(v.data.size)
+		if size > math.MaxUint32-3 {
+			size = math.MaxUint32
+		}
+		put(uint32(size))
+		var begin int64
+		if begins != nil {
+			begin = begins[k]
+		}
+		if version == 1 {
+			put(uint32(begin))
+		} else {
+			binary.Write(&b, binary.BigEndian, begin)
+		}
+	}
+	return b.Bytes()
+}
+
+// pad4 rounds a size up to a multiple of 4 octets
+func pad4(n int64) int64 {
+	return (n + 3) &^ 3
+}
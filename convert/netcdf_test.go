@@ -0,0 +1,309 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// parsedNC is a NetCDF header read back
+type parsedNC struct {
+	version byte
+	dims    []ncDim
+	attrs   map[string]interface{}
+	vars    []parsedVar
+	size    int
+}
+
+type parsedVar struct {
+	name  string
+	dims  []int
+	attrs map[string]interface{}
+	typ   int
+	vsize uint32
+	begin int64
+}
+
+// parseNC reads the header of a NetCDF classic or 64-bit offset file
+func parseNC(t *testing.T, data []byte) *parsedNC {
+	pos := 0
+	u32 := func() uint32 {
+		if pos+4 > len(data) {
+			t.Fatalf("header is truncated at %d", pos)
+		}
+		v := binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+		return v
+	}
+	name := func() string {
+		n := int(u32())
+		s := string(data[pos : pos+n])
+		pos += int(pad4(int64(n)))
+		return s
+	}
+	attrs := func() map[string]interface{} {
+		tag, n := u32(), int(u32())
+		if tag != ncAttributeTag && (tag != 0 || n != 0) {
+			t.Fatalf("attribute list tag %#x", tag)
+		}
+		m := make(map[string]interface{})
+		for k := 0; k < n; k++ {
+			key := name()
+			switch typ := u32(); typ {
+			case ncChar:
+				m[key] = name()
+			case ncFloat:
+				if count := u32(); count != 1 {
+					t.Fatalf("%s: %d floats", key, count)
+				}
+				m[key] = math.Float32frombits(u32())
+			case ncDouble:
+				values := make([]float64, u32())
+				for i := range values {
+					values[i] = math.Float64frombits(uint64(u32())<<32 | uint64(u32()))
+				}
+				m[key] = values
+			default:
+				t.Fatalf("%s: type %d", key, typ)
+			}
+		}
+		return m
+	}
+
+	if string(data[:3]) != "CDF" {
+		t.Fatalf("magic %q", data[:4])
+	}
+	h := &parsedNC{version: data[3]}
+	pos = 4
+	if records := u32(); records != 0 {
+		t.Errorf("%d records", records)
+	}
+	tag, n := u32(), int(u32())
+	if tag != ncDimensionTag && (tag != 0 || n != 0) {
+		t.Fatalf("dimension list tag %#x", tag)
+	}
+	for k := 0; k < n; k++ {
+		h.dims = append(h.dims, ncDim{name(), int(u32())})
+	}
+	h.attrs = attrs()
+	tag, n = u32(), int(u32())
+	if tag != ncVariableTag && (tag != 0 || n != 0) {
+		t.Fatalf("variable list tag %#x", tag)
+	}
+	for k := 0; k < n; k++ {
+		v := parsedVar{name: name()}
+		dims := int(u32())
+		for d := 0; d < dims; d++ {
+			v.dims = append(v.dims, int(u32()))
+		}
+		v.attrs = attrs()
+		v.typ = int(u32())
+		v.vsize = u32()
+		if h.version == 1 {
+			v.begin = int64(u32())
+		} else {
+			v.begin = int64(uint64(u32())<<32 | uint64(u32()))
+		}
+		h.vars = append(h.vars, v)
+	}
+	h.size = pos
+	return h
+}
+
+// testNCFile is a small file with dimensions, attributes of every type and
+// variables of odd sizes so their data is padded
+func testNCFile() *ncFile {
+	var f ncFile
+	f.attr("Conventions", "CF-1.8")
+	f.attr("title", "odd")
+	x := f.dim("x", 3)
+	y := f.dim("y", 2)
+	v := f.variable("x", ncDouble, []int{x}, doubles([]float64{1, 2, 3}))
+	v.attr("units", "m")
+	v.attr("valid_range", []float64{0, 10})
+	v = f.variable("scalar", ncDouble, nil, doubles([]float64{42}))
+	v = f.variable("name", ncChar, []int{x}, ncData{size: 3, write: func(w io.Writer) error {
+		_, err := w.Write([]byte("abc"))
+		return err
+	}})
+	v = f.variable("field", ncFloat, []int{y, x}, ncData{size: 24, write: func(w io.Writer) error {
+		return binary.Write(w, binary.BigEndian, []float32{1, 2, 3, 4, 5, 6})
+	}})
+	v.attr("_FillValue", ncFill)
+	return &f
+}
+
+func TestNCHeader(t *testing.T) {
+	f := testNCFile()
+	sizes := []int64{24, 8, 4, 24}
+	for _, version := range []byte{1, 2} {
+		// the offsets are those writeTo works out
+		begins := make([]int64, len(f.vars))
+		offset := int64(len(f.header(version, nil)))
+		for k, v := range f.vars {
+			begins[k] = offset
+			offset += pad4(v.data.size)
+		}
+		header := f.header(version, begins)
+		if int64(len(header)) != begins[0] {
+			t.Fatalf("version %d: header of %d octets, data at %d", version, len(header), begins[0])
+		}
+
+		h := parseNC(t, header)
+		if h.version != version || h.size != len(header) {
+			t.Errorf("version %d: read version %d from %d of %d octets", version, h.version, h.size, len(header))
+		}
+		if len(h.dims) != 2 || h.dims[0] != (ncDim{"x", 3}) || h.dims[1] != (ncDim{"y", 2}) {
+			t.Errorf("version %d: dimensions %v", version, h.dims)
+		}
+		if len(h.attrs) != 2 || h.attrs["Conventions"] != "CF-1.8" || h.attrs["title"] != "odd" {
+			t.Errorf("version %d: attributes %v", version, h.attrs)
+		}
+		if len(h.vars) != 4 {
+			t.Fatalf("version %d: %d variables", version, len(h.vars))
+		}
+		for k, v := range h.vars {
+			want := f.vars[k]
+			if v.name != want.name || v.typ != want.typ || len(v.dims) != len(want.dims) || len(v.attrs) != len(want.attrs) {
+				t.Errorf("version %d: variable %d = %+v", version, k, v)
+			}
+			if v.begin != begins[k] || int64(v.vsize) != pad4(sizes[k]) {
+				t.Errorf("version %d: %s at %d of %d octets, want %d of %d", version, v.name, v.begin, v.vsize, begins[k], pad4(sizes[k]))
+			}
+		}
+		if r := h.vars[0].attrs["valid_range"].([]float64); len(r) != 2 || r[1] != 10 {
+			t.Errorf("version %d: valid_range %v", version, r)
+		}
+		if h.vars[3].attrs["_FillValue"] != ncFill || h.vars[3].dims[0] != 1 {
+			t.Errorf("version %d: field %+v", version, h.vars[3])
+		}
+	}
+
+	// the classic format is written when offsets fit in 32 bits, the data
+	// of each variable is where the header says
+	var buf bytes.Buffer
+	if err := f.writeTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	h := parseNC(t, data)
+	if h.version != 1 {
+		t.Errorf("version %d, want 1", h.version)
+	}
+	if last := h.vars[3]; int(last.begin)+int(last.vsize) != len(data) {
+		t.Errorf("file of %d octets, the last variable ends at %d", len(data), int(last.begin)+int(last.vsize))
+	}
+	if x := math.Float64frombits(binary.BigEndian.Uint64(data[h.vars[0].begin+16:])); x != 3 {
+		t.Errorf("x[2] = %v", x)
+	}
+	if s := string(data[h.vars[2].begin : h.vars[2].begin+4]); s != "abc\x00" {
+		t.Errorf("name = %q", s)
+	}
+	if v := math.Float32frombits(binary.BigEndian.Uint32(data[h.vars[3].begin+20:])); v != 6 {
+		t.Errorf("field[1][2] = %v", v)
+	}
+}
+
+func TestNetCDFTimes(t *testing.T) {
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	for _, stack := range []bool{false, true} {
+		dir, cleanup := tempDir(t)
+		path := filepath.Join(dir, "out.nc")
+		w, err := createNetCDF(path, Options{Stack: stack})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the fields are written again 3 hours later
+		for _, hours := range []int{0, 3} {
+			for _, f := range fields {
+				f.VerfTime = f.VerfTime.Add(time.Duration(hours) * time.Hour)
+				if err = w.WriteField(&f); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var paths []string
+		if stack {
+			paths = []string{path}
+		} else {
+			paths = []string{filepath.Join(dir, "out.2017072001.nc"), filepath.Join(dir, "out.2017072004.nc")}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("out.nc written without --stack: %v", err)
+			}
+		}
+		for k, p := range paths {
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := parseNC(t, data)
+			times := 1
+			if stack {
+				times = 2
+			}
+			if h.dims[0] != (ncDim{"time", times}) || h.dims[1] != (ncDim{"latitude", 98}) || h.dims[2] != (ncDim{"longitude", 117}) {
+				t.Errorf("%s: dimensions %v", p, h.dims)
+			}
+			if h.attrs["Conventions"] != "CF-1.8" {
+				t.Errorf("%s: attributes %v", p, h.attrs)
+			}
+			var names []string
+			for _, v := range h.vars {
+				names = append(names, v.name)
+			}
+			// time, reference_time, latitude, longitude and the 2 fields
+			if len(h.vars) != 6 || h.vars[4].attrs["standard_name"] != "eastward_wind" {
+				t.Fatalf("%s: variables %v", p, names)
+			}
+			u := h.vars[4]
+			if int(u.vsize) != 4*times*98*117 || int(h.vars[5].begin+int64(h.vars[5].vsize)) != len(data) {
+				t.Errorf("%s: %s of %d octets at %d", p, u.name, u.vsize, u.begin)
+			}
+			// latitudes go northwards like the grid, the first value is the
+			// first grid point
+			first := math.Float32frombits(binary.BigEndian.Uint32(data[u.begin:]))
+			last := math.Float32frombits(binary.BigEndian.Uint32(data[u.begin+int64(u.vsize)-4:]))
+			if first != fields[0].Values[0] || last != fields[0].Values[len(fields[0].Values)-1] {
+				t.Errorf("%s: %s from %v to %v, file %d", p, u.name, first, last, k)
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestNetCDFEmpty(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "empty.nc")
+	c := &Converter{
+		Format: lookupFormat(t, "netcdf"),
+		Options: Options{
+			Stack:  true,
+			Region: &gfs.Region{LeftLon: 100, RightLon: 110, TopLat: 10, BottomLat: 0},
+		},
+		Output: path,
+	}
+	err := c.ConvertFile(fixture("gfs.t00z.pgrb2.0p25.f001"))
+	if err == nil || !strings.Contains(err.Error(), "no grid points") {
+		t.Errorf("converting a region outside the grid: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("empty.nc written: %v", err)
+	}
+}
@@ -0,0 +1,502 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+	"os"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// Parquet physical types, encodings and codecs of parquet.thrift
+const (
+	pqInt32     = 1
+	pqInt64     = 2
+	pqFloat     = 4
+	pqDouble    = 5
+	pqByteArray = 6
+
+	pqPlain         = 0
+	pqRLE           = 3
+	pqRLEDictionary = 8
+
+	pqDataPage       = 0
+	pqDictionaryPage = 2
+
+	pqUncompressed = 0
+	pqGzip         = 2
+)
+
+// pqPageRows is the most rows of a data page, pages of about 1 MB for the
+// double columns
+const pqPageRows = 1 << 17
+
+// pqColumn is a column of the long format
+type pqColumn struct {
+	name     string
+	typ      int32
+	optional bool
+	// annotate writes the converted and logical types of the schema
+	// element of the column, if any
+	annotate func(t *thriftWriter)
+}
+
+func annotateString(t *thriftWriter) {
+	t.i32(6, 0) // UTF8
+	t.begin(10)
+	t.begin(1) // STRING
+	t.end()
+	t.end()
+}
+
+func annotateTimestamp(t *thriftWriter) {
+	t.i32(6, 10) // TIMESTAMP_MICROS
+	t.begin(10)
+	t.begin(8) // TIMESTAMP
+	t.bool(1, true)
+	t.begin(2)
+	t.begin(2) // MICROS
+	t.end()
+	t.end()
+	t.end()
+	t.end()
+}
+
+// parquetColumns are the columns of the long format, time is the reference
+// time and fh the hours from it to the valid time
+var parquetColumns = []pqColumn{
+	{name: "time", typ: pqInt64, annotate: annotateTimestamp},
+	{name: "fh", typ: pqInt32},
+	{name: "variable", typ: pqByteArray, annotate: annotateString},
+	{name: "level", typ: pqByteArray, annotate: annotateString},
+	{name: "lat", typ: pqDouble},
+	{name: "lon", typ: pqDouble},
+	{name: "value", typ: pqFloat, optional: true},
+}
+
+// pqChunk is the metadata of a column chunk
+type pqChunk struct {
+	column       *pqColumn
+	encodings    []int32
+	values       int64
+	nulls        int64
+	offset       int64
+	dictOffset   int64
+	dataOffset   int64
+	uncompressed int64
+	compressed   int64
+	min, max     []byte
+}
+
+type pqRowGroup struct {
+	chunks []pqChunk
+	rows   int64
+}
+
+// parquetWriter writes fields to a Parquet file in the long format, one row
+// per grid point. Every field is a row group, written as it comes, and the
+// metadata of the row groups is written on Close. The columns the points
+// of a field share are dictionary encoded, missing values are null. Pages
+// are gzip compressed when asked for
+type parquetWriter struct {
+	file   *os.File
+	w      *bufio.Writer
+	offset int64
+	opts   Options
+	codec  int32
+	groups []pqRowGroup
+	rows   int64
+
+	// buffers reused between pages
+	page bytes.Buffer
+	gz   *gzip.Writer
+}
+
+func createParquet(path string, opts Options) (Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &parquetWriter{file: file, w: bufio.NewWriterSize(file, 1<<20), opts: opts, codec: pqUncompressed}
+	if opts.Gzip {
+		w.codec = pqGzip
+		w.gz = gzip.NewWriter(nil)
+	}
+	if err = w.write([]byte("PAR1")); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *parquetWriter) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+func (w *parquetWriter) WriteField(f *gfs.Field) error {
+	var lats, lons []float64
+	var values []float32
+	err := eachPoint(f, w.opts, func(i, j int, lat, lon float64, v float32) error {
+		lats = append(lats, lat)
+		lons = append(lons, lon)
+		values = append(values, v)
+		return nil
+	})
+	if err != nil || len(values) == 0 {
+		return err
+	}
+
+	n := len(values)
+	g := pqRowGroup{rows: int64(n)}
+	chunk := func(c pqChunk, err error) error {
+		g.chunks = append(g.chunks, c)
+		return err
+	}
+	refTime := make([]byte, 8)
+	binary.LittleEndian.PutUint64(refTime, uint64(f.RefTime.UnixNano()/1e3))
+	fh := make([]byte, 4)
+	binary.LittleEndian.PutUint32(fh, uint32(int32(f.VerfTime.Sub(f.RefTime)/time.Hour)))
+	if err = chunk(w.writeConstant(&parquetColumns[0], refTime, n)); err != nil {
+		return err
+	}
+	if err = chunk(w.writeConstant(&parquetColumns[1], fh, n)); err != nil {
+		return err
+	}
+	if err = chunk(w.writeConstant(&parquetColumns[2], []byte(f.Name), n)); err != nil {
+		return err
+	}
+	if err = chunk(w.writeConstant(&parquetColumns[3], []byte(f.Level), n)); err != nil {
+		return err
+	}
+	if err = chunk(w.writeDoubles(&parquetColumns[4], lats)); err != nil {
+		return err
+	}
+	if err = chunk(w.writeDoubles(&parquetColumns[5], lons)); err != nil {
+		return err
+	}
+	if err = chunk(w.writeFloats(&parquetColumns[6], values)); err != nil {
+		return err
+	}
+	w.groups = append(w.groups, g)
+	w.rows += g.rows
+	return nil
+}
+
+// writeConstant writes a column chunk of n rows of the same value as a
+// dictionary of the value and pages of indexes to it. The indexes of a
+// dictionary of one value have no bits, so pages are a single run
+func (w *parquetWriter) writeConstant(c *pqColumn, value []byte, n int) (pqChunk, error) {
+	chunk := pqChunk{column: c, values: int64(n), offset: w.offset, min: value, max: value}
+	chunk.encodings = []int32{pqPlain, pqRLE, pqRLEDictionary}
+
+	var dict []byte
+	if c.typ == pqByteArray {
+		dict = make([]byte, 4, 4+len(value))
+		binary.LittleEndian.PutUint32(dict, uint32(len(value)))
+	}
+	dict = append(dict, value...)
+	chunk.dictOffset = w.offset
+	err := w.writePage(&chunk, pqDictionaryPage, dict, func(t *thriftWriter) {
+		t.begin(7)
+		t.i32(1, 1)
+		t.i32(2, pqPlain)
+		t.end()
+	})
+	if err != nil {
+		return chunk, err
+	}
+
+	chunk.dataOffset = w.offset
+	for start := 0; start < n; start += pqPageRows {
+		rows := n - start
+		if rows > pqPageRows {
+			rows = pqPageRows
+		}
+		// bit width 0, then one run of index 0
+		page := appendUvarint([]byte{0}, uint64(rows)<<1)
+		err = w.writePage(&chunk, pqDataPage, page, dataPageHeader(rows, pqRLEDictionary))
+		if err != nil {
+			return chunk, err
+		}
+	}
+	return chunk, nil
+}
+
+// writeDoubles writes a column chunk of plain encoded doubles
+func (w *parquetWriter) writeDoubles(c *pqColumn, values []float64) (pqChunk, error) {
+	chunk := pqChunk{column: c, values: int64(len(values)), offset: w.offset, dataOffset: w.offset, dictOffset: -1}
+	chunk.encodings = []int32{pqPlain}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	chunk.min = make([]byte, 8)
+	chunk.max = make([]byte, 8)
+	binary.LittleEndian.PutUint64(chunk.min, math.Float64bits(min))
+	binary.LittleEndian.PutUint64(chunk.max, math.Float64bits(max))
+
+	var page []byte
+	for start := 0; start < len(values); start += pqPageRows {
+		end := start + pqPageRows
+		if end > len(values) {
+			end = len(values)
+		}
+		page = page[:0]
+		for _, v := range values[start:end] {
+			page = appendUint64(page, math.Float64bits(v))
+		}
+		err := w.writePage(&chunk, pqDataPage, page, dataPageHeader(end-start, pqPlain))
+		if err != nil {
+			return chunk, err
+		}
+	}
+	return chunk, nil
+}
+
+// writeFloats writes a column chunk of plain encoded floats, NaN is null
+func (w *parquetWriter) writeFloats(c *pqColumn, values []float32) (pqChunk, error) {
+	chunk := pqChunk{column: c, values: int64(len(values)), offset: w.offset, dataOffset: w.offset, dictOffset: -1}
+	chunk.encodings = []int32{pqPlain, pqRLE}
+
+	min, max := float32(math.Inf(1)), float32(math.Inf(-1))
+	defined := make([]bool, len(values))
+	for k, v := range values {
+		if math.IsNaN(float64(v)) {
+			chunk.nulls++
+			continue
+		}
+		defined[k] = true
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	if chunk.nulls < chunk.values {
+		chunk.min = make([]byte, 4)
+		chunk.max = make([]byte, 4)
+		binary.LittleEndian.PutUint32(chunk.min, math.Float32bits(min))
+		binary.LittleEndian.PutUint32(chunk.max, math.Float32bits(max))
+	}
+
+	var page []byte
+	for start := 0; start < len(values); start += pqPageRows {
+		end := start + pqPageRows
+		if end > len(values) {
+			end = len(values)
+		}
+		// definition levels, prefixed by their length
+		page = appendLevels(append(page[:0], 0, 0, 0, 0), defined[start:end])
+		binary.LittleEndian.PutUint32(page, uint32(len(page)-4))
+		for k, v := range values[start:end] {
+			if defined[start+k] {
+				page = appendUint32(page, math.Float32bits(v))
+			}
+		}
+		err := w.writePage(&chunk, pqDataPage, page, dataPageHeader(end-start, pqPlain))
+		if err != nil {
+			return chunk, err
+		}
+	}
+	return chunk, nil
+}
+
+// dataPageHeader writes the header of a data page of rows values, levels
+// are always RLE encoded
+func dataPageHeader(rows int, encoding int32) func(t *thriftWriter) {
+	return func(t *thriftWriter) {
+		t.begin(5)
+		t.i32(1, int32(rows))
+		t.i32(2, encoding)
+		t.i32(3, pqRLE)
+		t.i32(4, pqRLE)
+		t.end()
+	}
+}
+
+// writePage compresses and writes a page, header writes the header of its
+// type, and adds its sizes to the chunk
+func (w *parquetWriter) writePage(chunk *pqChunk, pageType int32, page []byte, header func(t *thriftWriter)) error {
+	data := page
+	if w.gz != nil {
+		w.page.Reset()
+		w.gz.Reset(&w.page)
+		if _, err := w.gz.Write(page); err != nil {
+			return err
+		}
+		if err := w.gz.Close(); err != nil {
+			return err
+		}
+		data = w.page.Bytes()
+	}
+
+	t := &thriftWriter{}
+	t.i32(1, pageType)
+	t.i32(2, int32(len(page)))
+	t.i32(3, int32(len(data)))
+	header(t)
+	t.end()
+
+	chunk.uncompressed += int64(len(t.b) + len(page))
+	chunk.compressed += int64(len(t.b) + len(data))
+	if err := w.write(t.b); err != nil {
+		return err
+	}
+	return w.write(data)
+}
+
+// appendLevels appends definition levels of bit width 1 in the RLE and bit
+// packing hybrid encoding. Runs of 8 or more are run length encoded, the
+// levels between them are bit packed in groups of 8
+func appendLevels(b []byte, defined []bool) []byte {
+	run := func(k int) int {
+		n := 1
+		for k+n < len(defined) && defined[k+n] == defined[k] {
+			n++
+		}
+		return n
+	}
+	for k := 0; k < len(defined); {
+		if n := run(k); n >= 8 {
+			b = appendUvarint(b, uint64(n)<<1)
+			if defined[k] {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+			k += n
+			continue
+		}
+
+		// the last group is padded with zeros
+		start := k
+		for k += 8; k < len(defined) && run(k) < 8; k += 8 {
+		}
+		groups := (k - start) / 8
+		b = appendUvarint(b, uint64(groups)<<1|1)
+		for g := 0; g < groups; g++ {
+			var packed byte
+			for bit := 0; bit < 8; bit++ {
+				if p := start + g*8 + bit; p < len(defined) && defined[p] {
+					packed |= 1 << uint(bit)
+				}
+			}
+			b = append(b, packed)
+		}
+	}
+	return b
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
+
+// footer returns the file metadata
+func (w *parquetWriter) footer() []byte {
+	t := &thriftWriter{}
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(parquetColumns)+1)
+	t.element()
+	t.string(4, "schema")
+	t.i32(5, int32(len(parquetColumns)))
+	t.end()
+	for k := range parquetColumns {
+		c := &parquetColumns[k]
+		t.element()
+		t.i32(1, c.typ)
+		if c.optional {
+			t.i32(3, 1)
+		} else {
+			t.i32(3, 0)
+		}
+		t.string(4, c.name)
+		if c.annotate != nil {
+			c.annotate(t)
+		}
+		t.end()
+	}
+
+	t.i64(3, w.rows)
+	t.list(4, thriftStruct, len(w.groups))
+	for _, g := range w.groups {
+		t.element()
+		var uncompressed, compressed int64
+		t.list(1, thriftStruct, len(g.chunks))
+		for _, c := range g.chunks {
+			uncompressed += c.uncompressed
+			compressed += c.compressed
+			t.element()
+			t.i64(2, c.offset)
+			t.begin(3)
+			t.i32(1, c.column.typ)
+			t.list(2, thriftI32, len(c.encodings))
+			for _, e := range c.encodings {
+				t.varint(int64(e))
+			}
+			t.list(3, thriftBinary, 1)
+			t.bytes([]byte(c.column.name))
+			t.i32(4, w.codec)
+			t.i64(5, c.values)
+			t.i64(6, c.uncompressed)
+			t.i64(7, c.compressed)
+			t.i64(9, c.dataOffset)
+			if c.dictOffset >= 0 {
+				t.i64(11, c.dictOffset)
+			}
+			t.begin(12)
+			t.i64(3, c.nulls)
+			if c.min != nil {
+				t.binary(5, c.max)
+				t.binary(6, c.min)
+			}
+			t.end()
+			t.end()
+			t.end()
+		}
+		t.i64(2, uncompressed)
+		t.i64(3, g.rows)
+		t.i64(5, g.chunks[0].offset)
+		t.i64(6, compressed)
+		t.end()
+	}
+	t.string(6, "nimbus")
+
+	// min and max values are in the order of their types
+	t.list(7, thriftStruct, len(parquetColumns))
+	for range parquetColumns {
+		t.element()
+		t.begin(1)
+		t.end()
+		t.end()
+	}
+	t.end()
+	return t.b
+}
+
+func (w *parquetWriter) Close() error {
+	footer := w.footer()
+	footer = appendUint32(footer, uint32(len(footer)))
+	footer = append(footer, "PAR1"...)
+	err := w.write(footer)
+	if err == nil {
+		err = w.w.Flush()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
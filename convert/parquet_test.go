@@ -0,0 +1,278 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// thriftReader decodes compact protocol structs into maps of field ids to
+// int64, bool, []byte, []interface{} or nested maps
+type thriftReader struct {
+	t *testing.T
+	b []byte
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.t.Fatalf("bad varint")
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		v := r.b[:n]
+		r.b = r.b[n:]
+		return v
+	case thriftList:
+		header := r.b[0]
+		r.b = r.b[1:]
+		n := int(header >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		elem := header & 0x0f
+		list := make([]interface{}, n)
+		for k := range list {
+			list[k] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	r.t.Fatalf("thrift type %d", typ)
+	return nil
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	s := make(map[int16]interface{})
+	var id int16
+	for {
+		header := r.b[0]
+		r.b = r.b[1:]
+		if header == 0 {
+			return s
+		}
+		if d := int16(header >> 4); d > 0 {
+			id += d
+		} else {
+			id = int16(r.varint())
+		}
+		s[id] = r.value(header & 0x0f)
+	}
+}
+
+func readThrift(t *testing.T, b []byte) (map[int16]interface{}, int) {
+	r := &thriftReader{t: t, b: b}
+	s := r.readStruct()
+	return s, len(b) - len(r.b)
+}
+
+// readParquet checks the magic numbers and footer length of a Parquet file
+// and returns it with its metadata
+func readParquet(t *testing.T, path string) ([]byte, map[int16]interface{}) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(data)
+	if string(data[:4]) != "PAR1" || string(data[n-4:]) != "PAR1" {
+		t.Fatalf("magic %q and %q", data[:4], data[n-4:])
+	}
+	length := int(binary.LittleEndian.Uint32(data[n-8:]))
+	if length <= 0 || length > n-12 {
+		t.Fatalf("footer of %d octets in a file of %d", length, n)
+	}
+	meta, read := readThrift(t, data[n-8-length:n-8])
+	if read != length {
+		t.Fatalf("footer of %d octets, %d read", length, read)
+	}
+	return data, meta
+}
+
+// columnValues reads the plain encoded values of the data pages of a
+// column chunk of required or optional columns without nulls
+func columnValues(t *testing.T, data []byte, chunk map[int16]interface{}, size int) []byte {
+	meta := chunk[3].(map[int16]interface{})
+	offset := meta[9].(int64)
+	end := chunk[2].(int64) + meta[7].(int64)
+	var values []byte
+	for offset < end {
+		header, n := readThrift(t, data[offset:])
+		page := data[offset+int64(n) : offset+int64(n)+header[3].(int64)]
+		offset += int64(n) + header[3].(int64)
+		if header[1].(int64) != pqDataPage {
+			t.Fatalf("page type %d", header[1])
+		}
+		rows := int(header[5].(map[int16]interface{})[1].(int64))
+		// skip the definition levels of optional columns
+		if len(page) > rows*size {
+			page = page[4+binary.LittleEndian.Uint32(page):]
+		}
+		values = append(values, page...)
+	}
+	return values
+}
+
+func TestParquet(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	c := &Converter{
+		Format: lookupFormat(t, "parquet"),
+		Output: filepath.Join(dir, "date={date}", "cycle={cycle}", "gfs.parquet"),
+	}
+	convert(t, c, "gfs.t00z.pgrb2.0p25.f001")
+
+	data, meta := readParquet(t, filepath.Join(dir, "date=20170720", "cycle=00", "gfs.parquet"))
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	points := int64(len(fields[0].Values))
+	if meta[1].(int64) != 1 || meta[3].(int64) != 2*points || string(meta[6].([]byte)) != "nimbus" {
+		t.Errorf("version %v, %v rows, created by %q", meta[1], meta[3], meta[6])
+	}
+
+	schema := meta[2].([]interface{})
+	root := schema[0].(map[int16]interface{})
+	if string(root[4].([]byte)) != "schema" || root[5].(int64) != 7 {
+		t.Errorf("root %v", root)
+	}
+	want := []struct {
+		name      string
+		typ       int64
+		optional  bool
+		converted int64
+	}{
+		{"time", pqInt64, false, 10},
+		{"fh", pqInt32, false, -1},
+		{"variable", pqByteArray, false, 0},
+		{"level", pqByteArray, false, 0},
+		{"lat", pqDouble, false, -1},
+		{"lon", pqDouble, false, -1},
+		{"value", pqFloat, true, -1},
+	}
+	if len(schema) != len(want)+1 {
+		t.Fatalf("%d schema elements", len(schema))
+	}
+	for k, w := range want {
+		e := schema[k+1].(map[int16]interface{})
+		converted, ok := e[6].(int64)
+		if !ok {
+			converted = -1
+		}
+		if string(e[4].([]byte)) != w.name || e[1].(int64) != w.typ || (e[3].(int64) == 1) != w.optional || converted != w.converted {
+			t.Errorf("column %d: %v", k, e)
+		}
+	}
+
+	// a row group per message, the chunks follow each other from the magic
+	groups := meta[4].([]interface{})
+	if len(groups) != 2 {
+		t.Fatalf("%d row groups", len(groups))
+	}
+	offset := int64(4)
+	for g, group := range groups {
+		group := group.(map[int16]interface{})
+		chunks := group[1].([]interface{})
+		if group[3].(int64) != points || len(chunks) != 7 || group[5].(int64) != offset {
+			t.Fatalf("row group %d: %v", g, group)
+		}
+		for k, chunk := range chunks {
+			chunk := chunk.(map[int16]interface{})
+			m := chunk[3].(map[int16]interface{})
+			if chunk[2].(int64) != offset || m[5].(int64) != points || string(m[3].([]interface{})[0].([]byte)) != want[k].name {
+				t.Errorf("row group %d, chunk %d: %v", g, k, chunk)
+			}
+			offset += m[7].(int64)
+		}
+
+		// the dictionary of the variable names
+		variable := chunks[2].(map[int16]interface{})
+		dict := variable[3].(map[int16]interface{})[11].(int64)
+		header, n := readThrift(t, data[dict:])
+		page := data[dict+int64(n):]
+		if header[1].(int64) != pqDictionaryPage || string(page[4:4+binary.LittleEndian.Uint32(page)]) != fields[g].Name {
+			t.Errorf("row group %d: dictionary %v of %q", g, header, page[:8])
+		}
+
+		lats := columnValues(t, data, chunks[4].(map[int16]interface{}), 8)
+		values := columnValues(t, data, chunks[6].(map[int16]interface{}), 4)
+		if int64(len(lats)) != 8*points || int64(len(values)) != 4*points {
+			t.Fatalf("row group %d: %d octets of latitudes, %d of values", g, len(lats), len(values))
+		}
+		for n, v := range fields[g].Values {
+			lat, _ := fields[g].Grid.LatLon(fields[g].Grid.Point(n))
+			if got := math.Float64frombits(binary.LittleEndian.Uint64(lats[8*n:])); got != lat {
+				t.Fatalf("row group %d, row %d: latitude %v, want %v", g, n, got, lat)
+			}
+			if got := math.Float32frombits(binary.LittleEndian.Uint32(values[4*n:])); got != v {
+				t.Fatalf("row group %d, row %d: value %v, want %v", g, n, got, v)
+			}
+		}
+	}
+	if offset != int64(len(data))-8-int64(binary.LittleEndian.Uint32(data[len(data)-8:])) {
+		t.Errorf("the chunks end at %d", offset)
+	}
+}
+
+func TestParquetNulls(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	c := &Converter{
+		Format:  lookupFormat(t, "parquet"),
+		Options: Options{Gzip: true},
+		Output:  filepath.Join(dir, "out.parquet"),
+	}
+	convert(t, c, "complex.grb2")
+
+	_, meta := readParquet(t, c.Output)
+	groups := meta[4].([]interface{})
+	if len(groups) != 5 {
+		t.Fatalf("%d row groups", len(groups))
+	}
+	// the fourth and fifth fields have missing values
+	for g, nulls := range []int64{0, 0, 0, 690, 1271} {
+		chunks := groups[g].(map[int16]interface{})[1].([]interface{})
+		m := chunks[6].(map[int16]interface{})[3].(map[int16]interface{})
+		stats := m[12].(map[int16]interface{})
+		if m[4].(int64) != pqGzip || stats[3].(int64) != nulls {
+			t.Errorf("row group %d: codec %v, %v nulls, want %d", g, m[4], stats[3], nulls)
+		}
+		min := math.Float32frombits(binary.LittleEndian.Uint32(stats[6].([]byte)))
+		max := math.Float32frombits(binary.LittleEndian.Uint32(stats[5].([]byte)))
+		if !(min <= max) {
+			t.Errorf("row group %d: from %v to %v", g, min, max)
+		}
+	}
+}
+
+func TestAppendLevels(t *testing.T) {
+	defined := make([]bool, 20)
+	for k := range defined {
+		defined[k] = k < 10 || k == 12
+	}
+	// a run of 10 defined, then 10 levels bit packed in 2 groups
+	got := appendLevels(nil, defined)
+	if want := []byte{10 << 1, 1, 2<<1 | 1, 0x04, 0x00}; !bytes.Equal(got, want) {
+		t.Errorf("levels % x, want % x", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package convert
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+
+	// the pure Go SQLite engine, registered as the sqlite driver
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTime is how times are written, the format of the SQLite date and
+// time functions
+const sqliteTime = "2006-01-02 15:04:05"
+
+// sqliteBusyTimeout is how long a writer waits for the locks of another
+// connection to the database before giving up
+const sqliteBusyTimeout = time.Minute
+
+// sqliteSchema are the tables and indexes of the databases. Values repeat
+// the valid time of their field so points can be looked up by location and
+// time with the index on them alone
+var sqliteSchema = []string{
+	"CREATE TABLE IF NOT EXISTS runs (id INTEGER PRIMARY KEY, ref_time TEXT NOT NULL)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS runs_ref_time ON runs (ref_time)",
+	"CREATE TABLE IF NOT EXISTS fields (id INTEGER PRIMARY KEY, run_id INTEGER NOT NULL REFERENCES runs (id), " +
+		"variable TEXT NOT NULL, level TEXT NOT NULL, forecast_hour INTEGER NOT NULL, " +
+		"valid_time TEXT NOT NULL, description TEXT, unit TEXT)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS fields_key ON fields (run_id, variable, level, forecast_hour)",
+	`CREATE TABLE IF NOT EXISTS "values" (id INTEGER PRIMARY KEY, field_id INTEGER NOT NULL REFERENCES fields (id), ` +
+		"lat REAL NOT NULL, lon REAL NOT NULL, valid_time TEXT NOT NULL, value REAL)",
+	`CREATE UNIQUE INDEX IF NOT EXISTS values_key ON "values" (field_id, lat, lon)`,
+	`CREATE INDEX IF NOT EXISTS values_lat_lon_valid_time ON "values" (lat, lon, valid_time)`,
+}
+
+// the upserts of sqliteWriter, a row that is already there is updated in
+// place and keeps its id
+const (
+	sqliteUpsertRun = "INSERT INTO runs (ref_time) VALUES (?) " +
+		"ON CONFLICT (ref_time) DO UPDATE SET ref_time = excluded.ref_time RETURNING id"
+	sqliteUpsertField = "INSERT INTO fields (run_id, variable, level, forecast_hour, valid_time, description, unit) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (run_id, variable, level, forecast_hour) DO UPDATE SET " +
+		"valid_time = excluded.valid_time, description = excluded.description, unit = excluded.unit RETURNING id"
+	sqliteUpsertValue = `INSERT INTO "values" (field_id, lat, lon, valid_time, value) VALUES (?, ?, ?, ?, ?) ` +
+		"ON CONFLICT (field_id, lat, lon) DO UPDATE SET valid_time = excluded.valid_time, value = excluded.value"
+)
+
+// sqliteWriter upserts the runs, their fields and the values of their points
+// into a SQLite database, created when it is not there. A run, a field of a
+// run or a point of a field that is already there is updated and keeps its
+// id, the other tables, indexes, views and triggers of the database are
+// left to SQLite. Every field is written in a transaction of its own, which
+// takes the locks of the database and waits for those of other connections
+type sqliteWriter struct {
+	db   *sql.DB
+	opts Options
+}
+
+func createSQLite(path string, opts Options) (Writer, error) {
+	if path == "-" {
+		return nil, errors.New("sqlite: databases can't be written to stdout")
+	}
+	if opts.Gzip {
+		return nil, errors.New("sqlite: databases can't be gzipped")
+	}
+	// transactions take the write lock when they begin, so a field is never
+	// half written when another connection got there first
+	query := url.Values{
+		"_pragma": {fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout.Milliseconds())},
+		"_txlock": {"immediate"},
+	}
+	db, err := sql.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// one connection, its statements and transactions run one after another
+	db.SetMaxOpenConns(1)
+	for _, stmt := range sqliteSchema {
+		if _, err = db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return &sqliteWriter{db: db, opts: opts}, nil
+}
+
+func (w *sqliteWriter) WriteField(f *gfs.Field) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = w.upsertField(tx, f); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("sqlite: %s: %v", f.Name, err)
+	}
+	return tx.Commit()
+}
+
+func (w *sqliteWriter) upsertField(tx *sql.Tx, f *gfs.Field) error {
+	var runID, fieldID int64
+	err := tx.QueryRow(sqliteUpsertRun, f.RefTime.UTC().Format(sqliteTime)).Scan(&runID)
+	if err != nil {
+		return err
+	}
+	validTime := f.VerfTime.UTC().Format(sqliteTime)
+	forecastHour := int64(f.VerfTime.Sub(f.RefTime).Hours())
+	err = tx.QueryRow(sqliteUpsertField, runID, f.Name, f.Level, forecastHour,
+		validTime, f.Description, f.Unit).Scan(&fieldID)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(sqliteUpsertValue)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	return eachPoint(f, w.opts, func(i, j int, lat, lon float64, v float32) error {
+		// a missing value is NULL
+		var value interface{}
+		if !math.IsNaN(float64(v)) {
+			value = float64(v)
+		}
+		_, err := stmt.Exec(fieldID, lat, lon, validTime, value)
+		return err
+	})
+}
+
+func (w *sqliteWriter) Close() error {
+	return w.db.Close()
+}
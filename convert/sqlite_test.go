@@ -0,0 +1,247 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// openSQLite opens a database the way another program would
+func openSQLite(t *testing.T, path string) *sql.DB {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(60000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// checkSQLite runs the integrity check of SQLite over a database
+func checkSQLite(t *testing.T, db *sql.DB) {
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result != "ok" {
+		t.Errorf("integrity check: %s", result)
+	}
+}
+
+// countSQLite returns the number of rows of a query
+func countSQLite(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// upsertSQLite writes the fields of the GFS fixture over 1W-1E, 36N-37N to
+// a database, shifted by some hours
+func upsertSQLite(t *testing.T, path string, hours ...int) {
+	region, err := gfs.ParseRegion("-1,1,37,36")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	w, err := createSQLite(path, Options{Region: region})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer w.Close()
+	for _, h := range hours {
+		for _, f := range readFields(t, "gfs.t00z.pgrb2.0p25.f001") {
+			f.RefTime = f.RefTime.Add(time.Duration(h) * time.Hour)
+			f.VerfTime = f.VerfTime.Add(time.Duration(h) * time.Hour)
+			if err = w.WriteField(&f); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "gfs.sqlite")
+	upsertSQLite(t, path, 0, 24)
+	db := openSQLite(t, path)
+	defer db.Close()
+	checkSQLite(t, db)
+
+	for _, name := range []string{"runs", "runs_ref_time", "fields", "fields_key", "values", "values_key", "values_lat_lon_valid_time"} {
+		if countSQLite(t, db, "SELECT count(*) FROM sqlite_master WHERE name = ?", name) != 1 {
+			t.Errorf("%s is missing", name)
+		}
+	}
+	rows, err := db.Query("SELECT id, ref_time FROM runs ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs []string
+	for rows.Next() {
+		var id int64
+		var refTime string
+		if err = rows.Scan(&id, &refTime); err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, refTime)
+	}
+	rows.Close()
+	if len(runs) != 2 || runs[0] != "2017-07-20 00:00:00" || runs[1] != "2017-07-21 00:00:00" {
+		t.Errorf("runs %v", runs)
+	}
+	if n := countSQLite(t, db, "SELECT count(*) FROM fields WHERE forecast_hour = 1"); n != 4 {
+		t.Errorf("%d fields", n)
+	}
+	if n := countSQLite(t, db, `SELECT count(*) FROM "values"`); n != 4*45 {
+		t.Errorf("%d values", n)
+	}
+	var variable, validTime string
+	var lat, lon float64
+	err = db.QueryRow(`SELECT f.variable, v.lat, v.lon, v.valid_time FROM "values" v JOIN fields f ON f.id = v.field_id WHERE v.id = 1`).
+		Scan(&variable, &lat, &lon, &validTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variable != "UGRD" || lat != 36 || lon != 359 || validTime != "2017-07-20 01:00:00" {
+		t.Errorf("first value of %s at %v, %v at %s", variable, lat, lon, validTime)
+	}
+}
+
+func TestSQLiteOwnObjects(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "own-objects.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "own.sqlite")
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the run there is updated and a new one added, twice
+	upsertSQLite(t, path, 0, 24)
+	db := openSQLite(t, path)
+	defer db.Close()
+	checkSQLite(t, db)
+	ids := countSQLite(t, db, `SELECT max(id) FROM "values"`)
+	db.Close()
+
+	upsertSQLite(t, path, 0, 24)
+	db = openSQLite(t, path)
+	checkSQLite(t, db)
+	if n := countSQLite(t, db, "SELECT count(*) FROM runs"); n != 2 {
+		t.Errorf("%d runs", n)
+	}
+	if n := countSQLite(t, db, `SELECT count(*) FROM "values"`); n != 4*45 {
+		t.Errorf("%d values", n)
+	}
+	if n := countSQLite(t, db, `SELECT max(id) FROM "values"`); n != ids {
+		t.Errorf("values upserted again up to id %d, %d before", n, ids)
+	}
+
+	// the objects of sqlite3 are kept, its index follows the values
+	for _, name := range []string{"notes", "notes_note", "values_value", "ugrd", "notes_touch", "sqlite_stat1"} {
+		if countSQLite(t, db, "SELECT count(*) FROM sqlite_master WHERE name = ?", name) != 1 {
+			t.Errorf("%s is missing", name)
+		}
+	}
+	if n := countSQLite(t, db, "SELECT count(*) FROM notes"); n != 40 {
+		t.Errorf("%d notes", n)
+	}
+	if n := countSQLite(t, db, "SELECT count(*) FROM ugrd"); n != 45 {
+		t.Errorf("%d UGRD values in the view", n)
+	}
+	if n := countSQLite(t, db, `SELECT count(*) FROM "values" INDEXED BY values_value WHERE value > -1000`); n != 4*45 {
+		t.Errorf("%d values in values_value", n)
+	}
+}
+
+func TestSQLiteConcurrent(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "gfs.sqlite")
+	upsertSQLite(t, path, 0)
+
+	// another program holds the database, the writers wait for it
+	db := openSQLite(t, path)
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.ExecContext(context.Background(), "BEGIN EXCLUSIVE"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.ExecContext(context.Background(), "DELETE FROM runs WHERE id > 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for _, h := range []int{24, 48, 72} {
+		wg.Add(1)
+		go func(h int) {
+			defer wg.Done()
+			upsertSQLite(t, path, 0, h)
+		}(h)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("wrote a database another program holds")
+	case <-time.After(200 * time.Millisecond):
+	}
+	if _, err = conn.ExecContext(context.Background(), "COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	<-done
+
+	checkSQLite(t, db)
+	if n := countSQLite(t, db, "SELECT count(*) FROM runs"); n != 4 {
+		t.Errorf("%d runs", n)
+	}
+	if n := countSQLite(t, db, "SELECT count(*) FROM fields"); n != 4*2 {
+		t.Errorf("%d fields", n)
+	}
+	if n := countSQLite(t, db, `SELECT count(*) FROM "values"`); n != 4*2*45 {
+		t.Errorf("%d values", n)
+	}
+}
+
+func TestSQLiteUnsupported(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	if _, err := createSQLite(filepath.Join(dir, "gfs.sqlite"), Options{Gzip: true}); err == nil {
+		t.Error("gzipped a database")
+	}
+	if _, err := createSQLite("-", Options{}); err == nil {
+		t.Error("wrote a database to stdout")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "text.sqlite"), []byte("not a database, but long enough to have a header"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createSQLite(filepath.Join(dir, "text.sqlite"), Options{}); err == nil {
+		t.Error("wrote to a file that is not a database")
+	}
+}
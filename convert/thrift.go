@@ -0,0 +1,113 @@
+package convert
+
+// thrift types of the compact protocol
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol, the
+// encoding of the metadata of Parquet files. Fields must be written in the
+// order of their ids and every struct, begun by begin or element, must be
+// ended by end
+type thriftWriter struct {
+	b []byte
+	// last is the id of the last field of the current struct, stack those
+	// of the structs around it
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if d := id - t.last; d > 0 && d <= 15 {
+		t.b = append(t.b, byte(d)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+// varint appends a zigzag encoded integer
+func (t *thriftWriter) varint(v int64) {
+	t.b = appendUvarint(t.b, uint64(v<<1^v>>63))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.bytes(v)
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.binary(id, []byte(v))
+}
+
+// bytes appends a binary value without a field header, for list elements
+func (t *thriftWriter) bytes(v []byte) {
+	t.b = appendUvarint(t.b, uint64(len(v)))
+	t.b = append(t.b, v...)
+}
+
+// list begins a list of n elements of a type, the elements follow
+func (t *thriftWriter) list(id int16, typ byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|typ)
+		return
+	}
+	t.b = append(t.b, 0xf0|typ)
+	t.b = appendUvarint(t.b, uint64(n))
+}
+
+// begin begins a struct field
+func (t *thriftWriter) begin(id int16) {
+	t.field(id, thriftStruct)
+	t.element()
+}
+
+// element begins a struct that is an element of a list
+func (t *thriftWriter) element() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// end ends the current struct
+func (t *thriftWriter) end() {
+	t.b = append(t.b, 0)
+	if n := len(t.stack); n > 0 {
+		t.last = t.stack[n-1]
+		t.stack = t.stack[:n-1]
+	}
+}
+
+// appendUvarint appends an unsigned LEB128 varint, as Parquet and Thrift use
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
@@ -0,0 +1,713 @@
+package convert
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// zarrChunks are the chunk sizes along time, rows and columns used unless
+// the options give others, small in space and long in time so a time series
+// of a point reads few chunks
+var zarrChunks = []int{24, 128, 128}
+
+// zarrEpoch is the origin of the time coordinates, fixed so stores of
+// different cycles can be appended to each other
+var zarrEpoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const zarrTimeUnits = "hours since 1970-01-01 00:00:00"
+
+// zarrArray is the .zarray metadata of an array, the keys in the order
+// zarr writes them
+type zarrArray struct {
+	Chunks     []int       `json:"chunks"`
+	Compressor *zarrCodec  `json:"compressor"`
+	DType      string      `json:"dtype"`
+	FillValue  interface{} `json:"fill_value"`
+	Filters    interface{} `json:"filters"`
+	Order      string      `json:"order"`
+	Shape      []int       `json:"shape"`
+	ZarrFormat int         `json:"zarr_format"`
+}
+
+// zarrCodec is a numcodecs compressor, only zlib and gzip are supported
+type zarrCodec struct {
+	ID    string `json:"id"`
+	Level int    `json:"level"`
+}
+
+// parseCompression parses a compression of the options, a codec with an
+// optional level like zlib:5, or none
+func parseCompression(s string, gz bool) (*zarrCodec, error) {
+	if s == "" {
+		s = "zlib"
+		if gz {
+			s = "gzip"
+		}
+	}
+	parts := strings.SplitN(strings.ToLower(s), ":", 2)
+	c := &zarrCodec{ID: parts[0], Level: 1}
+	switch c.ID {
+	case "none":
+		return nil, nil
+	case "zlib", "gzip":
+	default:
+		return nil, fmt.Errorf("unknown compression: %s, must be zlib, gzip or none", s)
+	}
+	if len(parts) == 2 {
+		level, err := strconv.Atoi(parts[1])
+		if err != nil || level < 0 || level > 9 {
+			return nil, fmt.Errorf("invalid compression level: %s", parts[1])
+		}
+		c.Level = level
+	}
+	return c, nil
+}
+
+func (c *zarrCodec) encode(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch c.ID {
+	case "zlib":
+		w, err = zlib.NewWriterLevel(&buf, c.Level)
+	case "gzip":
+		w, err = gzip.NewWriterLevel(&buf, c.Level)
+	default:
+		err = fmt.Errorf("unsupported zarr compressor: %s", c.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *zarrCodec) decode(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	var r io.Reader
+	var err error
+	switch c.ID {
+	case "zlib":
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	default:
+		err = fmt.Errorf("unsupported zarr compressor: %s", c.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+// zarrRecord is a field spooled to the temporary file, its values are the
+// little endian float32 of the window
+type zarrRecord struct {
+	field  gfs.Field
+	offset int64
+}
+
+// zarrKey identifies an entry of the time dimension, entries of different
+// cycles with the same valid time are apart
+type zarrKey struct {
+	ref, valid float64
+}
+
+func newZarrKey(f *gfs.Field) zarrKey {
+	return zarrKey{ref: f.RefTime.Sub(zarrEpoch).Hours(), valid: f.VerfTime.Sub(zarrEpoch).Hours()}
+}
+
+// zarrWriter writes fields to a Zarr v2 directory store, one array of
+// dimensions (time, lat, lon) per variable and level, or (time, y, x) on
+// projected grids. Fields are spooled to a temporary file and the chunks
+// are written on Close. Writing to an existing store appends the times it
+// lacks, the chunks they share with the times already there are read and
+// rewritten, and the times it has are overwritten
+type zarrWriter struct {
+	path       string
+	opts       Options
+	compressor *zarrCodec
+	chunks     []int
+	spool      *os.File
+	size       int64
+	window     *window
+	records    []zarrRecord
+}
+
+func createZarr(path string, opts Options) (Writer, error) {
+	compressor, err := parseCompression(opts.Compression, opts.Gzip)
+	if err != nil {
+		return nil, err
+	}
+	chunks := append([]int(nil), zarrChunks...)
+	for k, n := range opts.Chunks {
+		if k < len(chunks) && n > 0 {
+			chunks[k] = n
+		}
+	}
+
+	// the store is only made when Close writes its metadata, a region
+	// outside the grid leaves no empty store behind
+	if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a zarr store", path)
+	}
+	if _, err := os.Stat(filepath.Join(path, ".zgroup")); os.IsNotExist(err) {
+		entries, _ := ioutil.ReadDir(path)
+		if len(entries) > 0 {
+			return nil, fmt.Errorf("%s is not a zarr store", path)
+		}
+	}
+	spool, err := ioutil.TempFile("", "nimbus-zarr-")
+	if err != nil {
+		return nil, err
+	}
+	return &zarrWriter{path: path, opts: opts, compressor: compressor, chunks: chunks, spool: spool}, nil
+}
+
+func (w *zarrWriter) WriteField(f *gfs.Field) error {
+	if w.window == nil {
+		win, err := newWindow(f.Grid, w.opts)
+		if err != nil {
+			return err
+		}
+		w.window = win
+	} else if !w.window.sameGrid(f.Grid) {
+		return fmt.Errorf("zarr: %s %s is on another grid than the fields before it, write one variable per store", f.Name, f.Level)
+	}
+
+	values := w.window.values(f)
+	buf := make([]byte, 4*len(values))
+	for k, v := range values {
+		binary.LittleEndian.PutUint32(buf[4*k:], math.Float32bits(v))
+	}
+	if _, err := w.spool.WriteAt(buf, w.size); err != nil {
+		return err
+	}
+
+	record := zarrRecord{field: *f, offset: w.size}
+	record.field.Values = nil
+	w.records = append(w.records, record)
+	w.size += int64(len(buf))
+	return nil
+}
+
+// Close writes the arrays and the consolidated metadata of the store
+func (w *zarrWriter) Close() error {
+	defer os.Remove(w.spool.Name())
+	defer w.spool.Close()
+	if len(w.records) == 0 {
+		return nil
+	}
+
+	// the horizontal coordinates, the axes of rectilinear grids or the
+	// latitude and longitude of every point
+	win := w.window
+	ny, nx := len(win.js), len(win.is)
+	dims := []string{"time", "lat", "lon"}
+	coordinates := ""
+	latAttrs := map[string]interface{}{
+		"standard_name": "latitude",
+		"long_name":     "latitude",
+		"units":         "degrees_north",
+	}
+	lonAttrs := map[string]interface{}{
+		"standard_name": "longitude",
+		"long_name":     "longitude",
+		"units":         "degrees_east",
+	}
+	var lats, lons []float64
+	latDims, lonDims := []string{"lat"}, []string{"lon"}
+	if win.grid.Rectilinear() {
+		lats, lons = win.axes()
+		latAttrs["axis"], lonAttrs["axis"] = "Y", "X"
+	} else {
+		dims = []string{"time", "y", "x"}
+		latDims, lonDims = dims[1:], dims[1:]
+		coordinates = "lat lon"
+		lats, lons = win.latLons()
+	}
+	if err := w.checkCoordinate("lat", latDims, lats); err != nil {
+		return err
+	}
+	if err := w.checkCoordinate("lon", lonDims, lons); err != nil {
+		return err
+	}
+
+	err := w.writeJSON(".zgroup", map[string]int{"zarr_format": 2})
+	if err == nil {
+		err = w.writeJSON(".zattrs", map[string]string{"Conventions": "CF-1.8", "source": "nimbus"})
+	}
+	if err != nil {
+		return err
+	}
+
+	// the time dimension, the entries of the store followed by new ones
+	var keys []zarrKey
+	if _, err := os.Stat(filepath.Join(w.path, "time", ".zarray")); err == nil {
+		valid, err := w.readFloats("time")
+		if err != nil {
+			return err
+		}
+		ref, err := w.readFloats("reference_time")
+		if err != nil {
+			return err
+		}
+		if len(ref) != len(valid) {
+			return fmt.Errorf("%s: time and reference_time differ in length", w.path)
+		}
+		for k := range valid {
+			keys = append(keys, zarrKey{ref: ref[k], valid: valid[k]})
+		}
+	}
+	stored := len(keys)
+	timeIndex := make(map[zarrKey]int)
+	for k, key := range keys {
+		timeIndex[key] = k
+	}
+	var added []zarrKey
+	for _, r := range w.records {
+		key := newZarrKey(&r.field)
+		if _, ok := timeIndex[key]; !ok {
+			timeIndex[key] = -1
+			added = append(added, key)
+		}
+	}
+	sort.Slice(added, func(a, b int) bool {
+		if added[a].valid != added[b].valid {
+			return added[a].valid < added[b].valid
+		}
+		return added[a].ref < added[b].ref
+	})
+	for _, key := range added {
+		timeIndex[key] = len(keys)
+		keys = append(keys, key)
+	}
+
+	valid := make([]float64, len(keys))
+	ref := make([]float64, len(keys))
+	fh := make([]float64, len(keys))
+	for k, key := range keys {
+		valid[k], ref[k], fh[k] = key.valid, key.ref, key.valid-key.ref
+	}
+	err = w.writeCoordinate("time", []string{"time"}, valid, map[string]interface{}{
+		"standard_name": "time",
+		"long_name":     "verification time",
+		"units":         zarrTimeUnits,
+		"calendar":      "proleptic_gregorian",
+		"axis":          "T",
+	})
+	if err == nil {
+		err = w.writeCoordinate("reference_time", []string{"time"}, ref, map[string]interface{}{
+			"standard_name": "forecast_reference_time",
+			"units":         zarrTimeUnits,
+			"calendar":      "proleptic_gregorian",
+		})
+	}
+	if err == nil {
+		err = w.writeCoordinate("fh", []string{"time"}, fh, map[string]interface{}{
+			"standard_name": "forecast_period",
+			"long_name":     "forecast hour",
+			"units":         "hours",
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	// the horizontal coordinates, the store has the same or none
+	err = w.writeCoordinate("lat", latDims, lats, latAttrs)
+	if err == nil {
+		err = w.writeCoordinate("lon", lonDims, lons, lonAttrs)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the data arrays, in the order their first fields were written
+	var names []string
+	byName := make(map[string][]zarrRecord)
+	for _, r := range w.records {
+		name := variableName(r.field.Name + "_" + r.field.Level)
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], r)
+	}
+	for _, name := range names {
+		err = w.writeData(name, dims, coordinates, byName[name], timeIndex, len(keys), ny, nx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// arrays of the store no field was written to get the new times as
+	// missing values, chunks that are not there read as the fill value
+	if len(keys) > stored {
+		err = w.growArrays(names, len(keys), ny, nx)
+		if err != nil {
+			return err
+		}
+	}
+	return w.consolidate()
+}
+
+// checkCoordinate returns an error when the store has a coordinate array
+// with other values, the times it holds would otherwise be given the
+// coordinates of the fields written now
+func (w *zarrWriter) checkCoordinate(name string, dims []string, values []float64) error {
+	meta, err := w.readMeta(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	mismatch := fmt.Errorf("%s: the %s of the store are not those of the fields, write another region or grid to another store", w.path, name)
+	size := 1
+	for _, n := range meta.Shape {
+		size *= n
+	}
+	if len(meta.Shape) != len(dims) || size != len(values) || meta.DType != "<f8" || len(meta.Chunks) != len(meta.Shape) {
+		return mismatch
+	}
+	for k := range meta.Shape {
+		if meta.Chunks[k] != meta.Shape[k] {
+			return mismatch
+		}
+	}
+
+	key := "0"
+	if len(meta.Shape) == 2 {
+		key = "0.0"
+	}
+	chunk, err := w.readChunk(name, key, meta, 8*size)
+	if err != nil {
+		return err
+	}
+	if chunk == nil {
+		return mismatch
+	}
+	for k, v := range values {
+		if math.Abs(math.Float64frombits(binary.LittleEndian.Uint64(chunk[8*k:]))-v) > 1e-6 {
+			return mismatch
+		}
+	}
+	return nil
+}
+
+// writeData writes the records of a data array
+func (w *zarrWriter) writeData(name string, dims []string, coordinates string, records []zarrRecord, timeIndex map[zarrKey]int, nt, ny, nx int) error {
+	meta, err := w.readMeta(name)
+	if os.IsNotExist(err) {
+		meta = &zarrArray{
+			Chunks:     []int{w.chunks[0], minInt(w.chunks[1], ny), minInt(w.chunks[2], nx)},
+			Compressor: w.compressor,
+			DType:      "<f4",
+			FillValue:  "NaN",
+			Order:      "C",
+			ZarrFormat: 2,
+		}
+	} else if err != nil {
+		return err
+	} else if len(meta.Shape) != 3 || meta.Shape[1] != ny || meta.Shape[2] != nx || meta.DType != "<f4" || meta.Order != "C" {
+		return fmt.Errorf("%s: the array %s of the store does not match the grid", w.path, name)
+	}
+	stored := 0
+	if meta.Shape != nil {
+		stored = meta.Shape[0]
+	}
+	meta.Shape = []int{nt, ny, nx}
+	if err = w.writeJSON(filepath.Join(name, ".zarray"), meta); err != nil {
+		return err
+	}
+
+	first := records[0].field
+	attrs := map[string]interface{}{
+		"_ARRAY_DIMENSIONS": dims,
+		"long_name":         first.Description,
+		"level":             first.Level,
+	}
+	if units := cfUnits(first.Unit); units != "" {
+		attrs["units"] = units
+	}
+	if sn, ok := standardNames[first.Name]; ok {
+		attrs["standard_name"] = sn
+	}
+	if coordinates != "" {
+		attrs["coordinates"] = coordinates
+	}
+	if err = w.writeJSON(filepath.Join(name, ".zattrs"), attrs); err != nil {
+		return err
+	}
+
+	// the records of each chunk along time, by their index in it
+	ct, cy, cx := meta.Chunks[0], meta.Chunks[1], meta.Chunks[2]
+	byChunk := make(map[int]map[int]zarrRecord)
+	var timeChunks []int
+	for _, r := range records {
+		t := timeIndex[newZarrKey(&r.field)]
+		if byChunk[t/ct] == nil {
+			byChunk[t/ct] = make(map[int]zarrRecord)
+			timeChunks = append(timeChunks, t/ct)
+		}
+		byChunk[t/ct][t%ct] = r
+	}
+	sort.Ints(timeChunks)
+
+	chunkSize := 4 * ct * cy * cx
+	for _, tc := range timeChunks {
+		// the rows of a band of chunks for every record of the time chunk
+		for j0 := 0; j0 < ny; j0 += cy {
+			rows := minInt(cy, ny-j0)
+			bands := make(map[int][]byte)
+			for t, r := range byChunk[tc] {
+				band := make([]byte, 4*rows*nx)
+				_, err := w.spool.ReadAt(band, r.offset+int64(4*j0*nx))
+				if err != nil {
+					return err
+				}
+				bands[t] = band
+			}
+
+			for i0 := 0; i0 < nx; i0 += cx {
+				cols := minInt(cx, nx-i0)
+				key := fmt.Sprintf("%d.%d.%d", tc, j0/cy, i0/cx)
+				var chunk []byte
+				if tc*ct < stored {
+					chunk, err = w.readChunk(name, key, meta, chunkSize)
+					if err != nil {
+						return err
+					}
+				}
+				if chunk == nil {
+					chunk = make([]byte, chunkSize)
+					nan := math.Float32bits(float32(math.NaN()))
+					for k := 0; k < len(chunk); k += 4 {
+						binary.LittleEndian.PutUint32(chunk[k:], nan)
+					}
+				}
+				for t, band := range bands {
+					for j := 0; j < rows; j++ {
+						at := 4 * ((t*cy + j) * cx)
+						copy(chunk[at:at+4*cols], band[4*(j*nx+i0):])
+					}
+				}
+				data, err := meta.Compressor.encode(chunk)
+				if err != nil {
+					return err
+				}
+				err = ioutil.WriteFile(filepath.Join(w.path, name, key), data, 0644)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// growArrays sets the length along time of the data arrays of the store not
+// in names
+func (w *zarrWriter) growArrays(names []string, nt, ny, nx int) error {
+	entries, err := ioutil.ReadDir(w.path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || containsString(names, name) {
+			continue
+		}
+		meta, err := w.readMeta(name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if len(meta.Shape) != 3 || meta.Shape[1] != ny || meta.Shape[2] != nx {
+			continue
+		}
+		meta.Shape[0] = nt
+		if err = w.writeJSON(filepath.Join(name, ".zarray"), meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCoordinate writes a float64 coordinate array as a single chunk, it
+// is written whole every time
+func (w *zarrWriter) writeCoordinate(name string, dims []string, values []float64, attrs map[string]interface{}) error {
+	shape := []int{len(values)}
+	if len(dims) == 2 {
+		shape = []int{len(w.window.js), len(w.window.is)}
+	}
+	meta := &zarrArray{
+		Chunks:     shape,
+		Compressor: w.compressor,
+		DType:      "<f8",
+		FillValue:  "NaN",
+		Order:      "C",
+		Shape:      shape,
+		ZarrFormat: 2,
+	}
+	attrs["_ARRAY_DIMENSIONS"] = dims
+	err := w.writeJSON(filepath.Join(name, ".zarray"), meta)
+	if err == nil {
+		err = w.writeJSON(filepath.Join(name, ".zattrs"), attrs)
+	}
+	if err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 8*len(values))
+	for k, v := range values {
+		binary.LittleEndian.PutUint64(chunk[8*k:], math.Float64bits(v))
+	}
+	data, err := meta.Compressor.encode(chunk)
+	if err != nil {
+		return err
+	}
+	key := "0"
+	if len(dims) == 2 {
+		key = "0.0"
+	}
+	return ioutil.WriteFile(filepath.Join(w.path, name, key), data, 0644)
+}
+
+// readFloats reads a one dimensional float64 array of the store
+func (w *zarrWriter) readFloats(name string) ([]float64, error) {
+	meta, err := w.readMeta(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.Shape) != 1 || meta.DType != "<f8" {
+		return nil, fmt.Errorf("%s: %s is not a float64 coordinate", w.path, name)
+	}
+	n, size := meta.Shape[0], meta.Chunks[0]
+	values := make([]float64, 0, n)
+	for c := 0; len(values) < n; c++ {
+		chunk, err := w.readChunk(name, strconv.Itoa(c), meta, 8*size)
+		if err != nil {
+			return nil, err
+		}
+		for k := 0; k < size && len(values) < n; k++ {
+			v := math.NaN()
+			if chunk != nil {
+				v = math.Float64frombits(binary.LittleEndian.Uint64(chunk[8*k:]))
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+func (w *zarrWriter) readMeta(name string) (*zarrArray, error) {
+	data, err := ioutil.ReadFile(filepath.Join(w.path, name, ".zarray"))
+	if err != nil {
+		return nil, err
+	}
+	var meta zarrArray
+	if err = json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("%s/.zarray: %v", name, err)
+	}
+	return &meta, nil
+}
+
+// readChunk reads a chunk of an array, nil if it is not there
+func (w *zarrWriter) readChunk(name, key string, meta *zarrArray, size int) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(w.path, name, key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	chunk, err := meta.Compressor.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %v", name, key, err)
+	}
+	if len(chunk) != size {
+		return nil, fmt.Errorf("%s/%s: chunk has %d bytes, not %d", name, key, len(chunk), size)
+	}
+	return chunk, nil
+}
+
+// writeJSON writes a metadata file of the store, indented as zarr does
+func (w *zarrWriter) writeJSON(name string, v interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	path := filepath.Join(w.path, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// consolidate writes .zmetadata, the metadata of every array of the store
+// in one file so it is opened with a single read
+func (w *zarrWriter) consolidate() error {
+	metadata := make(map[string]json.RawMessage)
+	err := filepath.Walk(w.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch info.Name() {
+		case ".zgroup", ".zattrs", ".zarray":
+		default:
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(w.path, path)
+		if err != nil {
+			return err
+		}
+		metadata[filepath.ToSlash(rel)] = json.RawMessage(bytes.TrimSpace(data))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.writeJSON(".zmetadata", map[string]interface{}{
+		"metadata":                 metadata,
+		"zarr_consolidated_format": 1,
+	})
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
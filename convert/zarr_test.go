@@ -0,0 +1,301 @@
+package convert
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// readZarrArray reads every chunk of an array of a store and returns its
+// shape and values, float32 arrays as float64
+func readZarrArray(t *testing.T, store, name string) ([]int, []float64) {
+	data, err := ioutil.ReadFile(filepath.Join(store, name, ".zarray"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta zarrArray
+	if err = json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	size := 8
+	if meta.DType == "<f4" {
+		size = 4
+	}
+	shape, chunks := meta.Shape, meta.Chunks
+	for len(shape) < 3 {
+		shape = append([]int{1}, shape...)
+		chunks = append([]int{1}, chunks...)
+	}
+	values := make([]float64, shape[0]*shape[1]*shape[2])
+	for k := range values {
+		values[k] = math.NaN()
+	}
+	for t0 := 0; t0 < shape[0]; t0 += chunks[0] {
+		for j0 := 0; j0 < shape[1]; j0 += chunks[1] {
+			for i0 := 0; i0 < shape[2]; i0 += chunks[2] {
+				var key string
+				switch len(meta.Shape) {
+				case 1:
+					key = "0"
+				case 2:
+					key = "0.0"
+				default:
+					key = fmt.Sprintf("%d.%d.%d", t0/chunks[0], j0/chunks[1], i0/chunks[2])
+				}
+				chunk, err := (&zarrWriter{path: store}).readChunk(name, key, &meta, size*chunks[0]*chunks[1]*chunks[2])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if chunk == nil {
+					continue
+				}
+				for c := 0; c < chunks[0]*chunks[1]*chunks[2]; c++ {
+					tt, j, i := t0+c/(chunks[1]*chunks[2]), j0+c/chunks[2]%chunks[1], i0+c%chunks[2]
+					if tt >= shape[0] || j >= shape[1] || i >= shape[2] {
+						continue
+					}
+					var v float64
+					if size == 4 {
+						v = float64(math.Float32frombits(binary.LittleEndian.Uint32(chunk[4*c:])))
+					} else {
+						v = math.Float64frombits(binary.LittleEndian.Uint64(chunk[8*c:]))
+					}
+					values[(tt*shape[1]+j)*shape[2]+i] = v
+				}
+			}
+		}
+	}
+	return meta.Shape, values
+}
+
+// writeZarr writes fields to a store, shifted by some hours and with an
+// offset added to their values
+func writeZarr(t *testing.T, path string, opts Options, fields []gfs.Field, hours int, offset float32) {
+	w, err := createZarr(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range fields {
+		f.RefTime = f.RefTime.Add(time.Duration(hours) * time.Hour)
+		f.VerfTime = f.VerfTime.Add(time.Duration(hours) * time.Hour)
+		values := make([]float32, len(f.Values))
+		for k, v := range f.Values {
+			values[k] = v + offset
+		}
+		f.Values = values
+		if err = w.WriteField(&f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZarrMetadata(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "gfs.zarr")
+	writeZarr(t, path, Options{Compression: "zlib:5"}, readFields(t, "gfs.t00z.pgrb2.0p25.f001"), 0, 0)
+
+	zarray, err := ioutil.ReadFile(filepath.Join(path, "UGRD_10maboveground", ".zarray"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+    "chunks": [
+        24,
+        98,
+        117
+    ],
+    "compressor": {
+        "id": "zlib",
+        "level": 5
+    },
+    "dtype": "<f4",
+    "fill_value": "NaN",
+    "filters": null,
+    "order": "C",
+    "shape": [
+        1,
+        98,
+        117
+    ],
+    "zarr_format": 2
+}
+`
+	if string(zarray) != want {
+		t.Errorf(".zarray\n%s\nwant\n%s", zarray, want)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(path, ".zmetadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var consolidated struct {
+		Metadata map[string]json.RawMessage
+		Format   int `json:"zarr_consolidated_format"`
+	}
+	if err = json.Unmarshal(data, &consolidated); err != nil {
+		t.Fatal(err)
+	}
+	if consolidated.Format != 1 {
+		t.Errorf("consolidated format %d", consolidated.Format)
+	}
+	// every metadata file of the store is in it as it is on disk
+	keys := []string{".zgroup", ".zattrs"}
+	for _, name := range []string{"time", "reference_time", "fh", "lat", "lon", "UGRD_10maboveground", "VGRD_10maboveground"} {
+		keys = append(keys, name+"/.zarray", name+"/.zattrs")
+	}
+	if len(consolidated.Metadata) != len(keys) {
+		t.Errorf("%d entries, want %d", len(consolidated.Metadata), len(keys))
+	}
+	for _, key := range keys {
+		file, err := ioutil.ReadFile(filepath.Join(path, filepath.FromSlash(key)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var a, b interface{}
+		json.Unmarshal(file, &a)
+		json.Unmarshal(consolidated.Metadata[key], &b)
+		if x, y := mustJSON(t, a), mustJSON(t, b); x != y {
+			t.Errorf("%s: %s, file %s", key, y, x)
+		}
+	}
+	var attrs map[string]interface{}
+	json.Unmarshal(consolidated.Metadata["VGRD_10maboveground/.zattrs"], &attrs)
+	if attrs["standard_name"] != "northward_wind" || attrs["units"] != "m/s" || mustJSON(t, attrs["_ARRAY_DIMENSIONS"]) != `["time","lat","lon"]` {
+		t.Errorf("VGRD attributes %v", attrs)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestZarrAppend(t *testing.T) {
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	// the default chunks put both cycles in one chunk along time, chunks
+	// of one time and 64 points put them in chunks of their own
+	for _, chunks := range [][]int{nil, {1, 64, 64}} {
+		dir, cleanup := tempDir(t)
+		path := filepath.Join(dir, "gfs.zarr")
+		opts := Options{Chunks: chunks}
+		writeZarr(t, path, opts, fields, 0, 0)
+		writeZarr(t, path, opts, fields, 6, 100)
+
+		shape, times := readZarrArray(t, path, "time")
+		_, ref := readZarrArray(t, path, "reference_time")
+		_, fh := readZarrArray(t, path, "fh")
+		first := time.Date(2017, 7, 20, 1, 0, 0, 0, time.UTC).Sub(zarrEpoch).Hours()
+		if len(shape) != 1 || shape[0] != 2 || times[0] != first || times[1] != first+6 || ref[1] != first+5 || fh[0] != 1 || fh[1] != 1 {
+			t.Errorf("chunks %v: times %v, reference times %v, forecast hours %v", chunks, times, ref, fh)
+		}
+		_, lats := readZarrArray(t, path, "lat")
+		_, lons := readZarrArray(t, path, "lon")
+		if len(lats) != 98 || lats[0] != 35.75 || len(lons) != 117 || lons[0] != -10 || lons[116] != 19 {
+			t.Errorf("chunks %v: latitudes from %v, longitudes %v to %v", chunks, lats[0], lons[0], lons[len(lons)-1])
+		}
+
+		for _, f := range fields {
+			name := variableName(f.Name + "_" + f.Level)
+			shape, values := readZarrArray(t, path, name)
+			if len(shape) != 3 || shape[0] != 2 || shape[1] != 98 || shape[2] != 117 {
+				t.Fatalf("chunks %v: %s of shape %v", chunks, name, shape)
+			}
+			for n, v := range f.Values {
+				i, j := f.Grid.Point(n)
+				at := j*117 + i
+				if float32(values[at]) != v || float32(values[98*117+at]) != v+100 {
+					t.Fatalf("chunks %v: %s at %d, %d = %v and %v, want %v", chunks, name, i, j, values[at], values[98*117+at], v)
+				}
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestZarrAppendRegion(t *testing.T) {
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "gfs.zarr")
+
+	region := func(s string) Options {
+		r, err := gfs.ParseRegion(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Options{Region: r}
+	}
+	writeZarr(t, path, region("-10,0,40,35"), fields, 0, 0)
+	writeZarr(t, path, region("-10,0,40,35"), fields, 6, 100)
+
+	// a window of the same shape 5 degrees east is another region
+	w, err := createZarr(path, region("-5,5,40,35"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range fields {
+		f.RefTime = f.RefTime.Add(12 * time.Hour)
+		f.VerfTime = f.VerfTime.Add(12 * time.Hour)
+		if err = w.WriteField(&f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err == nil || !strings.Contains(err.Error(), "the lon of the store") {
+		t.Errorf("err = %v, want the longitudes to differ", err)
+	}
+
+	shape, _ := readZarrArray(t, path, "time")
+	_, lons := readZarrArray(t, path, "lon")
+	if len(shape) != 1 || shape[0] != 2 {
+		t.Errorf("%v times after a failed append, want 2", shape)
+	}
+	if len(lons) != 41 || lons[0] != -10 || lons[40] != 0 {
+		t.Errorf("longitudes %v to %v after a failed append, want -10 to 0", lons[0], lons[len(lons)-1])
+	}
+}
+
+func TestZarrEmpty(t *testing.T) {
+	fields := readFields(t, "gfs.t00z.pgrb2.0p25.f001")
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "gfs.zarr")
+
+	// a region outside the grid leaves no store to append to
+	w, err := createZarr(path, Options{Region: &gfs.Region{LeftLon: 100, RightLon: 110, TopLat: 10, BottomLat: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteField(&fields[0]); err == nil {
+		t.Error("wrote a region outside the grid")
+	}
+	if err = w.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("store made without a field: %v", err)
+	}
+
+	if err = ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createZarr(path, Options{}); err == nil {
+		t.Error("a file taken for a store")
+	}
+}
@@ -0,0 +1,60 @@
+package gfs
+
+import (
+	"github.com/azillion/nimbus/grib2"
+)
+
+// DecodeOptions changes how fields are turned into values
+type DecodeOptions struct {
+	// SkipMissing leaves grid points without a value, such as land points of
+	// an ocean field, out of Values instead of keeping them as NaN
+	SkipMissing bool
+}
+
+// ReadGRIB2 decodes every field of every message in a GRIB2 file
+func ReadGRIB2(data []byte) ([]GRIB2, error) {
+	return ReadGRIB2WithOptions(data, DecodeOptions{})
+}
+
+// ReadGRIB2WithOptions decodes every field of every message in a GRIB2 file
+func ReadGRIB2WithOptions(data []byte, opts DecodeOptions) ([]GRIB2, error) {
+	messages, err := grib2.Read(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var gribs []GRIB2
+	for _, m := range messages {
+		for _, f := range m.Fields {
+			g, err := NewGRIB2WithOptions(f, opts)
+			if err != nil {
+				return nil, err
+			}
+			gribs = append(gribs, *g)
+		}
+	}
+	return gribs, nil
+}
+
+// NewGRIB2 unpacks a decoded field into the simplified structure, missing
+// values are NaN
+func NewGRIB2(f *grib2.Field) (*GRIB2, error) {
+	return NewGRIB2WithOptions(f, DecodeOptions{})
+}
+
+// NewGRIB2WithOptions unpacks a decoded field into the simplified structure
+func NewGRIB2WithOptions(f *grib2.Field, opts DecodeOptions) (*GRIB2, error) {
+	field, err := NewField(f)
+	if err != nil {
+		return nil, err
+	}
+	return &GRIB2{
+		RefTime:     field.RefTime,
+		VerfTime:    field.VerfTime,
+		Name:        field.Name,
+		Description: field.Description,
+		Unit:        field.Unit,
+		Level:       field.Level,
+		Values:      field.PointList(opts),
+	}, nil
+}
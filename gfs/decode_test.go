@@ -0,0 +1,66 @@
+package gfs
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// readFixture reads a GRIB2 file from the testdata of the grib2 package
+func readFixture(tb testing.TB, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("..", "grib2", "testdata", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func TestReadGRIB2SkipMissing(t *testing.T) {
+	// the fourth message of complex.grb2 marks 690 values missing with
+	// primary missing value substitution
+	data := readFixture(t, "complex.grb2")
+
+	all, err := ReadGRIB2(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	present, err := ReadGRIB2WithOptions(data, DecodeOptions{SkipMissing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 || len(present) != 5 {
+		t.Fatalf("%d and %d fields, want 5", len(all), len(present))
+	}
+
+	missing := []int{0, 0, 0, 690, 1271}
+	for k := range all {
+		if len(all[k].Values) != 117*98 {
+			t.Errorf("field %d: %d values, want %d", k, len(all[k].Values), 117*98)
+		}
+		nan := 0
+		for _, v := range all[k].Values {
+			if math.IsNaN(float64(v.Value)) {
+				nan++
+			}
+		}
+		if nan != missing[k] {
+			t.Errorf("field %d: %d NaN values, want %d", k, nan, missing[k])
+		}
+		if len(present[k].Values) != 117*98-missing[k] {
+			t.Errorf("field %d: %d values skipping missing ones, want %d", k, len(present[k].Values), 117*98-missing[k])
+		}
+		for _, v := range present[k].Values {
+			if math.IsNaN(float64(v.Value)) {
+				t.Errorf("field %d: missing value kept at %v, %v", k, v.Latitude, v.Longitude)
+				break
+			}
+		}
+	}
+
+	// the points kept are where they were
+	v := present[3].Values[0]
+	if w := all[3].Values[1]; v != w {
+		t.Errorf("first value kept = %+v, want %+v", v, w)
+	}
+}
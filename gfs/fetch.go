@@ -0,0 +1,204 @@
+package gfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultClient sends the requests of repositories given no client
+var defaultClient = &http.Client{Timeout: 10 * time.Minute}
+
+// clientOrDefault returns the client, or the default client when it is nil
+func clientOrDefault(client *http.Client) *http.Client {
+	if client == nil {
+		return defaultClient
+	}
+	return client
+}
+
+// fetch reads a file from an http(s) or file URL
+func fetch(client *http.Client, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		return ioutil.ReadFile(filepath.FromSlash(u.Path))
+	}
+
+	resp, err := clientOrDefault(client).Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{url: rawURL, code: resp.StatusCode, status: resp.Status}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// ReaderAtCloser is a file that can be read at any offset
+type ReaderAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// OpenURL opens an http(s) or file URL for reading at any offset, http
+// files are read with range requests so only the parts asked for are
+// downloaded. It suits a grib2.Scanner picking a few fields out of a file
+func OpenURL(rawURL string) (ReaderAtCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		return os.Open(filepath.FromSlash(u.Path))
+	}
+	return &rangeReader{client: defaultClient, url: rawURL}, nil
+}
+
+// rangeReader reads parts of a file over http with range requests. A server
+// that ignores ranges sends the whole file, which is then kept and read from
+// instead of being downloaded again for every read
+type rangeReader struct {
+	client *http.Client
+	url    string
+
+	mu    sync.Mutex
+	whole *bytes.Reader
+}
+
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	r.mu.Lock()
+	whole := r.whole
+	r.mu.Unlock()
+	if whole != nil {
+		return whole.ReadAt(p, off)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// the server ignored the range and sends the whole file
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return 0, err
+		}
+		whole = bytes.NewReader(data)
+		r.mu.Lock()
+		r.whole = whole
+		r.mu.Unlock()
+		return whole.ReadAt(p, off)
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	default:
+		return 0, &statusError{url: r.url, code: resp.StatusCode, status: resp.Status}
+	}
+
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *rangeReader) Close() error {
+	return nil
+}
+
+// exists checks whether an http(s) or file URL is published without
+// downloading it
+func exists(client *http.Client, rawURL string) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+	if u.Scheme == "file" {
+		info, err := os.Stat(filepath.FromSlash(u.Path))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return !info.IsDir(), nil
+	}
+
+	resp, err := clientOrDefault(client).Head(rawURL)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, &statusError{url: rawURL, code: resp.StatusCode, status: resp.Status}
+	}
+	return true, nil
+}
+
+// statusError is returned when a server answers with anything other than 200
+type statusError struct {
+	url    string
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.url, e.status)
+}
+
+// isFallThrough reports whether the next mirror should be tried after err,
+// which is the case when the file is missing or the server is unavailable
+func isFallThrough(err error) bool {
+	if se, ok := err.(*statusError); ok {
+		return se.code == http.StatusNotFound || se.code >= 500
+	}
+	// missing local files and network errors
+	return true
+}
+
+// localPath maps a repository URI to the path the file is saved under, which
+// follows the NOMADS directory layout so the output folder can be read back
+// by a LocalRepository
+func localPath(uri string) (string, error) {
+	if !strings.HasPrefix(uri, "?") {
+		return filepath.FromSlash(uri), nil
+	}
+
+	query, err := url.ParseQuery(uri[1:])
+	if err != nil {
+		return "", err
+	}
+	file := query.Get("file")
+	if file == "" {
+		return "", fmt.Errorf("no file in uri: %s", uri)
+	}
+	p := strings.TrimPrefix(path.Join(query.Get("dir"), file), "/")
+	return filepath.FromSlash(p), nil
+}
@@ -0,0 +1,124 @@
+package gfs
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azillion/nimbus/grib2"
+)
+
+// fileServer serves a file, with range requests unless ranges is false, and
+// counts the requests and the octets sent
+type fileServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+	sent     int64
+}
+
+func newFileServer(data []byte, ranges bool) *fileServer {
+	s := &fileServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ranges {
+			r.Header.Del("Range")
+		}
+		cw := &countingWriter{ResponseWriter: w}
+		http.ServeContent(cw, r, "file", time.Time{}, bytes.NewReader(data))
+		s.mu.Lock()
+		s.requests++
+		s.sent += cw.n
+		s.mu.Unlock()
+	}))
+	return s
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func TestRangeReader(t *testing.T) {
+	data := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")
+	for _, ranges := range []bool{true, false} {
+		s := newFileServer(data, ranges)
+		r := &rangeReader{client: s.Client(), url: s.URL}
+
+		p := make([]byte, 100)
+		for _, off := range []int64{0, 17378, int64(len(data)) - 100, 5000} {
+			n, err := r.ReadAt(p, off)
+			if n != len(p) || err != nil {
+				t.Errorf("ranges %v: read at %d = %d, %v", ranges, off, n, err)
+			} else if !bytes.Equal(p, data[off:off+100]) {
+				t.Errorf("ranges %v: read at %d returned other octets", ranges, off)
+			}
+		}
+		// reads past the end are short
+		if n, err := r.ReadAt(p, int64(len(data))-10); n != 10 || err != io.EOF {
+			t.Errorf("ranges %v: read over the end = %d, %v, want 10, EOF", ranges, n, err)
+		}
+		if n, err := r.ReadAt(p, int64(len(data))+10); n != 0 || err != io.EOF {
+			t.Errorf("ranges %v: read past the end = %d, %v, want 0, EOF", ranges, n, err)
+		}
+
+		s.mu.Lock()
+		if ranges && s.sent > 600 {
+			t.Errorf("%d octets sent for 6 reads of at most 100", s.sent)
+		}
+		// a server ignoring ranges sends the file once
+		if !ranges && (s.requests != 1 || s.sent != int64(len(data))) {
+			t.Errorf("ranges ignored: %d requests sending %d octets, want 1 sending %d", s.requests, s.sent, len(data))
+		}
+		s.mu.Unlock()
+		s.Close()
+	}
+}
+
+func TestRangeReaderScanner(t *testing.T) {
+	data := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")
+	s := newFileServer(data, true)
+	defer s.Close()
+
+	scanner := grib2.NewScanner(&rangeReader{client: s.Client(), url: s.URL})
+	scanner.Filter = func(m *grib2.Message) bool {
+		return m.Fields[0].Parameter().Name == "VGRD"
+	}
+	var names []string
+	for scanner.Scan() {
+		f := scanner.Message().Fields[0]
+		names = append(names, f.Parameter().Name)
+		values, err := f.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != 117*98 {
+			t.Errorf("%d values, want %d", len(values), 117*98)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "VGRD" {
+		t.Errorf("scanned %v, want VGRD", names)
+	}
+}
+
+func TestRangeReaderStatus(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+	r := &rangeReader{client: s.Client(), url: s.URL}
+	_, err := r.ReadAt(make([]byte, 10), 0)
+	if se, ok := err.(*statusError); !ok || se.code != http.StatusNotFound {
+		t.Errorf("err = %v, want a 404 status error", err)
+	}
+}
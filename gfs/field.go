@@ -0,0 +1,121 @@
+package gfs
+
+import (
+	"math"
+	"time"
+
+	"github.com/azillion/nimbus/grib2"
+)
+
+// Field is a decoded field kept as its grid and one float32 per grid point,
+// about a fifth of the memory of the Value list of a GRIB2. Coordinates are
+// computed from the grid when they are asked for
+type Field struct {
+	RefTime     time.Time
+	VerfTime    time.Time
+	Name        string
+	Description string
+	Unit        string
+	Level       string
+	// FirstSurface and SecondSurface are the fixed surfaces Level describes
+	FirstSurface  grib2.Surface
+	SecondSurface grib2.Surface
+
+	Grid *grib2.Grid
+	// Values holds one value per grid point in the order the grid is
+	// scanned, missing points are NaN
+	Values []float32
+}
+
+// ReadFields decodes every field of every message in a GRIB2 file
+func ReadFields(data []byte) ([]Field, error) {
+	messages, err := grib2.Read(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []Field
+	for _, m := range messages {
+		for _, f := range m.Fields {
+			field, err := NewField(f)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, *field)
+		}
+	}
+	return fields, nil
+}
+
+// NewField unpacks a decoded field into the compact structure
+func NewField(f *grib2.Field) (*Field, error) {
+	verfTime, err := f.VerfTime()
+	if err != nil {
+		return nil, err
+	}
+	values, err := f.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	param := f.Parameter()
+	return &Field{
+		RefTime:       f.RefTime(),
+		VerfTime:      verfTime,
+		Name:          param.Name,
+		Description:   param.Description,
+		Unit:          param.Unit,
+		Level:         f.Level(),
+		FirstSurface:  f.Product.FirstSurface,
+		SecondSurface: f.Product.SecondSurface,
+		Grid:          f.Grid.Grid(),
+		Values:        values,
+	}, nil
+}
+
+// At returns the value of grid point (i, j), see grib2.Grid for how points
+// are numbered
+func (f *Field) At(i, j int) float32 {
+	return f.Values[f.Grid.Index(i, j)]
+}
+
+// LatLon returns the latitude and longitude of grid point (i, j)
+func (f *Field) LatLon(i, j int) (lat, lon float64) {
+	return f.Grid.LatLon(i, j)
+}
+
+// Nearest returns the value of the grid point closest to a latitude and
+// longitude, ok is false when the coordinates are off the grid
+func (f *Field) Nearest(lat, lon float64) (v float32, ok bool) {
+	i, j, ok := f.Grid.Nearest(lat, lon)
+	if !ok {
+		return 0, false
+	}
+	return f.At(i, j), true
+}
+
+// Points calls fn with every point of the field and its coordinates, in
+// the order the grid is scanned, until fn returns false. Coordinates are
+// computed one point at a time so nothing is kept
+func (f *Field) Points(opts DecodeOptions, fn func(Value) bool) {
+	for n, v := range f.Values {
+		if opts.SkipMissing && math.IsNaN(float64(v)) {
+			continue
+		}
+		lat, lon := f.Grid.LatLon(f.Grid.Point(n))
+		if !fn(Value{Longitude: lon, Latitude: lat, Value: v}) {
+			return
+		}
+	}
+}
+
+// PointList converts the field to a list of values with their coordinates,
+// in the order the grid is scanned
+func (f *Field) PointList(opts DecodeOptions) []Value {
+	points := make([]Value, 0, len(f.Values))
+	f.Points(opts, func(v Value) bool {
+		points = append(points, v)
+		return true
+	})
+	return points
+}
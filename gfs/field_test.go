@@ -0,0 +1,130 @@
+package gfs
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReadFields(t *testing.T) {
+	data := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")
+	fields, err := ReadFields(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gribs, err := ReadGRIB2(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || len(gribs) != 2 {
+		t.Fatalf("%d fields and %d GRIB2s, want 2", len(fields), len(gribs))
+	}
+
+	for k, f := range fields {
+		if f.Name != gribs[k].Name || f.Level != "10 m above ground" {
+			t.Errorf("field %d: %s at %s", k, f.Name, f.Level)
+		}
+		points := f.PointList(DecodeOptions{})
+		if len(points) != len(gribs[k].Values) {
+			t.Fatalf("field %d: %d points, want %d", k, len(points), len(gribs[k].Values))
+		}
+		for n := range points {
+			if points[n] != gribs[k].Values[n] {
+				t.Fatalf("field %d: point %d = %+v, want %+v", k, n, points[n], gribs[k].Values[n])
+			}
+		}
+
+		// the grid starts at 35.75N 350E and is scanned northwards
+		if lat, lon := f.LatLon(0, 0); lat != 35.75 || lon != 350 {
+			t.Errorf("field %d: first point at %v, %v", k, lat, lon)
+		}
+		if v := f.At(116, 97); v != f.Values[len(f.Values)-1] {
+			t.Errorf("field %d: last point %v, want %v", k, v, f.Values[len(f.Values)-1])
+		}
+		// 36N 350.125E is between points, 36N 350.25E is the point of
+		// row 1 and column 1
+		for _, lon := range []float64{350.2, 350.25, -9.75} {
+			v, ok := f.Nearest(36, lon)
+			if !ok || v != f.At(1, 1) {
+				t.Errorf("field %d: nearest to 36N %vE = %v %v, want %v", k, lon, v, ok, f.At(1, 1))
+			}
+		}
+		if _, ok := f.Nearest(0, 0); ok {
+			t.Errorf("field %d: the equator is on the grid", k)
+		}
+	}
+}
+
+func TestFieldPoints(t *testing.T) {
+	fields, err := ReadFields(readFixture(t, "complex.grb2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fourth field has 690 missing values
+	f := fields[3]
+	n := 0
+	f.Points(DecodeOptions{SkipMissing: true}, func(v Value) bool {
+		if math.IsNaN(float64(v.Value)) {
+			t.Fatalf("missing value at %v, %v", v.Latitude, v.Longitude)
+		}
+		n++
+		return true
+	})
+	if n != len(f.Values)-690 {
+		t.Errorf("%d points, want %d", n, len(f.Values)-690)
+	}
+
+	// iteration stops when the callback returns false
+	n = 0
+	f.Points(DecodeOptions{}, func(v Value) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("%d points after stopping at 10", n)
+	}
+}
+
+// BenchmarkReadGRIB2 decodes into lists of points with their coordinates
+func BenchmarkReadGRIB2(b *testing.B) {
+	data := readFixture(b, "gfs.t00z.pgrb2.0p25.f001")
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := ReadGRIB2(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkReadFields decodes into compact fields
+func BenchmarkReadFields(b *testing.B) {
+	data := readFixture(b, "gfs.t00z.pgrb2.0p25.f001")
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := ReadFields(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFieldValues decodes into compact fields and visits every point
+// with its coordinates
+func BenchmarkFieldValues(b *testing.B) {
+	data := readFixture(b, "gfs.t00z.pgrb2.0p25.f001")
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		fields, err := ReadFields(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var sum float64
+		for k := range fields {
+			fields[k].Points(DecodeOptions{}, func(v Value) bool {
+				sum += float64(v.Value)
+				return true
+			})
+		}
+		if math.IsNaN(sum) {
+			b.Fatal("NaN in the sum")
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package gfs
+
+// Level is a region at a certain altitude
+type Level struct {
+	uriKey     string
+	isIncluded bool
+}
+
+// ClimateVariable is a portion of GRIB2 climate data
+type ClimateVariable struct {
+	uriKey     string
+	isIncluded bool
+}
+
+// filter holds the level, variable and region selections shared by the
+// NOMADS filter scripts
+type filter struct {
+	levels                   map[string]Level
+	levelsURICache           string
+	climateVariables         map[string]ClimateVariable
+	climateVariablesURICache string
+
+	region Region
+}
+
+func (f *filter) getLevels() string {
+	if f.levelsURICache != "" {
+		return f.levelsURICache
+	}
+
+	if len(f.levels) == 0 {
+		f.levelsURICache = "all_lev=on"
+		return f.levelsURICache
+	}
+	// TODO: Read levels from csv file
+	// TODO: Read config for which levels to enable
+	return ""
+}
+
+func (f *filter) getClimateVariables() string {
+	if f.climateVariablesURICache != "" {
+		return f.climateVariablesURICache
+	}
+
+	if len(f.climateVariables) == 0 {
+		f.climateVariablesURICache = "all_var=on"
+		return f.climateVariablesURICache
+	}
+	// TODO: Read vars from csv file
+	// TODO: Read config for which vars to enable
+	return ""
+}
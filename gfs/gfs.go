@@ -1,6 +1,7 @@
 package gfs
 
 import (
+	"net/http"
 	"time"
 )
 
@@ -60,13 +61,33 @@ type DateRangeStrings struct {
 // TimeFrame is the time the data was recorded
 type TimeFrame string
 
+// RepositoryParams selects one repository of an ordered list of mirrors
+type RepositoryParams struct {
+	RepositoryType RepositoryType `mapstructure:"repository_type"`
+	RepositoryURL  string         `mapstructure:"repository_url"`
+}
+
 // Params used when downloading grib2 GFS files
 type Params struct {
+	DataSource                 string         `mapstructure:"data_source"`
 	RepositoryType             RepositoryType `mapstructure:"repository_type"`
+	RepositoryURL              string         `mapstructure:"repository_url"`
 	Resolution                 Resolution     `mapstructure:"resolution"`
 	DateRange                  DateRange
 	TimeFrame                  TimeFrame `mapstructure:"time_frame"`
+	ForecastHours              []int     `mapstructure:"forecast_hours"`
 	IsAdditionalPrecipIncluded bool      `mapstructure:"is_additional_precipitation_included"`
+	OutputFolder               string    `mapstructure:"output_folder"`
+	// WriteIndex saves a wgrib2 style .idx file next to every downloaded file
+	WriteIndex bool `mapstructure:"write_index"`
+
+	// Repositories are tried in order for every file, when empty only
+	// RepositoryType and RepositoryURL are used
+	Repositories []RepositoryParams `mapstructure:"repositories"`
+
+	// HTTPClient sends every request, a client with a 10 minute timeout is
+	// used when it is nil
+	HTTPClient *http.Client `mapstructure:"-"`
 }
 
 // TODO: Reimplement below
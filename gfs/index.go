@@ -0,0 +1,35 @@
+package gfs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/azillion/nimbus/grib2"
+)
+
+// IndexSuffix is appended to the name of a GRIB2 file to name its index
+const IndexSuffix string = ".idx"
+
+// WriteIndexFile writes the wgrib2 style inventory of a GRIB2 file next to
+// it, replacing an index that is already there
+func WriteIndexFile(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeIndex(fileName, f)
+}
+
+// writeIndex writes the index of a GRIB2 file read through r, nothing is
+// written when the file cannot be read
+func writeIndex(fileName string, r io.ReaderAt) error {
+	var buf bytes.Buffer
+	err := grib2.WriteIndex(&buf, r)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName+IndexSuffix, buf.Bytes(), 0644)
+}
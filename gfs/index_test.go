@@ -0,0 +1,135 @@
+package gfs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixtureIndex is the index of the GFS fixture
+const fixtureIndex = `1:0:d=2017072000:UGRD:10 m above ground:1 hour fcst:
+2:17378:d=2017072000:VGRD:10 m above ground:1 hour fcst:
+`
+
+func TestGetFilesWritesIndex(t *testing.T) {
+	data := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	p := &Params{
+		DataSource:     "gfs",
+		RepositoryType: NCEPRepoType,
+		RepositoryURL:  server.URL,
+		Resolution:     OneDegree,
+		DateRange:      DateRange{Start: day, End: day.Add(24 * time.Hour)},
+		TimeFrame:      Zulu,
+		ForecastHours:  []int{0, 3},
+		OutputFolder:   dir,
+		WriteIndex:     true,
+		HTTPClient:     server.Client(),
+	}
+	if err = NewService(p).GetFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(path) != IndexSuffix && info.Name() != ManifestFileName {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 || len(files) != requests {
+		t.Fatalf("%d files saved from %d requests", len(files), requests)
+	}
+	for _, f := range files {
+		index, err := ioutil.ReadFile(f + IndexSuffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(index) != fixtureIndex {
+			t.Errorf("index of %s:\n%s\nwant\n%s", f, index, fixtureIndex)
+		}
+	}
+
+	// files already downloaded are not fetched again but get their missing
+	// index
+	downloaded := requests
+	if err = os.Remove(files[0] + IndexSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewService(p).GetFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != downloaded {
+		t.Errorf("%d files downloaded again", requests-downloaded)
+	}
+	index, err := ioutil.ReadFile(files[0] + IndexSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(index) != fixtureIndex {
+		t.Errorf("index written again:\n%s", index)
+	}
+}
+
+func TestWriteIndexFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "gfs.t00z.pgrb2.0p25.f001")
+	if err = ioutil.WriteFile(fileName, readFixture(t, "gfs.t00z.pgrb2.0p25.f001"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// an index that is there is replaced
+	if err = ioutil.WriteFile(fileName+IndexSuffix, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteIndexFile(fileName); err != nil {
+		t.Fatal(err)
+	}
+	index, err := ioutil.ReadFile(fileName + IndexSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(index) != fixtureIndex {
+		t.Errorf("index:\n%s\nwant\n%s", index, fixtureIndex)
+	}
+
+	// nothing is written for a file that is not GRIB2
+	junk := filepath.Join(dir, "junk")
+	if err = ioutil.WriteFile(junk, []byte("GRIB and nothing else"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteIndexFile(junk); err == nil {
+		t.Error("indexed a damaged file")
+	}
+	if _, err = os.Stat(junk + IndexSuffix); !os.IsNotExist(err) {
+		t.Errorf("index of a damaged file: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package gfs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLookback is how far back LatestCycle searches for a published cycle
+const DefaultLookback = 48 * time.Hour
+
+// CycleProber is implemented by repositories that can check whether every
+// requested file of a cycle is published without downloading it
+type CycleProber interface {
+	IsCyclePublished(date string, timeFrame TimeFrame) (bool, error)
+}
+
+// IsCyclePublished probes the NOMADS directory the filter scripts read from
+func (ncep *NCEPRepository) IsCyclePublished(date string, timeFrame TimeFrame) (bool, error) {
+	if ncep.prodURL == "" {
+		return false, fmt.Errorf("%s has no directory to probe", ncep.model.Name)
+	}
+	return isCyclePublished(ncep.client, ncep.prodURL+"/", ncep.grid, ncep.model.FileSuffixes(timeFrame, ncep.forecastHours), date, timeFrame)
+}
+
+// IsCyclePublished probes the mirror
+func (mirror *MirrorRepository) IsCyclePublished(date string, timeFrame TimeFrame) (bool, error) {
+	return isCyclePublished(mirror.client, mirror.baseURL, mirror.grid, mirror.model.FileSuffixes(timeFrame, mirror.forecastHours), date, timeFrame)
+}
+
+// IsCyclePublished checks the directory
+func (local *LocalRepository) IsCyclePublished(date string, timeFrame TimeFrame) (bool, error) {
+	baseURL, err := local.GetBaseURL()
+	if err != nil {
+		return false, err
+	}
+	return isCyclePublished(nil, baseURL, local.grid, local.model.FileSuffixes(timeFrame, local.forecastHours), date, timeFrame)
+}
+
+// isCyclePublished checks the files from the last suffix to the first since
+// forecast hours are published progressively and the last is the most likely
+// to be missing
+func isCyclePublished(client *http.Client, baseURL string, grid *ModelGrid, suffixes []FileSuffix, date string, timeFrame TimeFrame) (bool, error) {
+	for i := len(suffixes) - 1; i >= 0; i-- {
+		uri, err := layoutURI(grid, date, timeFrame, suffixes[i])
+		if err != nil {
+			return false, err
+		}
+		ok, err := exists(client, baseURL+uri)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			logrus.Debugf("%s %sz is not published yet: %s", date, timeFrame, uri)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// LatestCycle finds the newest cycle of the data source that has every
+// requested file published on one of the repositories, it looks back no
+// further than lookback from now
+func (s *Service) LatestCycle(now time.Time, lookback time.Duration) (time.Time, error) {
+	m, err := LookupModel(s.params.DataSource)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	grid, err := m.Grid(s.params.Resolution)
+	if err != nil {
+		return time.Time{}, err
+	}
+	timeFrames, err := m.TimeFrames(grid, s.params.TimeFrame)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now = now.UTC()
+	for t := now.Truncate(time.Hour); !t.Before(now.Add(-lookback)); t = t.Add(-time.Hour) {
+		timeFrame := TimeFrame(t.Format("15"))
+		if !containsTimeFrame(timeFrames, timeFrame) {
+			continue
+		}
+		date := t.Format("20060102")
+
+		for _, mr := range s.mirrors {
+			prober, ok := mr.repository.(CycleProber)
+			if !ok {
+				continue
+			}
+			published, err := prober.IsCyclePublished(date, timeFrame)
+			if err != nil {
+				logrus.Warnf("failed to probe %s for %s %sz: %v", mr.repositoryType, date, timeFrame, err)
+				continue
+			}
+			if published {
+				return t, nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("no complete %s cycle published in the last %s", m.Name, lookback)
+}
+
+func containsTimeFrame(timeFrames []TimeFrame, tf TimeFrame) bool {
+	for _, t := range timeFrames {
+		if t == tf {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveLatest narrows the params down to the latest published cycle
+func ResolveLatest(p *Params, now time.Time, lookback time.Duration) (time.Time, error) {
+	cycle, err := NewService(p).LatestCycle(now, lookback)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	day := cycle.Truncate(24 * time.Hour)
+	p.DateRange = DateRange{Start: day, End: day.Add(24 * time.Hour)}
+	p.TimeFrame = TimeFrame(cycle.Format("15"))
+	return cycle, nil
+}
+
+// IsLatest reports whether a date range config value asks for the latest cycle
+func IsLatest(dateRange string) bool {
+	return strings.EqualFold(dateRange, "latest")
+}
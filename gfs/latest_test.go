@@ -0,0 +1,137 @@
+package gfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// prodServer answers HEAD requests for the NOMADS directory of a model with
+// 404 for every file that is not published
+type prodServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	published map[string]bool
+	probed    []string
+}
+
+func newProdServer(t *testing.T, model string, files ...string) *prodServer {
+	s := &prodServer{published: map[string]bool{}}
+	prefix := "/pub/data/nccf/com/" + model + "/prod/"
+	for _, f := range files {
+		s.published[prefix+f] = true
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.mu.Lock()
+		s.probed = append(s.probed, r.URL.Path)
+		ok := s.published[r.URL.Path]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+		}
+	}))
+	return s
+}
+
+// cycleFiles lists the files of a GFS 1 degree cycle
+func cycleFiles(date, timeFrame string, suffixes ...string) []string {
+	var files []string
+	for _, s := range suffixes {
+		files = append(files, "gfs."+date+"/"+timeFrame+"/atmos/gfs.t"+timeFrame+"z.pgrb2.1p00."+s)
+	}
+	return files
+}
+
+func latestParams(s *prodServer) *Params {
+	return &Params{
+		DataSource:     "gfs",
+		RepositoryType: NCEPRepoType,
+		RepositoryURL:  s.URL,
+		Resolution:     OneDegree,
+		TimeFrame:      AllTimeFrames,
+		ForecastHours:  []int{0, 3, 6},
+		HTTPClient:     s.Client(),
+	}
+}
+
+func TestLatestCycle(t *testing.T) {
+	var files []string
+	// 00z and 06z are complete, 12z is still being published and 18z has
+	// not started
+	files = append(files, cycleFiles("20240101", "18", "anl", "f000", "f003", "f006")...)
+	files = append(files, cycleFiles("20240102", "00", "anl", "f000", "f003", "f006")...)
+	files = append(files, cycleFiles("20240102", "06", "anl", "f000", "f003", "f006")...)
+	files = append(files, cycleFiles("20240102", "12", "anl", "f000", "f003")...)
+	s := newProdServer(t, "gfs", files...)
+	defer s.Close()
+
+	now := time.Date(2024, 1, 2, 19, 30, 0, 0, time.UTC)
+	tests := []struct {
+		lookback time.Duration
+		want     time.Time
+		ok       bool
+	}{
+		{DefaultLookback, time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC), true},
+		{14 * time.Hour, time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC), true},
+		{13 * time.Hour, time.Time{}, false},
+	}
+	for _, test := range tests {
+		got, err := NewService(latestParams(s)).LatestCycle(now, test.lookback)
+		if (err == nil) != test.ok {
+			t.Errorf("lookback %s: err = %v, want ok %v", test.lookback, err, test.ok)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("lookback %s: latest cycle = %s, want %s", test.lookback, got, test.want)
+		}
+	}
+
+	// the last forecast hour is probed first since it is published last
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.probed) == 0 || !strings.HasSuffix(s.probed[0], "gfs.t18z.pgrb2.1p00.f006") {
+		t.Errorf("first probe = %v, want the 18z f006 file", s.probed)
+	}
+}
+
+func TestResolveLatest(t *testing.T) {
+	s := newProdServer(t, "gfs", cycleFiles("20240102", "00", "anl", "f000", "f003", "f006")...)
+	defer s.Close()
+
+	p := latestParams(s)
+	cycle, err := ResolveLatest(p, time.Date(2024, 1, 2, 5, 0, 0, 0, time.UTC), DefaultLookback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC); !cycle.Equal(want) {
+		t.Errorf("cycle = %s, want %s", cycle, want)
+	}
+	if p.TimeFrame != Zulu {
+		t.Errorf("time frame = %s, want %s", p.TimeFrame, Zulu)
+	}
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if !p.DateRange.Start.Equal(day) || !p.DateRange.End.Equal(day.Add(24*time.Hour)) {
+		t.Errorf("date range = %v, want the day of %s", p.DateRange, day)
+	}
+
+	// the filter script moves to the repository URL as well
+	repo := NewRepository(NCEPRepoType)
+	if err = repo.LoadParams(p); err != nil {
+		t.Fatal(err)
+	}
+	base, err := repo.GetBaseURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := s.URL + "/cgi-bin/filter_gfs_1p00.pl"; base != want {
+		t.Errorf("base URL = %s, want %s", base, want)
+	}
+}
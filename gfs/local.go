@@ -0,0 +1,151 @@
+package gfs
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LocalRepository discovers files already on disk that follow the NOMADS
+// directory layout of a model, e.g. gfs.20190801/00/atmos/gfs.t00z.pgrb2.1p00.f000
+type LocalRepository struct {
+	root          string
+	model         *Model
+	grid          *ModelGrid
+	dateRange     DateRange
+	timeFrames    []TimeFrame
+	forecastHours []int
+
+	URIs []string
+}
+
+// LoadParams reads the param object into the repository
+func (local *LocalRepository) LoadParams(p *Params) error {
+	root, err := localRoot(p.RepositoryURL)
+	if err != nil {
+		return err
+	}
+	m, err := LookupModel(p.DataSource)
+	if err != nil {
+		return err
+	}
+	grid, err := m.Grid(p.Resolution)
+	if err != nil {
+		return err
+	}
+	timeFrames, err := m.TimeFrames(grid, p.TimeFrame)
+	if err != nil {
+		return err
+	}
+
+	local.root = root
+	local.model = m
+	local.grid = grid
+	local.dateRange = p.DateRange
+	local.timeFrames = timeFrames
+	local.forecastHours = p.ForecastHours
+	return nil
+}
+
+// GetBaseURL gets the file URL of the root directory
+func (local *LocalRepository) GetBaseURL() (string, error) {
+	if local.root == "" {
+		return "", fmt.Errorf("no repository url set")
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(local.root) + "/"}).String(), nil
+}
+
+// GetURIs get the URIs of the files found in the date range
+func (local *LocalRepository) GetURIs() ([]string, error) {
+	var uris []string
+	loops := getNumberOfLoops(local.dateRange.Start, local.dateRange.End)
+	d := local.dateRange.Start
+	for i := 0; i < loops; i++ {
+		date := d.Format("20060102")
+		dateURIs, err := local.GetURIsForDate(date)
+		if err != nil {
+			return nil, err
+		}
+		uris = append(uris, dateURIs...)
+		d = d.Add(time.Hour * 24)
+	}
+
+	local.URIs = uris
+	return local.URIs, nil
+}
+
+// GetURIsForDate get the URIs of the files found for a specific date
+func (local *LocalRepository) GetURIsForDate(date string) ([]string, error) {
+	var uris []string
+	for _, tf := range local.timeFrames {
+		tfURIs, err := local.GetURIsForDateAndTime(date, tf)
+		if err != nil {
+			return nil, err
+		}
+		uris = append(uris, tfURIs...)
+	}
+	local.URIs = uris
+	return local.URIs, nil
+}
+
+// GetURIsForDateAndTime get the URIs of the files found for a specific date
+// and time frame, files missing from the directory are skipped
+func (local *LocalRepository) GetURIsForDateAndTime(date string, timeFrame TimeFrame) ([]string, error) {
+	local.URIs = local.URIs[:0]
+
+	for _, suffix := range local.model.FileSuffixes(timeFrame, local.forecastHours) {
+		uri, err := layoutURI(local.grid, date, timeFrame, suffix)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(filepath.Join(local.root, filepath.FromSlash(uri)))
+		if err != nil || info.IsDir() {
+			logrus.Debugf("not found in %s: %s", local.root, uri)
+			continue
+		}
+		local.URIs = append(local.URIs, uri)
+	}
+
+	return local.URIs, nil
+}
+
+// layoutURI builds the path of a file relative to the root of a NOMADS style
+// directory tree from the same templates used for the filter scripts
+func layoutURI(grid *ModelGrid, date string, timeFrame TimeFrame, fs FileSuffix) (string, error) {
+	dir, err := url.PathUnescape(fmt.Sprintf(grid.DirURIFormat, date, timeFrame))
+	if err != nil {
+		return "", err
+	}
+	file := fmt.Sprintf(grid.FileURIFormat, timeFrame, fs)
+	return strings.TrimPrefix(path.Join(dir, file), "/"), nil
+}
+
+// localRoot accepts a file:// URL or a plain path to a directory
+func localRoot(repositoryURL string) (string, error) {
+	if repositoryURL == "" {
+		return "", fmt.Errorf("a repository_url is required for %s repositories", LocalRepoType)
+	}
+	root := repositoryURL
+	if strings.HasPrefix(repositoryURL, "file://") {
+		u, err := url.Parse(repositoryURL)
+		if err != nil {
+			return "", err
+		}
+		root = filepath.FromSlash(u.Path)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("repository is not a directory: %s", root)
+	}
+	return filepath.Abs(root)
+}
@@ -0,0 +1,196 @@
+package gfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// layOut writes files under root following their slash separated paths
+func layOut(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		fileName := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layOut(t, dir, map[string]string{"file": ""})
+
+	tests := []struct {
+		repositoryURL string
+		ok            bool
+	}{
+		{dir, true},
+		{"file://" + filepath.ToSlash(dir), true},
+		{"", false},
+		{filepath.Join(dir, "missing"), false},
+		{filepath.Join(dir, "file"), false},
+	}
+	for _, test := range tests {
+		root, err := localRoot(test.repositoryURL)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: err = %v, want ok %v", test.repositoryURL, err, test.ok)
+			continue
+		}
+		if test.ok && root != dir {
+			t.Errorf("%q: root = %s, want %s", test.repositoryURL, root, dir)
+		}
+	}
+}
+
+func TestLayoutURI(t *testing.T) {
+	tests := []struct {
+		model      *Model
+		resolution Resolution
+		suffix     FileSuffix
+		want       string
+	}{
+		{GFSModel, OneDegree, "f003", "gfs.20240102/06/atmos/gfs.t06z.pgrb2.1p00.f003"},
+		{GDASModel, ZeroPointTwoFiveDegree, "anl", "gdas.20240102/06/atmos/gdas.t06z.pgrb2.0p25.anl"},
+		{NAMModel, "", "03", "nam.20240102/nam.t06z.awphys03.tm00.grib2"},
+		{HRRRModel, "alaska", "03", "hrrr.20240102/alaska/hrrr.t06z.wrfsfcf03.ak.grib2"},
+	}
+	for _, test := range tests {
+		grid, err := test.model.Grid(test.resolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := layoutURI(grid, "20240102", ZeroSixHundredHours, test.suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s %s: uri = %s, want %s", test.model.Name, test.suffix, got, test.want)
+		}
+	}
+}
+
+func TestLocalRepository(t *testing.T) {
+	root, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	output, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(output)
+
+	// f006 of 00z is missing, 06z is outside the time frame and a directory
+	// named like a file is not one
+	files := map[string]string{
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.anl":       "anl",
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.f000":      "f000",
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.f003":      "f003",
+		"gfs.20240102/06/atmos/gfs.t06z.pgrb2.1p00.f000":      "06z",
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.f006/file": "",
+	}
+	layOut(t, root, files)
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	p := &Params{
+		DataSource:     "gfs",
+		RepositoryType: LocalRepoType,
+		RepositoryURL:  "file://" + filepath.ToSlash(root),
+		Resolution:     OneDegree,
+		DateRange:      DateRange{Start: day, End: day.Add(24 * time.Hour)},
+		TimeFrame:      Zulu,
+		ForecastHours:  []int{0, 3, 6},
+		OutputFolder:   output,
+	}
+	repo := NewRepository(LocalRepoType)
+	if err = repo.LoadParams(p); err != nil {
+		t.Fatal(err)
+	}
+	uris, err := repo.GetURIs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.anl",
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.f000",
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.f003",
+	}
+	if len(uris) != len(want) {
+		t.Fatalf("uris = %v, want %v", uris, want)
+	}
+	for i := range want {
+		if uris[i] != want[i] {
+			t.Errorf("uri %d = %s, want %s", i, uris[i], want[i])
+		}
+	}
+
+	// the files are copied to the same layout in the output folder
+	if err = NewService(p).GetFiles(); err != nil {
+		t.Fatal(err)
+	}
+	for _, uri := range want {
+		data, err := ioutil.ReadFile(filepath.Join(output, filepath.FromSlash(uri)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != files[uri] {
+			t.Errorf("%s = %q, want %q", uri, data, files[uri])
+		}
+	}
+	if _, err = os.Stat(filepath.Join(output, "gfs.20240102", "06")); !os.IsNotExist(err) {
+		t.Errorf("copied a file outside the time frame: %v", err)
+	}
+}
+
+func TestLocalCyclePublished(t *testing.T) {
+	root, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	layOut(t, root, map[string]string{
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.anl":  "",
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.f000": "",
+		"gfs.20240102/00/atmos/gfs.t00z.pgrb2.1p00.f003": "",
+	})
+
+	tests := []struct {
+		timeFrame     TimeFrame
+		forecastHours []int
+		want          bool
+	}{
+		{Zulu, []int{0, 3}, true},
+		{Zulu, []int{0, 3, 6}, false},
+		{ZeroSixHundredHours, []int{0}, false},
+	}
+	for _, test := range tests {
+		local := new(LocalRepository)
+		err = local.LoadParams(&Params{
+			DataSource:    "gfs",
+			RepositoryURL: root,
+			Resolution:    OneDegree,
+			TimeFrame:     test.timeFrame,
+			ForecastHours: test.forecastHours,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := local.IsCyclePublished("20240102", test.timeFrame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%sz %v: published = %v, want %v", test.timeFrame, test.forecastHours, got, test.want)
+		}
+	}
+}
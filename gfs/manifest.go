@@ -0,0 +1,67 @@
+package gfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest saved in the output folder
+const ManifestFileName string = "nimbus.manifest.json"
+
+// ManifestEntry records where a downloaded file came from
+type ManifestEntry struct {
+	File           string         `json:"file"`
+	RepositoryType RepositoryType `json:"repository_type"`
+	URL            string         `json:"url"`
+	Size           int            `json:"size"`
+	DownloadedAt   time.Time      `json:"downloaded_at"`
+}
+
+// Manifest lists the files in an output folder and the mirror that served each
+type Manifest struct {
+	DataSource string          `json:"data_source"`
+	Files      []ManifestEntry `json:"files"`
+}
+
+// LoadManifest reads a manifest, a missing file gives an empty manifest
+func LoadManifest(fileName string) (*Manifest, error) {
+	m := new(Manifest)
+	data, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Add records a file, replacing any earlier entry for the same file
+func (m *Manifest) Add(entry ManifestEntry) {
+	for i := range m.Files {
+		if m.Files[i].File == entry.File {
+			m.Files[i] = entry
+			return
+		}
+	}
+	m.Files = append(m.Files, entry)
+}
+
+// Save writes the manifest sorted by file name
+func (m *Manifest) Save(fileName string) error {
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].File < m.Files[j].File
+	})
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
@@ -0,0 +1,107 @@
+package gfs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MirrorRepository holds the data that constructs the URL for an http server
+// that mirrors the NOMADS directory layout of a model, such as the NOAA open
+// data buckets
+type MirrorRepository struct {
+	baseURL       string
+	model         *Model
+	grid          *ModelGrid
+	dateRange     DateRange
+	timeFrames    []TimeFrame
+	forecastHours []int
+	client        *http.Client
+
+	URIs []string
+}
+
+// LoadParams reads the param object into the repository
+func (mirror *MirrorRepository) LoadParams(p *Params) error {
+	if p.RepositoryURL == "" {
+		return fmt.Errorf("a repository_url is required for %s repositories", MirrorRepoType)
+	}
+	m, err := LookupModel(p.DataSource)
+	if err != nil {
+		return err
+	}
+	grid, err := m.Grid(p.Resolution)
+	if err != nil {
+		return err
+	}
+	timeFrames, err := m.TimeFrames(grid, p.TimeFrame)
+	if err != nil {
+		return err
+	}
+
+	mirror.baseURL = strings.TrimSuffix(p.RepositoryURL, "/") + "/"
+	mirror.model = m
+	mirror.grid = grid
+	mirror.dateRange = p.DateRange
+	mirror.timeFrames = timeFrames
+	mirror.forecastHours = p.ForecastHours
+	mirror.client = p.HTTPClient
+	return nil
+}
+
+// GetBaseURL gets the base URL of the repository
+func (mirror *MirrorRepository) GetBaseURL() (string, error) {
+	if mirror.baseURL == "" {
+		return "", fmt.Errorf("no repository url set")
+	}
+	return mirror.baseURL, nil
+}
+
+// GetURIs get the URIs
+func (mirror *MirrorRepository) GetURIs() ([]string, error) {
+	var uris []string
+	loops := getNumberOfLoops(mirror.dateRange.Start, mirror.dateRange.End)
+	d := mirror.dateRange.Start
+	for i := 0; i < loops; i++ {
+		date := d.Format("20060102")
+		dateURIs, err := mirror.GetURIsForDate(date)
+		if err != nil {
+			return nil, err
+		}
+		uris = append(uris, dateURIs...)
+		d = d.Add(time.Hour * 24)
+	}
+
+	mirror.URIs = uris
+	return mirror.URIs, nil
+}
+
+// GetURIsForDate get the URIs for a specific date
+func (mirror *MirrorRepository) GetURIsForDate(date string) ([]string, error) {
+	var uris []string
+	for _, tf := range mirror.timeFrames {
+		tfURIs, err := mirror.GetURIsForDateAndTime(date, tf)
+		if err != nil {
+			return nil, err
+		}
+		uris = append(uris, tfURIs...)
+	}
+	mirror.URIs = uris
+	return mirror.URIs, nil
+}
+
+// GetURIsForDateAndTime get the URIs for a specific date and time frame
+func (mirror *MirrorRepository) GetURIsForDateAndTime(date string, timeFrame TimeFrame) ([]string, error) {
+	mirror.URIs = mirror.URIs[:0]
+
+	for _, suffix := range mirror.model.FileSuffixes(timeFrame, mirror.forecastHours) {
+		uri, err := layoutURI(mirror.grid, date, timeFrame, suffix)
+		if err != nil {
+			return nil, err
+		}
+		mirror.URIs = append(mirror.URIs, uri)
+	}
+
+	return mirror.URIs, nil
+}
@@ -0,0 +1,242 @@
+package gfs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	nomadsFilterURLFormat string = nomadsURL + "/cgi-bin/%s"                // filter script
+	nomadsProdURLFormat   string = nomadsURL + "/pub/data/nccf/com/%s/prod" // model directory
+)
+
+// ModelGrid is a grid a model is published on and the filter script serving it
+type ModelGrid struct {
+	Resolution Resolution
+	BaseURL    string
+	// FileURIFormat builds the file name from the time frame and forecast suffix
+	FileURIFormat string
+	// DirURIFormat builds the directory from the date and time frame, use
+	// explicit argument indexes when the directory has no time frame
+	DirURIFormat string
+	// Cycles are the time frames the model runs at on this grid
+	Cycles []TimeFrame
+}
+
+// ForecastRange is a run of forecast hours published at a fixed interval
+type ForecastRange struct {
+	Step  int
+	Until int
+}
+
+// Model describes a NOMADS model and how its files are addressed
+type Model struct {
+	Name string
+	// Grids the model is published on, the first grid is the default
+	Grids []ModelGrid
+	// ProdURL is the NOMADS directory the filter scripts read from, it is
+	// probed to find the latest cycle
+	ProdURL string
+	// HasAnalysis is true when an anl file is published next to the forecasts
+	HasAnalysis    bool
+	ForecastFormat string
+	Forecasts      []ForecastRange
+	// ExtendedForecasts replaces Forecasts for the cycles that run longer
+	ExtendedForecasts map[TimeFrame][]ForecastRange
+}
+
+var models = map[string]*Model{}
+
+// RegisterModel adds a model to the registry used by the data source lookup
+func RegisterModel(m *Model) {
+	models[strings.ToLower(m.Name)] = m
+}
+
+// LookupModel finds a registered model by its data source name
+func LookupModel(name string) (*Model, error) {
+	m, ok := models[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown data source: %s", name)
+	}
+	return m, nil
+}
+
+// ModelNames returns the names of all registered models
+func ModelNames() []string {
+	var names []string
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Grid returns the grid matching the resolution, or the default grid when the
+// resolution is empty
+func (m *Model) Grid(r Resolution) (*ModelGrid, error) {
+	if r == "" {
+		return &m.Grids[0], nil
+	}
+	for i := range m.Grids {
+		if m.Grids[i].Resolution == r {
+			return &m.Grids[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not published at %s resolution", m.Name, r)
+}
+
+// TimeFrames returns the time frames to download on the grid for the
+// requested time frame
+func (m *Model) TimeFrames(g *ModelGrid, tf TimeFrame) ([]TimeFrame, error) {
+	if tf == "" || tf == AllTimeFrames {
+		return g.Cycles, nil
+	}
+	for _, c := range g.Cycles {
+		if c == tf {
+			return []TimeFrame{tf}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s does not run at %sz on the %s grid", m.Name, tf, g.Resolution)
+}
+
+// ForecastHours returns every forecast hour published for the time frame
+func (m *Model) ForecastHours(tf TimeFrame) []int {
+	ranges := m.Forecasts
+	if extended, ok := m.ExtendedForecasts[tf]; ok {
+		ranges = extended
+	}
+
+	var hours []int
+	for _, r := range ranges {
+		f := 0
+		if len(hours) > 0 {
+			f = hours[len(hours)-1] + r.Step
+		}
+		for ; f <= r.Until; f += r.Step {
+			hours = append(hours, f)
+		}
+	}
+	return hours
+}
+
+// FileSuffixes returns the suffixes of the files published for the time
+// frame, only the forecast hours listed in only are kept unless it is empty
+func (m *Model) FileSuffixes(tf TimeFrame, only []int) []FileSuffix {
+	var suffixes []FileSuffix
+	if m.HasAnalysis {
+		suffixes = append(suffixes, "anl")
+	}
+	for _, f := range m.ForecastHours(tf) {
+		if len(only) > 0 && !containsHour(only, f) {
+			continue
+		}
+		suffixes = append(suffixes, FileSuffix(fmt.Sprintf(m.ForecastFormat, f)))
+	}
+	return suffixes
+}
+
+func containsHour(hours []int, f int) bool {
+	for _, h := range hours {
+		if h == f {
+			return true
+		}
+	}
+	return false
+}
+
+// everyCycles returns the time frames of a model running every step hours
+func everyCycles(step int) []TimeFrame {
+	var cycles []TimeFrame
+	for h := 0; h < 24; h += step {
+		cycles = append(cycles, TimeFrame(fmt.Sprintf("%02d", h)))
+	}
+	return cycles
+}
+
+func extendCycles(cycles []TimeFrame, ranges []ForecastRange) map[TimeFrame][]ForecastRange {
+	extended := make(map[TimeFrame][]ForecastRange)
+	for _, c := range cycles {
+		extended[c] = ranges
+	}
+	return extended
+}
+
+func filterURL(script string) string {
+	return fmt.Sprintf(nomadsFilterURLFormat, script)
+}
+
+// GFSModel is the Global Forecast System
+var GFSModel = &Model{
+	Name: "gfs",
+	Grids: []ModelGrid{
+		{OneDegree, filterURL("filter_gfs_1p00.pl"), "gfs.t%sz.pgrb2.1p00.%s", "%%2Fgfs.%s%%2F%s%%2Fatmos", allTimeFrames()},
+		{ZeroPointFiveDegree, filterURL("filter_gfs_0p50.pl"), "gfs.t%sz.pgrb2.0p50.%s", "%%2Fgfs.%s%%2F%s%%2Fatmos", allTimeFrames()},
+		{ZeroPointTwoFiveDegree, filterURL("filter_gfs_0p25.pl"), "gfs.t%sz.pgrb2.0p25.%s", "%%2Fgfs.%s%%2F%s%%2Fatmos", allTimeFrames()},
+	},
+	ProdURL:        fmt.Sprintf(nomadsProdURLFormat, "gfs"),
+	HasAnalysis:    true,
+	ForecastFormat: "f%03d",
+	Forecasts:      []ForecastRange{{Step: 3, Until: maxFRange * 3}},
+}
+
+// GDASModel is the Global Data Assimilation System, its short forecasts bridge
+// the gap to the next cycle
+var GDASModel = &Model{
+	Name: "gdas",
+	Grids: []ModelGrid{
+		{OneDegree, filterURL("filter_gdas_1p00.pl"), "gdas.t%sz.pgrb2.1p00.%s", "%%2Fgdas.%s%%2F%s%%2Fatmos", allTimeFrames()},
+		{ZeroPointTwoFiveDegree, filterURL("filter_gdas_0p25.pl"), "gdas.t%sz.pgrb2.0p25.%s", "%%2Fgdas.%s%%2F%s%%2Fatmos", allTimeFrames()},
+	},
+	ProdURL:        fmt.Sprintf(nomadsProdURLFormat, "gfs"),
+	HasAnalysis:    true,
+	ForecastFormat: "f%03d",
+	Forecasts:      []ForecastRange{{Step: 1, Until: 9}},
+}
+
+// NAMModel is the North American Mesoscale model on its 12 km CONUS grid
+var NAMModel = &Model{
+	Name: "nam",
+	Grids: []ModelGrid{
+		{"awphys", filterURL("filter_nam.pl"), "nam.t%sz.awphys%s.tm00.grib2", "%%2Fnam.%[1]s", allTimeFrames()},
+	},
+	ProdURL:        fmt.Sprintf(nomadsProdURLFormat, "nam"),
+	ForecastFormat: "%02d",
+	Forecasts:      []ForecastRange{{Step: 1, Until: 36}, {Step: 3, Until: 84}},
+}
+
+// HRRRModel is the High-Resolution Rapid Refresh, it runs every hour over
+// CONUS and every 3 hours over Alaska, the synoptic cycles run out to 48 hours
+var HRRRModel = &Model{
+	Name: "hrrr",
+	Grids: []ModelGrid{
+		{"conus", filterURL("filter_hrrr_2d.pl"), "hrrr.t%sz.wrfsfcf%s.grib2", "%%2Fhrrr.%[1]s%%2Fconus", everyCycles(1)},
+		{"alaska", filterURL("filter_hrrr_ak_2d.pl"), "hrrr.t%sz.wrfsfcf%s.ak.grib2", "%%2Fhrrr.%[1]s%%2Falaska", everyCycles(3)},
+	},
+	ProdURL:           fmt.Sprintf(nomadsProdURLFormat, "hrrr"),
+	ForecastFormat:    "%02d",
+	Forecasts:         []ForecastRange{{Step: 1, Until: 18}},
+	ExtendedForecasts: extendCycles(allTimeFrames(), []ForecastRange{{Step: 1, Until: 48}}),
+}
+
+// RAPModel is the Rapid Refresh, it runs every hour and the 03z, 09z, 15z and
+// 21z cycles run out to 51 hours
+var RAPModel = &Model{
+	Name: "rap",
+	Grids: []ModelGrid{
+		{"awp130", filterURL("filter_rap.pl"), "rap.t%sz.awp130pgrbf%s.grib2", "%%2Frap.%[1]s", everyCycles(1)},
+		{"awip32", filterURL("filter_rap32.pl"), "rap.t%sz.awip32f%s.grib2", "%%2Frap.%[1]s", everyCycles(1)},
+	},
+	ProdURL:           fmt.Sprintf(nomadsProdURLFormat, "rap"),
+	ForecastFormat:    "%02d",
+	Forecasts:         []ForecastRange{{Step: 1, Until: 21}},
+	ExtendedForecasts: extendCycles([]TimeFrame{"03", "09", "15", "21"}, []ForecastRange{{Step: 1, Until: 51}}),
+}
+
+func init() {
+	RegisterModel(GFSModel)
+	RegisterModel(GDASModel)
+	RegisterModel(NAMModel)
+	RegisterModel(HRRRModel)
+	RegisterModel(RAPModel)
+}
@@ -0,0 +1,143 @@
+package gfs
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestGDASRepository(t *testing.T) {
+	repo := NewRepository(GDASRepoType)
+	// the data source is ignored, GDAS repositories always read GDAS
+	if err := repo.LoadParams(&Params{DataSource: "gfs", Resolution: OneDegree, TimeFrame: ZeroSixHundredHours}); err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := repo.GetBaseURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://nomads.ncep.noaa.gov/cgi-bin/filter_gdas_1p00.pl"; base != want {
+		t.Errorf("base URL = %s, want %s", base, want)
+	}
+
+	uris, err := repo.GetURIsForDateAndTime("20240102", ZeroSixHundredHours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"gdas.t06z.pgrb2.1p00.anl",
+		"gdas.t06z.pgrb2.1p00.f000", "gdas.t06z.pgrb2.1p00.f001", "gdas.t06z.pgrb2.1p00.f002",
+		"gdas.t06z.pgrb2.1p00.f003", "gdas.t06z.pgrb2.1p00.f004", "gdas.t06z.pgrb2.1p00.f005",
+		"gdas.t06z.pgrb2.1p00.f006", "gdas.t06z.pgrb2.1p00.f007", "gdas.t06z.pgrb2.1p00.f008",
+		"gdas.t06z.pgrb2.1p00.f009",
+	}
+	if len(uris) != len(want) {
+		t.Fatalf("got %d URIs, want %d: %v", len(uris), len(want), uris)
+	}
+	for i, uri := range uris {
+		query, err := url.ParseQuery(uri[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file := query.Get("file"); file != want[i] {
+			t.Errorf("URI %d file = %s, want %s", i, file, want[i])
+		}
+		if dir := query.Get("dir"); dir != "/gdas.20240102/06/atmos" {
+			t.Errorf("URI %d dir = %s, want /gdas.20240102/06/atmos", i, dir)
+		}
+		if query.Get("all_lev") != "on" || query.Get("all_var") != "on" {
+			t.Errorf("URI %d does not reuse the level and variable filters: %s", i, uri)
+		}
+	}
+}
+
+func TestGDASResolutions(t *testing.T) {
+	tests := []struct {
+		resolution Resolution
+		ok         bool
+	}{
+		{"", true},
+		{OneDegree, true},
+		{ZeroPointTwoFiveDegree, true},
+		{ZeroPointFiveDegree, false},
+	}
+	for _, test := range tests {
+		err := NewRepository(GDASRepoType).LoadParams(&Params{Resolution: test.resolution, TimeFrame: AllTimeFrames})
+		if (err == nil) != test.ok {
+			t.Errorf("resolution %q: err = %v, want ok %v", test.resolution, err, test.ok)
+		}
+	}
+}
+
+func TestModelCycles(t *testing.T) {
+	tests := []struct {
+		model      string
+		resolution Resolution
+		timeFrame  TimeFrame
+		want       []TimeFrame
+		ok         bool
+	}{
+		{"gfs", OneDegree, AllTimeFrames, []TimeFrame{"00", "06", "12", "18"}, true},
+		{"gfs", OneDegree, "03", nil, false},
+		{"hrrr", "conus", "", everyCycles(1), true},
+		{"hrrr", "conus", "05", []TimeFrame{"05"}, true},
+		{"hrrr", "alaska", AllTimeFrames, []TimeFrame{"00", "03", "06", "09", "12", "15", "18", "21"}, true},
+		{"hrrr", "alaska", "03", []TimeFrame{"03"}, true},
+		{"hrrr", "alaska", "05", nil, false},
+		{"rap", "awp130", "23", []TimeFrame{"23"}, true},
+	}
+	for _, test := range tests {
+		m, err := LookupModel(test.model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		grid, err := m.Grid(test.resolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.TimeFrames(grid, test.timeFrame)
+		if (err == nil) != test.ok {
+			t.Errorf("%s %s %sz: err = %v, want ok %v", test.model, test.resolution, test.timeFrame, err, test.ok)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s %s %sz: got %v, want %v", test.model, test.resolution, test.timeFrame, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%s %s %sz: got %v, want %v", test.model, test.resolution, test.timeFrame, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestModelForecastHours(t *testing.T) {
+	tests := []struct {
+		model     string
+		timeFrame TimeFrame
+		first     int
+		last      int
+		n         int
+	}{
+		{"gfs", "00", 0, 384, 129},
+		{"gdas", "06", 0, 9, 10},
+		{"nam", "12", 0, 84, 53},
+		{"hrrr", "00", 0, 48, 49},
+		{"hrrr", "01", 0, 18, 19},
+		{"rap", "03", 0, 51, 52},
+		{"rap", "04", 0, 21, 22},
+	}
+	for _, test := range tests {
+		m, err := LookupModel(test.model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hours := m.ForecastHours(test.timeFrame)
+		if len(hours) != test.n || hours[0] != test.first || hours[len(hours)-1] != test.last {
+			t.Errorf("%s %sz: got %d hours from %d to %d, want %d from %d to %d", test.model, test.timeFrame,
+				len(hours), hours[0], hours[len(hours)-1], test.n, test.first, test.last)
+		}
+	}
+}
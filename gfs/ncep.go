@@ -2,96 +2,120 @@ package gfs
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	ncepBaseURLFormat string = "https://nomads.ncep.noaa.gov/cgi-bin/filter_gfs_%s.pl" // resolution
-	ncepFileURIFormat string = "gfs.t%sz.pgrb2.%s.%s"                                  // time, resolution, anl/filesuffix
-	ncepDirURIFormat  string = "%%2Fgfs.%s%%2F%s"                                      // date of data, time frame of data
-
 	maxFRange int = 128
-)
-
-// Level is a region at a certain altitude
-type Level struct {
-	uriKey     string
-	isIncluded bool
-}
 
-// ClimateVariable is a portion of GRIB2 climate data
-type ClimateVariable struct {
-	uriKey     string
-	isIncluded bool
-}
+	nomadsURL string = "https://nomads.ncep.noaa.gov"
+)
 
-// NCEPRepository holds the data that constructs the URL
+// NCEPRepository holds the data that constructs the URL for the NOMADS
+// filter scripts of a model. A repository URL replaces the NOMADS server,
+// e.g. with a proxy serving the same paths
 type NCEPRepository struct {
-	resolution Resolution
-	dateRange  DateRange
-	timeFrames []TimeFrame
+	model         *Model
+	grid          *ModelGrid
+	baseURL       string
+	prodURL       string
+	client        *http.Client
+	dateRange     DateRange
+	timeFrames    []TimeFrame
+	forecastHours []int
 
-	// params
-	levels                   map[string]Level
-	levelsURICache           string
-	climateVariables         map[string]ClimateVariable
-	climateVariablesURICache string
-
-	region Region
+	filter
 
 	URIs []string
 }
 
 // LoadParams reads the param object into the repository
 func (ncep *NCEPRepository) LoadParams(p *Params) error {
-	ncep.resolution = p.Resolution
+	if ncep.model == nil {
+		m, err := LookupModel(p.DataSource)
+		if err != nil {
+			return err
+		}
+		ncep.model = m
+	}
+
+	grid, err := ncep.model.Grid(p.Resolution)
+	if err != nil {
+		return err
+	}
+	timeFrames, err := ncep.model.TimeFrames(grid, p.TimeFrame)
+	if err != nil {
+		return err
+	}
+
+	ncep.grid = grid
+	ncep.baseURL = rebaseURL(grid.BaseURL, p.RepositoryURL)
+	ncep.prodURL = rebaseURL(ncep.model.ProdURL, p.RepositoryURL)
+	ncep.client = p.HTTPClient
 	ncep.dateRange = p.DateRange
-	ncep.timeFrames = getTimeFrames(p.TimeFrame)
+	ncep.timeFrames = timeFrames
+	ncep.forecastHours = p.ForecastHours
 	return nil
 }
 
 // GetBaseURL gets the base URL of the repository
 func (ncep *NCEPRepository) GetBaseURL() (string, error) {
-	if ncep.resolution == "" {
+	if ncep.grid == nil {
 		return "", fmt.Errorf("no resolution set")
 	}
-	return fmt.Sprintf(ncepBaseURLFormat, ncep.resolution), nil
+	return ncep.baseURL, nil
+}
+
+// rebaseURL moves a NOMADS URL onto another server
+func rebaseURL(u, server string) string {
+	if server == "" || !strings.HasPrefix(u, nomadsURL) {
+		return u
+	}
+	return strings.TrimSuffix(server, "/") + strings.TrimPrefix(u, nomadsURL)
 }
 
 // GetURIs get the URIs
 func (ncep *NCEPRepository) GetURIs() ([]string, error) {
 	ncep.URIs = ncep.URIs[:0]
 
-	if ncep.dateRange.End.Sub(time.Now()) > 0 {
-		return nil, fmt.Errorf("end date can not be in the future")
+	if ncep.dateRange.Start.After(time.Now()) {
+		return nil, fmt.Errorf("start date can not be in the future")
 	}
 
+	// collect into a separate slice since every date resets ncep.URIs
+	var uris []string
 	loops := getNumberOfLoops(ncep.dateRange.Start, ncep.dateRange.End)
 	d := ncep.dateRange.Start
 	for i := 0; i < loops; i++ {
 		date := d.Format("20060102")
-		_, err := ncep.GetURIsForDate(date)
+		dateURIs, err := ncep.GetURIsForDate(date)
 		if err != nil {
 			return nil, err
 		}
+		uris = append(uris, dateURIs...)
 		d = d.Add(time.Hour * 24)
 	}
 
+	ncep.URIs = uris
 	return ncep.URIs, nil
 }
 
 // GetURIsForDate get the URIs for a specific date
 func (ncep *NCEPRepository) GetURIsForDate(date string) ([]string, error) {
-	ncep.URIs = ncep.URIs[:0]
+	var uris []string
 	// loop through the time frames and build the URIs for each
 	for _, tf := range ncep.timeFrames {
-		_, err := ncep.GetURIsForDateAndTime(date, tf)
+		tfURIs, err := ncep.GetURIsForDateAndTime(date, tf)
 		if err != nil {
 			return nil, err
 		}
+		uris = append(uris, tfURIs...)
 	}
+	ncep.URIs = uris
 	return ncep.URIs, nil
 }
 
@@ -99,16 +123,7 @@ func (ncep *NCEPRepository) GetURIsForDate(date string) ([]string, error) {
 func (ncep *NCEPRepository) GetURIsForDateAndTime(date string, timeFrame TimeFrame) ([]string, error) {
 	ncep.URIs = ncep.URIs[:0]
 
-	// build .anl URI since it's unique
-	anlURI := ncep.buildURI(date, timeFrame, "anl")
-	ncep.URIs = append(ncep.URIs, anlURI)
-
-	// build the f URIs
-	for i := 0; i <= maxFRange; i++ {
-		f := i * 3
-		// build the file suffix
-		suffix := FileSuffix(fmt.Sprintf("f%03d", f))
-
+	for _, suffix := range ncep.model.FileSuffixes(timeFrame, ncep.forecastHours) {
 		// build the URI
 		fURI := ncep.buildURI(date, timeFrame, suffix)
 
@@ -120,41 +135,13 @@ func (ncep *NCEPRepository) GetURIsForDateAndTime(date string, timeFrame TimeFra
 }
 
 func (ncep *NCEPRepository) buildURI(date string, timeFrame TimeFrame, fs FileSuffix) string {
-	fileURI := fmt.Sprintf(ncepFileURIFormat, timeFrame, ncep.resolution, fs)
+	fileURI := fmt.Sprintf(ncep.grid.FileURIFormat, timeFrame, fs)
 	levelURI := ncep.getLevels()
 	climateVariableURI := ncep.getClimateVariables()
 	regionURI := ncep.region.ToURI()
-	dirURI := fmt.Sprintf(ncepDirURIFormat, date, timeFrame)
+	dirURI := fmt.Sprintf(ncep.grid.DirURIFormat, date, timeFrame)
 
-	URI := fmt.Sprintf("?file=%s&%s&%s&%s&%s", fileURI, levelURI, climateVariableURI, regionURI, dirURI)
+	URI := fmt.Sprintf("?file=%s&%s&%s&%s&dir=%s", fileURI, levelURI, climateVariableURI, regionURI, dirURI)
 	logrus.Debug(URI)
 	return URI
 }
-
-func (ncep *NCEPRepository) getLevels() string {
-	if ncep.levelsURICache != "" {
-		return ncep.levelsURICache
-	}
-
-	if len(ncep.levels) == 0 {
-		ncep.levelsURICache = "all_lev=on"
-		return ncep.levelsURICache
-	}
-	// TODO: Read levels from csv file
-	// TODO: Read config for which levels to enable
-	return ""
-}
-
-func (ncep *NCEPRepository) getClimateVariables() string {
-	if ncep.climateVariablesURICache != "" {
-		return ncep.climateVariablesURICache
-	}
-
-	if len(ncep.climateVariables) == 0 {
-		ncep.climateVariablesURICache = "all_var=on"
-		return ncep.climateVariablesURICache
-	}
-	// TODO: Read vars from csv file
-	// TODO: Read config for which vars to enable
-	return ""
-}
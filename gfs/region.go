@@ -1,6 +1,11 @@
 package gfs
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 // Region contains the region of the data
 type Region struct {
@@ -24,3 +29,50 @@ func FullEarthRegion() *Region {
 		BottomLat: -90.0,
 	}
 }
+
+// ParseRegion reads a region written as leftlon,rightlon,toplat,bottomlat,
+// e.g. "-10,40,60,35"
+func ParseRegion(s string) (*Region, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("region must be leftlon,rightlon,toplat,bottomlat: %s", s)
+	}
+	var v [4]float32
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("region must be leftlon,rightlon,toplat,bottomlat: %s", s)
+		}
+		v[i] = float32(f)
+	}
+	r := &Region{LeftLon: v[0], RightLon: v[1], TopLat: v[2], BottomLat: v[3]}
+	if r.TopLat < r.BottomLat {
+		return nil, fmt.Errorf("region top latitude %g is below its bottom latitude %g", r.TopLat, r.BottomLat)
+	}
+	return r, nil
+}
+
+// Contains reports whether a point is inside the region. Longitudes wrap, so
+// a region from 350 to 10 or from -10 to 10 spans the prime meridian
+func (r *Region) Contains(lat, lon float64) bool {
+	if lat < float64(r.BottomLat) || lat > float64(r.TopLat) {
+		return false
+	}
+	if r.RightLon-r.LeftLon >= 360 {
+		return true
+	}
+	left := float64(r.LeftLon)
+	return normalizeLon(lon-left) <= normalizeLon(float64(r.RightLon)-left)
+}
+
+// normalizeLon puts a longitude in [0, 360)
+func normalizeLon(lon float64) float64 {
+	lon = math.Mod(lon, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	if lon >= 360 {
+		lon -= 360
+	}
+	return lon
+}
@@ -10,6 +10,12 @@ const (
 	NCEPRepoType RepositoryType = "NCEP"
 	// NCDCRepoType get files from NCDC
 	NCDCRepoType RepositoryType = "NCDC"
+	// GDASRepoType get GDAS analyses from NCEP regardless of the data source
+	GDASRepoType RepositoryType = "GDAS"
+	// LocalRepoType get files from a local directory
+	LocalRepoType RepositoryType = "LOCAL"
+	// MirrorRepoType get files from an http mirror of the NOMADS directories
+	MirrorRepoType RepositoryType = "MIRROR"
 )
 
 // RepositoryType the type of repository being accessed
@@ -30,6 +36,12 @@ func NewRepository(rt RepositoryType) Repository {
 		return new(NCEPRepository)
 	} else if rt == NCDCRepoType {
 		return nil
+	} else if rt == GDASRepoType {
+		return &NCEPRepository{model: GDASModel}
+	} else if rt == LocalRepoType {
+		return new(LocalRepository)
+	} else if rt == MirrorRepoType {
+		return new(MirrorRepository)
 	}
 	return nil
 }
@@ -1,43 +1,194 @@
 package gfs
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/sirupsen/logrus"
+
+	"github.com/azillion/nimbus/util"
 )
 
-// Service holds the repository and params of the service
+// mirror is one repository of the ordered list a service downloads from
+type mirror struct {
+	repositoryType RepositoryType
+	repository     Repository
+}
+
+// Service holds the repositories and params of the service
 type Service struct {
-	repository Repository
-	params     *Params
+	mirrors []mirror
+	params  *Params
 }
 
-// GetFiles from NOMADS
+// task is a file to download and the URI of it on each mirror that has it
+type task struct {
+	fileName string
+	uris     map[int]string
+}
+
+// GetFiles from NOMADS, every file is requested from the repositories in
+// order until one of them serves it
 func (s *Service) GetFiles() error {
-	baseURL, err := s.repository.GetBaseURL()
+	tasks, err := s.getTasks()
 	if err != nil {
-		logrus.Fatal(err)
+		return err
 	}
-	logrus.Debug(baseURL)
 
-	_, err = s.repository.GetURIs()
+	manifestFileName := filepath.Join(s.params.OutputFolder, ManifestFileName)
+	manifest, err := LoadManifest(manifestFileName)
 	if err != nil {
-		logrus.Fatal(err)
+		return err
+	}
+	manifest.DataSource = s.params.DataSource
+
+	var failed int
+	for _, t := range tasks {
+		entry, err := s.getFile(t)
+		if err != nil {
+			logrus.Warnf("failed to get %s: %v", t.fileName, err)
+			failed++
+			continue
+		}
+		if entry != nil {
+			manifest.Add(*entry)
+		}
+	}
+
+	if len(manifest.Files) > 0 {
+		err = manifest.Save(manifestFileName)
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to get %d of %d files", failed, len(tasks))
 	}
 
 	return nil
 }
 
+// getTasks lines up the URIs of every repository by the file they save to
+func (s *Service) getTasks() ([]*task, error) {
+	var tasks []*task
+	byFileName := make(map[string]*task)
+	for i, m := range s.mirrors {
+		uris, err := m.repository.GetURIs()
+		if err != nil {
+			return nil, err
+		}
+		for _, uri := range uris {
+			fileName, err := localPath(uri)
+			if err != nil {
+				return nil, err
+			}
+			t, ok := byFileName[fileName]
+			if !ok {
+				t = &task{fileName: fileName, uris: make(map[int]string)}
+				byFileName[fileName] = t
+				tasks = append(tasks, t)
+			}
+			t.uris[i] = uri
+		}
+	}
+	return tasks, nil
+}
+
+// getFile downloads a single file into the output folder from the first
+// mirror that has it, files that were already downloaded are skipped but
+// still get an index when it is missing
+func (s *Service) getFile(t *task) (*ManifestEntry, error) {
+	fileName := filepath.Join(s.params.OutputFolder, t.fileName)
+	if util.FileExists(fileName) {
+		logrus.Debugf("already downloaded %s", fileName)
+		if s.params.WriteIndex && !util.FileExists(fileName+IndexSuffix) {
+			err := WriteIndexFile(fileName)
+			if err != nil {
+				logrus.Warnf("failed to index %s: %v", fileName, err)
+			}
+		}
+		return nil, nil
+	}
+
+	var lastErr error
+	for i, m := range s.mirrors {
+		uri, ok := t.uris[i]
+		if !ok {
+			continue
+		}
+		baseURL, err := m.repository.GetBaseURL()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fetch(s.params.HTTPClient, baseURL+uri)
+		if err != nil {
+			if !isFallThrough(err) {
+				return nil, err
+			}
+			logrus.Debugf("%s did not serve %s: %v", m.repositoryType, t.fileName, err)
+			lastErr = err
+			continue
+		}
+
+		err = os.MkdirAll(filepath.Dir(fileName), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = util.SaveFile(fileName, data)
+		if err != nil {
+			return nil, err
+		}
+		if s.params.WriteIndex {
+			err = writeIndex(fileName, bytes.NewReader(data))
+			if err != nil {
+				logrus.Warnf("failed to index %s: %v", fileName, err)
+			}
+		}
+		return &ManifestEntry{
+			File:           filepath.ToSlash(t.fileName),
+			RepositoryType: m.repositoryType,
+			URL:            baseURL + uri,
+			Size:           len(data),
+			DownloadedAt:   time.Now().UTC(),
+		}, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no repository has the file")
+	}
+	return nil, lastErr
+}
+
 // NewService creates a new gfs service
 func NewService(p *Params) *Service {
-	r := NewRepository(p.RepositoryType)
-	if r == nil {
-		logrus.Fatal("no repository of that type")
+	repositories := p.Repositories
+	if len(repositories) == 0 {
+		repositories = []RepositoryParams{{RepositoryType: p.RepositoryType, RepositoryURL: p.RepositoryURL}}
 	}
-	err := r.LoadParams(p)
-	if err != nil {
-		logrus.Fatalf("error loading params: %v", err)
+
+	var mirrors []mirror
+	for _, rp := range repositories {
+		r := NewRepository(rp.RepositoryType)
+		if r == nil {
+			logrus.Fatalf("no repository of that type: %s", rp.RepositoryType)
+		}
+
+		// every repository shares the params other than where it lives
+		mp := *p
+		mp.RepositoryType = rp.RepositoryType
+		mp.RepositoryURL = rp.RepositoryURL
+		err := r.LoadParams(&mp)
+		if err != nil {
+			logrus.Fatalf("error loading params: %v", err)
+		}
+		mirrors = append(mirrors, mirror{repositoryType: rp.RepositoryType, repository: r})
 	}
 	return &Service{
-		repository: r,
-		params:     p,
+		mirrors: mirrors,
+		params:  p,
 	}
 }
@@ -0,0 +1,161 @@
+package gfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mirrorLog records the requests of every mirror in the order they arrive
+type mirrorLog struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+// newMirrorServer answers with the status of the file name, 200 with the
+// name as the content for any file not listed
+func newMirrorServer(name string, log *mirrorLog, statuses map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// filter scripts name the file in the query, mirrors in the path
+		file := r.URL.Query().Get("file")
+		if file == "" {
+			file = path.Base(r.URL.Path)
+		}
+		log.mu.Lock()
+		log.requests = append(log.requests, name+" "+file)
+		log.mu.Unlock()
+
+		if code, ok := statuses[file]; ok {
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		w.Write([]byte(name + " " + file))
+	}))
+}
+
+func TestGetFilesFallThrough(t *testing.T) {
+	log := new(mirrorLog)
+	primary := newMirrorServer("primary", log, map[string]int{
+		"gdas.t00z.pgrb2.1p00.f000": http.StatusNotFound,
+		"gdas.t00z.pgrb2.1p00.f001": http.StatusServiceUnavailable,
+		"gdas.t00z.pgrb2.1p00.f002": http.StatusForbidden,
+		"gdas.t00z.pgrb2.1p00.f003": http.StatusNotFound,
+		"gdas.t00z.pgrb2.1p00.f004": http.StatusNotFound,
+	})
+	defer primary.Close()
+	secondary := newMirrorServer("secondary", log, map[string]int{
+		"gdas.t00z.pgrb2.1p00.f003": http.StatusBadGateway,
+		"gdas.t00z.pgrb2.1p00.f004": http.StatusNotFound,
+	})
+	defer secondary.Close()
+
+	dirURI := "gdas.20240102/00/atmos/"
+	local, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(local)
+	layOut(t, local, map[string]string{dirURI + "gdas.t00z.pgrb2.1p00.f003": "local gdas.t00z.pgrb2.1p00.f003"})
+
+	dir, err := ioutil.TempDir("", "nimbus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	p := &Params{
+		DataSource: "gdas",
+		Repositories: []RepositoryParams{
+			{RepositoryType: MirrorRepoType, RepositoryURL: primary.URL},
+			{RepositoryType: MirrorRepoType, RepositoryURL: secondary.URL},
+			{RepositoryType: LocalRepoType, RepositoryURL: local},
+		},
+		Resolution:   OneDegree,
+		DateRange:    DateRange{Start: day, End: day.Add(24 * time.Hour)},
+		TimeFrame:    Zulu,
+		OutputFolder: dir,
+	}
+	// a 403 stops at the primary and nobody has f004
+	err = NewService(p).GetFiles()
+	if err == nil || !strings.Contains(err.Error(), "failed to get 2 of 11 files") {
+		t.Errorf("err = %v, want 2 of 11 files failed", err)
+	}
+
+	want := []string{
+		"primary gdas.t00z.pgrb2.1p00.anl",
+		"primary gdas.t00z.pgrb2.1p00.f000",
+		"secondary gdas.t00z.pgrb2.1p00.f000",
+		"primary gdas.t00z.pgrb2.1p00.f001",
+		"secondary gdas.t00z.pgrb2.1p00.f001",
+		"primary gdas.t00z.pgrb2.1p00.f002",
+		"primary gdas.t00z.pgrb2.1p00.f003",
+		"secondary gdas.t00z.pgrb2.1p00.f003",
+		"primary gdas.t00z.pgrb2.1p00.f004",
+		"secondary gdas.t00z.pgrb2.1p00.f004",
+		"primary gdas.t00z.pgrb2.1p00.f005",
+		"primary gdas.t00z.pgrb2.1p00.f006",
+		"primary gdas.t00z.pgrb2.1p00.f007",
+		"primary gdas.t00z.pgrb2.1p00.f008",
+		"primary gdas.t00z.pgrb2.1p00.f009",
+	}
+	if strings.Join(log.requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant\n%s", strings.Join(log.requests, "\n"), strings.Join(want, "\n"))
+	}
+
+	manifest, err := LoadManifest(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.DataSource != "gdas" {
+		t.Errorf("manifest data source = %s, want gdas", manifest.DataSource)
+	}
+	type entry struct {
+		file           string
+		server         string
+		repositoryType RepositoryType
+		baseURL        string
+	}
+	wantEntries := []entry{
+		{"gdas.t00z.pgrb2.1p00.anl", "primary", MirrorRepoType, primary.URL + "/"},
+		{"gdas.t00z.pgrb2.1p00.f000", "secondary", MirrorRepoType, secondary.URL + "/"},
+		{"gdas.t00z.pgrb2.1p00.f001", "secondary", MirrorRepoType, secondary.URL + "/"},
+		{"gdas.t00z.pgrb2.1p00.f003", "local", LocalRepoType, "file://" + filepath.ToSlash(local) + "/"},
+	}
+	for f := 5; f <= 9; f++ {
+		wantEntries = append(wantEntries, entry{fmt.Sprintf("gdas.t00z.pgrb2.1p00.f%03d", f), "primary", MirrorRepoType, primary.URL + "/"})
+	}
+	if len(manifest.Files) != len(wantEntries) {
+		t.Fatalf("manifest lists %d files, want %d: %+v", len(manifest.Files), len(wantEntries), manifest.Files)
+	}
+	for i, want := range wantEntries {
+		got := manifest.Files[i]
+		if got.File != dirURI+want.file {
+			t.Errorf("entry %d file = %s, want %s", i, got.File, dirURI+want.file)
+		}
+		if got.RepositoryType != want.repositoryType {
+			t.Errorf("%s repository type = %s, want %s", want.file, got.RepositoryType, want.repositoryType)
+		}
+		if wantURL := want.baseURL + dirURI + want.file; got.URL != wantURL {
+			t.Errorf("%s url = %s, want %s", want.file, got.URL, wantURL)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(got.File)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want.server+" "+want.file {
+			t.Errorf("%s = %q, want the file of the %s", want.file, data, want.server)
+		}
+		if got.Size != len(data) {
+			t.Errorf("%s size = %d, want %d", want.file, got.Size, len(data))
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package grib2
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// missing values of the unsigned octet fields
+const (
+	missing1 = 0xff
+	missing2 = 0xffff
+	missing4 = 0xffffffff
+)
+
+func uint16At(b []byte, octet int) uint16 {
+	return binary.BigEndian.Uint16(b[octet-1:])
+}
+
+func uint32At(b []byte, octet int) uint32 {
+	return binary.BigEndian.Uint32(b[octet-1:])
+}
+
+func uint64At(b []byte, octet int) uint64 {
+	return binary.BigEndian.Uint64(b[octet-1:])
+}
+
+// int8At reads a one octet integer, GRIB2 stores negative integers as sign
+// and magnitude rather than two's complement
+func int8At(b []byte, octet int) int {
+	v := int(b[octet-1])
+	if v&0x80 != 0 {
+		return -(v & 0x7f)
+	}
+	return v
+}
+
+func int16At(b []byte, octet int) int {
+	v := int(uint16At(b, octet))
+	if v&0x8000 != 0 {
+		return -(v & 0x7fff)
+	}
+	return v
+}
+
+func int32At(b []byte, octet int) int {
+	v := int64(uint32At(b, octet))
+	if v&0x80000000 != 0 {
+		return -int(v & 0x7fffffff)
+	}
+	return int(v)
+}
+
+func float32At(b []byte, octet int) float32 {
+	return math.Float32frombits(uint32At(b, octet))
+}
+
+// scaled returns value / 10^factor for the scale factor and scaled value
+// pairs used throughout the templates
+func scaled(factor, value int) float64 {
+	return float64(value) / math.Pow(10, float64(factor))
+}
+
+// checkLength makes sure a section or template is long enough to read up to
+// and including the given octet
+func checkLength(b []byte, octet int, what string) error {
+	if len(b) < octet {
+		return fmt.Errorf("grib2: %s is %d octets, need %d", what, len(b), octet)
+	}
+	return nil
+}
+
+// bitReader reads big endian unsigned integers of any width up to 32 bits
+type bitReader struct {
+	data []byte
+	pos  uint64 // bit offset
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+// read returns the next n bits, reading past the end of the data is an error
+func (r *bitReader) read(n uint) (uint32, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	if r.pos+uint64(n) > uint64(len(r.data))*8 {
+		return 0, fmt.Errorf("grib2: packed data is truncated")
+	}
+
+	var v uint64
+	byteIndex := r.pos >> 3
+	bitOffset := uint(r.pos & 7)
+	// gather enough bytes to cover the bit offset plus n bits
+	need := (bitOffset + n + 7) >> 3
+	for i := uint(0); i < need; i++ {
+		v = v<<8 | uint64(r.data[byteIndex+uint64(i)])
+	}
+	v >>= need*8 - bitOffset - n
+	r.pos += uint64(n)
+	return uint32(v & (1<<n - 1)), nil
+}
+
+// align skips to the start of the next octet
+func (r *bitReader) align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+// unpackBits reads count unsigned integers of width bits each
+func unpackBits(data []byte, width uint, count int) ([]uint32, error) {
+	values := make([]uint32, count)
+	if width == 0 {
+		return values, nil
+	}
+	r := newBitReader(data)
+	for i := range values {
+		v, err := r.read(width)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func putUint16(b []byte, octet int, v uint16) {
+	binary.BigEndian.PutUint16(b[octet-1:], v)
+}
+
+func putUint32(b []byte, octet int, v uint32) {
+	binary.BigEndian.PutUint32(b[octet-1:], v)
+}
+
+func putUint64(b []byte, octet int, v uint64) {
+	binary.BigEndian.PutUint64(b[octet-1:], v)
+}
+
+// putInt16 writes a sign and magnitude integer, the inverse of int16At
+func putInt16(b []byte, octet int, v int) {
+	if v < 0 {
+		putUint16(b, octet, uint16(-v)|0x8000)
+		return
+	}
+	putUint16(b, octet, uint16(v))
+}
+
+// putInt32 writes a sign and magnitude integer, the inverse of int32At
+func putInt32(b []byte, octet int, v int) {
+	if v < 0 {
+		putUint32(b, octet, uint32(-v)|0x80000000)
+		return
+	}
+	putUint32(b, octet, uint32(v))
+}
+
+func putFloat32(b []byte, octet int, v float32) {
+	putUint32(b, octet, math.Float32bits(v))
+}
+
+// bitWriter packs big endian unsigned integers of any width up to 32 bits,
+// the inverse of bitReader
+type bitWriter struct {
+	data []byte
+	acc  uint64
+	n    uint // bits held in acc
+}
+
+// write appends the low n bits of v
+func (w *bitWriter) write(v uint32, n uint) {
+	if n == 0 {
+		return
+	}
+	w.acc = w.acc<<n | uint64(v)&(1<<n-1)
+	w.n += n
+	for w.n >= 8 {
+		w.n -= 8
+		w.data = append(w.data, byte(w.acc>>w.n))
+	}
+}
+
+// align pads with zero bits to the start of the next octet
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.write(0, 8-w.n)
+	}
+}
+
+// bytes returns the packed octets, the last one padded with zero bits
+func (w *bitWriter) bytes() []byte {
+	w.align()
+	return w.data
+}
+
+// bitLength is the number of bits needed to hold v
+func bitLength(v uint64) uint {
+	n := uint(0)
+	for ; v != 0; v >>= 1 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,241 @@
+package grib2
+
+import (
+	"fmt"
+	"math"
+)
+
+// markers of missing values while complex packing is unpacked, every packed
+// value fits in 32 bits so they never collide with one
+const (
+	primaryMissing   = math.MaxInt64
+	secondaryMissing = math.MaxInt64 - 1
+)
+
+// Complex holds the group and spatial differencing parameters of templates
+// 5.2 and 5.3
+type Complex struct {
+	// GroupSplitting is code table 5.4 and MissingManagement code table 5.5
+	GroupSplitting    int
+	MissingManagement int
+	// PrimaryMissing and SecondaryMissing are the substitutes for missing
+	// values, they are floats or integers as OriginalType says
+	PrimaryMissing   uint32
+	SecondaryMissing uint32
+
+	NumGroups       int
+	WidthReference  int
+	WidthBits       int
+	LengthReference int
+	LengthIncrement int
+	LastLength      int
+	LengthBits      int
+
+	// SpatialOrder is the order of spatial differencing, 0 without it, and
+	// DescriptorOctets the width of the values that undo it
+	SpatialOrder     int
+	DescriptorOctets int
+}
+
+func (r *DataRepresentation) parseComplex(section []byte) error {
+	end := 47
+	if r.TemplateNumber == 3 {
+		end = 49
+	}
+	if err := checkLength(section, end, fmt.Sprintf("data representation template 5.%d", r.TemplateNumber)); err != nil {
+		return err
+	}
+	r.parseSimple(section)
+	r.Complex = &Complex{
+		GroupSplitting:    int(section[21]),
+		MissingManagement: int(section[22]),
+		PrimaryMissing:    uint32At(section, 24),
+		SecondaryMissing:  uint32At(section, 28),
+		NumGroups:         int(uint32At(section, 32)),
+		WidthReference:    int(section[35]),
+		WidthBits:         int(section[36]),
+		LengthReference:   int(uint32At(section, 38)),
+		LengthIncrement:   int(section[41]),
+		LastLength:        int(uint32At(section, 43)),
+		LengthBits:        int(section[46]),
+	}
+	if r.TemplateNumber == 3 {
+		r.Complex.SpatialOrder = int(section[47])
+		r.Complex.DescriptorOctets = int(section[48])
+		if order := r.Complex.SpatialOrder; order != 1 && order != 2 {
+			r.err = &UnsupportedError{fmt.Sprintf("spatial differencing of order %d", order)}
+		}
+	}
+	return nil
+}
+
+// unpackComplex decodes templates 5.2 and 5.3, values are split into groups
+// that each have a reference and a width, optionally after spatial
+// differencing
+func (r *DataRepresentation) unpackComplex(data []byte) ([]float32, error) {
+	c := r.Complex
+	if c.MissingManagement > 2 {
+		return nil, fmt.Errorf("grib2: missing value management %d is not supported", c.MissingManagement)
+	}
+	if r.NumValues == 0 {
+		return []float32{}, nil
+	}
+
+	br := newBitReader(data)
+
+	// the first values and the minimum of the differences come first
+	var first []int64
+	var minimum int64
+	if c.SpatialOrder > 0 {
+		for i := 0; i <= c.SpatialOrder; i++ {
+			v, err := br.readSigned(uint(c.DescriptorOctets) * 8)
+			if err != nil {
+				return nil, err
+			}
+			first = append(first, v)
+		}
+		minimum, first = first[c.SpatialOrder], first[:c.SpatialOrder]
+	}
+
+	refs, err := br.readGroup(uint(r.Bits), c.NumGroups)
+	if err != nil {
+		return nil, err
+	}
+	widths, err := br.readGroup(uint(c.WidthBits), c.NumGroups)
+	if err != nil {
+		return nil, err
+	}
+	lengths, err := br.readGroup(uint(c.LengthBits), c.NumGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]int64, 0, r.NumValues)
+	for g := 0; g < c.NumGroups; g++ {
+		length := c.LengthReference + int(lengths[g])*c.LengthIncrement
+		if g == c.NumGroups-1 {
+			length = c.LastLength
+		}
+		if len(values)+length > r.NumValues {
+			return nil, fmt.Errorf("grib2: complex packing groups hold more than %d values", r.NumValues)
+		}
+		width := uint(c.WidthReference + int(widths[g]))
+		if width > 32 {
+			return nil, fmt.Errorf("grib2: complex packing group of %d bits", width)
+		}
+
+		// with missing value management the largest values of a width are
+		// the missing values, a group without width is missing as a whole
+		// when its reference is
+		constant := r.missing(int64(refs[g]), uint(r.Bits))
+		for i := 0; i < length; i++ {
+			v := constant
+			if width > 0 {
+				x, err := br.read(width)
+				if err != nil {
+					return nil, err
+				}
+				if v = r.missing(int64(x), width); v < secondaryMissing {
+					v += int64(refs[g])
+				}
+			}
+			values = append(values, v)
+		}
+	}
+	if len(values) != r.NumValues {
+		return nil, fmt.Errorf("grib2: complex packing groups hold %d values, want %d", len(values), r.NumValues)
+	}
+
+	// undo spatial differencing, the first values replace the placeholders
+	// packed in their place and missing values are skipped over
+	var seen int
+	var last, penultimate int64
+	for i, v := range values {
+		if c.SpatialOrder == 0 || v >= secondaryMissing {
+			continue
+		}
+		switch {
+		case seen < c.SpatialOrder:
+			v = first[seen]
+		case c.SpatialOrder == 1:
+			v += minimum + last
+		default:
+			v += minimum + 2*last - penultimate
+		}
+		values[i] = v
+		penultimate, last = last, v
+		seen++
+	}
+
+	out := make([]float32, len(values))
+	scale := r.scale()
+	nan := float32(math.NaN())
+	for i, v := range values {
+		if v >= secondaryMissing {
+			out[i] = nan
+			continue
+		}
+		out[i] = scale(float64(v))
+	}
+	return out, nil
+}
+
+// missing returns x, or a marker when x is a missing value of a bits wide
+// field under the missing value management of the packing
+func (r *DataRepresentation) missing(x int64, bits uint) int64 {
+	mm := r.Complex.MissingManagement
+	if mm == 0 || bits == 0 {
+		return x
+	}
+	all := int64(1)<<bits - 1
+	switch {
+	case x == all:
+		return primaryMissing
+	case mm == 2 && x == all-1:
+		return secondaryMissing
+	}
+	return x
+}
+
+// readGroup reads count values of width bits and skips to the next octet,
+// the group descriptors of complex packing each start on an octet
+func (r *bitReader) readGroup(width uint, count int) ([]uint32, error) {
+	values := make([]uint32, count)
+	for i := range values {
+		v, err := r.read(width)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	r.align()
+	return values, nil
+}
+
+// readSigned reads a sign and magnitude integer of width bits
+func (r *bitReader) readSigned(width uint) (int64, error) {
+	if width == 0 {
+		return 0, nil
+	}
+	sign, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for n := width - 1; n > 0; {
+		chunk := n
+		if chunk > 32 {
+			chunk = 32
+		}
+		bits, err := r.read(chunk)
+		if err != nil {
+			return 0, err
+		}
+		v = v<<chunk | uint64(bits)
+		n -= chunk
+	}
+	if sign == 1 {
+		return -int64(v), nil
+	}
+	return int64(v), nil
+}
@@ -0,0 +1,245 @@
+package grib2
+
+import (
+	"math"
+	"testing"
+)
+
+type packingTest struct {
+	file     string
+	message  int
+	template int
+	// order is the order of spatial differencing and missing the missing
+	// value management
+	order   int
+	missing int
+	// nan are the points that are missing
+	nan      int
+	nanAt    []int
+	min, max float32
+}
+
+// The fixtures other than gfs.t00z.pgrb2.0p25.f001, written by
+// grib2/internal/genfixtures, repack its UGRD field with every template, keeping its reference value, scale factors and
+// packed integers, so they decode to the same values. The complex packing
+// fixtures use groups of random length, those with missing value management
+// mark every 23rd point and runs of 200 and 100 points as missing
+var packingTests = []packingTest{
+	{"gfs.t00z.pgrb2.0p25.f001", 0, 0, 0, 0, 0, nil, -13.498762, 12.861238},
+	{"complex.grb2", 0, 2, 0, 0, 0, nil, -13.498762, 12.861238},
+	{"complex.grb2", 1, 3, 1, 0, 0, nil, -13.498762, 12.861238},
+	{"complex.grb2", 2, 3, 2, 0, 0, nil, -13.498762, 12.861238},
+	{"complex.grb2", 3, 2, 0, 1, 690, []int{0}, -13.498762, 12.861238},
+	{"complex.grb2", 4, 3, 2, 2, 1271, []int{0, 5000}, -13.498762, 12.861238},
+	{"jpeg2000.grb2", 0, 40, 0, 0, 0, nil, -13.498762, 12.861238},
+	{"jpeg2000.grb2", 1, 40, 0, 0, 0, nil, -13.498762, 12.861238},
+	{"png.grb2", 0, 41, 0, 0, 0, nil, -13.498762, 12.861238},
+	{"png.grb2", 1, 41, 0, 0, 0, nil, -13.498762, 12.861238},
+}
+
+func TestUnpack(t *testing.T) {
+	reference, err := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")[0].Fields[0].Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range packingTests {
+		name := test.file
+		f := readFixture(t, test.file)[test.message].Fields[0]
+		r := f.Representation
+		if r.TemplateNumber != test.template {
+			t.Errorf("%s message %d: template 5.%d, want 5.%d", name, test.message, r.TemplateNumber, test.template)
+		}
+		if r.Complex != nil && (r.Complex.SpatialOrder != test.order || r.Complex.MissingManagement != test.missing) {
+			t.Errorf("%s message %d: order %d missing management %d, want %d %d", name, test.message,
+				r.Complex.SpatialOrder, r.Complex.MissingManagement, test.order, test.missing)
+		}
+
+		values, err := f.Values()
+		if err != nil {
+			t.Errorf("%s message %d: %v", name, test.message, err)
+			continue
+		}
+		if len(values) != 117*98 {
+			t.Errorf("%s message %d: %d values, want %d", name, test.message, len(values), 117*98)
+			continue
+		}
+
+		nan := map[int]bool{}
+		for _, i := range test.nanAt {
+			nan[i] = true
+		}
+		for i, want := range gfsPoints["UGRD"] {
+			got := values[i]
+			if nan[i] {
+				if !math.IsNaN(float64(got)) {
+					t.Errorf("%s message %d: point %d = %v, want NaN", name, test.message, i, got)
+				}
+				continue
+			}
+			if got != want {
+				t.Errorf("%s message %d: point %d = %v, want %v", name, test.message, i, got, want)
+			}
+		}
+
+		count := 0
+		min, max := float32(math.Inf(1)), float32(math.Inf(-1))
+		for i, v := range values {
+			if math.IsNaN(float64(v)) {
+				count++
+				continue
+			}
+			if v != reference[i] {
+				t.Errorf("%s message %d: point %d = %v, want %v", name, test.message, i, v, reference[i])
+				break
+			}
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+		if count != test.nan {
+			t.Errorf("%s message %d: %d NaN, want %d", name, test.message, count, test.nan)
+		}
+		if min != test.min || max != test.max {
+			t.Errorf("%s message %d: range %v to %v, want %v to %v", name, test.message, min, max, test.min, test.max)
+		}
+	}
+}
+
+func TestUnpackVGRD(t *testing.T) {
+	values, err := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")[1].Fields[0].Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range gfsPoints["VGRD"] {
+		if values[i] != want {
+			t.Errorf("point %d = %v, want %v", i, values[i], want)
+		}
+	}
+}
+
+func TestUnpackComplexErrors(t *testing.T) {
+	f := readFixture(t, "complex.grb2")[2].Fields[0]
+	data, err := f.src.read(f.data.offset, int(f.data.length))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *DataRepresentation)
+		data   []byte
+	}{
+		{"truncated", nil, data[:len(data)/2]},
+		{"too few groups", func(r *DataRepresentation) { r.Complex.NumGroups /= 2 }, data},
+		{"unknown missing management", func(r *DataRepresentation) { r.Complex.MissingManagement = 3 }, data},
+		{"too many values", func(r *DataRepresentation) { r.NumValues /= 2 }, data},
+	}
+	for _, test := range tests {
+		r := *f.Representation
+		c := *r.Complex
+		r.Complex = &c
+		if test.modify != nil {
+			test.modify(&r)
+		}
+		if _, err := r.unpack(test.data); err == nil {
+			t.Errorf("%s: unpacked without an error", test.name)
+		}
+	}
+}
+
+// handComplex are the sections 5 and 7 of 12 temperatures, in degrees
+// Celsius, packed by hand following the templates 5.3 and 7.3: with the
+// reference 270, no binary scale and one decimal, they are the integers
+// 5 7 10 14 17 19 20 20 19 16 12 7 added to 270
+var handComplex = []struct {
+	name     string
+	section5 []byte
+	section7 []byte
+}{
+	// the second order differences 1 1 -1 -1 -1 -1 -1 -2 -1 -1 less their
+	// minimum -2, after 2 placeholders, are 0 0 3 3 | 1 1 1 1 1 | 0 1 1: 3
+	// groups of references 0 1 0, widths 2 0 1 and lengths 4 5 3
+	{"order 2", handSection(5,
+		0, 0, 0, 12, // values
+		0, 3, // template 5.3
+		0x43, 0x87, 0, 0, // reference 270
+		0, 0, 0, 1, // binary and decimal scale
+		1,                      // bits of the group references
+		0,                      // floats
+		1,                      // groups of general lengths
+		0,                      // no missing values
+		0, 0, 0, 0, 0, 0, 0, 0, // their substitutes
+		0, 0, 0, 3, // groups
+		0, 2, // widths are 0 plus 2 bits
+		0, 0, 0, 3, 1, // lengths are 3 plus 1 times the scaled lengths
+		0, 0, 0, 3, // the last group is 3 long
+		2,    // bits of the scaled lengths
+		2, 1, // second order differences, descriptors of an octet
+	), handSection(7,
+		0x05, 0x07, 0x82, // the first values 5 and 7, the minimum -2
+		0x40,       // references 0 1 0
+		0x84,       // widths 2 0 1
+		0x60,       // scaled lengths 1 2 0
+		0x0f, 0x60, // 00 00 11 11 and 0 1 1
+	)},
+	// the first order differences 2 3 4 3 2 1 0 -1 -3 -4 -5 less their
+	// minimum -5, after a placeholder, are 0 7 8 9 8 7 | 6 5 4 2 1 0: 2
+	// groups of references 0 0, widths 4 3 and lengths 6 6
+	{"order 1", handSection(5,
+		0, 0, 0, 12,
+		0, 3,
+		0x43, 0x87, 0, 0,
+		0, 0, 0, 1,
+		1,
+		0,
+		1,
+		0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 2, // groups
+		3, 1, // widths are 3 plus 1 bit
+		0, 0, 0, 6, 1, // lengths are 6, no bits
+		0, 0, 0, 6,
+		0,
+		1, 1, // first order differences
+	), handSection(7,
+		0x05, 0x85, // the first value 5, the minimum -5
+		0x00,             // references 0 0
+		0x80,             // widths 4 3
+		0x07, 0x89, 0x87, // 0000 0111 1000 1001 1000 0111
+		0xd6, 0x22, 0x00, // 110 101 100 010 001 000
+	)},
+}
+
+// handComplexValues are the values of handComplex
+var handComplexValues = []float32{27.5, 27.7, 28, 28.4, 28.7, 28.9, 29, 29, 28.9, 28.6, 28.2, 27.7}
+
+func TestUnpackHandPacked(t *testing.T) {
+	for _, test := range handComplex {
+		m := handMessage(0, handIdentification, handGrid(4, 3), handProduct(0, 0),
+			test.section5, handSection(6, 255), test.section7)
+		messages, err := Read(m)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		f := messages[0].Fields[0]
+		if f.Grid.Ni != 4 || f.Grid.Nj != 3 || f.Representation.Complex == nil {
+			t.Fatalf("%s: %dx%d grid, packing %+v", test.name, f.Grid.Ni, f.Grid.Nj, f.Representation)
+		}
+		values, err := f.Values()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(values) != len(handComplexValues) {
+			t.Fatalf("%s: %d values, want %d", test.name, len(values), len(handComplexValues))
+		}
+		for i, want := range handComplexValues {
+			if math.Abs(float64(values[i]-want)) > 1e-5 {
+				t.Errorf("%s: value %d = %v, want %v", test.name, i, values[i], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package grib2
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// EncodeOptions sets how values are packed by Encode
+type EncodeOptions struct {
+	// Template is the data representation template, 0 for simple packing, 2
+	// for complex packing and 3 for complex packing with second order spatial
+	// differencing, which suits smooth fields best
+	Template int
+	// Bits and DecimalScale set the precision, values are scaled by
+	// 10^DecimalScale and rounded to Bits bits over their range, which is
+	// finer than 10^-DecimalScale when the range leaves bits to spare. When
+	// Bits is 0 the scale factors and reference value of the original field
+	// are kept instead, so its values are packed to the integers they were
+	// decoded from and read back unchanged
+	Bits         int
+	DecimalScale int
+}
+
+// complexGroupLengths are the group lengths tried by complex packing, the
+// one giving the smallest message is used
+var complexGroupLengths = []int{8, 16, 32, 64, 128}
+
+// Encode writes a message holding a single field to w. The discipline,
+// identification and product definition are copied from f, the grid and
+// values are the ones given, such as those returned by Crop or f.Grid and
+// f.Values. Missing values, NaN, are left out with a bitmap
+func Encode(w io.Writer, f *Field, grid *GridDefinition, values []float32, opts EncodeOptions) error {
+	if len(values) != grid.NumPoints {
+		return fmt.Errorf("grib2: %d values for %d grid points", len(values), grid.NumPoints)
+	}
+
+	var present []float64
+	var bitmap []byte
+	for n, v := range values {
+		if math.IsNaN(float64(v)) {
+			if bitmap == nil {
+				bitmap = make([]byte, (len(values)+7)/8)
+				for k := 0; k < n; k++ {
+					bitmap[k>>3] |= 0x80 >> uint(k&7)
+				}
+			}
+			continue
+		}
+		if bitmap != nil {
+			bitmap[n>>3] |= 0x80 >> uint(n&7)
+		}
+		present = append(present, float64(v))
+	}
+
+	representation, data, err := pack(present, f.Representation, opts)
+	if err != nil {
+		return err
+	}
+
+	// sections 6 and 7 are written here, the others are copied
+	bitmapSection := []byte{0, 0, 0, 6, 6, 255}
+	if bitmap != nil {
+		bitmapSection[5] = 0
+		bitmapSection = append(bitmapSection, bitmap...)
+	}
+	putUint32(bitmapSection, 1, uint32(len(bitmapSection)))
+	dataSection := append([]byte{0, 0, 0, 0, 7}, data...)
+	putUint32(dataSection, 1, uint32(len(dataSection)))
+
+	sections := [][]byte{
+		f.Message.Identification.section,
+		grid.section,
+		f.Product.section,
+		representation,
+		bitmapSection,
+		dataSection,
+		[]byte(endMarker),
+	}
+	length := indicatorLength
+	for _, s := range sections {
+		length += len(s)
+	}
+	indicator := []byte{'G', 'R', 'I', 'B', 0, 0, byte(f.Discipline()), 2, 0, 0, 0, 0, 0, 0, 0, 0}
+	putUint64(indicator, 9, uint64(length))
+
+	if _, err := w.Write(indicator); err != nil {
+		return err
+	}
+	for _, s := range sections {
+		if _, err := w.Write(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quantizer rounds values to integers X of the packing equation
+// Y * 10^D = R + X * 2^E
+type quantizer struct {
+	reference    float32
+	binaryScale  int
+	decimalScale int
+	// bits is the width of the largest X
+	bits int
+}
+
+// newQuantizer chooses the packing equation for values, keeping the scale
+// factors of the original packing when opts.Bits is 0
+func newQuantizer(values []float64, original *DataRepresentation, opts EncodeOptions) (*quantizer, error) {
+	q := &quantizer{decimalScale: opts.DecimalScale}
+	if opts.Bits == 0 {
+		q.decimalScale = original.DecimalScale
+		q.binaryScale = original.BinaryScale
+	} else if opts.Bits < 0 || opts.Bits > 31 {
+		return nil, fmt.Errorf("grib2: cannot pack values in %d bits", opts.Bits)
+	}
+	if len(values) == 0 {
+		return q, nil
+	}
+
+	dscale := intPower(10, q.decimalScale)
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		min = math.Min(min, v*dscale)
+		max = math.Max(max, v*dscale)
+	}
+	// the reference must not be above the smallest value. The original one
+	// is kept when it can be, so values of a crop are packed as they were
+	q.reference = float32(min)
+	if float64(q.reference) > min {
+		q.reference = math.Nextafter32(q.reference, float32(math.Inf(-1)))
+	}
+	// values decoded from it may sit a rounding below it, they still pack
+	// to an X of 0 and so to the packed integers they were decoded from
+	if opts.Bits == 0 && (min-float64(original.Reference))*intPower(2, -q.binaryScale) >= -0.5 {
+		q.reference = original.Reference
+	}
+	// a spread within a step of the float32 reference is only its rounding
+	spread := max - float64(q.reference)
+	if spread == 0 || float64(math.Nextafter32(q.reference, float32(math.Inf(1)))) >= max {
+		return q, nil
+	}
+
+	if opts.Bits > 0 {
+		// the smallest binary scale that fits the spread in opts.Bits bits,
+		// negative when the spread is narrower than 2^opts.Bits
+		largest := float64(uint64(1)<<uint(opts.Bits) - 1)
+		q.binaryScale = int(math.Ceil(math.Log2(spread / largest)))
+		for math.Floor(spread*intPower(2, -q.binaryScale)+0.5) > largest {
+			q.binaryScale++
+		}
+	}
+	q.bits = int(bitLength(uint64(q.quantize(max / dscale))))
+	if q.bits > 31 {
+		return nil, fmt.Errorf("grib2: values need %d bits with binary scale %d", q.bits, q.binaryScale)
+	}
+	return q, nil
+}
+
+// quantize returns the integer X a value is packed as
+func (q *quantizer) quantize(v float64) int64 {
+	x := (v*intPower(10, q.decimalScale) - float64(q.reference)) * intPower(2, -q.binaryScale)
+	return int64(math.Floor(x + 0.5))
+}
+
+// pack encodes values with the template of opts, it returns section 5 and
+// the contents of section 7
+func pack(values []float64, original *DataRepresentation, opts EncodeOptions) ([]byte, []byte, error) {
+	q, err := newQuantizer(values, original, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	xs := make([]int64, len(values))
+	for k, v := range values {
+		xs[k] = q.quantize(v)
+	}
+
+	var section, data []byte
+	switch opts.Template {
+	case 0:
+		section, data = packSimple(xs, q)
+	case 2, 3:
+		section, data, err = packComplex(xs, q, opts.Template)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("grib2: cannot encode data representation template 5.%d", opts.Template)
+	}
+
+	putUint32(section, 1, uint32(len(section)))
+	section[4] = 5
+	putUint32(section, 6, uint32(len(values)))
+	putUint16(section, 10, uint16(opts.Template))
+	putFloat32(section, 12, q.reference)
+	putInt16(section, 16, q.binaryScale)
+	putInt16(section, 18, q.decimalScale)
+	// the original values are floating point
+	section[20] = 0
+	return section, data, nil
+}
+
+// packSimple encodes template 5.0, every value in the same number of bits
+func packSimple(xs []int64, q *quantizer) ([]byte, []byte) {
+	section := make([]byte, 21)
+	section[19] = byte(q.bits)
+	w := &bitWriter{}
+	for _, x := range xs {
+		w.write(uint32(x), uint(q.bits))
+	}
+	return section, w.bytes()
+}
+
+// packComplex encodes templates 5.2 and 5.3. Values are split into groups
+// of equal length, all but the last, that each have their minimum as the
+// reference and the width of their spread, template 5.3 packs second order
+// differences instead of the values
+func packComplex(xs []int64, q *quantizer, template int) ([]byte, []byte, error) {
+	var first []int64
+	var minimum int64
+	if template == 3 && len(xs) > 2 {
+		diffs := make([]int64, len(xs))
+		minimum = math.MaxInt64
+		for k := 2; k < len(xs); k++ {
+			diffs[k] = xs[k] - 2*xs[k-1] + xs[k-2]
+			if diffs[k] < minimum {
+				minimum = diffs[k]
+			}
+		}
+		// the first values are packed as placeholders
+		for k := 2; k < len(xs); k++ {
+			diffs[k] -= minimum
+		}
+		first, xs = xs[:2], diffs
+	} else if template == 3 {
+		// too few values to difference, they are all first values
+		first, xs = xs, make([]int64, len(xs))
+		for len(first) < 2 {
+			first = append(first, 0)
+		}
+	}
+
+	var best []byte
+	var bestGroups *complexGroups
+	for _, length := range complexGroupLengths {
+		g := newComplexGroups(xs, length)
+		data, err := g.pack(xs, first, minimum)
+		if err != nil {
+			return nil, nil, err
+		}
+		if best == nil || len(data) < len(best) {
+			best, bestGroups = data, g
+		}
+		if length >= len(xs) {
+			break
+		}
+	}
+
+	size := 47
+	if template == 3 {
+		size = 49
+	}
+	section := make([]byte, size)
+	g := bestGroups
+	section[19] = byte(g.refBits)
+	// general group splitting without missing value management
+	section[21] = 1
+	section[22] = 0
+	putUint32(section, 32, uint32(len(g.refs)))
+	section[35] = byte(g.widthReference)
+	section[36] = byte(g.widthBits)
+	putUint32(section, 38, uint32(g.length))
+	section[41] = 1
+	putUint32(section, 43, uint32(g.lastLength))
+	section[46] = 0
+	if template == 3 {
+		section[47] = 2
+		section[48] = byte(g.descriptorOctets)
+	}
+	return section, best, nil
+}
+
+// complexGroups is a split of values into groups for complex packing
+type complexGroups struct {
+	length     int
+	lastLength int
+	refs       []int64
+	widths     []uint
+
+	refBits        uint
+	widthReference uint
+	widthBits      uint
+	// descriptorOctets is the width of the first values and minimum of
+	// spatial differencing
+	descriptorOctets int
+}
+
+func newComplexGroups(xs []int64, length int) *complexGroups {
+	g := &complexGroups{length: length}
+	for start := 0; start < len(xs); start += length {
+		end := start + length
+		if end > len(xs) {
+			end = len(xs)
+		}
+		min, max := xs[start], xs[start]
+		for _, x := range xs[start:end] {
+			if x < min {
+				min = x
+			}
+			if x > max {
+				max = x
+			}
+		}
+		g.refs = append(g.refs, min)
+		g.widths = append(g.widths, bitLength(uint64(max-min)))
+		g.lastLength = end - start
+	}
+
+	var maxRef int64
+	var minWidth, maxWidth uint
+	for k := range g.refs {
+		if g.refs[k] > maxRef {
+			maxRef = g.refs[k]
+		}
+		if k == 0 || g.widths[k] < minWidth {
+			minWidth = g.widths[k]
+		}
+		if g.widths[k] > maxWidth {
+			maxWidth = g.widths[k]
+		}
+	}
+	g.refBits = bitLength(uint64(maxRef))
+	g.widthReference = minWidth
+	g.widthBits = bitLength(uint64(maxWidth - minWidth))
+	return g
+}
+
+// pack writes the contents of section 7, in the order unpackComplex reads it
+func (g *complexGroups) pack(xs, first []int64, minimum int64) ([]byte, error) {
+	w := &bitWriter{}
+	if first != nil {
+		descriptors := append(append([]int64{}, first...), minimum)
+		var largest int64
+		for _, v := range descriptors {
+			if v < 0 {
+				v = -v
+			}
+			if v > largest {
+				largest = v
+			}
+		}
+		// a sign bit and the magnitude, in whole octets
+		g.descriptorOctets = int(bitLength(uint64(largest))+1+7) / 8
+		if g.descriptorOctets > 4 {
+			return nil, fmt.Errorf("grib2: spatial differencing needs %d octet descriptors", g.descriptorOctets)
+		}
+		for _, v := range descriptors {
+			writeSigned(w, v, uint(g.descriptorOctets)*8)
+		}
+	}
+
+	for _, ref := range g.refs {
+		w.write(uint32(ref), g.refBits)
+	}
+	w.align()
+	for _, width := range g.widths {
+		w.write(uint32(width-g.widthReference), g.widthBits)
+	}
+	w.align()
+	// every group but the last has the reference length, so the lengths
+	// take no bits
+
+	for k, ref := range g.refs {
+		width := g.widths[k]
+		if width > 32 {
+			return nil, fmt.Errorf("grib2: complex packing group of %d bits", width)
+		}
+		start := k * g.length
+		end := start + g.length
+		if end > len(xs) {
+			end = len(xs)
+		}
+		for _, x := range xs[start:end] {
+			w.write(uint32(x-ref), width)
+		}
+	}
+	return w.bytes(), nil
+}
+
+// writeSigned writes a sign and magnitude integer of width bits, the inverse
+// of readSigned
+func writeSigned(w *bitWriter, v int64, width uint) {
+	sign := uint32(0)
+	if v < 0 {
+		sign, v = 1, -v
+	}
+	w.write(sign, 1)
+	for n := width - 1; n > 0; {
+		chunk := n
+		if chunk > 32 {
+			chunk = 32
+		}
+		n -= chunk
+		w.write(uint32(uint64(v)>>n), chunk)
+	}
+}
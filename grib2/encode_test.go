@@ -0,0 +1,291 @@
+package grib2
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// encodeAndRead encodes values and reads the message back
+func encodeAndRead(t *testing.T, f *Field, grid *GridDefinition, values []float32, opts EncodeOptions) (*Field, []float32) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, f, grid, values, opts); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || len(messages[0].Fields) != 1 {
+		t.Fatalf("encoded %d messages", len(messages))
+	}
+	decoded := messages[0].Fields[0]
+	got, err := decoded.Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded, got
+}
+
+func TestEncode(t *testing.T) {
+	for _, m := range readFixture(t, "gfs.t00z.pgrb2.0p25.f001") {
+		f := m.Fields[0]
+		values, err := f.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, v := range values {
+			min = math.Min(min, float64(v))
+			max = math.Max(max, float64(v))
+		}
+
+		tests := []struct {
+			opts EncodeOptions
+			// tolerance is how far values may move, 0 for none
+			tolerance float64
+		}{
+			// the scale factors of the original keep every value
+			{EncodeOptions{Template: 0}, 0},
+			{EncodeOptions{Template: 2}, 0},
+			{EncodeOptions{Template: 3}, 0},
+			// values rounded to 0.1 and then to 8 bits over their range
+			{EncodeOptions{Template: 0, Bits: 8, DecimalScale: 1}, (max-min)/255 + 0.05},
+			{EncodeOptions{Template: 3, Bits: 8, DecimalScale: 1}, (max-min)/255 + 0.05},
+			{EncodeOptions{Template: 2, Bits: 16, DecimalScale: 3}, (max-min)/65535 + 0.0005},
+		}
+		name := f.Parameter().Name
+		for _, test := range tests {
+			decoded, got := encodeAndRead(t, f, f.Grid, values, test.opts)
+			if r := decoded.Representation; r.TemplateNumber != test.opts.Template || test.opts.Bits != 0 && r.Bits > test.opts.Bits {
+				t.Errorf("%s %+v: packed with template 5.%d in %d bits", name, test.opts, r.TemplateNumber, r.Bits)
+			}
+			for n := range values {
+				if d := math.Abs(float64(got[n] - values[n])); d > test.tolerance+1e-6 {
+					t.Errorf("%s %+v: value %d = %v, want %v", name, test.opts, n, got[n], values[n])
+					break
+				}
+			}
+
+			// sections 1, 3 and 4 are kept
+			if !decoded.RefTime().Equal(f.RefTime()) || decoded.Discipline() != f.Discipline() ||
+				decoded.Message.Identification.Centre != f.Message.Identification.Centre {
+				t.Errorf("%s %+v: identification changed", name, test.opts)
+			}
+			if decoded.Parameter() != f.Parameter() || decoded.Level() != f.Level() {
+				t.Errorf("%s %+v: product changed to %v at %s", name, test.opts, decoded.Parameter(), decoded.Level())
+			}
+			if !bytes.Equal(decoded.Grid.section, f.Grid.section) {
+				t.Errorf("%s %+v: grid changed", name, test.opts)
+			}
+		}
+	}
+}
+
+func TestEncodeKeepsValues(t *testing.T) {
+	for _, m := range readFixture(t, "gfs.t00z.pgrb2.0p25.f001") {
+		f := m.Fields[0]
+		values, err := f.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := f.Parameter().Name
+		for _, template := range []int{0, 2, 3} {
+			decoded, got := encodeAndRead(t, f, f.Grid, values, EncodeOptions{Template: template})
+			r, o := decoded.Representation, f.Representation
+			if r.Reference != o.Reference || r.BinaryScale != o.BinaryScale || r.DecimalScale != o.DecimalScale {
+				t.Errorf("%s 5.%d: reference %v, scales %d %d, want %v, %d %d", name, template,
+					r.Reference, r.BinaryScale, r.DecimalScale, o.Reference, o.BinaryScale, o.DecimalScale)
+			}
+			changed := 0
+			for n := range values {
+				if got[n] != values[n] {
+					changed++
+				}
+			}
+			if changed > 0 {
+				t.Errorf("%s 5.%d: %d values changed", name, template, changed)
+			}
+		}
+	}
+}
+
+func TestEncodeBits(t *testing.T) {
+	// 10 m winds span a few tens of m/s, with no decimal scale every bit
+	// past 5 or 6 has to come from a negative binary scale
+	f := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")[0].Fields[0]
+	values, err := f.Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := math.Inf(1)
+	for _, bits := range []int{6, 8, 12, 16} {
+		for _, template := range []int{0, 3} {
+			decoded, got := encodeAndRead(t, f, f.Grid, values, EncodeOptions{Template: template, Bits: bits})
+			if r := decoded.Representation; r.Bits > bits || bits > 8 && r.BinaryScale >= 0 {
+				t.Errorf("%d bits, template 5.%d: packed in %d bits with binary scale %d", bits, template, r.Bits, r.BinaryScale)
+			}
+			worst := 0.0
+			for n := range values {
+				worst = math.Max(worst, math.Abs(float64(got[n]-values[n])))
+			}
+			if template == 0 {
+				if worst >= last {
+					t.Errorf("%d bits: error %v, no better than %v with fewer bits", bits, worst, last)
+				}
+				last = worst
+			}
+		}
+	}
+	if last > 0.001 {
+		t.Errorf("16 bits: error %v, want under 0.001", last)
+	}
+}
+
+func TestEncodeConstant(t *testing.T) {
+	f := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")[0].Fields[0]
+	values := make([]float32, f.NumPoints())
+	for n := range values {
+		values[n] = 273.15
+	}
+	for _, template := range []int{0, 2, 3} {
+		decoded, got := encodeAndRead(t, f, f.Grid, values, EncodeOptions{Template: template, Bits: 12, DecimalScale: 2})
+		if decoded.Representation.Bits != 0 {
+			t.Errorf("template 5.%d: constant field packed in %d bits", template, decoded.Representation.Bits)
+		}
+		for n := range got {
+			if math.Abs(float64(got[n])-273.15) > 0.005 {
+				t.Errorf("template 5.%d: value %d = %v, want 273.15", template, n, got[n])
+				break
+			}
+		}
+	}
+}
+
+func TestCropEncode(t *testing.T) {
+	for _, m := range readFixture(t, "gfs.t00z.pgrb2.0p25.f001") {
+		f := m.Fields[0]
+		values, err := f.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		grid := f.Grid.Grid()
+
+		// 50x30 points from column 10 and row 20
+		crop, cropped, err := f.Grid.Crop(values, 10, 20, 50, 30)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, template := range []int{0, 2, 3} {
+			decoded, got := encodeAndRead(t, f, crop, cropped, EncodeOptions{Template: template})
+			g := decoded.Grid
+			if g.Ni != 50 || g.Nj != 30 || g.NumPoints != 1500 || g.ScanningMode != f.Grid.ScanningMode {
+				t.Fatalf("template 5.%d: cropped to %dx%d, %d points", template, g.Ni, g.Nj, g.NumPoints)
+			}
+			// the scan goes northwards from 35.75N 350E in steps of 0.25
+			if g.La1 != 40.75 || g.Lo1 != 352.5 || g.La2 != 48 || g.Lo2 != 4.75 || g.Di != 0.25 || g.Dj != 0.25 {
+				t.Errorf("template 5.%d: cropped grid %v,%v to %v,%v by %v,%v", template, g.La1, g.Lo1, g.La2, g.Lo2, g.Di, g.Dj)
+			}
+			for j := 0; j < 30; j++ {
+				for i := 0; i < 50; i++ {
+					want := values[grid.Index(10+i, 20+j)]
+					if v := got[g.Grid().Index(i, j)]; v != want {
+						t.Fatalf("template 5.%d: point %d,%d = %v, want %v", template, i, j, v, want)
+					}
+					lat, lon := g.Grid().LatLon(i, j)
+					wlat, wlon := grid.LatLon(10+i, 20+j)
+					if math.Abs(lat-wlat) > 1e-9 || lonDiff(lon, wlon) > 1e-9 {
+						t.Fatalf("template 5.%d: point %d,%d at %v,%v, want %v,%v", template, i, j, lat, lon, wlat, wlon)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCropProjected(t *testing.T) {
+	// the HRRR CONUS grid has no message in testdata, its section 3 is built
+	// from the definition
+	g := projectedGrids[0].g
+	section := make([]byte, 81)
+	putUint32(section, 1, 81)
+	section[4] = 3
+	putUint32(section, 7, uint32(g.Ni*g.Nj))
+	putUint16(section, 13, 30)
+	section[14] = 6
+	putUint32(section, 31, uint32(g.Ni))
+	putUint32(section, 35, uint32(g.Nj))
+	putInt32(section, 39, microDegrees(g.La1))
+	putUint32(section, 43, uint32(microDegrees(g.Lo1)))
+	putInt32(section, 48, microDegrees(g.LaD))
+	putUint32(section, 52, uint32(microDegrees(g.LoV)))
+	putUint32(section, 56, uint32(g.Dx*1000))
+	putUint32(section, 60, uint32(g.Dy*1000))
+	section[65] = byte(g.ScanningMode)
+	putInt32(section, 66, microDegrees(g.Latin1))
+	putInt32(section, 70, microDegrees(g.Latin2))
+	putInt32(section, 74, -90000000)
+	putUint32(section, 78, 0)
+	def, err := parseGridDefinition(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make([]float32, def.NumPoints)
+	for n := range values {
+		values[n] = float32(n)
+	}
+	crop, cropped, err := def.Crop(values, 900, 500, 40, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lat, lon := def.Grid().LatLon(900, 500)
+	if math.Abs(crop.La1-lat) > 1e-6 || lonDiff(crop.Lo1, lon) > 1e-6 || crop.LoV != g.LoV || crop.Dx != g.Dx {
+		t.Errorf("cropped grid starts at %v,%v, want %v,%v", crop.La1, crop.Lo1, lat, lon)
+	}
+	for j := 0; j < 20; j++ {
+		for i := 0; i < 40; i++ {
+			la, lo := crop.Grid().LatLon(i, j)
+			wla, wlo := def.Grid().LatLon(900+i, 500+j)
+			// the first point is stored to a micro degree
+			if math.Abs(la-wla) > 1e-5 || lonDiff(lo, wlo) > 1e-5 {
+				t.Fatalf("point %d,%d at %v,%v, want %v,%v", i, j, la, lo, wla, wlo)
+			}
+			if v := cropped[crop.Grid().Index(i, j)]; v != values[def.Grid().Index(900+i, 500+j)] {
+				t.Fatalf("point %d,%d = %v", i, j, v)
+			}
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	f := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")[0].Fields[0]
+	values, err := f.Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		values []float32
+		opts   EncodeOptions
+		err    string
+	}{
+		{"too few values", values[1:], EncodeOptions{}, "values for"},
+		{"32 bits", values, EncodeOptions{Bits: 32}, "in 32 bits"},
+		{"template 5.40", values, EncodeOptions{Template: 40}, "template 5.40"},
+	}
+	for _, test := range tests {
+		err := Encode(&bytes.Buffer{}, f, f.Grid, test.values, test.opts)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: err = %v, want one about %q", test.name, err, test.err)
+		}
+	}
+
+	if _, _, err := f.Grid.Crop(values, 100, 0, 20, 10); err == nil {
+		t.Error("cropped past the last column")
+	}
+	if _, _, err := f.Grid.Crop(values[:10], 0, 0, 2, 2); err == nil {
+		t.Error("cropped with too few values")
+	}
+}
@@ -0,0 +1,399 @@
+/*
+Package grib2 decodes GRIB Edition 2 messages, the format NOMADS serves model
+output in. It is written in pure Go so nimbus has no cgo dependency.
+
+A message is made of numbered sections:
+
+	0 indicator, 1 identification, 2 local use, 3 grid definition,
+	4 product definition, 5 data representation, 6 bitmap, 7 data, 8 end
+
+Sections 2 through 7 can repeat inside a message, every section 7 closes one
+Field that uses the most recent sections before it.
+
+Values packed with data representation templates 5.0 (simple), 5.2 and 5.3
+(complex, with spatial differencing), 5.40 (JPEG 2000) and 5.41 (PNG) are
+unpacked to the same float32 values wgrib2 prints.
+
+Fields can be written back with Encode, optionally after cropping their grid
+with GridDefinition.Crop, using simple or complex packing.
+*/
+package grib2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	indicatorLength = 16
+	endMarker       = "7777"
+)
+
+// Identification is section 1 of a message
+type Identification struct {
+	Centre              int
+	SubCentre           int
+	MasterTablesVersion int
+	LocalTablesVersion  int
+	// RefTimeSignificance is code table 1.2, 1 is the start of a forecast
+	RefTimeSignificance int
+	RefTime             time.Time
+	ProductionStatus    int
+	DataType            int
+
+	section []byte
+}
+
+// Message is a single GRIB2 message
+type Message struct {
+	// Offset and Length locate the message in the file it was read from
+	Offset     int64
+	Length     int64
+	Discipline int
+
+	Identification Identification
+	Fields         []*Field
+}
+
+// Field is one product of a message, most messages hold a single field
+type Field struct {
+	Message *Message
+
+	Grid           *GridDefinition
+	Product        *ProductDefinition
+	Representation *DataRepresentation
+
+	// bitmapIndicator is code table 6.0, 0 means bitmap holds a bit per
+	// grid point and 255 means every grid point has a value
+	bitmapIndicator int
+	// bitmap and data locate the bitmap and the packed values, they are only
+	// read from src when the field is unpacked
+	bitmap extent
+	data   extent
+	src    source
+	// err is the first template of the field that is not supported
+	err error
+}
+
+// UnsupportedError is returned for templates and features the package cannot
+// decode. Messages using them are still read, their fields keep the raw
+// template numbers and fail with the error when they are unpacked
+type UnsupportedError struct {
+	What string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "grib2: " + e.What + " is not supported"
+}
+
+// errIndexOnly is the error of fields read for an inventory only
+var errIndexOnly = errors.New("grib2: only the product of the field was read")
+
+// fatal returns err unless it only reports a template that is not supported
+func fatal(err error) error {
+	if _, ok := err.(*UnsupportedError); ok {
+		return nil
+	}
+	return err
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extent is a run of octets of a source
+type extent struct {
+	offset int64
+	length int64
+}
+
+// Read decodes every message in a GRIB2 file, anything between messages is
+// skipped the way wgrib2 does
+func Read(data []byte) ([]*Message, error) {
+	var messages []*Message
+	offset := 0
+	for {
+		i := bytes.Index(data[offset:], []byte("GRIB"))
+		if i < 0 {
+			break
+		}
+		offset += i
+
+		m, err := ParseMessage(data[offset:], int64(offset))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+		offset += int(m.Length)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("grib2: no messages found")
+	}
+	return messages, nil
+}
+
+// ParseMessage decodes the message at the start of data, offset is only
+// recorded to locate the message later
+func ParseMessage(data []byte, offset int64) (*Message, error) {
+	return readMessage(&memory{data: data, base: offset}, offset, false)
+}
+
+// readMessage decodes the definitions of the message at offset of src. With
+// index set only sections 0, 1 and 4 are read, which is all an inventory
+// needs, and the fields have no grid and data representation
+func readMessage(src source, offset int64, index bool) (*Message, error) {
+	indicator, err := src.read(offset, indicatorLength)
+	if err != nil || string(indicator[:4]) != "GRIB" {
+		return nil, fmt.Errorf("grib2: no message at offset %d", offset)
+	}
+	if edition := int(indicator[7]); edition != 2 {
+		return nil, fmt.Errorf("grib2: message at offset %d is edition %d", offset, edition)
+	}
+
+	m := &Message{
+		Offset:     offset,
+		Length:     int64(uint64At(indicator, 9)),
+		Discipline: int(indicator[6]),
+	}
+	if m.Length < int64(indicatorLength+len(endMarker)) {
+		return nil, fmt.Errorf("grib2: message at offset %d has a bad length %d", offset, m.Length)
+	}
+	end, err := src.read(offset+m.Length-int64(len(endMarker)), len(endMarker))
+	if err != nil {
+		return nil, fmt.Errorf("grib2: message at offset %d is truncated", offset)
+	}
+	if string(end) != endMarker {
+		return nil, fmt.Errorf("grib2: message at offset %d has no end section", offset)
+	}
+
+	err = m.parseSections(src, index)
+	if err != nil {
+		return nil, fmt.Errorf("%v in message at offset %d", err, offset)
+	}
+	return m, nil
+}
+
+// parseSections reads sections 1 to 7, the bitmaps and packed values are
+// only located. With index set sections 2, 3, 5 and 6 are skipped
+func (m *Message) parseSections(src source, index bool) error {
+	var (
+		grid           *GridDefinition
+		product        *ProductDefinition
+		representation *DataRepresentation
+		// the bitmap is kept for fields that reuse the previous one
+		bitmapIndicator = 255
+		bitmap          extent
+		bitmapErr       error
+	)
+
+	pos := m.Offset + indicatorLength
+	end := m.Offset + m.Length - int64(len(endMarker))
+	for pos < end {
+		if pos+5 > end {
+			return fmt.Errorf("grib2: section header is truncated")
+		}
+		header, err := src.read(pos, 5)
+		if err != nil {
+			return err
+		}
+		length := int64(uint32At(header, 1))
+		number := int(header[4])
+		if length < 5 || pos+length > end {
+			return fmt.Errorf("grib2: section %d has a bad length %d", number, length)
+		}
+
+		var section []byte
+		switch {
+		case index && number != 1 && number != 4:
+			if number == 7 {
+				if product == nil {
+					return fmt.Errorf("grib2: data section before its definitions")
+				}
+				m.Fields = append(m.Fields, &Field{Message: m, Product: product, err: errIndexOnly})
+			} else if number < 2 || number > 6 {
+				return fmt.Errorf("grib2: unknown section %d", number)
+			}
+			pos += length
+			continue
+		case number == 6:
+			if section, err = src.read(pos, 6); err != nil {
+				return err
+			}
+		case number == 7:
+			section = header
+		default:
+			if section, err = src.read(pos, int(length)); err != nil {
+				return err
+			}
+		}
+
+		switch number {
+		case 1:
+			err = m.Identification.parse(section)
+		case 2:
+			// local use, nothing standard to read
+		case 3:
+			grid, err = parseGridDefinition(section)
+			err = fatal(err)
+		case 4:
+			product, err = parseProductDefinition(section)
+			err = fatal(err)
+		case 5:
+			representation, err = parseDataRepresentation(section)
+			err = fatal(err)
+		case 6:
+			if err = checkLength(section, 6, "section 6"); err != nil {
+				break
+			}
+			indicator := int(section[5])
+			switch indicator {
+			case 254:
+				// reuse the previously defined bitmap
+				if bitmapIndicator != 0 {
+					err = fmt.Errorf("grib2: no bitmap to reuse")
+				}
+			case 0, 255:
+				bitmapIndicator = indicator
+				bitmap = extent{pos + 6, length - 6}
+				bitmapErr = nil
+			default:
+				bitmapIndicator = indicator
+				bitmapErr = &UnsupportedError{fmt.Sprintf("predefined bitmap %d", indicator)}
+			}
+		case 7:
+			if grid == nil || product == nil || representation == nil {
+				return fmt.Errorf("grib2: data section before its definitions")
+			}
+			m.Fields = append(m.Fields, &Field{
+				Message:         m,
+				Grid:            grid,
+				Product:         product,
+				Representation:  representation,
+				bitmapIndicator: bitmapIndicator,
+				bitmap:          bitmap,
+				data:            extent{pos + 5, length - 5},
+				src:             src,
+				err:             firstError(grid.err, product.err, representation.err, bitmapErr),
+			})
+		default:
+			return fmt.Errorf("grib2: unknown section %d", number)
+		}
+		if err != nil {
+			return err
+		}
+		pos += length
+	}
+
+	if len(m.Fields) == 0 {
+		return fmt.Errorf("grib2: no data section")
+	}
+	return nil
+}
+
+func (id *Identification) parse(section []byte) error {
+	if err := checkLength(section, 21, "section 1"); err != nil {
+		return err
+	}
+	id.Centre = int(uint16At(section, 6))
+	id.SubCentre = int(uint16At(section, 8))
+	id.MasterTablesVersion = int(section[9])
+	id.LocalTablesVersion = int(section[10])
+	id.RefTimeSignificance = int(section[11])
+	id.RefTime = time.Date(
+		int(uint16At(section, 13)), time.Month(section[14]), int(section[15]),
+		int(section[16]), int(section[17]), int(section[18]), 0, time.UTC)
+	id.ProductionStatus = int(section[19])
+	id.DataType = int(section[20])
+	id.section = section
+	return nil
+}
+
+// RefTime is the reference time of the field, usually the start of the
+// forecast
+func (f *Field) RefTime() time.Time {
+	return f.Message.Identification.RefTime
+}
+
+// VerfTime is the time the field is valid at, for statistically processed
+// fields such as accumulations it is the end of the processing interval
+func (f *Field) VerfTime() (time.Time, error) {
+	return f.Product.verfTime(f.RefTime())
+}
+
+// Discipline is code table 0.0 of the message the field is in
+func (f *Field) Discipline() int {
+	return f.Message.Discipline
+}
+
+// Err returns why the field cannot be unpacked, nil when it can. Fields with
+// templates that are not supported keep their template numbers, reference
+// time and parameter
+func (f *Field) Err() error {
+	return f.err
+}
+
+// NumPoints is the number of grid points including missing points
+func (f *Field) NumPoints() int {
+	return f.Grid.NumPoints
+}
+
+// Values unpacks the field into one value per grid point in the order the
+// grid is scanned. Points outside the bitmap and values marked missing by
+// complex packing are NaN
+func (f *Field) Values() ([]float32, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, err := f.src.read(f.data.offset, int(f.data.length))
+	if err != nil {
+		return nil, err
+	}
+	if f.bitmapIndicator == 255 {
+		if f.Representation.NumValues != f.Grid.NumPoints {
+			return nil, fmt.Errorf("grib2: %d values for %d grid points", f.Representation.NumValues, f.Grid.NumPoints)
+		}
+		return f.Representation.unpack(data)
+	}
+
+	bitmap, err := f.src.read(f.bitmap.offset, int(f.bitmap.length))
+	if err != nil {
+		return nil, err
+	}
+	if len(bitmap)*8 < f.Grid.NumPoints {
+		return nil, fmt.Errorf("grib2: bitmap of %d octets for %d grid points", len(bitmap), f.Grid.NumPoints)
+	}
+	present := 0
+	for n := 0; n < f.Grid.NumPoints; n++ {
+		if bitmap[n>>3]&(0x80>>uint(n&7)) != 0 {
+			present++
+		}
+	}
+	if f.Representation.NumValues != present {
+		return nil, fmt.Errorf("grib2: %d values for %d grid points in the bitmap", f.Representation.NumValues, present)
+	}
+	packed, err := f.Representation.unpack(data)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float32, f.Grid.NumPoints)
+	nan := float32(math.NaN())
+	k := 0
+	for n := range values {
+		if bitmap[n>>3]&(0x80>>uint(n&7)) == 0 {
+			values[n] = nan
+			continue
+		}
+		values[n] = packed[k]
+		k++
+	}
+	return values, nil
+}
@@ -0,0 +1,445 @@
+package grib2
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readFixtureBytes(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func readFixture(t *testing.T, name string) []*Message {
+	messages, err := Read(readFixtureBytes(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+// handSection returns a section of a number, its octets from the sixth on
+// following it
+func handSection(number byte, octets ...byte) []byte {
+	s := make([]byte, 5, 5+len(octets))
+	binary.BigEndian.PutUint32(s, uint32(5+len(octets)))
+	s[4] = number
+	return append(s, octets...)
+}
+
+// handMessage assembles a message of a discipline from its sections 1 to 7
+func handMessage(discipline byte, sections ...[]byte) []byte {
+	m := []byte{'G', 'R', 'I', 'B', 0, 0, discipline, 2, 0, 0, 0, 0, 0, 0, 0, 0}
+	for _, s := range sections {
+		m = append(m, s...)
+	}
+	m = append(m, endMarker...)
+	binary.BigEndian.PutUint64(m[8:], uint64(len(m)))
+	return m
+}
+
+// handIdentification is section 1 of an NCEP analysis of 2024-01-02 00z
+var handIdentification = handSection(1,
+	0, 7, 0, 0, // centre 7, NCEP, and subcentre 0
+	2, 1, // master and local tables
+	1,                // significance of the reference time, start of forecast
+	0x07, 0xe8, 1, 2, // 2024-01-02
+	0, 0, 0, // 00:00:00
+	0, 1, // operational products, forecast
+)
+
+// handGrid is section 3 of a template 3.0 grid of ni by nj points 0.25
+// degrees apart from 37N 0E, scanned east and south
+func handGrid(ni, nj int) []byte {
+	s := handSection(3,
+		0,          // grid defined by template
+		0, 0, 0, 0, // number of points, set below
+		0, 0, // no list of points
+		0, 0, // template 3.0
+		6,             // spherical earth of radius 6371229 m
+		0, 0, 0, 0, 0, // radius
+		0, 0, 0, 0, 0, // major axis
+		0, 0, 0, 0, 0, // minor axis
+		0, 0, 0, 0, // Ni, set below
+		0, 0, 0, 0, // Nj, set below
+		0, 0, 0, 0, // basic angle
+		0xff, 0xff, 0xff, 0xff, // subdivisions of the basic angle
+		0x02, 0x34, 0x95, 0x40, // La1 37000000 microdegrees
+		0, 0, 0, 0, // Lo1
+		0x30,       // increments given, winds relative to the grid
+		0, 0, 0, 0, // La2, set below
+		0, 0, 0, 0, // Lo2, set below
+		0, 0x03, 0xd0, 0x90, // Di 250000
+		0, 0x03, 0xd0, 0x90, // Dj 250000
+		0, // scanning mode
+	)
+	binary.BigEndian.PutUint32(s[6:], uint32(ni*nj))
+	binary.BigEndian.PutUint32(s[30:], uint32(ni))
+	binary.BigEndian.PutUint32(s[34:], uint32(nj))
+	binary.BigEndian.PutUint32(s[55:], uint32(37000000-(nj-1)*250000))
+	binary.BigEndian.PutUint32(s[59:], uint32((ni-1)*250000))
+	return s
+}
+
+// handProduct is section 4 of a template 4.0 analysis of a parameter at
+// the surface
+func handProduct(category, number byte) []byte {
+	return handSection(4,
+		0, 0, // no coordinates
+		0, 0, // template 4.0
+		category, number,
+		0,     // analysis
+		0, 96, // background process, and 96, the GFS
+		0, 0, 0, // cut off
+		1,          // hours
+		0, 0, 0, 0, // forecast time
+		1, 0, 0, 0, 0, 0, // the ground or water surface
+		255, 0, 0, 0, 0, 0, // no second surface
+	)
+}
+
+// gfsPoints are the values of the 10 m UGRD and VGRD of the GFS 0.25 degree
+// subset in testdata, as decoded by go-grib2, a port of wgrib2 2.0.6c, at
+// the first and last points, the ends of the first row and points inside
+var gfsPoints = map[string]map[int]float32{
+	"UGRD": {0: 1.4112378, 1: 1.6712378, 116: 2.0912378, 117: 1.6312377, 5000: -0.9787622, 5733: 8.171238, 11465: 3.9512377},
+	"VGRD": {0: -9.021034, 1: -9.011035, 116: -4.5510345, 117: -9.071034, 5000: -0.68103456, 5733: -3.8210344, 11465: 1.3489654},
+}
+
+func TestRead(t *testing.T) {
+	data := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")
+	// anything between messages is skipped
+	padded := append(append([]byte("junk"), data[:17378]...), append([]byte("\x00\x00"), data[17378:]...)...)
+
+	for _, data := range [][]byte{data, padded} {
+		messages, err := Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("%d messages, want 2", len(messages))
+		}
+
+		lengths := []int64{17378, 15945}
+		parameters := [][2]int{{2, 2}, {2, 3}}
+		for k, m := range messages {
+			if m.Length != lengths[k] || string(data[m.Offset:m.Offset+4]) != "GRIB" {
+				t.Errorf("message %d at %d of %d octets, want %d octets", k, m.Offset, m.Length, lengths[k])
+			}
+			if m.Discipline != 0 {
+				t.Errorf("message %d: discipline %d, want 0", k, m.Discipline)
+			}
+			id := m.Identification
+			if id.Centre != 7 || id.RefTimeSignificance != 1 {
+				t.Errorf("message %d: centre %d significance %d, want 7 1", k, id.Centre, id.RefTimeSignificance)
+			}
+			if want := time.Date(2017, 7, 20, 0, 0, 0, 0, time.UTC); !id.RefTime.Equal(want) {
+				t.Errorf("message %d: reference time %s, want %s", k, id.RefTime, want)
+			}
+			if len(m.Fields) != 1 {
+				t.Fatalf("message %d: %d fields, want 1", k, len(m.Fields))
+			}
+
+			f := m.Fields[0]
+			if f.Grid.TemplateNumber != 0 || f.Grid.Ni != 117 || f.Grid.Nj != 98 || f.NumPoints() != 117*98 {
+				t.Errorf("message %d: grid 3.%d of %dx%d", k, f.Grid.TemplateNumber, f.Grid.Ni, f.Grid.Nj)
+			}
+			p := f.Product
+			if p.TemplateNumber != 0 || p.Category != parameters[k][0] || p.Number != parameters[k][1] {
+				t.Errorf("message %d: product 4.%d parameter %d.%d, want 4.0 %d.%d", k,
+					p.TemplateNumber, p.Category, p.Number, parameters[k][0], parameters[k][1])
+			}
+			verf, err := f.VerfTime()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := time.Date(2017, 7, 20, 1, 0, 0, 0, time.UTC); !verf.Equal(want) {
+				t.Errorf("message %d: valid at %s, want %s", k, verf, want)
+			}
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	message := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")[:17378]
+	modified := func(modify func(m []byte) []byte) []byte {
+		m := append([]byte(nil), message...)
+		return modify(m)
+	}
+	setLength := func(m []byte, n uint64) []byte {
+		binary.BigEndian.PutUint64(m[8:], n)
+		return m
+	}
+	// section 1 is 21 octets, section 3 follows it
+	const section3 = 16 + 21
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"no message", []byte("nothing to see"), "no messages found"},
+		{"edition 1", modified(func(m []byte) []byte { m[7] = 1; return m }), "edition 1"},
+		{"length below 20", modified(func(m []byte) []byte { return setLength(m, 19) }), "bad length 19"},
+		{"length of 0", modified(func(m []byte) []byte { return setLength(m, 0) }), "bad length 0"},
+		{"truncated", message[:len(message)-100], "truncated"},
+		{"no end section", modified(func(m []byte) []byte { copy(m[len(m)-4:], "6666"); return m }), "no end section"},
+		{"indicator only", setLength(append(append([]byte(nil), message[:16]...), "7777"...), 20), "no data section"},
+		{"section past the end", modified(func(m []byte) []byte {
+			binary.BigEndian.PutUint32(m[section3:], 1<<20)
+			return m
+		}), "section 3 has a bad length"},
+		{"section below 5 octets", modified(func(m []byte) []byte {
+			binary.BigEndian.PutUint32(m[section3:], 4)
+			return m
+		}), "bad length 4"},
+		{"unknown section", modified(func(m []byte) []byte { m[section3+4] = 9; return m }), "unknown section 9"},
+		{"short section 1", modified(func(m []byte) []byte {
+			// shrink section 1 and pad the octets given up with a local
+			// use section
+			binary.BigEndian.PutUint32(m[16:], 15)
+			binary.BigEndian.PutUint32(m[16+15:], 6)
+			m[16+15+4] = 2
+			return m
+		}), "section 1"},
+	}
+	for _, test := range tests {
+		_, err := Read(test.data)
+		if err == nil {
+			t.Errorf("%s: read without an error", test.name)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "grib2: ") || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %q, want one about %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestParseMessageOffset(t *testing.T) {
+	data := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")
+	m, err := ParseMessage(data[17378:], 17378)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Offset != 17378 || m.Length != 15945 {
+		t.Errorf("message at %d of %d octets, want 17378 and 15945", m.Offset, m.Length)
+	}
+	values, err := m.Fields[0].Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] != gfsPoints["VGRD"][0] {
+		t.Errorf("first value %v, want %v", values[0], gfsPoints["VGRD"][0])
+	}
+}
+
+// handIce are the sections 5 and 7 of the sea ice cover of 8 points packed
+// by hand following the templates 5.0 and 7.0: with no reference or binary
+// scale and two decimals, the 7 bit integers 0 15 40 72 100 100 88 55
+// are the fractions below
+var handIce = [][]byte{
+	handSection(5,
+		0, 0, 0, 8, // values
+		0, 0, // template 5.0
+		0, 0, 0, 0, // reference 0
+		0, 0, 0, 2, // binary and decimal scale
+		7, // bits
+		0, // floats
+	),
+	// 0000000 0001111 0101000 1001000 1100100 1100100 1011000 0110111
+	handSection(7, 0x00, 0x3d, 0x44, 0x8c, 0x99, 0x2c, 0x37),
+}
+
+var handIceValues = []float32{0, 0.15, 0.4, 0.72, 1, 1, 0.88, 0.55}
+
+// unmask spreads values over the points set in a bitmap, the others are NaN
+func unmask(bitmap []byte, n int, values []float32) []float32 {
+	out := make([]float32, n)
+	k := 0
+	for i := range out {
+		if bitmap[i/8]&(0x80>>uint(i%8)) == 0 {
+			out[i] = float32(math.NaN())
+			continue
+		}
+		out[i] = values[k]
+		k++
+	}
+	return out
+}
+
+// checkValues compares the values of a field with the ones wanted, NaN
+// where they are missing
+func checkValues(t *testing.T, name string, f *Field, want []float32) {
+	got, err := f.Values()
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if len(got) != len(want) {
+		t.Errorf("%s: %d values, want %d", name, len(got), len(want))
+		return
+	}
+	for i := range want {
+		if math.IsNaN(float64(want[i])) != math.IsNaN(float64(got[i])) ||
+			!math.IsNaN(float64(want[i])) && math.Abs(float64(got[i]-want[i])) > 1e-5 {
+			t.Errorf("%s: value %d = %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+// sections splits a message into its sections, the indicator and end
+// section left out
+func sections(m []byte) [][]byte {
+	var s [][]byte
+	for pos := indicatorLength; pos < len(m)-len(endMarker); {
+		length := int(binary.BigEndian.Uint32(m[pos:]))
+		s = append(s, m[pos:pos+length])
+		pos += length
+	}
+	return s
+}
+
+func TestBitmap(t *testing.T) {
+	tests := []struct {
+		name                         string
+		ni, nj                       int
+		bitmap                       []byte
+		packed                       [][]byte
+		discipline, category, number byte
+		values                       []float32
+	}{
+		// ICEC over 4 by 3 points, land at 1, 4, 8 and 10:
+		// 1011 0111 0101
+		{"simple", 4, 3, []byte{0xb7, 0x50}, handIce, 10, 2, 0, handIceValues},
+		// TMP over 4 by 4 points, missing at 3, 7, 8 and 13:
+		// 1110 1110 0111 1011
+		{"complex", 4, 4, []byte{0xee, 0x7b},
+			[][]byte{handComplex[0].section5, handComplex[0].section7}, 0, 0, 0, handComplexValues},
+	}
+	for _, test := range tests {
+		m := handMessage(test.discipline, handIdentification, handGrid(test.ni, test.nj),
+			handProduct(test.category, test.number), test.packed[0],
+			handSection(6, append([]byte{0}, test.bitmap...)...), test.packed[1])
+		messages, err := Read(m)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		f := messages[0].Fields[0]
+		if n := f.Representation.NumValues; n != len(test.values) {
+			t.Errorf("%s: %d packed values, want %d", test.name, n, len(test.values))
+		}
+		checkValues(t, test.name, f, unmask(test.bitmap, test.ni*test.nj, test.values))
+	}
+}
+
+func TestBitmapReuse(t *testing.T) {
+	// the sea ice thickness of the second field reuses the bitmap of the
+	// cover with indicator 254
+	bitmap := []byte{0xb7, 0x50}
+	build := func(indicator byte) []byte {
+		return handMessage(10, handIdentification, handGrid(4, 3),
+			handProduct(2, 0), handIce[0], handSection(6, indicator, 0xb7, 0x50), handIce[1],
+			handProduct(2, 1), handIce[0], handSection(6, 254), handIce[1])
+	}
+
+	messages, err := Read(build(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages[0].Fields) != 2 {
+		t.Fatalf("%d fields, want 2", len(messages[0].Fields))
+	}
+	want := unmask(bitmap, 12, handIceValues)
+	for k, f := range messages[0].Fields {
+		checkValues(t, fmt.Sprintf("field %d", k), f, want)
+	}
+
+	// without a bitmap there is nothing to reuse
+	if _, err := Read(build(255)); err == nil || !strings.Contains(err.Error(), "no bitmap to reuse") {
+		t.Errorf("reusing a missing bitmap: err = %v", err)
+	}
+}
+
+func TestBitmapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		bitmap []byte
+		err    string
+	}{
+		{"predefined bitmap", []byte{3}, "predefined bitmap 3"},
+		// 7 points for 8 values
+		{"point cleared", []byte{0, 0xb6, 0x50}, "grid points in the bitmap"},
+	}
+	for _, test := range tests {
+		m := handMessage(10, handIdentification, handGrid(4, 3), handProduct(2, 0),
+			handIce[0], handSection(6, test.bitmap...), handIce[1])
+		messages, err := Read(m)
+		if err == nil {
+			_, err = messages[0].Fields[0].Values()
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: err = %v, want one about %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestUnsupportedTemplates(t *testing.T) {
+	data := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")[:17378]
+	s := sections(data)
+	// sections 1, 3, 4, 5, 6 and 7, the template numbers are at octets 13
+	// and 14 of section 3, 8 and 9 of section 4 and 10 and 11 of section 5
+	tests := []struct {
+		name    string
+		section int
+		octet   int
+		err     string
+	}{
+		{"grid", 1, 12, "grid definition template 3.99"},
+		{"product", 2, 7, "product definition template 4.99"},
+		{"packing", 3, 9, "data representation template 5.99"},
+	}
+	for _, test := range tests {
+		m := append([]byte(nil), data...)
+		pos := indicatorLength
+		for k := 0; k < test.section; k++ {
+			pos += len(s[k])
+		}
+		binary.BigEndian.PutUint16(m[pos+test.octet:], 99)
+
+		messages, err := Read(m)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		f := messages[0].Fields[0]
+		if f.Err() == nil || !strings.Contains(f.Err().Error(), test.err) {
+			t.Errorf("%s: field error %v, want one about %s", test.name, f.Err(), test.err)
+		}
+		if _, err := f.Values(); err != f.Err() {
+			t.Errorf("%s: unpacked with error %v", test.name, err)
+		}
+		// the parameter and the other sections are still read
+		if name := f.Parameter().Name; name != "UGRD" {
+			t.Errorf("%s: parameter %s, want UGRD", test.name, name)
+		}
+		if f.NumPoints() != 117*98 || f.Representation.NumValues != 117*98 {
+			t.Errorf("%s: %d points and %d values", test.name, f.NumPoints(), f.Representation.NumValues)
+		}
+		if test.section != 1 && f.Grid.Ni != 117 {
+			t.Errorf("%s: grid of %d columns, want 117", test.name, f.Grid.Ni)
+		}
+		_, err = f.VerfTime()
+		if (err == nil) != (test.section != 2) {
+			t.Errorf("%s: valid time error %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package grib2
+
+import (
+	"fmt"
+	"math"
+)
+
+// scanning mode flags of flag table 3.4
+const (
+	// ScanNegativeI points are scanned from east to west
+	ScanNegativeI = 0x80
+	// ScanPositiveJ points are scanned from south to north
+	ScanPositiveJ = 0x40
+	// ScanConsecutiveJ adjacent points are consecutive in j rather than i
+	ScanConsecutiveJ = 0x20
+	// ScanBoustrophedon every other row is scanned in the opposite direction
+	ScanBoustrophedon = 0x10
+)
+
+// GridDefinition is section 3 of a field
+type GridDefinition struct {
+	TemplateNumber int
+	NumPoints      int
+
+	// Ni and Nj are the number of points along the x and y axes of the grid,
+	// along a parallel and a meridian for latitude/longitude grids
+	Ni int
+	Nj int
+	// La1, Lo1 is the first grid point and La2, Lo2 the last in degrees
+	La1 float64
+	Lo1 float64
+	La2 float64
+	Lo2 float64
+	// Di and Dj are the increments in degrees
+	Di           float64
+	Dj           float64
+	ScanningMode int
+
+	// N is the number of parallels between a pole and the equator of a
+	// Gaussian grid
+	N int
+
+	// LaD is the latitude where Dx and Dy, the increments in metres of
+	// projected grids, are true and LoV the longitude parallel to the y axis.
+	// Latin1 and Latin2 are the secants of a Lambert conformal projection
+	LaD       float64
+	LoV       float64
+	Dx        float64
+	Dy        float64
+	Latin1    float64
+	Latin2    float64
+	SouthPole bool
+
+	// EarthShape is code table 3.2 and Radius the radius in metres of the
+	// sphere projections are computed on
+	EarthShape int
+	Radius     float64
+
+	grid *Grid
+	// err is set when the template is not supported, only TemplateNumber
+	// and NumPoints are read then
+	err error
+	// section holds the whole section so templates can be re-encoded
+	section []byte
+}
+
+func parseGridDefinition(section []byte) (*GridDefinition, error) {
+	if err := checkLength(section, 14, "section 3"); err != nil {
+		return nil, err
+	}
+	g := &GridDefinition{
+		TemplateNumber: int(uint16At(section, 13)),
+		NumPoints:      int(uint32At(section, 7)),
+		section:        section,
+	}
+	switch {
+	case section[5] != 0:
+		g.err = &UnsupportedError{"predefined grid definitions"}
+	case section[10] != 0:
+		g.err = &UnsupportedError{"quasi-regular grids"}
+	}
+	if g.err != nil {
+		return g, g.err
+	}
+
+	var err error
+	switch g.TemplateNumber {
+	case 0, 40:
+		err = g.parseLatLon(section)
+	case 20:
+		err = g.parsePolarStereographic(section)
+	case 30:
+		err = g.parseLambert(section)
+	default:
+		g.err = &UnsupportedError{fmt.Sprintf("grid definition template 3.%d", g.TemplateNumber)}
+		return g, g.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if g.Ni*g.Nj != g.NumPoints {
+		return nil, fmt.Errorf("grib2: %dx%d grid with %d points", g.Ni, g.Nj, g.NumPoints)
+	}
+	if g.grid, err = g.newGrid(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// parseLatLon reads template 3.0, a regular latitude/longitude grid, and
+// template 3.40, a Gaussian grid, which only differ in octets 68 to 71
+func (g *GridDefinition) parseLatLon(section []byte) error {
+	what := fmt.Sprintf("grid definition template 3.%d", g.TemplateNumber)
+	if err := checkLength(section, 72, what); err != nil {
+		return err
+	}
+	g.parseEarth(section)
+	g.Ni = int(uint32At(section, 31))
+	g.Nj = int(uint32At(section, 35))
+
+	angle := angleUnit(section, 39)
+	g.La1 = angle(int32At(section, 47))
+	g.Lo1 = angle(int32At(section, 51))
+	g.La2 = angle(int32At(section, 56))
+	g.Lo2 = angle(int32At(section, 60))
+	g.Di = angle(int(uint32At(section, 64)))
+	if g.TemplateNumber == 40 {
+		g.N = int(uint32At(section, 68))
+	} else {
+		g.Dj = angle(int(uint32At(section, 68)))
+	}
+	g.ScanningMode = int(section[71])
+	return nil
+}
+
+// parsePolarStereographic reads template 3.20
+func (g *GridDefinition) parsePolarStereographic(section []byte) error {
+	if err := checkLength(section, 65, "grid definition template 3.20"); err != nil {
+		return err
+	}
+	g.parseProjection(section)
+	g.SouthPole = section[63]&0x80 != 0
+	return nil
+}
+
+// parseLambert reads template 3.30, a Lambert conformal grid
+func (g *GridDefinition) parseLambert(section []byte) error {
+	if err := checkLength(section, 73, "grid definition template 3.30"); err != nil {
+		return err
+	}
+	g.parseProjection(section)
+	g.SouthPole = section[63]&0x80 != 0
+	g.Latin1 = float64(int32At(section, 66)) / 1e6
+	g.Latin2 = float64(int32At(section, 70)) / 1e6
+	return nil
+}
+
+// parseProjection reads octets 15 to 65, which templates 3.20 and 3.30 share
+func (g *GridDefinition) parseProjection(section []byte) {
+	g.parseEarth(section)
+	g.Ni = int(uint32At(section, 31))
+	g.Nj = int(uint32At(section, 35))
+	g.La1 = float64(int32At(section, 39)) / 1e6
+	g.Lo1 = float64(uint32At(section, 43)) / 1e6
+	g.LaD = float64(int32At(section, 48)) / 1e6
+	g.LoV = float64(uint32At(section, 52)) / 1e6
+	g.Dx = float64(uint32At(section, 56)) / 1e3
+	g.Dy = float64(uint32At(section, 60)) / 1e3
+	g.ScanningMode = int(section[64])
+}
+
+// parseEarth reads the shape of the earth, octets 15 to 30 of every grid
+// template. Projections are computed on a sphere like wgrib2 does, oblate
+// spheroids use the mean of their axes
+func (g *GridDefinition) parseEarth(section []byte) {
+	g.EarthShape = int(section[14])
+	radius := scaled(int8At(section, 16), int(uint32At(section, 17)))
+	major := scaled(int8At(section, 21), int(uint32At(section, 22)))
+	minor := scaled(int8At(section, 26), int(uint32At(section, 27)))
+
+	switch g.EarthShape {
+	case 1:
+		g.Radius = radius
+	case 2:
+		g.Radius = (6378160 + 6356775) / 2
+	case 3:
+		g.Radius = (major + minor) / 2 * 1000
+	case 4, 5:
+		g.Radius = (6378137 + 6356752) / 2
+	case 6:
+		g.Radius = 6371229
+	case 7:
+		g.Radius = (major + minor) / 2
+	case 8:
+		g.Radius = 6371200
+	case 9:
+		g.Radius = (6377563.396 + 6356256.909) / 2
+	default:
+		g.Radius = 6367470
+	}
+}
+
+// angleUnit returns a conversion to degrees for the basic angle and
+// subdivisions at octet, angles are in micro degrees unless a basic angle is
+// given
+func angleUnit(section []byte, octet int) func(int) float64 {
+	basic, subdivisions := uint32At(section, octet), uint32At(section, octet+4)
+	if basic == 0 || basic == missing4 || subdivisions == 0 || subdivisions == missing4 {
+		return func(v int) float64 {
+			return float64(v) / 1e6
+		}
+	}
+	return func(v int) float64 {
+		return float64(v) * float64(basic) / float64(subdivisions)
+	}
+}
+
+// newGrid sets up the projection of the grid
+func (g *GridDefinition) newGrid() (*Grid, error) {
+	// increments point the way the grid is scanned
+	di, dj := 1.0, 1.0
+	if g.ScanningMode&ScanNegativeI != 0 {
+		di = -1
+	}
+	if g.ScanningMode&ScanPositiveJ == 0 {
+		dj = -1
+	}
+
+	var p projection
+	switch g.TemplateNumber {
+	case 0:
+		p = newLatLon(g.La1, g.Lo1, di*g.Di, dj*g.Dj, g.Ni)
+	case 40:
+		gaussian, err := newGaussian(g.La1, g.Lo1, di*g.Di, dj, g.N, g.Ni, g.Nj)
+		if err != nil {
+			return nil, err
+		}
+		p = gaussian
+	case 20:
+		p = newPolarStereographic(g.La1, g.Lo1, di*g.Dx, dj*g.Dy, g.LaD, g.LoV, g.SouthPole, g.Radius)
+	case 30:
+		lambert, err := newLambert(g.La1, g.Lo1, di*g.Dx, dj*g.Dy, g.LaD, g.LoV, g.Latin1, g.Latin2, g.Radius)
+		if err != nil {
+			return nil, err
+		}
+		p = lambert
+	}
+	return &Grid{Ni: g.Ni, Nj: g.Nj, ScanningMode: g.ScanningMode, proj: p}, nil
+}
+
+// Err returns why the grid cannot be read, nil when its template is
+// supported
+func (g *GridDefinition) Err() error {
+	return g.err
+}
+
+// Grid returns the mapping between the grid points and their coordinates, nil
+// when the template is not supported
+func (g *GridDefinition) Grid() *Grid {
+	return g.grid
+}
+
+// LatLons returns the latitude and longitude of every grid point in the order
+// the grid is scanned, longitudes are in [0, 360)
+func (g *GridDefinition) LatLons() (lats, lons []float64, err error) {
+	if g.err != nil {
+		return nil, nil, g.err
+	}
+	lats, lons = g.grid.LatLons()
+	return lats, lons, nil
+}
+
+// Grid maps the points of a grid to latitudes and longitudes and back.
+//
+// Point (i, j) is i points along the x axis and j points along the y axis
+// from the first grid point, in the directions the grid is scanned, so (0, 0)
+// is always La1, Lo1. The scanning mode only decides the order points are
+// stored in, Index and Point convert between the two.
+type Grid struct {
+	Ni           int
+	Nj           int
+	ScanningMode int
+
+	proj projection
+}
+
+// projection converts between coordinates and grid positions, positions are
+// fractional so points between grid points can be located
+type projection interface {
+	latLon(i, j float64) (lat, lon float64)
+	ij(lat, lon float64) (i, j float64)
+}
+
+// Index returns the position of point (i, j) in the values of a field
+func (g *Grid) Index(i, j int) int {
+	if g.ScanningMode&ScanConsecutiveJ != 0 {
+		if g.ScanningMode&ScanBoustrophedon != 0 && i%2 == 1 {
+			j = g.Nj - 1 - j
+		}
+		return i*g.Nj + j
+	}
+	if g.ScanningMode&ScanBoustrophedon != 0 && j%2 == 1 {
+		i = g.Ni - 1 - i
+	}
+	return j*g.Ni + i
+}
+
+// Point returns the grid point stored at position n of the values of a field
+func (g *Grid) Point(n int) (i, j int) {
+	if g.ScanningMode&ScanConsecutiveJ != 0 {
+		i, j = n/g.Nj, n%g.Nj
+		if g.ScanningMode&ScanBoustrophedon != 0 && i%2 == 1 {
+			j = g.Nj - 1 - j
+		}
+		return i, j
+	}
+	i, j = n%g.Ni, n/g.Ni
+	if g.ScanningMode&ScanBoustrophedon != 0 && j%2 == 1 {
+		i = g.Ni - 1 - i
+	}
+	return i, j
+}
+
+// LatLon returns the latitude and longitude of point (i, j), longitudes are
+// in [0, 360)
+func (g *Grid) LatLon(i, j int) (lat, lon float64) {
+	lat, lon = g.proj.latLon(float64(i), float64(j))
+	return lat, normalizeLon(lon)
+}
+
+// LatLonAt returns the latitude and longitude of a fractional grid position,
+// such as the corner between four points, it is the inverse of IJ
+func (g *Grid) LatLonAt(i, j float64) (lat, lon float64) {
+	lat, lon = g.proj.latLon(i, j)
+	return lat, normalizeLon(lon)
+}
+
+// IJ returns the fractional grid position of a latitude and longitude, it is
+// outside [0, Ni-1] x [0, Nj-1] for coordinates off the grid
+func (g *Grid) IJ(lat, lon float64) (i, j float64) {
+	return g.proj.ij(lat, lon)
+}
+
+// Nearest returns the grid point closest to a latitude and longitude, ok is
+// false when the coordinates are off the grid
+func (g *Grid) Nearest(lat, lon float64) (i, j int, ok bool) {
+	x, y := g.IJ(lat, lon)
+	// written so NaN and infinite positions are off the grid too
+	if !(x >= -0.5 && x < float64(g.Ni)-0.5 && y >= -0.5 && y < float64(g.Nj)-0.5) {
+		return 0, 0, false
+	}
+	return int(math.Floor(x + 0.5)), int(math.Floor(y + 0.5)), true
+}
+
+// Rectilinear reports whether the latitude of a point only depends on j and
+// its longitude only on i, as on latitude/longitude and Gaussian grids
+func (g *Grid) Rectilinear() bool {
+	switch g.proj.(type) {
+	case *latLon, *gaussian:
+		return true
+	}
+	return false
+}
+
+// Regular reports whether the grid is a latitude/longitude grid, equally
+// spaced in both
+func (g *Grid) Regular() bool {
+	_, ok := g.proj.(*latLon)
+	return ok
+}
+
+// LatLons returns the latitude and longitude of every grid point in the order
+// the grid is scanned, longitudes are in [0, 360)
+func (g *Grid) LatLons() (lats, lons []float64) {
+	n := g.Ni * g.Nj
+	lats = make([]float64, n)
+	lons = make([]float64, n)
+	for k := 0; k < n; k++ {
+		lats[k], lons[k] = g.LatLon(g.Point(k))
+	}
+	return lats, lons
+}
+
+// normalizeLon puts a longitude in [0, 360)
+func normalizeLon(lon float64) float64 {
+	lon = math.Mod(lon, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	if lon >= 360 {
+		lon -= 360
+	}
+	return lon
+}
+
+// Crop returns the grid definition of the points i0 <= i < i0+ni and
+// j0 <= j < j0+nj of the grid, with the values of those points taken from
+// the values of a field. Points are numbered as in Grid, the scanning mode
+// and projection are kept and section 3 is rewritten for the new first and
+// last points, so the result can be encoded
+func (g *GridDefinition) Crop(values []float32, i0, j0, ni, nj int) (*GridDefinition, []float32, error) {
+	if g.err != nil {
+		return nil, nil, g.err
+	}
+	if len(values) != g.NumPoints {
+		return nil, nil, fmt.Errorf("grib2: %d values for %d grid points", len(values), g.NumPoints)
+	}
+	if i0 < 0 || j0 < 0 || ni < 1 || nj < 1 || i0+ni > g.Ni || j0+nj > g.Nj {
+		return nil, nil, fmt.Errorf("grib2: %dx%d points at %d,%d are outside the %dx%d grid", ni, nj, i0, j0, g.Ni, g.Nj)
+	}
+
+	section := make([]byte, len(g.section))
+	copy(section, g.section)
+	putUint32(section, 7, uint32(ni*nj))
+	putUint32(section, 31, uint32(ni))
+	putUint32(section, 35, uint32(nj))
+
+	la1, lo1 := g.grid.LatLon(i0, j0)
+	la2, lo2 := g.grid.LatLon(i0+ni-1, j0+nj-1)
+	switch g.TemplateNumber {
+	case 0, 40:
+		angle := angleValue(section, 39)
+		putInt32(section, 47, angle(la1))
+		putInt32(section, 51, angle(lo1))
+		putInt32(section, 56, angle(la2))
+		putInt32(section, 60, angle(lo2))
+	case 20, 30:
+		putInt32(section, 39, microDegrees(la1))
+		putUint32(section, 43, uint32(microDegrees(lo1)))
+	}
+
+	crop, err := parseGridDefinition(section)
+	if err != nil {
+		return nil, nil, err
+	}
+	cropped := make([]float32, ni*nj)
+	for j := 0; j < nj; j++ {
+		for i := 0; i < ni; i++ {
+			cropped[crop.grid.Index(i, j)] = values[g.grid.Index(i0+i, j0+j)]
+		}
+	}
+	return crop, cropped, nil
+}
+
+// angleValue is the inverse of angleUnit, it returns a conversion from
+// degrees to the units angles are stored in
+func angleValue(section []byte, octet int) func(float64) int {
+	basic, subdivisions := uint32At(section, octet), uint32At(section, octet+4)
+	if basic == 0 || basic == missing4 || subdivisions == 0 || subdivisions == missing4 {
+		return microDegrees
+	}
+	return func(v float64) int {
+		return int(math.Floor(v*float64(subdivisions)/float64(basic) + 0.5))
+	}
+}
+
+func microDegrees(v float64) int {
+	return int(math.Floor(v*1e6 + 0.5))
+}
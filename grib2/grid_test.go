@@ -0,0 +1,220 @@
+package grib2
+
+import (
+	"math"
+	"testing"
+)
+
+// greatCircle is the distance in metres between two points on a sphere
+func greatCircle(lat1, lon1, lat2, lon2, radius float64) float64 {
+	dlat := (lat2 - lat1) * radians
+	dlon := (lon2 - lon1) * radians
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1*radians)*math.Cos(lat2*radians)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * radius * math.Asin(math.Sqrt(a))
+}
+
+func lonDiff(a, b float64) float64 {
+	return math.Abs(normalizeLon(a-b+180) - 180)
+}
+
+func TestGaussianLatitudes(t *testing.T) {
+	// the roots of the Legendre polynomial of degree 4 are
+	// +-sqrt(3/7 -+ 2/7 sqrt(6/5))
+	inner := math.Asin(math.Sqrt(3.0/7-2.0/7*math.Sqrt(6.0/5))) / radians
+	outer := math.Asin(math.Sqrt(3.0/7+2.0/7*math.Sqrt(6.0/5))) / radians
+
+	tests := []struct {
+		n         int
+		first     []float64
+		tolerance float64
+	}{
+		{4, []float64{outer, inner, -inner, -outer}, 1e-12},
+		// the T62 grid of the NCEP reanalysis, published to 4 decimals
+		{94, []float64{88.542, 86.6531, 84.7532, 82.8508}, 1e-4},
+		// the N80 grid of ECMWF
+		{160, []float64{89.1415194, 88.0294289, 86.9107708}, 1e-7},
+	}
+	for _, test := range tests {
+		lats := gaussianLatitudes(test.n)
+		if len(lats) != test.n {
+			t.Fatalf("%d latitudes, want %d", len(lats), test.n)
+		}
+		for k, want := range test.first {
+			if math.Abs(lats[k]-want) > test.tolerance {
+				t.Errorf("n %d: latitude %d = %.7f, want %.7f", test.n, k, lats[k], want)
+			}
+		}
+		for k := range lats {
+			if lats[k] != -lats[test.n-1-k] {
+				t.Errorf("n %d: latitudes %d and %d are not symmetric", test.n, k, test.n-1-k)
+			}
+			if k > 0 && lats[k] >= lats[k-1] {
+				t.Errorf("n %d: latitude %d is not south of the one before", test.n, k)
+			}
+		}
+	}
+}
+
+func TestGaussianGrid(t *testing.T) {
+	// a T62 Gaussian grid scanned southwards from its first latitude
+	g := &GridDefinition{TemplateNumber: 40, Ni: 192, Nj: 94, N: 47, La1: 88.542, Lo1: 0, Di: 1.875}
+	grid, err := g.newGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lats := gaussianLatitudes(94)
+	for j := 0; j < g.Nj; j++ {
+		lat, _ := grid.LatLon(0, j)
+		if lat != lats[j] {
+			t.Errorf("row %d at latitude %v, want %v", j, lat, lats[j])
+		}
+	}
+	for _, p := range [][2]float64{{0, 0}, {191, 93}, {10.5, 20.25}, {100, 46.5}} {
+		lat, lon := grid.LatLonAt(p[0], p[1])
+		i, j := grid.IJ(lat, lon)
+		if math.Abs(i-p[0]) > 1e-9 || math.Abs(j-p[1]) > 1e-9 {
+			t.Errorf("(%g, %g) maps to %g, %g and back to (%g, %g)", p[0], p[1], lat, lon, i, j)
+		}
+	}
+
+	// the first latitude has to be one of the grid
+	g.La1 = 45
+	if _, err := g.newGrid(); err == nil {
+		t.Error("a Gaussian grid starting between latitudes was accepted")
+	}
+}
+
+// projectedGrids are the HRRR CONUS Lambert conformal grid, with its last
+// point as wgrib2 reports it, and the HRRR Alaska polar stereographic grid
+var projectedGrids = []struct {
+	name     string
+	g        GridDefinition
+	la2, lo2 float64
+}{
+	{"HRRR CONUS", GridDefinition{
+		TemplateNumber: 30, Ni: 1799, Nj: 1059, ScanningMode: ScanPositiveJ,
+		La1: 21.138123, Lo1: 237.280472, LaD: 38.5, LoV: 262.5, Latin1: 38.5, Latin2: 38.5,
+		Dx: 3000, Dy: 3000, Radius: 6371229,
+	}, 47.842195, 299.082807},
+	{"HRRR Alaska", GridDefinition{
+		TemplateNumber: 20, Ni: 1299, Nj: 919, ScanningMode: ScanPositiveJ,
+		La1: 41.612949, Lo1: 185.117126, LaD: 60, LoV: 225,
+		Dx: 3000, Dy: 3000, Radius: 6371229,
+	}, math.NaN(), math.NaN()},
+}
+
+func TestProjectedGrids(t *testing.T) {
+	for _, test := range projectedGrids {
+		grid, err := test.g.newGrid()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lat, lon := grid.LatLon(0, 0)
+		if math.Abs(lat-test.g.La1) > 1e-9 || lonDiff(lon, test.g.Lo1) > 1e-9 {
+			t.Errorf("%s: first point at %v, %v, want %v, %v", test.name, lat, lon, test.g.La1, test.g.Lo1)
+		}
+		lat, lon = grid.LatLon(test.g.Ni-1, test.g.Nj-1)
+		if !math.IsNaN(test.la2) && (math.Abs(lat-test.la2) > 1e-4 || lonDiff(lon, test.lo2) > 1e-4) {
+			t.Errorf("%s: last point at %v, %v, want %v, %v", test.name, lat, lon, test.la2, test.lo2)
+		}
+
+		// the increments are true at LaD, the grid point nearest to it on
+		// the meridian LoV is checked
+		i, j, ok := grid.Nearest(test.g.LaD, test.g.LoV)
+		if !ok {
+			t.Fatalf("%s: %v, %v is off the grid", test.name, test.g.LaD, test.g.LoV)
+		}
+		lat1, lon1 := grid.LatLon(i, j)
+		lat2, lon2 := grid.LatLon(i+1, j)
+		if d := greatCircle(lat1, lon1, lat2, lon2, test.g.Radius); math.Abs(d-test.g.Dx) > 1 {
+			t.Errorf("%s: points %d and %d of row %d are %v m apart, want %v", test.name, i, i+1, j, d, test.g.Dx)
+		}
+
+		// positions survive a round trip through latitude and longitude
+		for _, p := range [][2]float64{{0, 0}, {float64(test.g.Ni - 1), float64(test.g.Nj - 1)}, {12.5, 700.25}, {900, 3}, {-10, -20}} {
+			lat, lon := grid.LatLonAt(p[0], p[1])
+			i, j := grid.IJ(lat, lon)
+			if math.Abs(i-p[0]) > 1e-6 || math.Abs(j-p[1]) > 1e-6 {
+				t.Errorf("%s: (%g, %g) maps to %g, %g and back to (%g, %g)", test.name, p[0], p[1], lat, lon, i, j)
+			}
+		}
+
+		if _, _, ok := grid.Nearest(-45, 0); ok {
+			t.Errorf("%s: a point of the other hemisphere is on the grid", test.name)
+		}
+	}
+}
+
+func TestSouthPolarStereographic(t *testing.T) {
+	g := &GridDefinition{
+		TemplateNumber: 20, Ni: 100, Nj: 100, La1: -50, Lo1: 225, LaD: -60, LoV: 0,
+		Dx: 50000, Dy: 50000, SouthPole: true, Radius: 6371229, ScanningMode: ScanPositiveJ,
+	}
+	grid, err := g.newGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][2]float64{{0, 0}, {99, 99}, {49.5, 49.5}, {10, 80}} {
+		lat, lon := grid.LatLonAt(p[0], p[1])
+		if lat > 0 {
+			t.Errorf("(%g, %g) is in the northern hemisphere at %v", p[0], p[1], lat)
+		}
+		i, j := grid.IJ(lat, lon)
+		if math.Abs(i-p[0]) > 1e-6 || math.Abs(j-p[1]) > 1e-6 {
+			t.Errorf("(%g, %g) maps to %g, %g and back to (%g, %g)", p[0], p[1], lat, lon, i, j)
+		}
+	}
+}
+
+func TestLatLonGrid(t *testing.T) {
+	// a global 1 degree grid scanned southwards from 90N 0E
+	g := &GridDefinition{TemplateNumber: 0, Ni: 360, Nj: 181, La1: 90, Lo1: 0, Di: 1, Dj: 1}
+	grid, err := g.newGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		lat, lon float64
+		i, j     int
+		ok       bool
+	}{
+		{90, 0, 0, 0, true},
+		{-90, 359, 359, 180, true},
+		{0, -1, 359, 90, true},
+		// longitudes wrap, 359.6 is nearer to 0 than to 359
+		{10, 359.6, 0, 80, true},
+		{45.4, 180.4, 180, 45, true},
+		{-90.6, 0, 0, 0, false},
+	}
+	for _, test := range tests {
+		i, j, ok := grid.Nearest(test.lat, test.lon)
+		if ok != test.ok || (ok && (i != test.i || j != test.j)) {
+			t.Errorf("nearest to %v, %v = (%d, %d) %v, want (%d, %d) %v", test.lat, test.lon, i, j, ok, test.i, test.j, test.ok)
+		}
+	}
+}
+
+func TestScanningModes(t *testing.T) {
+	for _, mode := range []int{0, ScanNegativeI, ScanPositiveJ, ScanConsecutiveJ, ScanBoustrophedon, ScanConsecutiveJ | ScanBoustrophedon} {
+		grid := &Grid{Ni: 5, Nj: 3, ScanningMode: mode}
+		seen := map[int]bool{}
+		for n := 0; n < 15; n++ {
+			i, j := grid.Point(n)
+			if got := grid.Index(i, j); got != n {
+				t.Errorf("mode %#x: point %d is (%d, %d) stored at %d", mode, n, i, j, got)
+			}
+			seen[j*5+i] = true
+		}
+		if len(seen) != 15 {
+			t.Errorf("mode %#x: %d distinct points, want 15", mode, len(seen))
+		}
+	}
+
+	// boustrophedon rows run back and forth
+	grid := &Grid{Ni: 5, Nj: 3, ScanningMode: ScanBoustrophedon}
+	if i, j := grid.Point(5); i != 4 || j != 1 {
+		t.Errorf("point 5 of a boustrophedon grid is (%d, %d), want (4, 1)", i, j)
+	}
+}
@@ -0,0 +1,90 @@
+package grib2
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/azillion/nimbus/grib2/jpeg2000"
+)
+
+// unpackJPEG2000 decodes template 5.40, the packed values are the samples
+// of a JPEG 2000 codestream
+func (r *DataRepresentation) unpackJPEG2000(data []byte) ([]float32, error) {
+	if r.Bits == 0 {
+		return r.constant(), nil
+	}
+	img, err := jpeg2000.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	samples := img.Components[0].Data
+	if len(samples) != r.NumValues {
+		return nil, fmt.Errorf("grib2: JPEG 2000 image has %d samples, want %d", len(samples), r.NumValues)
+	}
+
+	values := make([]float32, len(samples))
+	scale := r.scale()
+	for i, x := range samples {
+		values[i] = scale(float64(x))
+	}
+	return values, nil
+}
+
+// unpackPNG decodes template 5.41, the packed values are the pixels of a PNG
+// image
+func (r *DataRepresentation) unpackPNG(data []byte) ([]float32, error) {
+	if r.Bits == 0 {
+		return r.constant(), nil
+	}
+	// octet 25 of the stream is the bit depth of the IHDR chunk
+	if len(data) < 26 {
+		return nil, fmt.Errorf("grib2: PNG image is truncated")
+	}
+	depth := data[24]
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("grib2: %v", err)
+	}
+
+	b := img.Bounds()
+	if b.Dx()*b.Dy() != r.NumValues {
+		return nil, fmt.Errorf("grib2: PNG image has %d pixels, want %d", b.Dx()*b.Dy(), r.NumValues)
+	}
+
+	// each pixel is one packed value, gray levels below 8 bits are scaled
+	// up by the decoder and colour channels are the octets of the value
+	var pixel func(x, y int) uint32
+	switch img := img.(type) {
+	case *image.Gray:
+		div := uint32(1)
+		if depth < 8 {
+			div = 0xff / (1<<depth - 1)
+		}
+		pixel = func(x, y int) uint32 { return uint32(img.GrayAt(x, y).Y) / div }
+	case *image.Gray16:
+		pixel = func(x, y int) uint32 { return uint32(img.Gray16At(x, y).Y) }
+	case *image.RGBA:
+		pixel = func(x, y int) uint32 {
+			c := img.RGBAAt(x, y)
+			return uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+		}
+	case *image.NRGBA:
+		pixel = func(x, y int) uint32 {
+			c := img.NRGBAAt(x, y)
+			return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+		}
+	default:
+		return nil, fmt.Errorf("grib2: PNG colour model %T is not supported", img)
+	}
+
+	values := make([]float32, 0, r.NumValues)
+	scale := r.scale()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			values = append(values, scale(float64(pixel(x, y))))
+		}
+	}
+	return values, nil
+}
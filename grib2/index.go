@@ -0,0 +1,147 @@
+package grib2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// timeUnitNames are the names wgrib2 gives the units of code table 4.4
+var timeUnitNames = map[int]string{
+	0:  "min",
+	1:  "hour",
+	2:  "day",
+	3:  "month",
+	4:  "year",
+	5:  "decade",
+	6:  "normal",
+	7:  "century",
+	13: "sec",
+}
+
+// statisticalProcesses are the names wgrib2 gives the processes of code table
+// 4.10
+var statisticalProcesses = map[int]string{
+	0:  "ave",
+	1:  "acc",
+	2:  "max",
+	3:  "min",
+	4:  "last-first",
+	5:  "RMS",
+	6:  "StdDev",
+	7:  "covar",
+	8:  "first-last",
+	9:  "ratio",
+	10: "standardized anomaly",
+	11: "summation",
+}
+
+// timeAmount converts an amount of a code table 4.4 unit to a unit wgrib2
+// names, units of several hours are converted to hours
+func timeAmount(unit, amount int) (int, string) {
+	switch unit {
+	case 10:
+		return 3 * amount, "hour"
+	case 11:
+		return 6 * amount, "hour"
+	case 12:
+		return 12 * amount, "hour"
+	}
+	if name, ok := timeUnitNames[unit]; ok {
+		return amount, name
+	}
+	return amount, fmt.Sprintf("unit%d", unit)
+}
+
+// ForecastTime describes the forecast time of the field the way wgrib2
+// prints it, e.g. "anl", "6 hour fcst" or "0-6 hour acc fcst"
+func (f *Field) ForecastTime() string {
+	p := f.Product
+	start, unit := timeAmount(p.TimeUnit, p.ForecastTime)
+	if !p.HasInterval {
+		if start == 0 {
+			return "anl"
+		}
+		return fmt.Sprintf("%d %s fcst", start, unit)
+	}
+
+	length, lengthUnit := timeAmount(p.IntervalUnit, p.IntervalLength)
+	if lengthUnit != unit {
+		// both ends are given in the unit of the forecast time
+		step, ok1 := Duration(p.TimeUnit, 1)
+		if p.TimeUnit >= 10 && p.TimeUnit <= 12 {
+			// timeAmount gave the start in hours
+			step = time.Hour
+		}
+		interval, ok2 := Duration(p.IntervalUnit, p.IntervalLength)
+		if ok1 && ok2 {
+			length = int(interval / step)
+		}
+	}
+	return fmt.Sprintf("%d-%d %s %s fcst", start, start+length, unit, StatisticalProcessName(p.StatisticalProcess))
+}
+
+// StatisticalProcessName is the name wgrib2 gives a process of code table
+// 4.10, such as "acc" for accumulations
+func StatisticalProcessName(process int) string {
+	if name, ok := statisticalProcesses[process]; ok {
+		return name
+	}
+	return "process" + strconv.Itoa(process)
+}
+
+// ensemble describes the ensemble member of the field like wgrib2, it is
+// empty for other products
+func (f *Field) ensemble() string {
+	p := f.Product
+	if !p.IsEnsemble {
+		return ""
+	}
+	switch p.EnsembleType {
+	case 0:
+		return "ENS=hi-res ctl"
+	case 1:
+		return "ENS=low-res ctl"
+	case 2:
+		return "ENS=-" + strconv.Itoa(p.PerturbationNumber)
+	case 3:
+		return "ENS=+" + strconv.Itoa(p.PerturbationNumber)
+	}
+	return "ENS=" + strconv.Itoa(p.EnsembleType) + "." + strconv.Itoa(p.PerturbationNumber)
+}
+
+// WriteIndex writes the inventory of a GRIB2 file in wgrib2's short format,
+// the .idx files NOMADS serves next to its GRIB2 files:
+//
+//	1:0:d=2019061800:UGRD:10 m above ground:1 hour fcst:
+//
+// Fields sharing a message are numbered message.field and have the offset of
+// their message. Only sections 0, 1 and 4 are read, so files of grids and
+// packings that are not supported are indexed as well
+func WriteIndex(w io.Writer, r io.ReaderAt) error {
+	bw := bufio.NewWriter(w)
+	s := &Scanner{src: &readerSource{r: r}, index: true}
+	for n := 1; s.Scan(); n++ {
+		m := s.Message()
+		for k, f := range m.Fields {
+			number := strconv.Itoa(n)
+			if len(m.Fields) > 1 {
+				number += "." + strconv.Itoa(k+1)
+			}
+			line := fmt.Sprintf("%s:%d:d=%s:%s:%s:%s:", number, m.Offset,
+				f.RefTime().Format("2006010215"), f.Parameter().Name, f.Level(), f.ForecastTime())
+			if ens := f.ensemble(); ens != "" {
+				line += ens + ":"
+			}
+			if _, err := fmt.Fprintln(bw, line); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,115 @@
+package grib2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestForecastTime(t *testing.T) {
+	tests := []struct {
+		p    ProductDefinition
+		want string
+	}{
+		{ProductDefinition{TimeUnit: 1}, "anl"},
+		{ProductDefinition{TimeUnit: 1, ForecastTime: 6}, "6 hour fcst"},
+		{ProductDefinition{TimeUnit: 0, ForecastTime: 90}, "90 min fcst"},
+		{ProductDefinition{TimeUnit: 2, ForecastTime: 3}, "3 day fcst"},
+		// units of several hours are given in hours
+		{ProductDefinition{TimeUnit: 11, ForecastTime: 2}, "12 hour fcst"},
+		{ProductDefinition{TimeUnit: 1, ForecastTime: 0, HasInterval: true, StatisticalProcess: 1, IntervalUnit: 1, IntervalLength: 6}, "0-6 hour acc fcst"},
+		{ProductDefinition{TimeUnit: 1, ForecastTime: 6, HasInterval: true, StatisticalProcess: 0, IntervalUnit: 1, IntervalLength: 6}, "6-12 hour ave fcst"},
+		{ProductDefinition{TimeUnit: 1, ForecastTime: 12, HasInterval: true, StatisticalProcess: 2, IntervalUnit: 1, IntervalLength: 1}, "12-13 hour max fcst"},
+		// the interval is converted to the unit of the forecast time
+		{ProductDefinition{TimeUnit: 1, ForecastTime: 0, HasInterval: true, StatisticalProcess: 1, IntervalUnit: 2, IntervalLength: 1}, "0-24 hour acc fcst"},
+		{ProductDefinition{TimeUnit: 0, ForecastTime: 60, HasInterval: true, StatisticalProcess: 1, IntervalUnit: 1, IntervalLength: 1}, "60-120 min acc fcst"},
+		{ProductDefinition{TimeUnit: 1, ForecastTime: 0, HasInterval: true, StatisticalProcess: 200, IntervalUnit: 1, IntervalLength: 3}, "0-3 hour process200 fcst"},
+	}
+	for _, test := range tests {
+		f := &Field{Product: &test.p}
+		if got := f.ForecastTime(); got != test.want {
+			t.Errorf("%+v: %q, want %q", test.p, got, test.want)
+		}
+	}
+}
+
+func TestEnsemble(t *testing.T) {
+	tests := []struct {
+		kind, number int
+		want         string
+	}{
+		{0, 0, "ENS=hi-res ctl"},
+		{1, 0, "ENS=low-res ctl"},
+		{2, 3, "ENS=-3"},
+		{3, 12, "ENS=+12"},
+		{192, 1, "ENS=192.1"},
+	}
+	for _, test := range tests {
+		f := &Field{Product: &ProductDefinition{IsEnsemble: true, EnsembleType: test.kind, PerturbationNumber: test.number}}
+		if got := f.ensemble(); got != test.want {
+			t.Errorf("type %d number %d: %q, want %q", test.kind, test.number, got, test.want)
+		}
+	}
+	if got := (&Field{Product: &ProductDefinition{}}).ensemble(); got != "" {
+		t.Errorf("deterministic product: %q", got)
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	data := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")
+	want := "1:0:d=2017072000:UGRD:10 m above ground:1 hour fcst:\n" +
+		"2:17378:d=2017072000:VGRD:10 m above ground:1 hour fcst:\n"
+
+	var buf bytes.Buffer
+	if err := WriteIndex(&buf, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("index:\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// sections 3 and 5 are not read, a grid whose size does not match its
+	// number of points and an unknown packing are indexed
+	damaged := append([]byte(nil), data...)
+	s := sections(damaged[:17378])
+	grid := indicatorLength + len(s[0])
+	binary.BigEndian.PutUint32(damaged[grid+30:], 1)
+	representation := grid + len(s[1]) + len(s[2])
+	binary.BigEndian.PutUint16(damaged[representation+9:], 61)
+	if _, err := Read(damaged); err == nil {
+		t.Fatal("the damaged grid was read")
+	}
+	buf.Reset()
+	if err := WriteIndex(&buf, bytes.NewReader(damaged)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("index of the damaged file:\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// a message that is cut off fails the index
+	if err := WriteIndex(&bytes.Buffer{}, bytes.NewReader(data[:len(data)-10])); err == nil {
+		t.Error("indexed a truncated file")
+	}
+}
+
+func TestWriteIndexAccumulation(t *testing.T) {
+	f := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")[0].Fields[0]
+	ref := f.RefTime()
+	p := *f.Product
+	p.TemplateNumber = 8
+	p.HasInterval = true
+	p.ForecastTime = 0
+	p.StatisticalProcess = 1
+	p.IntervalUnit = 1
+	p.IntervalLength = 6
+	p.IntervalEnd = ref.Add(6 * time.Hour)
+	accumulation := &Field{Message: f.Message, Product: &p}
+	if got := accumulation.ForecastTime(); got != "0-6 hour acc fcst" {
+		t.Errorf("forecast time %q", got)
+	}
+	if verf, err := accumulation.VerfTime(); err != nil || !verf.Equal(ref.Add(6*time.Hour)) {
+		t.Errorf("valid at %s, %v", verf, err)
+	}
+}
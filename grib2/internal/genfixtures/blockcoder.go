@@ -0,0 +1,581 @@
+package main
+
+import "math"
+
+// qeTable is the probability estimation of the MQ coder (table C.2): Qe,
+// NMPS, NLPS and SWITCH of every state
+var qeTable = [47][4]uint32{
+	{0x5601, 1, 1, 1}, {0x3401, 2, 6, 0}, {0x1801, 3, 9, 0}, {0x0AC1, 4, 12, 0}, {0x0521, 5, 29, 0}, {0x0221, 38, 33, 0},
+	{0x5601, 7, 6, 1}, {0x5401, 8, 14, 0}, {0x4801, 9, 14, 0}, {0x3801, 10, 14, 0}, {0x3001, 11, 17, 0}, {0x2401, 12, 18, 0},
+	{0x1C01, 13, 20, 0}, {0x1601, 29, 21, 0}, {0x5601, 15, 14, 1}, {0x5401, 16, 14, 0}, {0x5101, 17, 15, 0}, {0x4801, 18, 16, 0},
+	{0x3801, 19, 17, 0}, {0x3401, 20, 18, 0}, {0x3001, 21, 19, 0}, {0x2801, 22, 19, 0}, {0x2401, 23, 20, 0}, {0x2201, 24, 21, 0},
+	{0x1C01, 25, 22, 0}, {0x1801, 26, 23, 0}, {0x1601, 27, 24, 0}, {0x1401, 28, 25, 0}, {0x1201, 29, 26, 0}, {0x1101, 30, 27, 0},
+	{0x0AC1, 31, 28, 0}, {0x09C1, 32, 29, 0}, {0x08A1, 33, 30, 0}, {0x0521, 34, 31, 0}, {0x0441, 35, 32, 0}, {0x02A1, 36, 33, 0},
+	{0x0221, 37, 34, 0}, {0x0141, 38, 35, 0}, {0x0111, 39, 36, 0}, {0x0085, 40, 37, 0}, {0x0049, 41, 38, 0}, {0x0025, 42, 39, 0},
+	{0x0015, 43, 40, 0}, {0x0009, 44, 41, 0}, {0x0005, 45, 42, 0}, {0x0001, 45, 43, 0}, {0x5601, 46, 46, 0},
+}
+
+// mqContext is the state and more probable symbol of a context
+type mqContext struct{ state, mps uint32 }
+
+// mqEncoder is the arithmetic encoder of annex C
+type mqEncoder struct {
+	a, c uint32
+	ct   int
+	out  []byte
+}
+
+func (m *mqEncoder) init() {
+	m.a, m.c, m.ct = 0x8000, 0, 12
+	// the byte before the first is a placeholder that carries can reach
+	m.out = []byte{0}
+}
+
+func (m *mqEncoder) byteOut() {
+	last := len(m.out) - 1
+	switch {
+	case m.out[last] == 0xff:
+		m.out = append(m.out, byte(m.c>>20))
+		m.c &= 0xfffff
+		m.ct = 7
+	case m.c < 0x8000000:
+		m.out = append(m.out, byte(m.c>>19))
+		m.c &= 0x7ffff
+		m.ct = 8
+	default:
+		m.out[last]++
+		if m.out[last] == 0xff {
+			m.c &= 0x7ffffff
+			m.out = append(m.out, byte(m.c>>20))
+			m.c &= 0xfffff
+			m.ct = 7
+		} else {
+			m.out = append(m.out, byte(m.c>>19))
+			m.c &= 0x7ffff
+			m.ct = 8
+		}
+	}
+}
+
+func (m *mqEncoder) renormalize() {
+	for {
+		m.a <<= 1
+		m.c <<= 1
+		m.ct--
+		if m.ct == 0 {
+			m.byteOut()
+		}
+		if m.a&0x8000 != 0 {
+			return
+		}
+	}
+}
+
+func (m *mqEncoder) encode(d uint32, cx *mqContext) {
+	q := qeTable[cx.state]
+	m.a -= q[0]
+	if d == cx.mps {
+		if m.a&0x8000 != 0 {
+			m.c += q[0]
+			return
+		}
+		if m.a < q[0] {
+			m.a = q[0]
+		} else {
+			m.c += q[0]
+		}
+		cx.state = q[1]
+	} else {
+		if m.a < q[0] {
+			m.c += q[0]
+		} else {
+			m.a = q[0]
+		}
+		if q[3] == 1 {
+			cx.mps = 1 - cx.mps
+		}
+		cx.state = q[2]
+	}
+	m.renormalize()
+}
+
+// flush terminates the codeword, the trailing 0xff is left out as the
+// decoder reads it back
+func (m *mqEncoder) flush() []byte {
+	tempc := m.c + m.a
+	m.c |= 0xffff
+	if m.c >= tempc {
+		m.c -= 0x8000
+	}
+	m.c <<= uint(m.ct)
+	m.byteOut()
+	m.c <<= uint(m.ct)
+	m.byteOut()
+	out := m.out[1:]
+	if len(out) > 0 && out[len(out)-1] == 0xff {
+		out = out[:len(out)-1]
+	}
+	return append([]byte(nil), out...)
+}
+
+// rawEncoder writes the passes of the arithmetic coding bypass mode, bits
+// are stuffed after 0xff like packet headers
+type rawEncoder struct {
+	out []byte
+	cur uint32
+	n   uint
+	max uint
+}
+
+func (r *rawEncoder) init() { *r = rawEncoder{max: 8} }
+
+func (r *rawEncoder) put(bit uint32) {
+	r.cur = r.cur<<1 | bit
+	r.n++
+	if r.n == r.max {
+		b := byte(r.cur)
+		r.out = append(r.out, b)
+		r.cur, r.n = 0, 0
+		r.max = 8
+		if b == 0xff {
+			r.max = 7
+		}
+	}
+}
+
+// flush pads the last byte with alternating bits
+func (r *rawEncoder) flush() []byte {
+	alt := uint32(0)
+	for r.n != 0 {
+		r.put(alt)
+		alt ^= 1
+	}
+	return append([]byte(nil), r.out...)
+}
+
+// states of a coefficient while a block is coded
+const (
+	flagSignificant = 1 << iota
+	flagNegative
+	flagVisited
+	flagRefined
+)
+
+// code-block styles of the COD marker
+const (
+	styleBypass        = 0x01
+	styleReset         = 0x02
+	styleTermAll       = 0x04
+	styleVerticalCause = 0x08
+	stylePredictable   = 0x10
+	styleSegSymbols    = 0x20
+)
+
+// blockEncoder codes the bit planes of a code-block (annex D), the flags
+// have a border of one coefficient so neighbours need no bounds checks
+type blockEncoder struct {
+	w, h, band, style int
+	flags             []uint8
+	mag               []int32
+	negative          []bool
+	mq                mqEncoder
+	raw               rawEncoder
+	contexts          [19]mqContext
+}
+
+func (t *blockEncoder) resetContexts() {
+	for i := range t.contexts {
+		t.contexts[i] = mqContext{}
+	}
+	t.contexts[0].state = 4
+	t.contexts[17].state = 3
+	t.contexts[18].state = 46
+}
+
+func (t *blockEncoder) index(x, y int) int { return (y+1)*(t.w+2) + x + 1 }
+
+// causal says whether the row below is ignored, the last of a stripe with
+// vertically causal context formation
+func (t *blockEncoder) causal(y int) bool {
+	return t.style&styleVerticalCause != 0 && y%4 == 3
+}
+
+// neighbours counts the significant horizontal, vertical and diagonal
+// neighbours of a coefficient
+func (t *blockEncoder) neighbours(i int, causal bool) (h, v, d int) {
+	s := t.w + 2
+	sig := func(j int) int { return int(t.flags[j] & flagSignificant) }
+	h = sig(i-1) + sig(i+1)
+	v = sig(i - s)
+	d = sig(i-s-1) + sig(i-s+1)
+	if !causal {
+		v += sig(i + s)
+		d += sig(i+s-1) + sig(i+s+1)
+	}
+	return
+}
+
+// zeroContext is the context of significance coding (table D.1), band is
+// 0 for LL, 1 for HL, 2 for LH and 3 for HH
+func zeroContext(band, h, v, d int) int {
+	if band == 3 {
+		hv := h + v
+		switch {
+		case d >= 3:
+			return 8
+		case d == 2 && hv >= 1:
+			return 7
+		case d == 2:
+			return 6
+		case d == 1 && hv >= 2:
+			return 5
+		case d == 1 && hv == 1:
+			return 4
+		case d == 1:
+			return 3
+		case hv >= 2:
+			return 2
+		}
+		return hv
+	}
+	if band == 1 {
+		h, v = v, h
+	}
+	switch {
+	case h == 2:
+		return 8
+	case h == 1 && v >= 1:
+		return 7
+	case h == 1 && d >= 1:
+		return 6
+	case h == 1:
+		return 5
+	case v == 2:
+		return 4
+	case v == 1:
+		return 3
+	case d >= 2:
+		return 2
+	}
+	return d
+}
+
+func (t *blockEncoder) contribution(j int) int {
+	switch {
+	case t.flags[j]&flagSignificant == 0:
+		return 0
+	case t.flags[j]&flagNegative != 0:
+		return -1
+	}
+	return 1
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// signContexts are the context and XOR bit of sign coding by the
+// horizontal and vertical contributions (table D.3)
+var signContexts = map[[2]int][2]int{
+	{1, 1}: {13, 0}, {1, 0}: {12, 0}, {1, -1}: {11, 0},
+	{0, 1}: {10, 0}, {0, 0}: {9, 0}, {0, -1}: {10, 1},
+	{-1, 1}: {11, 1}, {-1, 0}: {12, 1}, {-1, -1}: {13, 1},
+}
+
+func (t *blockEncoder) signContext(i int, causal bool) (int, uint32) {
+	s := t.w + 2
+	h := sign(t.contribution(i-1) + t.contribution(i+1))
+	below := 0
+	if !causal {
+		below = t.contribution(i + s)
+	}
+	v := sign(t.contribution(i-s) + below)
+	e := signContexts[[2]int{h, v}]
+	return e[0], uint32(e[1])
+}
+
+func (t *blockEncoder) bit(x, y int, bp uint) uint32 { return uint32(t.mag[y*t.w+x]>>bp) & 1 }
+
+// encodeSign codes the sign of a coefficient becoming significant
+func (t *blockEncoder) encodeSign(x, y int, raw bool) {
+	i := t.index(x, y)
+	neg := t.negative[y*t.w+x]
+	n := uint32(0)
+	if neg {
+		n = 1
+	}
+	if raw {
+		t.raw.put(n)
+	} else {
+		cx, xor := t.signContext(i, t.causal(y))
+		t.mq.encode(n^xor, &t.contexts[cx])
+	}
+	t.flags[i] |= flagSignificant
+	if neg {
+		t.flags[i] |= flagNegative
+	}
+}
+
+// significancePass codes the coefficients with a significant neighbour
+func (t *blockEncoder) significancePass(bp uint, raw bool) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := t.index(x, y)
+				if t.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				h, v, d := t.neighbours(i, t.causal(y))
+				if h+v+d == 0 {
+					continue
+				}
+				b := t.bit(x, y, bp)
+				if raw {
+					t.raw.put(b)
+				} else {
+					t.mq.encode(b, &t.contexts[zeroContext(t.band, h, v, d)])
+				}
+				if b == 1 {
+					t.encodeSign(x, y, raw)
+				}
+				t.flags[i] |= flagVisited
+			}
+		}
+	}
+}
+
+// refinementPass codes the next bit of coefficients already significant
+func (t *blockEncoder) refinementPass(bp uint, raw bool) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := t.index(x, y)
+				f := t.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				b := t.bit(x, y, bp)
+				if raw {
+					t.raw.put(b)
+				} else {
+					cx := 16
+					if f&flagRefined == 0 {
+						cx = 14
+						if h, v, d := t.neighbours(i, t.causal(y)); h+v+d > 0 {
+							cx = 15
+						}
+					}
+					t.mq.encode(b, &t.contexts[cx])
+				}
+				t.flags[i] |= flagRefined
+			}
+		}
+	}
+}
+
+// cleanupPass codes the remaining coefficients, with run-length coding of
+// columns of four without significant neighbours
+func (t *blockEncoder) cleanupPass(bp uint) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			y := y0
+			if y0+4 <= t.h && t.runLength(x, y0) {
+				first := -1
+				for yy := y0; yy < y0+4; yy++ {
+					if t.bit(x, yy, bp) == 1 {
+						first = yy - y0
+						break
+					}
+				}
+				if first < 0 {
+					t.mq.encode(0, &t.contexts[17])
+					continue
+				}
+				t.mq.encode(1, &t.contexts[17])
+				t.mq.encode(uint32(first>>1), &t.contexts[18])
+				t.mq.encode(uint32(first&1), &t.contexts[18])
+				y = y0 + first
+				t.encodeSign(x, y, false)
+				y++
+			}
+			for ; y < y0+4 && y < t.h; y++ {
+				i := t.index(x, y)
+				if t.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				h, v, d := t.neighbours(i, t.causal(y))
+				b := t.bit(x, y, bp)
+				t.mq.encode(b, &t.contexts[zeroContext(t.band, h, v, d)])
+				if b == 1 {
+					t.encodeSign(x, y, false)
+				}
+			}
+		}
+	}
+	for i := range t.flags {
+		t.flags[i] &^= flagVisited
+	}
+	if t.style&styleSegSymbols != 0 {
+		for _, b := range []uint32{1, 0, 1, 0} {
+			t.mq.encode(b, &t.contexts[18])
+		}
+	}
+}
+
+// runLength says whether the column of four at x, y0 is run-length coded
+func (t *blockEncoder) runLength(x, y0 int) bool {
+	for y := y0; y < y0+4; y++ {
+		i := t.index(x, y)
+		h, v, d := t.neighbours(i, t.causal(y))
+		if t.flags[i]&(flagSignificant|flagVisited) != 0 || h+v+d != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentPasses is the number of passes of codeword segment n, a block is
+// one segment unless each pass is terminated or bypass splits it
+func segmentPasses(style, n int) int {
+	switch {
+	case style&styleTermAll != 0:
+		return 1
+	case style&styleBypass != 0 && n == 0:
+		return 10
+	case style&styleBypass != 0 && n%2 == 1:
+		return 2
+	case style&styleBypass != 0:
+		return 1
+	}
+	return 1 << 30
+}
+
+// encodeBlock codes the quantized coefficients of a code-block and returns
+// its number of bit planes, of passes, and its codeword segments with the
+// number of passes of each
+func encodeBlock(q []int32, w, h, band, style int) (planes, passes int, segments [][]byte, segmentLengths []int) {
+	t := &blockEncoder{w: w, h: h, band: band, style: style}
+	t.flags = make([]uint8, (w+2)*(h+2))
+	t.mag = make([]int32, w*h)
+	t.negative = make([]bool, w*h)
+	var max int32
+	for i, v := range q {
+		if v < 0 {
+			t.negative[i] = true
+			v = -v
+		}
+		t.mag[i] = v
+		if v > max {
+			max = v
+		}
+	}
+	for ; max > 0; max >>= 1 {
+		planes++
+	}
+	if planes == 0 {
+		return 0, 0, nil, nil
+	}
+	t.resetContexts()
+	passes = 3*planes - 2
+	inSegment := 0
+	bp := planes - 1
+	for pass := 0; pass < passes; {
+		// the first pass is a cleanup, then significance, refinement and
+		// cleanup for every lower bit plane
+		kind := (pass + 2) % 3
+		raw := style&styleBypass != 0 && pass >= 10 && kind != 2
+		if inSegment == 0 {
+			if raw {
+				t.raw.init()
+			} else {
+				t.mq.init()
+			}
+		}
+		switch kind {
+		case 0:
+			t.significancePass(uint(bp), raw)
+		case 1:
+			t.refinementPass(uint(bp), raw)
+		case 2:
+			t.cleanupPass(uint(bp))
+			bp--
+		}
+		if style&styleReset != 0 {
+			t.resetContexts()
+		}
+		pass++
+		inSegment++
+		if inSegment == segmentPasses(style, len(segments)) || pass == passes {
+			if raw {
+				segments = append(segments, t.raw.flush())
+			} else {
+				segments = append(segments, t.mq.flush())
+			}
+			segmentLengths = append(segmentLengths, inSegment)
+			inSegment = 0
+		}
+	}
+	return planes, passes, segments, segmentLengths
+}
+
+// lifting coefficients of the irreversible 9/7 wavelet (table F.4)
+const (
+	alpha = -1.586134342059924
+	beta  = -0.052980118572961
+	gamma = 0.882911075530934
+	delta = 0.443506852043971
+	kappa = 1.230174104914001
+)
+
+// analyze is the one dimensional forward transform (F.4.8) of a row or
+// column starting at coordinate i0, low pass coefficients end up at the even
+// coordinates and high pass ones at the odd
+func analyze(x []float64, i0 int, reversible bool) {
+	n := len(x)
+	switch n {
+	case 0:
+		return
+	case 1:
+		if i0&1 == 1 {
+			x[0] *= 2
+		}
+		return
+	}
+	// symmetric extension by e coefficients on each side
+	const e = 4
+	y := make([]float64, n+2*e)
+	p := 2 * (n - 1)
+	for k := -e; k < n+e; k++ {
+		j := ((k % p) + p) % p
+		if j >= n {
+			j = p - j
+		}
+		y[k+e] = x[j]
+	}
+	even := func(k int) bool { return (i0+k-e)&1 == 0 }
+	step := func(low bool, from, to int, f func(k int) float64) {
+		for k := from; k < to; k++ {
+			if even(k) == low {
+				y[k] = f(k)
+			}
+		}
+	}
+	if reversible {
+		step(false, e-1, n+e+1, func(k int) float64 { return y[k] - math.Floor((y[k-1]+y[k+1])/2) })
+		step(true, e, n+e, func(k int) float64 { return y[k] + math.Floor((y[k-1]+y[k+1]+2)/4) })
+	} else {
+		step(false, 1, n+2*e-1, func(k int) float64 { return y[k] + alpha*(y[k-1]+y[k+1]) })
+		step(true, 2, n+2*e-2, func(k int) float64 { return y[k] + beta*(y[k-1]+y[k+1]) })
+		step(false, 3, n+2*e-3, func(k int) float64 { return y[k] + gamma*(y[k-1]+y[k+1]) })
+		step(true, e, n+e, func(k int) float64 { return y[k] + delta*(y[k-1]+y[k+1]) })
+		step(false, e, n+e, func(k int) float64 { return y[k] * kappa })
+		step(true, e, n+e, func(k int) float64 { return y[k] / kappa })
+	}
+	copy(x, y[e:e+n])
+}
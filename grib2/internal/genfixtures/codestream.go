@@ -0,0 +1,889 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// markers of the codestream
+const (
+	markerSOC = 0xff4f
+	markerSIZ = 0xff51
+	markerCOD = 0xff52
+	markerQCD = 0xff5c
+	markerQCC = 0xff5d
+	markerSOT = 0xff90
+	markerSOP = 0xff91
+	markerEPH = 0xff92
+	markerSOD = 0xff93
+	markerEOC = 0xffd9
+)
+
+// progression orders of the COD marker
+const (
+	progressionLRCP = iota
+	progressionRLCP
+	progressionRPCL
+	progressionPCRL
+	progressionCPRL
+)
+
+// rng splits the passes of code-blocks between layers and their codewords
+// between packets, it is seeded before each codestream so they do not
+// change between runs
+var rng *rand.Rand
+
+// componentParams are the SIZ and COD parameters of a component
+type componentParams struct {
+	precision int
+	signed    bool
+	dx, dy    int
+	levels    int
+	// xcb and ycb are the code-block size exponents
+	xcb, ycb int
+}
+
+// codestreamParams describes the codestream to write, every component is
+// coded with the COD parameters of the first
+type codestreamParams struct {
+	x0, y0, x1, y1   int
+	tw, th, tx0, ty0 int
+	components       []componentParams
+	// precincts are the size exponents of every resolution, nil for the
+	// maximum precincts
+	precincts   [][2]int
+	layers      int
+	progression int
+	blockStyle  int
+	reversible  bool
+	sop, eph    bool
+	mct         bool
+	// splitTiles writes each tile in two tile-parts
+	splitTiles bool
+	guardBits  int
+}
+
+func (c *codestreamParams) precinctSize(r int) (ppx, ppy int) {
+	if c.precincts == nil {
+		return 15, 15
+	}
+	return c.precincts[r][0], c.precincts[r][1]
+}
+
+// band is a sub-band of a tile-component, kind is 0 for LL, 1 for HL, 2 for
+// LH and 3 for HH
+type band struct {
+	kind           int
+	x0, y0, x1, y1 int
+	coefficients   []float64
+	quantized      []int32
+	// magnitudeBits is the number of bit planes of the band (E-2)
+	magnitudeBits int
+	exponent      int
+	mantissa      int
+}
+
+// codeBlock is a coded code-block and what its packets have sent of it
+type codeBlock struct {
+	x0, y0, x1, y1 int
+	planes, passes int
+	segments       [][]byte
+	segmentPasses  []int
+	zeroPlanes     int
+	// layerPasses is the number of passes each layer adds
+	layerPasses []int
+	firstLayer  int
+	included    bool
+	lblock      int
+	// sentBytes and sentPasses are what was sent of each segment
+	sentBytes  []int
+	sentPasses []int
+}
+
+// precinctBand are the code-blocks of a band in a precinct
+type precinctBand struct {
+	blocks        []*codeBlock
+	cw, ch        int
+	inclusion     *tagTree
+	zeroBitPlanes *tagTree
+}
+
+type precinct struct {
+	bands []*precinctBand
+}
+
+type resolution struct {
+	x0, y0, x1, y1 int
+	bands          []*band
+	precincts      []*precinct
+	// pw and ph are the number of precincts across and down
+	pw, ph   int
+	ppx, ppy int
+}
+
+type tileComponent struct {
+	x0, y0, x1, y1 int
+	resolutions    []*resolution
+	params         componentParams
+}
+
+// encode writes a codestream of the samples of each component, unsigned
+// samples run from 0 and signed ones are centred on 0
+func encode(c codestreamParams, img [][]int32) []byte {
+	out := be16(nil, markerSOC)
+
+	siz := be16(nil, 0)
+	for _, v := range []int{c.x1, c.y1, c.x0, c.y0, c.tw, c.th, c.tx0, c.ty0} {
+		siz = be32(siz, v)
+	}
+	siz = be16(siz, len(c.components))
+	for _, comp := range c.components {
+		s := byte(comp.precision - 1)
+		if comp.signed {
+			s |= 0x80
+		}
+		siz = append(siz, s, byte(comp.dx), byte(comp.dy))
+	}
+	out = appendSegment(out, markerSIZ, siz)
+	out = appendSegment(out, markerCOD, c.cod())
+
+	numX := ceilDiv(c.x1-c.tx0, c.tw)
+	numY := ceilDiv(c.y1-c.ty0, c.th)
+	var tiles [][]byte
+	// the bands of the first tile give the quantization of every tile,
+	// their exponents only depend on the band kind and precision
+	var quantized [][]*band
+	for q := 0; q < numY; q++ {
+		for p := 0; p < numX; p++ {
+			tx0 := maxInt(c.tx0+p*c.tw, c.x0)
+			ty0 := maxInt(c.ty0+q*c.th, c.y0)
+			tx1 := minInt(c.tx0+(p+1)*c.tw, c.x1)
+			ty1 := minInt(c.ty0+(q+1)*c.th, c.y1)
+			data, bands := encodeTile(c, img, tx0, ty0, tx1, ty1)
+			tiles = append(tiles, data)
+			if quantized == nil {
+				quantized = bands
+			}
+		}
+	}
+	for i := range c.components {
+		style := 0
+		if !c.reversible {
+			// scalar expounded
+			style = 2
+		}
+		b := []byte{byte(style | c.guardBits<<5)}
+		for _, bd := range quantized[i] {
+			if c.reversible {
+				b = append(b, byte(bd.exponent<<3))
+			} else {
+				b = be16(b, bd.exponent<<11|bd.mantissa)
+			}
+		}
+		if i == 0 {
+			out = appendSegment(out, markerQCD, b)
+		} else {
+			out = appendSegment(out, markerQCC, append([]byte{byte(i)}, b...))
+		}
+	}
+
+	for i, data := range tiles {
+		parts := [][]byte{data}
+		if c.splitTiles && len(data) > 4 {
+			// the decoder joins tile-parts before reading packets, so
+			// they may split a packet
+			parts = [][]byte{data[:len(data)/2], data[len(data)/2:]}
+		}
+		for k, part := range parts {
+			sot := be16(nil, markerSOT)
+			sot = be16(sot, 10)
+			sot = be16(sot, i)
+			sot = be32(sot, 12+2+len(part))
+			sot = append(sot, byte(k), byte(len(parts)))
+			out = append(out, sot...)
+			out = be16(out, markerSOD)
+			out = append(out, part...)
+		}
+	}
+	return be16(out, markerEOC)
+}
+
+// cod is the body of the COD marker
+func (c *codestreamParams) cod() []byte {
+	scod := 0
+	if c.precincts != nil {
+		scod |= 1
+	}
+	if c.sop {
+		scod |= 2
+	}
+	if c.eph {
+		scod |= 4
+	}
+	b := []byte{byte(scod), byte(c.progression)}
+	b = be16(b, c.layers)
+	mct := 0
+	if c.mct {
+		mct = 1
+	}
+	b = append(b, byte(mct))
+	comp := c.components[0]
+	transform := 0
+	if c.reversible {
+		transform = 1
+	}
+	b = append(b, byte(comp.levels), byte(comp.xcb-2), byte(comp.ycb-2), byte(c.blockStyle), byte(transform))
+	if c.precincts != nil {
+		for r := 0; r <= comp.levels; r++ {
+			ppx, ppy := c.precinctSize(r)
+			b = append(b, byte(ppx|ppy<<4))
+		}
+	}
+	return b
+}
+
+// encodeTile returns the packets of a tile and the bands of each of its
+// components
+func encodeTile(c codestreamParams, img [][]int32, tx0, ty0, tx1, ty1 int) ([]byte, [][]*band) {
+	var tcs []*tileComponent
+	samples := make([][]float64, len(c.components))
+	for i, comp := range c.components {
+		tc := &tileComponent{
+			x0: ceilDiv(tx0, comp.dx), y0: ceilDiv(ty0, comp.dy),
+			x1: ceilDiv(tx1, comp.dx), y1: ceilDiv(ty1, comp.dy),
+			params: comp,
+		}
+		width := ceilDiv(c.x1, comp.dx) - ceilDiv(c.x0, comp.dx)
+		ox, oy := ceilDiv(c.x0, comp.dx), ceilDiv(c.y0, comp.dy)
+		w := tc.x1 - tc.x0
+		s := make([]float64, w*(tc.y1-tc.y0))
+		for y := tc.y0; y < tc.y1; y++ {
+			for x := tc.x0; x < tc.x1; x++ {
+				v := float64(img[i][(y-oy)*width+x-ox])
+				if !comp.signed {
+					// DC level shift
+					v -= math.Ldexp(1, comp.precision-1)
+				}
+				s[(y-tc.y0)*w+x-tc.x0] = v
+			}
+		}
+		samples[i] = s
+		tcs = append(tcs, tc)
+	}
+	if c.mct {
+		forwardTransform(samples[0], samples[1], samples[2], c.reversible)
+	}
+
+	var bands [][]*band
+	for i, tc := range tcs {
+		bands = append(bands, tc.transform(c, samples[i]))
+		tc.partition(c)
+	}
+
+	var out []byte
+	emit := func(l, r, i, k int) {
+		out = append(out, encodePacket(c, tcs[i].resolutions[r].precincts[k], l)...)
+	}
+	progress(c, tcs, tx0, ty0, tx1, ty1, emit)
+
+	for _, tc := range tcs {
+		for _, res := range tc.resolutions {
+			for _, p := range res.precincts {
+				for _, pb := range p.bands {
+					for _, cb := range pb.blocks {
+						for s := range cb.segments {
+							if cb.sentBytes[s] != len(cb.segments[s]) {
+								panic("code-block not fully sent")
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return out, bands
+}
+
+// forwardTransform is the reversible (G.2) or irreversible (G.3)
+// component transform
+func forwardTransform(r, g, b []float64, reversible bool) {
+	for i := range r {
+		if reversible {
+			y := math.Floor((r[i] + 2*g[i] + b[i]) / 4)
+			u := b[i] - g[i]
+			v := r[i] - g[i]
+			r[i], g[i], b[i] = y, u, v
+		} else {
+			y := 0.299*r[i] + 0.587*g[i] + 0.114*b[i]
+			u := -0.16875*r[i] - 0.33126*g[i] + 0.5*b[i]
+			v := 0.5*r[i] - 0.41869*g[i] - 0.08131*b[i]
+			r[i], g[i], b[i] = y, u, v
+		}
+	}
+}
+
+// transform applies the wavelet to the samples of a tile-component and
+// quantizes its bands, which it returns from the lowest resolution up
+func (tc *tileComponent) transform(c codestreamParams, samples []float64) []*band {
+	levels := tc.params.levels
+	for r := 0; r <= levels; r++ {
+		scale := 1 << uint(levels-r)
+		tc.resolutions = append(tc.resolutions, &resolution{
+			x0: ceilDiv(tc.x0, scale), y0: ceilDiv(tc.y0, scale),
+			x1: ceilDiv(tc.x1, scale), y1: ceilDiv(tc.y1, scale),
+		})
+	}
+	cur := samples
+	for r := levels; r >= 1; r-- {
+		res := tc.resolutions[r]
+		w, h := res.x1-res.x0, res.y1-res.y0
+		col := make([]float64, h)
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				col[y] = cur[y*w+x]
+			}
+			analyze(col, res.y0, c.reversible)
+			for y := 0; y < h; y++ {
+				cur[y*w+x] = col[y]
+			}
+		}
+		for y := 0; y < h; y++ {
+			analyze(cur[y*w:(y+1)*w], res.x0, c.reversible)
+		}
+		// deinterleave the sub-bands (B-15)
+		lx, ly := res.x0&1, res.y0&1
+		subBand := func(kind, xo, yo int) *band {
+			nb := levels - r + 1
+			b := &band{
+				kind: kind,
+				x0:   ceilDiv(tc.x0-(1<<uint(nb-1))*xo, 1<<uint(nb)),
+				y0:   ceilDiv(tc.y0-(1<<uint(nb-1))*yo, 1<<uint(nb)),
+				x1:   ceilDiv(tc.x1-(1<<uint(nb-1))*xo, 1<<uint(nb)),
+				y1:   ceilDiv(tc.y1-(1<<uint(nb-1))*yo, 1<<uint(nb)),
+			}
+			bw, bh := b.x1-b.x0, b.y1-b.y0
+			b.coefficients = make([]float64, bw*bh)
+			px, py := lx, ly
+			if xo == 1 {
+				px = 1 - lx
+			}
+			if yo == 1 {
+				py = 1 - ly
+			}
+			for y := 0; y < bh; y++ {
+				for x := 0; x < bw; x++ {
+					b.coefficients[y*bw+x] = cur[(2*y+py)*w+2*x+px]
+				}
+			}
+			return b
+		}
+		ll := subBand(0, 0, 0)
+		res.bands = []*band{subBand(1, 1, 0), subBand(2, 0, 1), subBand(3, 1, 1)}
+		cur = ll.coefficients
+	}
+	r0 := tc.resolutions[0]
+	r0.bands = []*band{{x0: r0.x0, y0: r0.y0, x1: r0.x1, y1: r0.y1, coefficients: cur}}
+
+	var bands []*band
+	for _, res := range tc.resolutions {
+		bands = append(bands, res.bands...)
+	}
+	for _, b := range bands {
+		gain := []int{0, 1, 1, 2}[b.kind]
+		rb := tc.params.precision + gain
+		b.quantized = make([]int32, len(b.coefficients))
+		if c.reversible {
+			b.exponent = rb + 2
+			for i, v := range b.coefficients {
+				b.quantized[i] = int32(v)
+			}
+		} else {
+			b.exponent = rb + 4
+			b.mantissa = 1000
+			step := math.Pow(2, float64(rb-b.exponent)) * (1 + float64(b.mantissa)/2048)
+			for i, v := range b.coefficients {
+				q := math.Floor(math.Abs(v) / step)
+				if v < 0 {
+					q = -q
+				}
+				b.quantized[i] = int32(q)
+			}
+		}
+		b.magnitudeBits = c.guardBits + b.exponent - 1
+		for _, v := range b.quantized {
+			if v < 0 {
+				v = -v
+			}
+			if v >= 1<<uint(b.magnitudeBits) {
+				panic(fmt.Sprintf("coefficient %d exceeds %d bit planes", v, b.magnitudeBits))
+			}
+		}
+	}
+	return bands
+}
+
+// partition divides the resolutions of a tile-component in precincts and
+// code-blocks, codes the blocks and spreads their passes over the layers
+func (tc *tileComponent) partition(c codestreamParams) {
+	for r, res := range tc.resolutions {
+		ppx, ppy := c.precinctSize(r)
+		res.ppx, res.ppy = ppx, ppy
+		// precincts of the bands of higher resolutions are half the size
+		bpx, bpy := ppx, ppy
+		if r > 0 {
+			bpx, bpy = ppx-1, ppy-1
+		}
+		xcb, ycb := minInt(tc.params.xcb, bpx), minInt(tc.params.ycb, bpy)
+		if res.x1 > res.x0 && res.y1 > res.y0 {
+			res.pw = ceilDiv(res.x1, 1<<uint(ppx)) - res.x0>>uint(ppx)
+			res.ph = ceilDiv(res.y1, 1<<uint(ppy)) - res.y0>>uint(ppy)
+		}
+		for k := 0; k < res.pw*res.ph; k++ {
+			px := res.x0>>uint(ppx) + k%res.pw
+			py := res.y0>>uint(ppy) + k/res.pw
+			p := &precinct{}
+			for _, b := range res.bands {
+				pb := &precinctBand{}
+				bx0 := maxInt(px<<uint(bpx), b.x0)
+				by0 := maxInt(py<<uint(bpy), b.y0)
+				bx1 := minInt((px+1)<<uint(bpx), b.x1)
+				by1 := minInt((py+1)<<uint(bpy), b.y1)
+				if bx1 > bx0 && by1 > by0 {
+					pb.codeBlocks(c, b, bx0, by0, bx1, by1, xcb, ycb)
+				}
+				p.bands = append(p.bands, pb)
+			}
+			res.precincts = append(res.precincts, p)
+		}
+	}
+}
+
+// codeBlocks codes the blocks of the part of a band in a precinct
+func (pb *precinctBand) codeBlocks(c codestreamParams, b *band, bx0, by0, bx1, by1, xcb, ycb int) {
+	c0x, c0y := bx0>>uint(xcb), by0>>uint(ycb)
+	c1x, c1y := ceilDiv(bx1, 1<<uint(xcb)), ceilDiv(by1, 1<<uint(ycb))
+	pb.cw, pb.ch = c1x-c0x, c1y-c0y
+	for cy := c0y; cy < c1y; cy++ {
+		for cx := c0x; cx < c1x; cx++ {
+			cb := &codeBlock{
+				x0: maxInt(cx<<uint(xcb), bx0), y0: maxInt(cy<<uint(ycb), by0),
+				x1: minInt((cx+1)<<uint(xcb), bx1), y1: minInt((cy+1)<<uint(ycb), by1),
+				lblock: 3,
+			}
+			w, h := cb.x1-cb.x0, cb.y1-cb.y0
+			q := make([]int32, w*h)
+			stride := b.x1 - b.x0
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					q[y*w+x] = b.quantized[(cb.y0-b.y0+y)*stride+cb.x0-b.x0+x]
+				}
+			}
+			cb.planes, cb.passes, cb.segments, cb.segmentPasses = encodeBlock(q, w, h, b.kind, c.blockStyle)
+			cb.zeroPlanes = b.magnitudeBits - cb.planes
+
+			cb.layerPasses = make([]int, c.layers)
+			left := cb.passes
+			for l := 0; l < c.layers; l++ {
+				n := left
+				if l < c.layers-1 && left > 0 {
+					n = rng.Intn(left + 1)
+				}
+				cb.layerPasses[l] = n
+				left -= n
+			}
+			cb.firstLayer = c.layers
+			for l := 0; l < c.layers; l++ {
+				if cb.layerPasses[l] > 0 {
+					cb.firstLayer = l
+					break
+				}
+			}
+			cb.sentBytes = make([]int, len(cb.segments))
+			cb.sentPasses = make([]int, len(cb.segments))
+			pb.blocks = append(pb.blocks, cb)
+		}
+	}
+	inclusion := make([]int, len(pb.blocks))
+	zeroPlanes := make([]int, len(pb.blocks))
+	for i, cb := range pb.blocks {
+		inclusion[i] = cb.firstLayer
+		zeroPlanes[i] = cb.zeroPlanes
+	}
+	pb.inclusion = newTagTree(pb.cw, pb.ch, inclusion)
+	pb.zeroBitPlanes = newTagTree(pb.cw, pb.ch, zeroPlanes)
+}
+
+// progress calls emit with the layer, resolution, component and precinct of
+// every packet of a tile in the progression order (B.12)
+func progress(c codestreamParams, tcs []*tileComponent, tx0, ty0, tx1, ty1 int, emit func(l, r, i, k int)) {
+	maxR := 0
+	for _, tc := range tcs {
+		maxR = maxInt(maxR, len(tc.resolutions))
+	}
+	precincts := func(l, r int) {
+		for i, tc := range tcs {
+			if r < len(tc.resolutions) {
+				for k := range tc.resolutions[r].precincts {
+					emit(l, r, i, k)
+				}
+			}
+		}
+	}
+	switch c.progression {
+	case progressionLRCP:
+		for l := 0; l < c.layers; l++ {
+			for r := 0; r < maxR; r++ {
+				precincts(l, r)
+			}
+		}
+		return
+	case progressionRLCP:
+		for r := 0; r < maxR; r++ {
+			for l := 0; l < c.layers; l++ {
+				precincts(l, r)
+			}
+		}
+		return
+	}
+
+	// position driven progressions step through the reference grid
+	xstep, ystep := 1<<30, 1<<30
+	for i, tc := range tcs {
+		comp := c.components[i]
+		nl := len(tc.resolutions) - 1
+		for r := range tc.resolutions {
+			ppx, ppy := c.precinctSize(r)
+			xstep = minInt(xstep, comp.dx<<uint(ppx+nl-r))
+			ystep = minInt(ystep, comp.dy<<uint(ppy+nl-r))
+		}
+	}
+	var ys, xs []int
+	for y := ty0; y < ty1; y += ystep - y%ystep {
+		ys = append(ys, y)
+	}
+	for x := tx0; x < tx1; x += xstep - x%xstep {
+		xs = append(xs, x)
+	}
+	// layers emits every layer of the precinct of resolution r of
+	// component i starting at x, y if there is one
+	layers := func(r, i, x, y int) {
+		tc := tcs[i]
+		nl := len(tc.resolutions) - 1
+		if r > nl {
+			return
+		}
+		res := tc.resolutions[r]
+		if res.pw == 0 || res.ph == 0 {
+			return
+		}
+		comp := c.components[i]
+		starts := func(pos, sub, pp, r0, t0 int) bool {
+			return pos%(sub<<uint(pp+nl-r)) == 0 || (pos == t0 && (r0<<uint(nl-r))%(1<<uint(pp+nl-r)) != 0)
+		}
+		if !starts(y, comp.dy, res.ppy, res.y0, ty0) || !starts(x, comp.dx, res.ppx, res.x0, tx0) {
+			return
+		}
+		kx := ceilDiv(x, comp.dx<<uint(nl-r))>>uint(res.ppx) - res.x0>>uint(res.ppx)
+		ky := ceilDiv(y, comp.dy<<uint(nl-r))>>uint(res.ppy) - res.y0>>uint(res.ppy)
+		for l := 0; l < c.layers; l++ {
+			emit(l, r, i, kx+ky*res.pw)
+		}
+	}
+	switch c.progression {
+	case progressionRPCL:
+		for r := 0; r < maxR; r++ {
+			for _, y := range ys {
+				for _, x := range xs {
+					for i := range tcs {
+						layers(r, i, x, y)
+					}
+				}
+			}
+		}
+	case progressionPCRL:
+		for _, y := range ys {
+			for _, x := range xs {
+				for i := range tcs {
+					for r := 0; r < maxR; r++ {
+						layers(r, i, x, y)
+					}
+				}
+			}
+		}
+	case progressionCPRL:
+		for i := range tcs {
+			for _, y := range ys {
+				for _, x := range xs {
+					for r := 0; r < maxR; r++ {
+						layers(r, i, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+// encodePacket writes the packet of layer l of a precinct (B.10)
+func encodePacket(c codestreamParams, p *precinct, l int) []byte {
+	var out []byte
+	if c.sop {
+		out = be16(out, markerSOP)
+		out = be16(out, 4)
+		out = be16(out, 0)
+	}
+	w := newHeaderWriter()
+	empty := true
+	for _, pb := range p.bands {
+		for _, cb := range pb.blocks {
+			if cb.layerPasses[l] > 0 {
+				empty = false
+			}
+		}
+	}
+	var body []byte
+	// packets without new passes are sometimes written as empty and
+	// sometimes with every block not included
+	if empty && rng.Intn(2) == 0 {
+		w.put(0)
+	} else {
+		w.put(1)
+		for _, pb := range p.bands {
+			for i, cb := range pb.blocks {
+				body = append(body, cb.encodeHeader(w, pb, i, l)...)
+			}
+		}
+	}
+	out = append(out, w.flush()...)
+	if c.eph {
+		out = be16(out, markerEPH)
+	}
+	return append(out, body...)
+}
+
+// encodeHeader writes what a packet header says of the block i of a band in
+// layer l and returns the codeword bytes it adds
+func (cb *codeBlock) encodeHeader(w *headerWriter, pb *precinctBand, i, l int) []byte {
+	n := cb.layerPasses[l]
+	if !cb.included {
+		pb.inclusion.encode(w, i, l+1)
+		if n == 0 {
+			return nil
+		}
+		pb.zeroBitPlanes.encode(w, i, cb.zeroPlanes+1)
+		cb.included = true
+	} else {
+		if n == 0 {
+			w.put(0)
+			return nil
+		}
+		w.put(1)
+	}
+
+	// number of passes (table B.4)
+	switch {
+	case n == 1:
+		w.put(0)
+	case n == 2:
+		w.putBits(2, 2)
+	case n <= 5:
+		w.putBits(3, 2)
+		w.putBits(n-3, 2)
+	case n <= 36:
+		w.putBits(15, 4)
+		w.putBits(n-6, 5)
+	default:
+		w.putBits(15, 4)
+		w.putBits(31, 5)
+		w.putBits(n-37, 7)
+	}
+
+	// the passes may end in several segments, each with its length
+	type contribution struct{ segment, passes, length int }
+	var contributions []contribution
+	for left := n; left > 0; {
+		s := 0
+		for s < len(cb.segments) && cb.sentPasses[s] == cb.segmentPasses[s] {
+			s++
+		}
+		take := minInt(left, cb.segmentPasses[s]-cb.sentPasses[s])
+		cb.sentPasses[s] += take
+		left -= take
+		var length int
+		if cb.sentPasses[s] == cb.segmentPasses[s] {
+			length = len(cb.segments[s]) - cb.sentBytes[s]
+		} else {
+			// a segment spread over layers splits its bytes anywhere
+			length = rng.Intn(len(cb.segments[s]) - cb.sentBytes[s] + 1)
+		}
+		contributions = append(contributions, contribution{s, take, length})
+	}
+	need := 0
+	for _, ct := range contributions {
+		need = maxInt(need, bitLength(ct.length)-floorLog2(ct.passes))
+	}
+	for cb.lblock < need {
+		w.put(1)
+		cb.lblock++
+	}
+	w.put(0)
+	var body []byte
+	for _, ct := range contributions {
+		w.putBits(ct.length, cb.lblock+floorLog2(ct.passes))
+		body = append(body, cb.segments[ct.segment][cb.sentBytes[ct.segment]:cb.sentBytes[ct.segment]+ct.length]...)
+		cb.sentBytes[ct.segment] += ct.length
+	}
+	return body
+}
+
+// headerWriter writes the bits of packet headers, with a 0 bit stuffed
+// after every 0xff
+type headerWriter struct {
+	out []byte
+	cur uint32
+	n   uint
+	max uint
+}
+
+func newHeaderWriter() *headerWriter { return &headerWriter{max: 8} }
+
+func (w *headerWriter) put(b int) {
+	w.cur = w.cur<<1 | uint32(b)
+	w.n++
+	if w.n == w.max {
+		v := byte(w.cur)
+		w.out = append(w.out, v)
+		w.cur, w.n = 0, 0
+		w.max = 8
+		if v == 0xff {
+			w.max = 7
+		}
+	}
+}
+
+func (w *headerWriter) putBits(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.put((v >> uint(i)) & 1)
+	}
+}
+
+func (w *headerWriter) flush() []byte {
+	for w.n != 0 {
+		w.put(0)
+	}
+	if len(w.out) > 0 && w.out[len(w.out)-1] == 0xff {
+		w.out = append(w.out, 0)
+	}
+	return w.out
+}
+
+// tagTreeNode is a node of a tag tree, low is the value known to the
+// decoder so far
+type tagTreeNode struct {
+	value, low int
+	known      bool
+	parent     *tagTreeNode
+}
+
+// tagTree codes a value for each leaf of a grid (B.10.2)
+type tagTree struct {
+	leaves []*tagTreeNode
+}
+
+func newTagTree(w, h int, values []int) *tagTree {
+	cur := make([]*tagTreeNode, w*h)
+	for i := range cur {
+		cur[i] = &tagTreeNode{value: values[i]}
+	}
+	t := &tagTree{leaves: cur}
+	for cw, ch := w, h; cw != 1 || ch != 1; {
+		nw, nh := (cw+1)/2, (ch+1)/2
+		next := make([]*tagTreeNode, nw*nh)
+		for i := range next {
+			next[i] = &tagTreeNode{value: 1 << 30}
+		}
+		for y := 0; y < ch; y++ {
+			for x := 0; x < cw; x++ {
+				n := cur[y*cw+x]
+				p := next[(y/2)*nw+x/2]
+				n.parent = p
+				if n.value < p.value {
+					p.value = n.value
+				}
+			}
+		}
+		cur, cw, ch = next, nw, nh
+	}
+	return t
+}
+
+// encode writes what the decoder needs to know whether leaf i is below the
+// threshold
+func (t *tagTree) encode(w *headerWriter, i, threshold int) {
+	var path []*tagTreeNode
+	for n := t.leaves[i]; n != nil; n = n.parent {
+		path = append(path, n)
+	}
+	low := 0
+	for k := len(path) - 1; k >= 0; k-- {
+		n := path[k]
+		if low > n.low {
+			n.low = low
+		} else {
+			low = n.low
+		}
+		for low < threshold {
+			if low >= n.value {
+				if !n.known {
+					w.put(1)
+					n.known = true
+				}
+				break
+			}
+			w.put(0)
+			low++
+		}
+		n.low = low
+	}
+}
+
+func appendSegment(b []byte, marker int, body []byte) []byte {
+	b = be16(b, marker)
+	b = be16(b, len(body)+2)
+	return append(b, body...)
+}
+
+func be16(b []byte, v int) []byte { return append(b, byte(v>>8), byte(v)) }
+
+func be32(b []byte, v int) []byte {
+	var t [4]byte
+	binary.BigEndian.PutUint32(t[:], uint32(v))
+	return append(b, t[:]...)
+}
+
+func bitLength(v int) int {
+	n := 0
+	for ; v > 0; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+func floorLog2(v int) int { return bitLength(v) - 1 }
+
+func ceilDiv(a, b int) int { return (a + b - 1) / b }
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
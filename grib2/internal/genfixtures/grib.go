@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+)
+
+// message is a GRIB2 message split in its sections 1 to 7
+type message struct {
+	head     []byte
+	sections [][]byte
+	numbers  []int
+}
+
+func splitMessage(m []byte) message {
+	out := message{head: m[:16]}
+	for pos := 16; pos < len(m)-4; {
+		l := int(binary.BigEndian.Uint32(m[pos:]))
+		out.sections = append(out.sections, m[pos:pos+l])
+		out.numbers = append(out.numbers, int(m[pos+4]))
+		pos += l
+	}
+	return out
+}
+
+// section returns the first section numbered n
+func (m message) section(n int) []byte {
+	for i, s := range m.sections {
+		if m.numbers[i] == n {
+			return s
+		}
+	}
+	return nil
+}
+
+// repack returns the message with its sections 5 and 7 replaced
+func (m message) repack(s5, s7 []byte) []byte {
+	out := append([]byte(nil), m.head...)
+	for i, s := range m.sections {
+		switch m.numbers[i] {
+		case 5:
+			s = s5
+		case 7:
+			s = s7
+		}
+		out = append(out, s...)
+	}
+	out = append(out, "7777"...)
+	binary.BigEndian.PutUint64(out[8:], uint64(len(out)))
+	return out
+}
+
+// retemplate returns section 5 with template tmpl, keeping the reference,
+// scale factors and bits of the simple packing and adding extra
+func retemplate(s5 []byte, tmpl int, extra []byte) []byte {
+	ns5 := append(append([]byte(nil), s5[:21]...), extra...)
+	binary.BigEndian.PutUint32(ns5, uint32(len(ns5)))
+	binary.BigEndian.PutUint16(ns5[9:], uint16(tmpl))
+	return ns5
+}
+
+func section7(data []byte) []byte {
+	s := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(s, uint32(5+len(data)))
+	s[4] = 7
+	return append(s, data...)
+}
+
+// unpackSimple returns the packed integers of a message with simple packing
+func unpackSimple(s5, s7 []byte, n int) []int64 {
+	bits := int(s5[19])
+	xs := make([]int64, n)
+	for i := range xs {
+		var v uint64
+		for b := 0; b < bits; b++ {
+			p := i*bits + b
+			v = v<<1 | uint64(s7[5+p/8]>>(7-uint(p%8))&1)
+		}
+		xs[i] = int64(v)
+	}
+	return xs
+}
+
+// bitWriter writes big endian fields of any width
+type bitWriter struct {
+	out []byte
+	cur uint64
+	n   uint
+}
+
+func (w *bitWriter) put(v uint64, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | (v>>uint(i))&1
+		w.n++
+		if w.n == 8 {
+			w.out = append(w.out, byte(w.cur))
+			w.cur, w.n = 0, 0
+		}
+	}
+}
+
+func (w *bitWriter) align() {
+	for w.n != 0 {
+		w.put(0, 1)
+	}
+}
+
+func bitsFor(v int64) int {
+	n := 0
+	for ; v > 0; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+// signMagnitude is v as the sign and magnitude integers of templates 5.3
+func signMagnitude(v int64, octets int) uint64 {
+	if v < 0 {
+		return uint64(-v) | 1<<uint(octets*8-1)
+	}
+	return uint64(v)
+}
+
+// missing says whether point i is missing with the missing value management
+// mm, 0 if present, 1 for the primary and 2 for the secondary missing value
+func missing(i, mm int) int {
+	if mm == 0 {
+		return 0
+	}
+	if i >= 3000 && i < 3200 {
+		return 1
+	}
+	if (i*7919)%23 == 0 {
+		return 1
+	}
+	if mm == 2 && ((i*7919)%23 == 1 || (i >= 5000 && i < 5100)) {
+		return 2
+	}
+	return 0
+}
+
+// complexPack packs the integers xs with complex packing (template 5.2) or,
+// if order is 1 or 2, with spatial differencing (template 5.3), marking
+// points missing with the management mm. The groups have lengths drawn
+// from r and the returned sections 5 and 7 use the reference and scale
+// factors of s5
+func complexPack(s5 []byte, xs []int64, order, mm int, r *rand.Rand) ([]byte, []byte) {
+	n := len(xs)
+	miss := make([]int, n)
+	var present []int
+	for i := range miss {
+		miss[i] = missing(i, mm)
+		if miss[i] == 0 {
+			present = append(present, i)
+		}
+	}
+
+	// spatial differencing runs over the values present only
+	vals := make([]int64, n)
+	var firsts []int64
+	var minDiff int64
+	if order > 0 {
+		d := make([]int64, len(present))
+		for k := order; k < len(present); k++ {
+			a, b := xs[present[k]], xs[present[k-1]]
+			if order == 1 {
+				d[k] = a - b
+			} else {
+				d[k] = a - 2*b + xs[present[k-2]]
+			}
+		}
+		minDiff = d[order]
+		for k := order; k < len(present); k++ {
+			if d[k] < minDiff {
+				minDiff = d[k]
+			}
+		}
+		for k, i := range present {
+			if k < order {
+				firsts = append(firsts, xs[i])
+			} else {
+				vals[i] = d[k] - minDiff
+			}
+		}
+	} else {
+		copy(vals, xs)
+	}
+
+	var lengths []int
+	for left := n; left > 0; {
+		l := 1 + r.Intn(60)
+		if l > left {
+			l = left
+		}
+		lengths = append(lengths, l)
+		left -= l
+	}
+	ng := len(lengths)
+	refs := make([]int64, ng)
+	widths := make([]int, ng)
+	// allMissing is 1 or 2 for groups of only primary or secondary missing
+	// values, which are coded by their reference alone
+	allMissing := make([]int, ng)
+	var maxRef int64
+	pos := 0
+	for g, l := range lengths {
+		var lo, hi int64
+		have := false
+		var count [3]int
+		for i := pos; i < pos+l; i++ {
+			count[miss[i]]++
+			if miss[i] != 0 {
+				continue
+			}
+			if !have || vals[i] < lo {
+				lo = vals[i]
+			}
+			if !have || vals[i] > hi {
+				hi = vals[i]
+			}
+			have = true
+		}
+		switch {
+		case !have && count[2] == 0:
+			allMissing[g] = 1
+		case !have && count[1] == 0:
+			allMissing[g] = 2
+		case !have:
+			refs[g], widths[g] = 0, 2
+		case count[1]+count[2] == 0 && lo == hi:
+			refs[g], widths[g] = lo, 0
+		case mm == 0:
+			refs[g], widths[g] = lo, bitsFor(hi-lo)
+		default:
+			refs[g], widths[g] = lo, bitsFor(hi-lo+int64(mm))
+		}
+		if allMissing[g] == 0 && refs[g] > maxRef {
+			maxRef = refs[g]
+		}
+		pos += l
+	}
+	bits := bitsFor(maxRef + int64(mm))
+	if bits == 0 {
+		bits = 1
+	}
+	top := int64(1)<<uint(bits) - 1
+	for g := range refs {
+		switch allMissing[g] {
+		case 1:
+			refs[g] = top
+		case 2:
+			refs[g] = top - 1
+		}
+	}
+	minWidth, maxWidth := widths[0], widths[0]
+	for _, w := range widths {
+		if w < minWidth {
+			minWidth = w
+		}
+		if w > maxWidth {
+			maxWidth = w
+		}
+	}
+	// the last group has its own length
+	minLength, maxLength := lengths[0], lengths[0]
+	for _, l := range lengths[:ng-1] {
+		if l < minLength {
+			minLength = l
+		}
+		if l > maxLength {
+			maxLength = l
+		}
+	}
+	widthBits := bitsFor(int64(maxWidth - minWidth))
+	lengthBits := bitsFor(int64(maxLength - minLength))
+
+	var w bitWriter
+	octets := 0
+	if order > 0 {
+		m := minDiff
+		if m < 0 {
+			m = -m
+		}
+		for _, v := range firsts {
+			if v > m {
+				m = v
+			}
+		}
+		octets = (bitsFor(m) + 1 + 7) / 8
+		for _, v := range firsts {
+			w.put(signMagnitude(v, octets), uint(octets*8))
+		}
+		w.put(signMagnitude(minDiff, octets), uint(octets*8))
+	}
+	for _, v := range refs {
+		w.put(uint64(v), uint(bits))
+	}
+	w.align()
+	for _, v := range widths {
+		w.put(uint64(v-minWidth), uint(widthBits))
+	}
+	w.align()
+	for _, l := range lengths {
+		w.put(uint64(l-minLength), uint(lengthBits))
+	}
+	w.align()
+	pos = 0
+	for g, l := range lengths {
+		if widths[g] > 0 {
+			top := int64(1)<<uint(widths[g]) - 1
+			for i := pos; i < pos+l; i++ {
+				switch miss[i] {
+				case 1:
+					w.put(uint64(top), uint(widths[g]))
+				case 2:
+					w.put(uint64(top-1), uint(widths[g]))
+				default:
+					w.put(uint64(vals[i]-refs[g]), uint(widths[g]))
+				}
+			}
+		}
+		pos += l
+	}
+	w.align()
+
+	tmpl, size := 2, 47
+	if order > 0 {
+		tmpl, size = 3, 49
+	}
+	ns5 := make([]byte, size)
+	copy(ns5, s5[:21])
+	binary.BigEndian.PutUint32(ns5, uint32(size))
+	binary.BigEndian.PutUint16(ns5[9:], uint16(tmpl))
+	ns5[19] = byte(bits)
+	// general group splitting
+	ns5[21] = 1
+	ns5[22] = byte(mm)
+	binary.BigEndian.PutUint32(ns5[23:], math.Float32bits(9.999e20))
+	binary.BigEndian.PutUint32(ns5[27:], math.Float32bits(9.999e20))
+	binary.BigEndian.PutUint32(ns5[31:], uint32(ng))
+	ns5[35] = byte(minWidth)
+	ns5[36] = byte(widthBits)
+	binary.BigEndian.PutUint32(ns5[37:], uint32(minLength))
+	ns5[41] = 1
+	binary.BigEndian.PutUint32(ns5[42:], uint32(lengths[ng-1]))
+	ns5[46] = byte(lengthBits)
+	if order > 0 {
+		ns5[47] = byte(order)
+		ns5[48] = byte(octets)
+	}
+	return ns5, section7(w.out)
+}
@@ -0,0 +1,223 @@
+// Command genfixtures writes the test fixtures of the grib2 and jpeg2000
+// packages that no producer we can run writes for us: the codestreams of
+// grib2/jpeg2000/testdata and the complex.grb2, jpeg2000.grb2 and png.grb2
+// messages of grib2/testdata, which repack the first field of the GFS
+// subset in the templates 5.2, 5.3, 5.40 and 5.41.
+//
+// Its JPEG 2000 encoder follows ITU-T T.800 directly and shares no code with
+// the decoder. Every codestream is decoded after it is written, so the run
+// fails if the decoder disagrees. Pass splits are drawn from seeded random
+// sources, so the output only changes with the code:
+//
+//	go run ./grib2/internal/genfixtures
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"path/filepath"
+
+	"github.com/azillion/nimbus/grib2"
+	"github.com/azillion/nimbus/grib2/jpeg2000"
+)
+
+func main() {
+	gribDir := flag.String("grib", "grib2/testdata", "directory of the GRIB2 fixtures")
+	j2kDir := flag.String("jpeg2000", "grib2/jpeg2000/testdata", "directory of the codestream fixtures")
+	source := flag.String("source", "grib2/testdata/gfs.t00z.pgrb2.0p25.f001", "GRIB2 file whose first field is repacked")
+	flag.Parse()
+	log.SetFlags(0)
+
+	if err := writeCodestreams(*j2kDir); err != nil {
+		log.Fatal(err)
+	}
+	if err := writeMessages(*source, *gribDir); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// noisySample and smoothSample are the samples of the codestreams, the
+// jpeg2000 tests compute them again
+func noisySample(x, y, c, prec int) int32 {
+	max := 1<<uint(prec) - 1
+	return int32((x*x*31 + y*17 + x*y*7 + c*1013) % (max + 1))
+}
+
+func smoothSample(x, y, c, prec int) int32 {
+	max := float64(int(1)<<uint(prec) - 1)
+	return int32((math.Sin(float64(x)/7)*math.Cos(float64(y+c)/5) + 1) / 2 * max)
+}
+
+var codestreams = []struct {
+	name   string
+	params codestreamParams
+	smooth bool
+}{
+	{"lossless.j2k", codestreamParams{
+		x1: 61, y1: 47, tw: 61, th: 47, reversible: true, layers: 1, guardBits: 2,
+		components: []componentParams{{precision: 12, dx: 1, dy: 1, levels: 3, xcb: 5, ycb: 5}},
+	}, false},
+	{"tiles.j2k", codestreamParams{
+		x1: 90, y1: 70, tw: 32, th: 24, reversible: true, layers: 3, progression: progressionRPCL, guardBits: 1,
+		blockStyle: styleBypass | styleTermAll | styleReset, sop: true, eph: true, splitTiles: true,
+		precincts:  [][2]int{{4, 4}, {4, 4}, {5, 5}},
+		components: []componentParams{{precision: 16, dx: 1, dy: 1, levels: 2, xcb: 4, ycb: 3}},
+	}, false},
+	{"signed.j2k", codestreamParams{
+		x0: 5, y0: 3, x1: 50, y1: 40, tx0: 2, ty0: 1, tw: 20, th: 20, reversible: true, layers: 2,
+		progression: progressionCPRL, guardBits: 1,
+		blockStyle: styleVerticalCause | styleSegSymbols | stylePredictable,
+		components: []componentParams{{precision: 10, signed: true, dx: 1, dy: 1, levels: 2, xcb: 4, ycb: 4}},
+	}, false},
+	{"irreversible.j2k", codestreamParams{
+		x1: 64, y1: 64, tw: 64, th: 64, layers: 1, guardBits: 3,
+		components: []componentParams{{precision: 8, dx: 1, dy: 1, levels: 4, xcb: 4, ycb: 4}},
+	}, true},
+	{"rct.j2k", codestreamParams{
+		x1: 33, y1: 29, tw: 33, th: 29, reversible: true, mct: true, layers: 1, progression: progressionRLCP, guardBits: 2,
+		components: []componentParams{
+			{precision: 8, dx: 1, dy: 1, levels: 3, xcb: 4, ycb: 4},
+			{precision: 8, dx: 1, dy: 1, levels: 3, xcb: 4, ycb: 4},
+			{precision: 8, dx: 1, dy: 1, levels: 3, xcb: 4, ycb: 4},
+		},
+	}, false},
+}
+
+func writeCodestreams(dir string) error {
+	for _, f := range codestreams {
+		rng = rand.New(rand.NewSource(1))
+		c := f.params
+		img := make([][]int32, len(c.components))
+		for i, comp := range c.components {
+			for y := 0; y < c.y1-c.y0; y++ {
+				for x := 0; x < c.x1-c.x0; x++ {
+					var v int32
+					if f.smooth {
+						v = smoothSample(x, y, i, comp.precision)
+					} else {
+						v = noisySample(x, y, i, comp.precision)
+					}
+					if comp.signed {
+						v -= 1 << uint(comp.precision-1)
+					}
+					img[i] = append(img[i], v)
+				}
+			}
+		}
+		data := encode(c, img)
+		maxErr, err := decodeError(data, img)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.name, err)
+		}
+		log.Printf("%s: %d octets, largest error %v", f.name, len(data), maxErr)
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeError decodes a codestream and returns the largest difference from
+// the samples it was written from
+func decodeError(data []byte, img [][]int32) (float64, error) {
+	dec, err := jpeg2000.Decode(data)
+	if err != nil {
+		return 0, err
+	}
+	maxErr := 0.0
+	for i := range img {
+		if len(dec.Components[i].Data) != len(img[i]) {
+			return 0, fmt.Errorf("component %d decodes %d samples, want %d", i, len(dec.Components[i].Data), len(img[i]))
+		}
+		for k, v := range img[i] {
+			maxErr = math.Max(maxErr, math.Abs(float64(v-dec.Components[i].Data[k])))
+		}
+	}
+	return maxErr, nil
+}
+
+// writeMessages repacks the first field of source, which must use simple
+// packing, in the templates the grib2 tests read
+func writeMessages(source, dir string) error {
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	messages, err := grib2.Read(data)
+	if err != nil {
+		return err
+	}
+	m := messages[0]
+	msg := splitMessage(data[m.Offset : m.Offset+m.Length])
+	s5 := msg.section(5)
+	f := m.Fields[0]
+	ni, nj := f.Grid.Ni, f.Grid.Nj
+	bits := int(s5[19])
+	xs := unpackSimple(s5, msg.section(7), f.Representation.NumValues)
+
+	// complex packing without and with spatial differencing and missing
+	// values, the groups of every message come from one source
+	r := rand.New(rand.NewSource(7))
+	var complexOut []byte
+	for _, v := range [][2]int{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {2, 2}} {
+		ns5, ns7 := complexPack(s5, xs, v[0], v[1], r)
+		complexOut = append(complexOut, msg.repack(ns5, ns7)...)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "complex.grb2"), complexOut, 0644); err != nil {
+		return err
+	}
+
+	// JPEG 2000 as NCEP writes it, one tile and layer, and with tiles,
+	// layers, precincts and the bypass style
+	img := make([]int32, len(xs))
+	for i, v := range xs {
+		img[i] = int32(v)
+	}
+	var j2kOut []byte
+	for i, c := range []codestreamParams{
+		{tw: ni, th: nj, layers: 1, guardBits: 2},
+		{tw: 64, th: 48, layers: 3, progression: progressionRPCL, blockStyle: styleBypass | styleTermAll, guardBits: 1,
+			precincts: [][2]int{{7, 7}, {8, 8}, {8, 8}, {8, 8}, {8, 8}, {8, 8}}},
+	} {
+		c.x1, c.y1 = ni, nj
+		c.reversible = true
+		c.components = []componentParams{{precision: bits, dx: 1, dy: 1, levels: 5, xcb: 6, ycb: 6}}
+		rng = rand.New(rand.NewSource(int64(i)))
+		cs := encode(c, [][]int32{img})
+		if maxErr, err := decodeError(cs, [][]int32{img}); err != nil || maxErr != 0 {
+			return fmt.Errorf("jpeg2000.grb2 message %d: error %v, largest difference %v", i+1, err, maxErr)
+		}
+		// lossless, no compression ratio
+		j2kOut = append(j2kOut, msg.repack(retemplate(s5, 40, []byte{0, 255}), section7(cs))...)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "jpeg2000.grb2"), j2kOut, 0644); err != nil {
+		return err
+	}
+
+	// PNG in 16 bit grey and in 24 bits spread over red, green and blue
+	gray := image.NewGray16(image.Rect(0, 0, ni, nj))
+	rgba := image.NewRGBA(image.Rect(0, 0, ni, nj))
+	for i, v := range xs {
+		gray.SetGray16(i%ni, i/ni, color.Gray16{uint16(v)})
+		rgba.SetRGBA(i%ni, i/ni, color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255})
+	}
+	var pngOut []byte
+	for k, im := range []image.Image{gray, rgba} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, im); err != nil {
+			return err
+		}
+		ns5 := retemplate(s5, 41, nil)
+		ns5[19] = []byte{16, 24}[k]
+		pngOut = append(pngOut, msg.repack(ns5, section7(buf.Bytes()))...)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "png.grb2"), pngOut, 0644)
+}
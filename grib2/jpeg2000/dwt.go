@@ -0,0 +1,306 @@
+package jpeg2000
+
+import (
+	"fmt"
+	"math"
+)
+
+// lifting coefficients of the irreversible 9-7 filter, table F.4
+const (
+	alpha = -1.586134342059924
+	beta  = -0.052980118572961
+	gamma = 0.882911075530934
+	delta = 0.443506852043971
+	kappa = 1.230174104914001
+)
+
+func (d *decoder) decodeTile(t *tile, img *Image) error {
+	s := &d.siz
+	tilesX := ceilDiv(s.x1-s.tx0, s.tw)
+	tilesY := ceilDiv(s.y1-s.ty0, s.th)
+	if t.index >= tilesX*tilesY {
+		return fmt.Errorf("jpeg2000: tile %d is outside the image", t.index)
+	}
+	p, q := t.index%tilesX, t.index/tilesX
+	tx0 := maxInt(s.tx0+p*s.tw, s.x0)
+	ty0 := maxInt(s.ty0+q*s.th, s.y0)
+	tx1 := minInt(s.tx0+(p+1)*s.tw, s.x1)
+	ty1 := minInt(s.ty0+(q+1)*s.th, s.y1)
+
+	tcs := make([]*tileComponent, len(s.comps))
+	for c, comp := range s.comps {
+		tcs[c] = newTileComponent(comp, d.codingStyle(t, c), d.quantization(t, c), tx0, ty0, tx1, ty1)
+	}
+
+	// the progression, layers and multiple component transform come from the
+	// COD marker
+	cod := d.main.cod
+	if t.header.hasCOD {
+		cod = t.header.cod
+	}
+	list, err := packets(tcs, cod)
+	if err != nil {
+		return err
+	}
+	pos := 0
+	for _, pk := range list {
+		if pos >= len(t.data) {
+			// truncated codestreams decode with the layers they have
+			break
+		}
+		tc := tcs[pk.comp]
+		pos, err = readPacket(t.data, pos, tc.resolutions[pk.res].precincts[pk.precinct], pk.layer, tc.cs)
+		if err != nil {
+			return err
+		}
+	}
+
+	samples := make([][]float64, len(tcs))
+	for c, tc := range tcs {
+		if samples[c], err = tc.decode(s.comps[c]); err != nil {
+			return err
+		}
+	}
+	if cod.mct == 1 && len(tcs) >= 3 {
+		inverseMCT(samples, tcs[0].cs.reversible)
+	}
+
+	for c, tc := range tcs {
+		comp := img.Components[c]
+		offset, lo, hi := 0.0, -math.Pow(2, float64(comp.Precision-1)), math.Pow(2, float64(comp.Precision-1))-1
+		if !comp.Signed {
+			offset, lo, hi = -lo, 0, math.Pow(2, float64(comp.Precision))-1
+		}
+		cx0, cy0 := ceilDiv(s.x0, comp.Dx), ceilDiv(s.y0, comp.Dy)
+		w := tc.x1 - tc.x0
+		for y := tc.y0; y < tc.y1; y++ {
+			for x := tc.x0; x < tc.x1; x++ {
+				v := math.Floor(samples[c][(y-tc.y0)*w+x-tc.x0]+0.5) + offset
+				if v < lo {
+					v = lo
+				} else if v > hi {
+					v = hi
+				}
+				comp.Data[(y-cy0)*comp.Width+x-cx0] = int32(v)
+			}
+		}
+	}
+	return nil
+}
+
+// decode decodes the code-blocks of a tile-component and transforms them
+// back to samples
+func (tc *tileComponent) decode(comp *Component) ([]float64, error) {
+	nl := tc.cs.levels
+	for r, res := range tc.resolutions {
+		for i, b := range res.bands {
+			index := 0
+			if r > 0 {
+				index = 3*(r-1) + i + 1
+			}
+			nb := nl
+			if r > 0 {
+				nb = nl - r + 1
+			}
+			mb, step, err := tc.q.band(index, nb, nl, b.kind, comp.Precision, tc.cs.reversible)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, p := range res.precincts {
+				for _, cb := range p.bands[i].blocks {
+					if err := tc.decodeBlock(b, cb, mb, step); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return tc.inverseDWT(), nil
+}
+
+// band returns the number of magnitude bit planes and the quantization step
+// of a subband, equations E-2 and E-3
+func (q *quantization) band(index, nb, nl, kind, precision int, reversible bool) (int, float64, error) {
+	var exponent, mantissa int
+	if q.style == 1 {
+		// derived quantization scales the values of the LL band
+		exponent = q.exponents[0] - nl + nb
+		mantissa = q.mantissas[0]
+	} else {
+		if index >= len(q.exponents) {
+			return 0, 0, fmt.Errorf("jpeg2000: no quantization for subband %d", index)
+		}
+		exponent, mantissa = q.exponents[index], q.mantissas[index]
+	}
+	mb := q.guardBits + exponent - 1
+	if reversible {
+		return mb, 1, nil
+	}
+
+	gain := 0
+	switch kind {
+	case bandHL, bandLH:
+		gain = 1
+	case bandHH:
+		gain = 2
+	}
+	step := math.Pow(2, float64(precision+gain-exponent)) * (1 + float64(mantissa)/2048)
+	return mb, step, nil
+}
+
+// decodeBlock decodes a code-block into the coefficients of its subband
+func (tc *tileComponent) decodeBlock(b *band, cb *codeBlock, mb int, step float64) error {
+	t, last, err := decodeBlock(cb, b.kind, tc.cs.blockStyle, mb)
+	if err != nil {
+		return err
+	}
+	// values of irreversible bands are reconstructed in the middle of the
+	// interval the undecoded bit planes leave
+	half := 0.0
+	if !tc.cs.reversible {
+		half = math.Ldexp(0.5, last)
+	}
+	w := b.width()
+	for y := 0; y < t.h; y++ {
+		for x := 0; x < t.w; x++ {
+			m := t.mag[y*t.w+x]
+			if m == 0 {
+				continue
+			}
+			v := (float64(m) + half) * step
+			if t.flags[t.index(x, y)]&flagNegative != 0 {
+				v = -v
+			}
+			b.coeffs[(cb.y0-b.y0+y)*w+cb.x0-b.x0+x] = v
+		}
+	}
+	return nil
+}
+
+// inverseDWT combines the subbands of every resolution, annex F
+func (tc *tileComponent) inverseDWT() []float64 {
+	res := tc.resolutions[0]
+	ll := res.bands[0].coeffs
+	for r := 1; r < len(tc.resolutions); r++ {
+		res = tc.resolutions[r]
+		u0, v0 := res.x0, res.y0
+		w, h := res.x1-res.x0, res.y1-res.y0
+		a := make([]float64, w*h)
+
+		// interleave the subbands, low-pass samples sit at even coordinates
+		hl, lh, hh := res.bands[0], res.bands[1], res.bands[2]
+		lw := lh.width()
+		put := func(coeffs []float64, bw, bh, xo, yo int) {
+			for y := 0; y < bh; y++ {
+				for x := 0; x < bw; x++ {
+					a[(2*y+yo)*w+2*x+xo] = coeffs[y*bw+x]
+				}
+			}
+		}
+		lowX, lowY := u0&1, v0&1
+		put(ll, lw, hl.height(), lowX, lowY)
+		put(hl.coeffs, hl.width(), hl.height(), 1-lowX, lowY)
+		put(lh.coeffs, lw, lh.height(), lowX, 1-lowY)
+		put(hh.coeffs, hh.width(), hh.height(), 1-lowX, 1-lowY)
+
+		row := make([]float64, w)
+		for y := 0; y < h; y++ {
+			copy(row, a[y*w:(y+1)*w])
+			tc.synthesize(row, u0)
+			copy(a[y*w:], row)
+		}
+		col := make([]float64, h)
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				col[y] = a[y*w+x]
+			}
+			tc.synthesize(col, v0)
+			for y := 0; y < h; y++ {
+				a[y*w+x] = col[y]
+			}
+		}
+		ll = a
+	}
+	return ll
+}
+
+// synthesize is the one dimensional synthesis of signal x starting at
+// coordinate i0, 1D_SR of F.3.6
+func (tc *tileComponent) synthesize(x []float64, i0 int) {
+	n := len(x)
+	if n == 0 {
+		return
+	}
+	if n == 1 {
+		if i0&1 == 1 {
+			if tc.cs.reversible {
+				x[0] = math.Floor(x[0] / 2)
+			} else {
+				x[0] /= 2
+			}
+		}
+		return
+	}
+
+	// periodic symmetric extension, F.3.7
+	const ext = 4
+	y := make([]float64, n+2*ext)
+	period := 2 * (n - 1)
+	for k := -ext; k < n+ext; k++ {
+		j := k % period
+		if j < 0 {
+			j += period
+		}
+		if j >= n {
+			j = period - j
+		}
+		y[k+ext] = x[j]
+	}
+	// even reports whether extended index k holds a low-pass sample
+	even := func(k int) bool { return (i0+k-ext)&1 == 0 }
+
+	lift := func(low bool, from, to int, f func(k int) float64) {
+		for k := from; k < to; k++ {
+			if even(k) == low {
+				y[k] = f(k)
+			}
+		}
+	}
+	if tc.cs.reversible {
+		lift(true, ext-1, n+ext+1, func(k int) float64 {
+			return y[k] - math.Floor((y[k-1]+y[k+1]+2)/4)
+		})
+		lift(false, ext, n+ext, func(k int) float64 {
+			return y[k] + math.Floor((y[k-1]+y[k+1])/2)
+		})
+	} else {
+		lift(true, 0, n+2*ext, func(k int) float64 { return y[k] * kappa })
+		lift(false, 0, n+2*ext, func(k int) float64 { return y[k] / kappa })
+		lift(true, ext-3, n+ext+3, func(k int) float64 { return y[k] - delta*(y[k-1]+y[k+1]) })
+		lift(false, ext-2, n+ext+2, func(k int) float64 { return y[k] - gamma*(y[k-1]+y[k+1]) })
+		lift(true, ext-1, n+ext+1, func(k int) float64 { return y[k] - beta*(y[k-1]+y[k+1]) })
+		lift(false, ext, n+ext, func(k int) float64 { return y[k] - alpha*(y[k-1]+y[k+1]) })
+	}
+	copy(x, y[ext:ext+n])
+}
+
+// inverseMCT undoes the multiple component transform of the first three
+// components, annex G
+func inverseMCT(c [][]float64, reversible bool) {
+	y0, y1, y2 := c[0], c[1], c[2]
+	for i := range y0 {
+		if i >= len(y1) || i >= len(y2) {
+			return
+		}
+		if reversible {
+			g := y0[i] - math.Floor((y1[i]+y2[i])/4)
+			y0[i], y1[i], y2[i] = y2[i]+g, g, y1[i]+g
+		} else {
+			r := y0[i] + 1.402*y2[i]
+			g := y0[i] - 0.34413*y1[i] - 0.71414*y2[i]
+			b := y0[i] + 1.772*y1[i]
+			y0[i], y1[i], y2[i] = r, g, b
+		}
+	}
+}
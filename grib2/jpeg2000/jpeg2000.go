@@ -0,0 +1,526 @@
+/*
+Package jpeg2000 decodes JPEG 2000 Part 1 codestreams (ITU-T T.800), the
+format GRIB2 data representation template 5.40 stores packed values in.
+
+Only what a raw codestream needs is supported: JP2 file boxes, region of
+interest coding, packed packet headers and progression order changes are
+rejected with an error.
+*/
+package jpeg2000
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// markers of the codestream
+const (
+	markerSOC = 0xff4f
+	markerSIZ = 0xff51
+	markerCOD = 0xff52
+	markerCOC = 0xff53
+	markerTLM = 0xff55
+	markerPLM = 0xff57
+	markerPLT = 0xff58
+	markerQCD = 0xff5c
+	markerQCC = 0xff5d
+	markerRGN = 0xff5e
+	markerPOC = 0xff5f
+	markerPPM = 0xff60
+	markerPPT = 0xff61
+	markerCRG = 0xff63
+	markerCOM = 0xff64
+	markerSOT = 0xff90
+	markerSOP = 0xff91
+	markerEPH = 0xff92
+	markerSOD = 0xff93
+	markerEOC = 0xffd9
+)
+
+// progression orders of the COD marker
+const (
+	progressionLRCP = iota
+	progressionRLCP
+	progressionRPCL
+	progressionPCRL
+	progressionCPRL
+)
+
+// code-block styles of the COD marker
+const (
+	styleBypass        = 0x01
+	styleReset         = 0x02
+	styleTermAll       = 0x04
+	styleVerticalCause = 0x08
+	stylePredictable   = 0x10
+	styleSegSymbols    = 0x20
+)
+
+// Component is one decoded image component
+type Component struct {
+	Precision int
+	Signed    bool
+	// Dx and Dy are the subsampling of the component on the reference grid
+	Dx, Dy int
+	// Width and Height are the size of the component, Data holds its samples
+	// row by row
+	Width, Height int
+	Data          []int32
+}
+
+// Image is a decoded codestream
+type Image struct {
+	Width, Height int
+	Components    []*Component
+}
+
+type siz struct {
+	x1, y1   int // Xsiz, Ysiz
+	x0, y0   int // XOsiz, YOsiz
+	tw, th   int // XTsiz, YTsiz
+	tx0, ty0 int // XTOsiz, YTOsiz
+	comps    []*Component
+}
+
+// codingStyle is a COD or COC marker
+type codingStyle struct {
+	userPrecincts bool
+	sop, eph      bool
+	progression   int
+	layers        int
+	mct           int
+
+	levels     int
+	xcb, ycb   int
+	blockStyle int
+	reversible bool
+	// ppx and ppy are the precinct size exponents of every resolution
+	ppx, ppy []int
+}
+
+// quantization is a QCD or QCC marker
+type quantization struct {
+	style     int
+	guardBits int
+	// exponents and mantissas of every subband, derived quantization only
+	// has the LL values
+	exponents []int
+	mantissas []int
+}
+
+// header holds the markers that apply to the main header or a tile
+type header struct {
+	cod    *codingStyle
+	coc    map[int]*codingStyle
+	qcd    *quantization
+	qcc    map[int]*quantization
+	hasCOD bool
+}
+
+func newHeader() *header {
+	return &header{coc: make(map[int]*codingStyle), qcc: make(map[int]*quantization)}
+}
+
+// tile collects the tile-parts of a tile
+type tile struct {
+	index  int
+	header *header
+	data   []byte
+}
+
+type decoder struct {
+	data  []byte
+	pos   int
+	siz   siz
+	main  *header
+	tiles map[int]*tile
+	order []int
+}
+
+// Decode decodes a raw JPEG 2000 codestream
+func Decode(data []byte) (*Image, error) {
+	d := &decoder{data: data, main: newHeader(), tiles: make(map[int]*tile)}
+	if err := d.parse(); err != nil {
+		return nil, err
+	}
+
+	img := &Image{
+		Width:      d.siz.x1 - d.siz.x0,
+		Height:     d.siz.y1 - d.siz.y0,
+		Components: d.siz.comps,
+	}
+	for _, c := range img.Components {
+		c.Width = ceilDiv(d.siz.x1, c.Dx) - ceilDiv(d.siz.x0, c.Dx)
+		c.Height = ceilDiv(d.siz.y1, c.Dy) - ceilDiv(d.siz.y0, c.Dy)
+		c.Data = make([]int32, c.Width*c.Height)
+	}
+
+	for _, index := range d.order {
+		if err := d.decodeTile(d.tiles[index], img); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+func (d *decoder) marker() (int, error) {
+	if d.pos+2 > len(d.data) {
+		return 0, fmt.Errorf("jpeg2000: codestream is truncated")
+	}
+	m := int(binary.BigEndian.Uint16(d.data[d.pos:]))
+	d.pos += 2
+	return m, nil
+}
+
+// segment returns the body of the marker segment at the current position
+func (d *decoder) segment() ([]byte, error) {
+	if d.pos+2 > len(d.data) {
+		return nil, fmt.Errorf("jpeg2000: codestream is truncated")
+	}
+	length := int(binary.BigEndian.Uint16(d.data[d.pos:]))
+	if length < 2 || d.pos+length > len(d.data) {
+		return nil, fmt.Errorf("jpeg2000: bad marker segment length %d", length)
+	}
+	body := d.data[d.pos+2 : d.pos+length]
+	d.pos += length
+	return body, nil
+}
+
+func (d *decoder) parse() error {
+	m, err := d.marker()
+	if err != nil {
+		return err
+	}
+	if m != markerSOC {
+		return fmt.Errorf("jpeg2000: not a codestream")
+	}
+
+	// main header
+	for {
+		m, err := d.marker()
+		if err != nil {
+			return err
+		}
+		if m == markerSOT {
+			d.pos -= 2
+			break
+		}
+		body, err := d.segment()
+		if err != nil {
+			return err
+		}
+		if m == markerSIZ {
+			err = d.parseSIZ(body)
+		} else {
+			err = d.parseHeaderSegment(m, body, d.main)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if d.siz.comps == nil {
+		return fmt.Errorf("jpeg2000: no SIZ marker")
+	}
+	if !d.main.hasCOD || d.main.qcd == nil {
+		return fmt.Errorf("jpeg2000: main header is missing COD or QCD")
+	}
+
+	// tile-parts
+	for {
+		m, err := d.marker()
+		if err != nil {
+			return err
+		}
+		if m == markerEOC {
+			return nil
+		}
+		if m != markerSOT {
+			return fmt.Errorf("jpeg2000: expected SOT marker, found %04x", m)
+		}
+		start := d.pos - 2
+		body, err := d.segment()
+		if err != nil {
+			return err
+		}
+		if len(body) < 8 {
+			return fmt.Errorf("jpeg2000: bad SOT marker")
+		}
+		index := int(binary.BigEndian.Uint16(body))
+		length := int(binary.BigEndian.Uint32(body[2:]))
+		end := start + length
+		if length == 0 {
+			// the last tile-part runs to the EOC marker
+			end = len(d.data)
+			if end >= 2 && binary.BigEndian.Uint16(d.data[end-2:]) == markerEOC {
+				end -= 2
+			}
+		}
+		if end > len(d.data) || end < d.pos {
+			return fmt.Errorf("jpeg2000: tile-part is truncated")
+		}
+
+		t, ok := d.tiles[index]
+		if !ok {
+			t = &tile{index: index, header: newHeader()}
+			d.tiles[index] = t
+			d.order = append(d.order, index)
+		}
+
+		for {
+			m, err := d.marker()
+			if err != nil {
+				return err
+			}
+			if m == markerSOD {
+				break
+			}
+			body, err := d.segment()
+			if err != nil {
+				return err
+			}
+			if err := d.parseHeaderSegment(m, body, t.header); err != nil {
+				return err
+			}
+		}
+		t.data = append(t.data, d.data[d.pos:end]...)
+		d.pos = end
+		if d.pos >= len(d.data) {
+			return nil
+		}
+	}
+}
+
+func (d *decoder) parseSIZ(b []byte) error {
+	if len(b) < 36 {
+		return fmt.Errorf("jpeg2000: bad SIZ marker")
+	}
+	u32 := func(i int) int { return int(binary.BigEndian.Uint32(b[i:])) }
+	d.siz = siz{
+		x1: u32(2), y1: u32(6), x0: u32(10), y0: u32(14),
+		tw: u32(18), th: u32(22), tx0: u32(26), ty0: u32(30),
+	}
+	n := int(binary.BigEndian.Uint16(b[34:]))
+	if len(b) < 36+3*n || n == 0 {
+		return fmt.Errorf("jpeg2000: bad SIZ marker")
+	}
+	if d.siz.tw == 0 || d.siz.th == 0 || d.siz.x1 <= d.siz.x0 || d.siz.y1 <= d.siz.y0 {
+		return fmt.Errorf("jpeg2000: bad image size")
+	}
+	for i := 0; i < n; i++ {
+		s := b[36+3*i]
+		c := &Component{
+			Precision: int(s&0x7f) + 1,
+			Signed:    s&0x80 != 0,
+			Dx:        int(b[37+3*i]),
+			Dy:        int(b[38+3*i]),
+		}
+		if c.Dx == 0 || c.Dy == 0 {
+			return fmt.Errorf("jpeg2000: bad component subsampling")
+		}
+		if c.Precision > 30 {
+			return fmt.Errorf("jpeg2000: %d bit components are not supported", c.Precision)
+		}
+		d.siz.comps = append(d.siz.comps, c)
+	}
+	return nil
+}
+
+// componentIndex reads the component number of a COC, QCC or RGN marker
+func (d *decoder) componentIndex(b []byte) (int, []byte, error) {
+	if len(d.siz.comps) < 257 {
+		if len(b) < 1 {
+			return 0, nil, fmt.Errorf("jpeg2000: marker segment is truncated")
+		}
+		return int(b[0]), b[1:], nil
+	}
+	if len(b) < 2 {
+		return 0, nil, fmt.Errorf("jpeg2000: marker segment is truncated")
+	}
+	return int(binary.BigEndian.Uint16(b)), b[2:], nil
+}
+
+func (d *decoder) parseHeaderSegment(m int, b []byte, h *header) error {
+	switch m {
+	case markerCOD:
+		if len(b) < 5 {
+			return fmt.Errorf("jpeg2000: bad COD marker")
+		}
+		cs := &codingStyle{
+			userPrecincts: b[0]&1 != 0,
+			sop:           b[0]&2 != 0,
+			eph:           b[0]&4 != 0,
+			progression:   int(b[1]),
+			layers:        int(binary.BigEndian.Uint16(b[2:])),
+			mct:           int(b[4]),
+		}
+		if err := parseSPcod(cs, b[5:]); err != nil {
+			return err
+		}
+		h.cod = cs
+		h.hasCOD = true
+	case markerCOC:
+		c, rest, err := d.componentIndex(b)
+		if err != nil {
+			return err
+		}
+		if len(rest) < 1 {
+			return fmt.Errorf("jpeg2000: bad COC marker")
+		}
+		cs := &codingStyle{userPrecincts: rest[0]&1 != 0}
+		if err := parseSPcod(cs, rest[1:]); err != nil {
+			return err
+		}
+		h.coc[c] = cs
+	case markerQCD:
+		q, err := parseQuantization(b)
+		if err != nil {
+			return err
+		}
+		h.qcd = q
+	case markerQCC:
+		c, rest, err := d.componentIndex(b)
+		if err != nil {
+			return err
+		}
+		q, err := parseQuantization(rest)
+		if err != nil {
+			return err
+		}
+		h.qcc[c] = q
+	case markerRGN:
+		return fmt.Errorf("jpeg2000: region of interest coding is not supported")
+	case markerPOC:
+		return fmt.Errorf("jpeg2000: progression order changes are not supported")
+	case markerPPM, markerPPT:
+		return fmt.Errorf("jpeg2000: packed packet headers are not supported")
+	case markerTLM, markerPLM, markerPLT, markerCRG, markerCOM:
+		// informational only
+	default:
+		if m < 0xff30 || m > 0xff3f {
+			// unknown marker segments are skipped, 0xff30-0xff3f have no body
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseSPcod reads the coding style parameters shared by COD and COC
+func parseSPcod(cs *codingStyle, b []byte) error {
+	if len(b) < 5 {
+		return fmt.Errorf("jpeg2000: bad coding style")
+	}
+	cs.levels = int(b[0])
+	cs.xcb = int(b[1]&0xf) + 2
+	cs.ycb = int(b[2]&0xf) + 2
+	cs.blockStyle = int(b[3])
+	cs.reversible = b[4] == 1
+	if cs.levels > 32 || cs.xcb+cs.ycb > 12 {
+		return fmt.Errorf("jpeg2000: bad coding style")
+	}
+
+	n := cs.levels + 1
+	cs.ppx = make([]int, n)
+	cs.ppy = make([]int, n)
+	for r := 0; r < n; r++ {
+		cs.ppx[r], cs.ppy[r] = 15, 15
+		if cs.userPrecincts {
+			if len(b) < 5+n {
+				return fmt.Errorf("jpeg2000: bad precinct sizes")
+			}
+			cs.ppx[r] = int(b[5+r] & 0xf)
+			cs.ppy[r] = int(b[5+r] >> 4)
+		}
+	}
+	return nil
+}
+
+func parseQuantization(b []byte) (*quantization, error) {
+	if len(b) < 1 {
+		return nil, fmt.Errorf("jpeg2000: bad quantization marker")
+	}
+	q := &quantization{style: int(b[0] & 0x1f), guardBits: int(b[0] >> 5)}
+	b = b[1:]
+	switch q.style {
+	case 0:
+		for _, v := range b {
+			q.exponents = append(q.exponents, int(v>>3))
+			q.mantissas = append(q.mantissas, 0)
+		}
+	case 1, 2:
+		for i := 0; i+1 < len(b); i += 2 {
+			v := int(binary.BigEndian.Uint16(b[i:]))
+			q.exponents = append(q.exponents, v>>11)
+			q.mantissas = append(q.mantissas, v&0x7ff)
+		}
+	default:
+		return nil, fmt.Errorf("jpeg2000: unknown quantization style %d", q.style)
+	}
+	if len(q.exponents) == 0 {
+		return nil, fmt.Errorf("jpeg2000: quantization marker has no subbands")
+	}
+	return q, nil
+}
+
+// codingStyle returns the coding style of a component in a tile, tile
+// markers override the main header and COC overrides COD
+func (d *decoder) codingStyle(t *tile, c int) *codingStyle {
+	base := d.main.cod
+	if t.header.hasCOD {
+		base = t.header.cod
+	}
+	if cs, ok := t.header.coc[c]; ok {
+		return mergeCOC(base, cs)
+	}
+	if !t.header.hasCOD {
+		if cs, ok := d.main.coc[c]; ok {
+			return mergeCOC(base, cs)
+		}
+	}
+	return base
+}
+
+// mergeCOC combines the component parameters of a COC with the progression
+// and layer parameters only a COD carries
+func mergeCOC(cod, coc *codingStyle) *codingStyle {
+	cs := *coc
+	cs.sop, cs.eph = cod.sop, cod.eph
+	cs.progression = cod.progression
+	cs.layers = cod.layers
+	cs.mct = cod.mct
+	return &cs
+}
+
+func (d *decoder) quantization(t *tile, c int) *quantization {
+	if q, ok := t.header.qcc[c]; ok {
+		return q
+	}
+	if t.header.qcd != nil {
+		return t.header.qcd
+	}
+	if q, ok := d.main.qcc[c]; ok {
+		return q
+	}
+	return d.main.qcd
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+func floorDiv(a, b int) int {
+	return a / b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,173 @@
+package jpeg2000
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// Most codestreams in testdata hold the samples below. They are written by
+// grib2/internal/genfixtures, an encoder made for these tests that shares
+// no code with the decoder, not by OpenJPEG or Jasper, so they only show
+// the decoder agrees with our reading of the standard. They use the options
+// GRIB2 producers use (reversible 5/3, one layer) and the ones they may use
+// (tiles, layers, precincts, SOP and EPH markers, every code-block style,
+// signed samples, image offsets, the 9/7 wavelet and the colour transform).
+// kakadu.j2k comes from an independent encoder, see TestDecodeKakadu
+
+// noisySample changes sharply between neighbours so every bit plane and
+// sub-band is coded
+func noisySample(x, y, c, prec int) int32 {
+	max := 1<<uint(prec) - 1
+	return int32((x*x*31 + y*17 + x*y*7 + c*1013) % (max + 1))
+}
+
+// smoothSample suits the lossy 9/7 wavelet
+func smoothSample(x, y, c, prec int) int32 {
+	max := float64(int(1)<<uint(prec) - 1)
+	return int32((math.Sin(float64(x)/7)*math.Cos(float64(y+c)/5) + 1) / 2 * max)
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		file          string
+		width, height int
+		components    int
+		precision     int
+		signed        bool
+		smooth        bool
+		// tolerance is the largest error allowed, lossless codestreams
+		// decode exactly
+		tolerance int32
+	}{
+		{"lossless.j2k", 61, 47, 1, 12, false, false, 0},
+		{"tiles.j2k", 90, 70, 1, 16, false, false, 0},
+		{"signed.j2k", 45, 37, 1, 10, true, false, 0},
+		{"irreversible.j2k", 64, 64, 1, 8, false, true, 1},
+		{"rct.j2k", 33, 29, 3, 8, false, false, 0},
+	}
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := Decode(data)
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if img.Width != test.width || img.Height != test.height || len(img.Components) != test.components {
+			t.Errorf("%s: %dx%d with %d components, want %dx%d with %d", test.file,
+				img.Width, img.Height, len(img.Components), test.width, test.height, test.components)
+			continue
+		}
+		for c, comp := range img.Components {
+			if comp.Precision != test.precision || comp.Signed != test.signed {
+				t.Errorf("%s component %d: precision %d signed %v, want %d %v", test.file, c,
+					comp.Precision, comp.Signed, test.precision, test.signed)
+			}
+			if comp.Width != test.width || comp.Height != test.height || len(comp.Data) != test.width*test.height {
+				t.Errorf("%s component %d: %dx%d with %d samples", test.file, c, comp.Width, comp.Height, len(comp.Data))
+				continue
+			}
+			bad := 0
+			for y := 0; y < comp.Height; y++ {
+				for x := 0; x < comp.Width; x++ {
+					want := noisySample(x, y, c, test.precision)
+					if test.smooth {
+						want = smoothSample(x, y, c, test.precision)
+					}
+					if test.signed {
+						want -= 1 << uint(test.precision-1)
+					}
+					got := comp.Data[y*comp.Width+x]
+					if d := got - want; d > test.tolerance || d < -test.tolerance {
+						if bad < 3 {
+							t.Errorf("%s component %d: sample (%d, %d) = %d, want %d", test.file, c, x, y, got, want)
+						}
+						bad++
+					}
+				}
+			}
+			if bad > 0 {
+				t.Errorf("%s component %d: %d samples differ", test.file, c, bad)
+			}
+		}
+	}
+}
+
+// TestDecodeKakadu decodes a photograph written by Kakadu 3.2 with 12
+// layers, 5 levels of the 5/3 wavelet and the reversible colour transform,
+// taken from the test files of github.com/gabriel-vasile/mimetype (MIT).
+// The image was checked by eye, the probes check its content and the
+// checksums that the samples do not change
+func TestDecodeKakadu(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "kakadu.j2k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 400 || img.Height != 300 || len(img.Components) != 3 {
+		t.Fatalf("%dx%d with %d components, want 400x300 with 3", img.Width, img.Height, len(img.Components))
+	}
+	checksums := []uint32{0x5c94dd15, 0x38b7628f, 0xa38b101c}
+	for c, comp := range img.Components {
+		if comp.Precision != 8 || comp.Signed || len(comp.Data) != 400*300 {
+			t.Fatalf("component %d: precision %d signed %v with %d samples", c, comp.Precision, comp.Signed, len(comp.Data))
+		}
+		b := make([]byte, 4*len(comp.Data))
+		for k, v := range comp.Data {
+			binary.BigEndian.PutUint32(b[4*k:], uint32(v))
+		}
+		if sum := crc32.ChecksumIEEE(b); sum != checksums[c] {
+			t.Errorf("component %d: checksum %08x, want %08x", c, sum, checksums[c])
+		}
+	}
+	rgb := func(x, y int) (r, g, b int32) {
+		i := y*img.Width + x
+		return img.Components[0].Data[i], img.Components[1].Data[i], img.Components[2].Data[i]
+	}
+	probes := []struct {
+		name string
+		x, y int
+		ok   func(r, g, b int32) bool
+	}{
+		{"white letter l", 108, 260, func(r, g, b int32) bool { return r > 230 && g > 230 && b > 230 }},
+		{"green moss", 40, 100, func(r, g, b int32) bool { return g > r+40 && g > b+20 }},
+		{"pale blue water", 100, 180, func(r, g, b int32) bool { return b > 200 && b > r+10 }},
+		{"dark rock", 180, 110, func(r, g, b int32) bool { return r < 70 && g < 70 && b < 70 }},
+	}
+	for _, p := range probes {
+		if r, g, b := rgb(p.x, p.y); !p.ok(r, g, b) {
+			t.Errorf("%s at (%d, %d): colour %d %d %d", p.name, p.x, p.y, r, g, b)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "lossless.j2k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"no SOC", data[2:]},
+		{"JP2 box", append([]byte{0, 0, 0, 12, 'j', 'P', ' ', ' '}, data...)},
+		{"truncated main header", data[:20]},
+		{"truncated tile", data[:len(data)/2]},
+	}
+	for _, test := range tests {
+		if _, err := Decode(test.data); err == nil {
+			t.Errorf("%s: decoded without an error", test.name)
+		}
+	}
+}
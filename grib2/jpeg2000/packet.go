@@ -0,0 +1,520 @@
+package jpeg2000
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// kinds of subband
+const (
+	bandLL = iota
+	bandHL
+	bandLH
+	bandHH
+)
+
+type band struct {
+	kind   int
+	x0, y0 int
+	x1, y1 int
+	// coefficients of the band row by row
+	coeffs []float64
+}
+
+func (b *band) width() int  { return b.x1 - b.x0 }
+func (b *band) height() int { return b.y1 - b.y0 }
+
+// precinctBand is the part of a subband inside a precinct
+type precinctBand struct {
+	band          *band
+	blocks        []*codeBlock
+	inclusion     *tagTree
+	zeroBitPlanes *tagTree
+}
+
+type precinct struct {
+	bands []*precinctBand
+	// x and y are the position of the precinct on the reference grid, they
+	// order the position driven progressions
+	x, y int
+}
+
+type resolution struct {
+	x0, y0    int
+	x1, y1    int
+	bands     []*band
+	precincts []*precinct
+}
+
+type tileComponent struct {
+	x0, y0      int
+	x1, y1      int
+	cs          *codingStyle
+	q           *quantization
+	resolutions []*resolution
+}
+
+// newTileComponent lays out the resolutions, subbands, precincts and
+// code-blocks of a component of the tile tx0, ty0, tx1, ty1
+func newTileComponent(c *Component, cs *codingStyle, q *quantization, tx0, ty0, tx1, ty1 int) *tileComponent {
+	tc := &tileComponent{
+		x0: ceilDiv(tx0, c.Dx), y0: ceilDiv(ty0, c.Dy),
+		x1: ceilDiv(tx1, c.Dx), y1: ceilDiv(ty1, c.Dy),
+		cs: cs, q: q,
+	}
+
+	nl := cs.levels
+	for r := 0; r <= nl; r++ {
+		scale := 1 << uint(nl-r)
+		res := &resolution{
+			x0: ceilDiv(tc.x0, scale), y0: ceilDiv(tc.y0, scale),
+			x1: ceilDiv(tc.x1, scale), y1: ceilDiv(tc.y1, scale),
+		}
+
+		if r == 0 {
+			res.bands = []*band{newBand(bandLL, tc, nl)}
+		} else {
+			for kind := bandHL; kind <= bandHH; kind++ {
+				res.bands = append(res.bands, newBand(kind, tc, nl-r+1))
+			}
+		}
+
+		// precincts partition the resolution, code-blocks the subbands
+		ppx, ppy := uint(cs.ppx[r]), uint(cs.ppy[r])
+		bppx, bppy := ppx, ppy
+		if r > 0 {
+			bppx, bppy = ppx-1, ppy-1
+		}
+		xcb := uint(minInt(cs.xcb, int(bppx)))
+		ycb := uint(minInt(cs.ycb, int(bppy)))
+
+		px0, py0 := res.x0>>ppx, res.y0>>ppy
+		px1, py1 := px0, py0
+		if res.x1 > res.x0 && res.y1 > res.y0 {
+			px1, py1 = ceilDiv(res.x1, 1<<ppx), ceilDiv(res.y1, 1<<ppy)
+		}
+		for py := py0; py < py1; py++ {
+			for px := px0; px < px1; px++ {
+				p := &precinct{
+					x: tx0, y: ty0,
+				}
+				// precincts clipped by the tile are ordered at its origin
+				if x := px << ppx; x >= res.x0 {
+					p.x = x * c.Dx << uint(nl-r)
+				}
+				if y := py << ppy; y >= res.y0 {
+					p.y = y * c.Dy << uint(nl-r)
+				}
+
+				for _, b := range res.bands {
+					pb := &precinctBand{band: b}
+					bx0 := maxInt(px<<bppx, b.x0)
+					by0 := maxInt(py<<bppy, b.y0)
+					bx1 := minInt((px+1)<<bppx, b.x1)
+					by1 := minInt((py+1)<<bppy, b.y1)
+					if bx1 > bx0 && by1 > by0 {
+						cx0, cy0 := bx0>>xcb, by0>>ycb
+						cx1, cy1 := ceilDiv(bx1, 1<<xcb), ceilDiv(by1, 1<<ycb)
+						for cy := cy0; cy < cy1; cy++ {
+							for cx := cx0; cx < cx1; cx++ {
+								pb.blocks = append(pb.blocks, &codeBlock{
+									x0:     maxInt(cx<<xcb, bx0),
+									y0:     maxInt(cy<<ycb, by0),
+									x1:     minInt((cx+1)<<xcb, bx1),
+									y1:     minInt((cy+1)<<ycb, by1),
+									lblock: 3,
+								})
+							}
+						}
+						pb.inclusion = newTagTree(cx1-cx0, cy1-cy0)
+						pb.zeroBitPlanes = newTagTree(cx1-cx0, cy1-cy0)
+					}
+					p.bands = append(p.bands, pb)
+				}
+				res.precincts = append(res.precincts, p)
+			}
+		}
+		tc.resolutions = append(tc.resolutions, res)
+	}
+	return tc
+}
+
+// newBand lays out a subband with nb decomposition levels, equation B-15
+func newBand(kind int, tc *tileComponent, nb int) *band {
+	var xo, yo int
+	if kind == bandHL || kind == bandHH {
+		xo = 1
+	}
+	if kind == bandLH || kind == bandHH {
+		yo = 1
+	}
+	scale := 1 << uint(nb)
+	offset := 0
+	if nb > 0 {
+		offset = 1 << uint(nb-1)
+	}
+	b := &band{
+		kind: kind,
+		x0:   ceilDiv(tc.x0-offset*xo, scale),
+		y0:   ceilDiv(tc.y0-offset*yo, scale),
+		x1:   ceilDiv(tc.x1-offset*xo, scale),
+		y1:   ceilDiv(tc.y1-offset*yo, scale),
+	}
+	if b.x1 < b.x0 {
+		b.x1 = b.x0
+	}
+	if b.y1 < b.y0 {
+		b.y1 = b.y0
+	}
+	b.coeffs = make([]float64, b.width()*b.height())
+	return b
+}
+
+// tagTree is a tag tree of B.10.2
+type tagTree struct {
+	levels []tagLevel
+}
+
+type tagLevel struct {
+	w     int
+	value []int
+	low   []int
+}
+
+const tagUnknown = 1 << 30
+
+func newTagTree(w, h int) *tagTree {
+	t := &tagTree{}
+	for {
+		l := tagLevel{w: w, value: make([]int, w*h), low: make([]int, w*h)}
+		for i := range l.value {
+			l.value[i] = tagUnknown
+		}
+		t.levels = append(t.levels, l)
+		if w == 1 && h == 1 {
+			return t
+		}
+		w, h = (w+1)/2, (h+1)/2
+	}
+}
+
+// decode reads bits until the value of leaf x, y is known to be below the
+// threshold or not, it reports whether it is below
+func (t *tagTree) decode(r *headerReader, x, y, threshold int) (bool, error) {
+	low := 0
+	for n := len(t.levels) - 1; n >= 0; n-- {
+		l := &t.levels[n]
+		i := (y>>uint(n))*l.w + x>>uint(n)
+		if low > l.low[i] {
+			l.low[i] = low
+		} else {
+			low = l.low[i]
+		}
+		for low < threshold && low < l.value[i] {
+			bit, err := r.bit()
+			if err != nil {
+				return false, err
+			}
+			if bit == 1 {
+				l.value[i] = low
+			} else {
+				low++
+			}
+		}
+		l.low[i] = low
+	}
+	return t.levels[0].value[y*t.levels[0].w+x] < threshold, nil
+}
+
+// value decodes the whole value of leaf x, y
+func (t *tagTree) value(r *headerReader, x, y int) (int, error) {
+	for threshold := 1; ; threshold++ {
+		known, err := t.decode(r, x, y, threshold)
+		if err != nil {
+			return 0, err
+		}
+		if known {
+			return t.levels[0].value[y*t.levels[0].w+x], nil
+		}
+	}
+}
+
+// headerReader reads the bits of packet headers, a zero bit is stuffed
+// after every 0xff byte
+type headerReader struct {
+	data []byte
+	pos  int
+	buf  byte
+	bits uint
+	last byte
+}
+
+func (r *headerReader) bit() (int, error) {
+	if r.bits == 0 {
+		if r.pos >= len(r.data) {
+			return 0, fmt.Errorf("jpeg2000: packet header is truncated")
+		}
+		r.bits = 8
+		if r.last == 0xff {
+			r.bits = 7
+		}
+		r.buf = r.data[r.pos]
+		r.last = r.buf
+		r.pos++
+	}
+	r.bits--
+	return int(r.buf>>r.bits) & 1, nil
+}
+
+func (r *headerReader) bitsValue(n int) (int, error) {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit, err := r.bit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | bit
+	}
+	return v, nil
+}
+
+// align skips to the end of the packet header
+func (r *headerReader) align() {
+	r.bits = 0
+	if r.last == 0xff {
+		r.pos++
+	}
+	r.last = 0
+}
+
+// numPasses reads the number of coding passes, table B.4
+func (r *headerReader) numPasses() (int, error) {
+	codes := []struct{ bits, base int }{{1, 1}, {1, 2}, {2, 3}, {5, 6}}
+	for _, c := range codes {
+		v, err := r.bitsValue(c.bits)
+		if err != nil {
+			return 0, err
+		}
+		// a value of all ones continues with a longer code
+		if v != 1<<uint(c.bits)-1 {
+			return c.base + v, nil
+		}
+	}
+	v, err := r.bitsValue(7)
+	return 37 + v, err
+}
+
+func floorLog2(v int) int {
+	n := 0
+	for v > 1 {
+		v >>= 1
+		n++
+	}
+	return n
+}
+
+// contribution is the data a packet adds to a segment
+type contribution struct {
+	segment *segment
+	length  int
+}
+
+// readPacket reads the packet of a layer of a precinct from data at pos and
+// returns the position after it
+func readPacket(data []byte, pos int, p *precinct, layer int, cs *codingStyle) (int, error) {
+	if cs.sop && pos+6 <= len(data) && binary.BigEndian.Uint16(data[pos:]) == markerSOP {
+		pos += 6
+	}
+
+	r := &headerReader{data: data, pos: pos}
+	present, err := r.bit()
+	if err != nil {
+		return 0, err
+	}
+
+	var blocks []*codeBlock
+	var contributions [][]contribution
+	if present == 1 {
+		for _, pb := range p.bands {
+			if len(pb.blocks) == 0 {
+				continue
+			}
+			w := pb.inclusion.levels[0].w
+			for i, cb := range pb.blocks {
+				x, y := i%w, i/w
+
+				var included bool
+				if cb.included {
+					bit, err := r.bit()
+					if err != nil {
+						return 0, err
+					}
+					included = bit == 1
+				} else {
+					included, err = pb.inclusion.decode(r, x, y, layer+1)
+					if err != nil {
+						return 0, err
+					}
+					if included {
+						cb.zeroBitPlanes, err = pb.zeroBitPlanes.value(r, x, y)
+						if err != nil {
+							return 0, err
+						}
+						cb.included = true
+					}
+				}
+				if !included {
+					continue
+				}
+
+				passes, err := r.numPasses()
+				if err != nil {
+					return 0, err
+				}
+				for {
+					bit, err := r.bit()
+					if err != nil {
+						return 0, err
+					}
+					if bit == 0 {
+						break
+					}
+					cb.lblock++
+				}
+
+				// one length for every segment the passes fall into
+				var contribs []contribution
+				for passes > 0 {
+					var seg *segment
+					if n := len(cb.segments); n > 0 && cb.segments[n-1].passes < cb.segments[n-1].max {
+						seg = cb.segments[n-1]
+					} else {
+						seg = &segment{max: segmentMax(cs.blockStyle, len(cb.segments))}
+						cb.segments = append(cb.segments, seg)
+					}
+					n := minInt(passes, seg.max-seg.passes)
+					length, err := r.bitsValue(cb.lblock + floorLog2(n))
+					if err != nil {
+						return 0, err
+					}
+					seg.passes += n
+					cb.passes += n
+					passes -= n
+					contribs = append(contribs, contribution{seg, length})
+				}
+				blocks = append(blocks, cb)
+				contributions = append(contributions, contribs)
+			}
+		}
+	}
+	r.align()
+	pos = r.pos
+
+	if cs.eph {
+		if pos+2 > len(data) || binary.BigEndian.Uint16(data[pos:]) != markerEPH {
+			return 0, fmt.Errorf("jpeg2000: missing EPH marker")
+		}
+		pos += 2
+	}
+
+	for i := range blocks {
+		for _, c := range contributions[i] {
+			if pos+c.length > len(data) {
+				return 0, fmt.Errorf("jpeg2000: packet body is truncated")
+			}
+			c.segment.data = append(c.segment.data, data[pos:pos+c.length]...)
+			pos += c.length
+		}
+	}
+	return pos, nil
+}
+
+// packet identifies a packet of a tile
+type packet struct {
+	layer, res, comp, precinct int
+}
+
+// packets lists the packets of a tile in the order of its progression
+func packets(tcs []*tileComponent, cs *codingStyle) ([]packet, error) {
+	var list []packet
+	maxRes := 0
+	for _, tc := range tcs {
+		if n := len(tc.resolutions); n > maxRes {
+			maxRes = n
+		}
+	}
+
+	switch cs.progression {
+	case progressionLRCP:
+		for l := 0; l < cs.layers; l++ {
+			for r := 0; r < maxRes; r++ {
+				for c, tc := range tcs {
+					if r < len(tc.resolutions) {
+						for p := range tc.resolutions[r].precincts {
+							list = append(list, packet{l, r, c, p})
+						}
+					}
+				}
+			}
+		}
+		return list, nil
+	case progressionRLCP:
+		for r := 0; r < maxRes; r++ {
+			for l := 0; l < cs.layers; l++ {
+				for c, tc := range tcs {
+					if r < len(tc.resolutions) {
+						for p := range tc.resolutions[r].precincts {
+							list = append(list, packet{l, r, c, p})
+						}
+					}
+				}
+			}
+		}
+		return list, nil
+	case progressionRPCL, progressionPCRL, progressionCPRL:
+	default:
+		return nil, fmt.Errorf("jpeg2000: unknown progression order %d", cs.progression)
+	}
+
+	// position driven progressions visit the precincts by their position on
+	// the reference grid
+	type entry struct {
+		r, c, p int
+		x, y    int
+	}
+	var entries []entry
+	for c, tc := range tcs {
+		for r, res := range tc.resolutions {
+			for p, pr := range res.precincts {
+				entries = append(entries, entry{r, c, p, pr.x, pr.y})
+			}
+		}
+	}
+	less := func(a, b []int) bool {
+		for i := range a {
+			if a[i] != b[i] {
+				return a[i] < b[i]
+			}
+		}
+		return false
+	}
+	key := func(e entry) []int {
+		switch cs.progression {
+		case progressionRPCL:
+			return []int{e.r, e.y, e.x, e.c}
+		case progressionPCRL:
+			return []int{e.y, e.x, e.c, e.r}
+		}
+		return []int{e.c, e.y, e.x, e.r}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(key(entries[i]), key(entries[j]))
+	})
+	for _, e := range entries {
+		for l := 0; l < cs.layers; l++ {
+			list = append(list, packet{l, e.r, e.c, e.p})
+		}
+	}
+	return list, nil
+}
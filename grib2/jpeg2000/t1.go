@@ -0,0 +1,576 @@
+package jpeg2000
+
+import (
+	"fmt"
+)
+
+// qe is table C.2, the probability estimates of the MQ coder
+var qe = [47]struct {
+	qe         uint32
+	nmps, nlps uint8
+	switchMPS  bool
+}{
+	{0x5601, 1, 1, true}, {0x3401, 2, 6, false}, {0x1801, 3, 9, false},
+	{0x0ac1, 4, 12, false}, {0x0521, 5, 29, false}, {0x0221, 38, 33, false},
+	{0x5601, 7, 6, true}, {0x5401, 8, 14, false}, {0x4801, 9, 14, false},
+	{0x3801, 10, 14, false}, {0x3001, 11, 17, false}, {0x2401, 12, 18, false},
+	{0x1c01, 13, 20, false}, {0x1601, 29, 21, false}, {0x5601, 15, 14, true},
+	{0x5401, 16, 14, false}, {0x5101, 17, 15, false}, {0x4801, 18, 16, false},
+	{0x3801, 19, 17, false}, {0x3401, 20, 18, false}, {0x3001, 21, 19, false},
+	{0x2801, 22, 19, false}, {0x2401, 23, 20, false}, {0x2201, 24, 21, false},
+	{0x1c01, 25, 22, false}, {0x1801, 26, 23, false}, {0x1601, 27, 24, false},
+	{0x1401, 28, 25, false}, {0x1201, 29, 26, false}, {0x1101, 30, 27, false},
+	{0x0ac1, 31, 28, false}, {0x09c1, 32, 29, false}, {0x08a1, 33, 30, false},
+	{0x0521, 34, 31, false}, {0x0441, 35, 32, false}, {0x02a1, 36, 33, false},
+	{0x0221, 37, 34, false}, {0x0141, 38, 35, false}, {0x0111, 39, 36, false},
+	{0x0085, 40, 37, false}, {0x0049, 41, 38, false}, {0x0025, 42, 39, false},
+	{0x0015, 43, 40, false}, {0x0009, 44, 41, false}, {0x0005, 45, 42, false},
+	{0x0001, 45, 43, false}, {0x5601, 46, 46, false},
+}
+
+// contexts of the tier-1 coder, 0-8 are zero coding, 9-13 sign coding and
+// 14-16 magnitude refinement
+const (
+	ctxSign    = 9
+	ctxRefine  = 14
+	ctxRun     = 17
+	ctxUniform = 18
+	numCtx     = 19
+)
+
+// context is the state index and most probable symbol of a context
+type context struct {
+	state uint8
+	mps   uint32
+}
+
+func resetContexts(cx *[numCtx]context) {
+	for i := range cx {
+		cx[i] = context{}
+	}
+	cx[0].state = 4
+	cx[ctxRun].state = 3
+	cx[ctxUniform].state = 46
+}
+
+// mqDecoder is the arithmetic decoder of annex C
+type mqDecoder struct {
+	data []byte
+	bp   int
+	a, c uint32
+	ct   int
+}
+
+func (m *mqDecoder) byteAt(i int) uint32 {
+	if i < len(m.data) {
+		return uint32(m.data[i])
+	}
+	// past the end the decoder is fed ones, as if a marker followed
+	return 0xff
+}
+
+func (m *mqDecoder) init(data []byte) {
+	m.data = data
+	m.bp = 0
+	m.c = m.byteAt(0) << 16
+	m.byteIn()
+	m.c <<= 7
+	m.ct -= 7
+	m.a = 0x8000
+}
+
+func (m *mqDecoder) byteIn() {
+	if m.byteAt(m.bp) == 0xff {
+		if m.byteAt(m.bp+1) > 0x8f {
+			m.c += 0xff00
+			m.ct = 8
+		} else {
+			m.bp++
+			m.c += m.byteAt(m.bp) << 9
+			m.ct = 7
+		}
+	} else {
+		m.bp++
+		m.c += m.byteAt(m.bp) << 8
+		m.ct = 8
+	}
+}
+
+func (m *mqDecoder) renormalize() {
+	for {
+		if m.ct == 0 {
+			m.byteIn()
+		}
+		m.a <<= 1
+		m.c <<= 1
+		m.ct--
+		if m.a&0x8000 != 0 {
+			return
+		}
+	}
+}
+
+func (m *mqDecoder) decode(cx *context) uint32 {
+	q := qe[cx.state]
+	m.a -= q.qe
+	var d uint32
+	if m.c>>16 < q.qe {
+		// the lower sub-interval
+		if m.a < q.qe {
+			d = cx.mps
+			cx.state = q.nmps
+		} else {
+			d = 1 - cx.mps
+			if q.switchMPS {
+				cx.mps = 1 - cx.mps
+			}
+			cx.state = q.nlps
+		}
+		m.a = q.qe
+		m.renormalize()
+		return d
+	}
+
+	m.c -= q.qe << 16
+	if m.a&0x8000 != 0 {
+		return cx.mps
+	}
+	if m.a < q.qe {
+		d = 1 - cx.mps
+		if q.switchMPS {
+			cx.mps = 1 - cx.mps
+		}
+		cx.state = q.nlps
+	} else {
+		d = cx.mps
+		cx.state = q.nmps
+	}
+	m.renormalize()
+	return d
+}
+
+// rawDecoder reads the passes of the arithmetic coding bypass mode
+type rawDecoder struct {
+	data []byte
+	pos  int
+	buf  uint32
+	bits uint
+	last byte
+}
+
+func (r *rawDecoder) init(data []byte) {
+	*r = rawDecoder{data: data}
+}
+
+func (r *rawDecoder) decode() uint32 {
+	if r.bits == 0 {
+		b := byte(0xff)
+		if r.pos < len(r.data) {
+			b = r.data[r.pos]
+			r.pos++
+		}
+		r.bits = 8
+		if r.last == 0xff {
+			// a bit is stuffed after every 0xff
+			r.bits = 7
+		}
+		r.last = b
+		r.buf = uint32(b)
+	}
+	r.bits--
+	return (r.buf >> r.bits) & 1
+}
+
+// flags of a coefficient
+const (
+	flagSignificant = 1 << iota
+	flagNegative
+	flagVisited
+	flagRefined
+)
+
+// segment is a codeword segment of a code-block, the passes that are coded
+// with one terminated arithmetic or raw codeword
+type segment struct {
+	data   []byte
+	passes int
+	max    int
+}
+
+// codeBlock is a code-block and the segments the packets contributed
+type codeBlock struct {
+	x0, y0, x1, y1 int
+
+	included      bool
+	zeroBitPlanes int
+	lblock        int
+	passes        int
+	segments      []*segment
+}
+
+// segmentMax is the number of passes that segment n of a code-block can hold
+func segmentMax(style, n int) int {
+	if style&styleTermAll != 0 {
+		return 1
+	}
+	if style&styleBypass != 0 {
+		if n == 0 {
+			return 10
+		}
+		if n%2 == 1 {
+			return 2
+		}
+		return 1
+	}
+	return 1 << 30
+}
+
+// t1 decodes the passes of a code-block, annex D
+type t1 struct {
+	w, h  int
+	flags []uint8
+	mag   []int32
+	style int
+	kind  int
+
+	mq  mqDecoder
+	raw rawDecoder
+	cx  [numCtx]context
+}
+
+// zcContexts is table D.1 indexed by band kind, then the number of
+// significant horizontal, vertical and diagonal neighbours
+var zcContexts [4][3][3][5]uint8
+
+func init() {
+	for kind := 0; kind < 4; kind++ {
+		for h := 0; h < 3; h++ {
+			for v := 0; v < 3; v++ {
+				for d := 0; d < 5; d++ {
+					zcContexts[kind][h][v][d] = zcContext(kind, h, v, d)
+				}
+			}
+		}
+	}
+}
+
+func zcContext(kind, h, v, d int) uint8 {
+	if kind == bandHH {
+		hv := h + v
+		switch {
+		case d >= 3:
+			return 8
+		case d == 2 && hv >= 1:
+			return 7
+		case d == 2:
+			return 6
+		case d == 1 && hv >= 2:
+			return 5
+		case d == 1 && hv == 1:
+			return 4
+		case d == 1:
+			return 3
+		case hv >= 2:
+			return 2
+		case hv == 1:
+			return 1
+		}
+		return 0
+	}
+	if kind == bandHL {
+		// the horizontally high-pass band swaps the roles of the neighbours
+		h, v = v, h
+	}
+	switch {
+	case h == 2:
+		return 8
+	case h == 1 && v >= 1:
+		return 7
+	case h == 1 && d >= 1:
+		return 6
+	case h == 1:
+		return 5
+	case v == 2:
+		return 4
+	case v == 1:
+		return 3
+	case d >= 2:
+		return 2
+	case d == 1:
+		return 1
+	}
+	return 0
+}
+
+// index of a coefficient in the padded flags
+func (t *t1) index(x, y int) int {
+	return (y+1)*(t.w+2) + x + 1
+}
+
+// causal reports whether the neighbours below the coefficient belong to the
+// next stripe and must be ignored
+func (t *t1) causal(y int) bool {
+	return t.style&styleVerticalCause != 0 && y%4 == 3
+}
+
+func (t *t1) neighbours(i int, causal bool) (h, v, d int) {
+	s := t.w + 2
+	f := t.flags
+	h = int(f[i-1]&flagSignificant + f[i+1]&flagSignificant)
+	v = int(f[i-s] & flagSignificant)
+	d = int(f[i-s-1]&flagSignificant + f[i-s+1]&flagSignificant)
+	if !causal {
+		v += int(f[i+s] & flagSignificant)
+		d += int(f[i+s-1]&flagSignificant + f[i+s+1]&flagSignificant)
+	}
+	return
+}
+
+func (t *t1) hasNeighbours(i int, causal bool) bool {
+	h, v, d := t.neighbours(i, causal)
+	return h+v+d != 0
+}
+
+// contribution of a neighbour to the sign context, table D.2
+func (t *t1) contribution(j int) int {
+	f := t.flags[j]
+	if f&flagSignificant == 0 {
+		return 0
+	}
+	if f&flagNegative != 0 {
+		return -1
+	}
+	return 1
+}
+
+func clampUnit(v int) int {
+	if v > 1 {
+		return 1
+	}
+	if v < -1 {
+		return -1
+	}
+	return v
+}
+
+// signContext returns the context and XOR bit of table D.3
+func (t *t1) signContext(i int, causal bool) (int, uint32) {
+	s := t.w + 2
+	h := clampUnit(t.contribution(i-1) + t.contribution(i+1))
+	down := 0
+	if !causal {
+		down = t.contribution(i + s)
+	}
+	v := clampUnit(t.contribution(i-s) + down)
+
+	var xor uint32
+	if h < 0 || (h == 0 && v < 0) {
+		h, v, xor = -h, -v, 1
+	}
+	switch {
+	case h == 1:
+		return ctxSign + 3 + v, xor
+	default:
+		return ctxSign + abs(v), xor
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (t *t1) decodeSign(i int, causal, raw bool) {
+	var negative uint32
+	if raw {
+		negative = t.raw.decode()
+	} else {
+		ctx, xor := t.signContext(i, causal)
+		negative = t.mq.decode(&t.cx[ctx]) ^ xor
+	}
+	t.flags[i] |= flagSignificant
+	if negative == 1 {
+		t.flags[i] |= flagNegative
+	}
+}
+
+func (t *t1) significancePass(bp uint, raw bool) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := t.index(x, y)
+				if t.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				causal := t.causal(y)
+				h, v, d := t.neighbours(i, causal)
+				if h+v+d == 0 {
+					continue
+				}
+				var bit uint32
+				if raw {
+					bit = t.raw.decode()
+				} else {
+					bit = t.mq.decode(&t.cx[zcContexts[t.kind][h][v][d]])
+				}
+				if bit == 1 {
+					t.decodeSign(i, causal, raw)
+					t.mag[y*t.w+x] |= 1 << bp
+				}
+				t.flags[i] |= flagVisited
+			}
+		}
+	}
+}
+
+func (t *t1) refinementPass(bp uint, raw bool) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := t.index(x, y)
+				f := t.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				var bit uint32
+				if raw {
+					bit = t.raw.decode()
+				} else {
+					ctx := ctxRefine + 2
+					if f&flagRefined == 0 {
+						ctx = ctxRefine
+						if t.hasNeighbours(i, t.causal(y)) {
+							ctx = ctxRefine + 1
+						}
+					}
+					bit = t.mq.decode(&t.cx[ctx])
+				}
+				t.mag[y*t.w+x] |= int32(bit) << bp
+				t.flags[i] |= flagRefined
+			}
+		}
+	}
+}
+
+func (t *t1) cleanupPass(bp uint) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			y := y0
+			if y0+4 <= t.h && t.runLength(x, y0) {
+				// all four coefficients of the column are coded at once
+				if t.mq.decode(&t.cx[ctxRun]) == 0 {
+					continue
+				}
+				run := t.mq.decode(&t.cx[ctxUniform]) << 1
+				run |= t.mq.decode(&t.cx[ctxUniform])
+				y = y0 + int(run)
+				i := t.index(x, y)
+				t.decodeSign(i, t.causal(y), false)
+				t.mag[y*t.w+x] |= 1 << bp
+				y++
+			}
+			for ; y < y0+4 && y < t.h; y++ {
+				i := t.index(x, y)
+				if t.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				causal := t.causal(y)
+				h, v, d := t.neighbours(i, causal)
+				if t.mq.decode(&t.cx[zcContexts[t.kind][h][v][d]]) == 1 {
+					t.decodeSign(i, causal, false)
+					t.mag[y*t.w+x] |= 1 << bp
+				}
+			}
+		}
+	}
+
+	for i := range t.flags {
+		t.flags[i] &^= flagVisited
+	}
+	if t.style&styleSegSymbols != 0 {
+		// the segmentation symbol 1010 is only a check for errors
+		for i := 0; i < 4; i++ {
+			t.mq.decode(&t.cx[ctxUniform])
+		}
+	}
+}
+
+// runLength reports whether the stripe column at x, y0 is coded in run
+// length mode
+func (t *t1) runLength(x, y0 int) bool {
+	for y := y0; y < y0+4; y++ {
+		i := t.index(x, y)
+		if t.flags[i]&(flagSignificant|flagVisited) != 0 {
+			return false
+		}
+		if t.hasNeighbours(i, t.causal(y)) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeBlock decodes the segments of a code-block with mb magnitude bit
+// planes, it returns the magnitudes, flags and the last bit plane decoded
+func decodeBlock(cb *codeBlock, kind, style, mb int) (*t1, int, error) {
+	t := &t1{
+		w:     cb.x1 - cb.x0,
+		h:     cb.y1 - cb.y0,
+		style: style,
+		kind:  kind,
+	}
+	t.flags = make([]uint8, (t.w+2)*(t.h+2))
+	t.mag = make([]int32, t.w*t.h)
+	resetContexts(&t.cx)
+
+	planes := mb - cb.zeroBitPlanes
+	if cb.passes == 0 || planes <= 0 {
+		return t, 0, nil
+	}
+	if planes > 31 {
+		return nil, 0, fmt.Errorf("jpeg2000: %d bit planes in a code-block", planes)
+	}
+
+	pass := 0
+	bp := planes - 1
+	for _, seg := range cb.segments {
+		for n := 0; n < seg.passes; n++ {
+			// passes are a cleanup pass followed by significance, refinement
+			// and cleanup passes of each lower bit plane
+			kind := (pass + 2) % 3
+			raw := style&styleBypass != 0 && pass >= 10 && kind != 2
+			if n == 0 {
+				if raw {
+					t.raw.init(seg.data)
+				} else {
+					t.mq.init(seg.data)
+				}
+			}
+			if bp < 0 {
+				return nil, 0, fmt.Errorf("jpeg2000: code-block has too many passes")
+			}
+			switch kind {
+			case 0:
+				t.significancePass(uint(bp), raw)
+			case 1:
+				t.refinementPass(uint(bp), raw)
+			case 2:
+				t.cleanupPass(uint(bp))
+			}
+			last := bp
+			if kind == 2 {
+				bp--
+			}
+			if style&styleReset != 0 {
+				resetContexts(&t.cx)
+			}
+			pass++
+			if pass == cb.passes {
+				return t, last, nil
+			}
+		}
+	}
+	return t, bp + 1, nil
+}
@@ -0,0 +1,147 @@
+package grib2
+
+import (
+	"fmt"
+)
+
+// DataRepresentation is section 5 of a field
+type DataRepresentation struct {
+	TemplateNumber int
+	// NumValues is the number of packed values, points outside the bitmap
+	// are not packed
+	NumValues int
+
+	// Reference is R, BinaryScale E and DecimalScale D of the packing
+	// equation Y * 10^D = R + X * 2^E
+	Reference    float32
+	BinaryScale  int
+	DecimalScale int
+	Bits         int
+	// OriginalType is code table 5.1, 0 floating point and 1 integer
+	OriginalType int
+
+	// Complex is set for templates 5.2 and 5.3
+	Complex *Complex
+
+	// err is set when the template is not supported, only TemplateNumber
+	// and NumValues are read then
+	err     error
+	section []byte
+}
+
+func parseDataRepresentation(section []byte) (*DataRepresentation, error) {
+	if err := checkLength(section, 11, "section 5"); err != nil {
+		return nil, err
+	}
+	r := &DataRepresentation{
+		NumValues:      int(uint32At(section, 6)),
+		TemplateNumber: int(uint16At(section, 10)),
+		section:        section,
+	}
+
+	switch r.TemplateNumber {
+	case 0, 40, 41:
+		if err := checkLength(section, 21, fmt.Sprintf("data representation template 5.%d", r.TemplateNumber)); err != nil {
+			return nil, err
+		}
+		r.parseSimple(section)
+		return r, nil
+	case 2, 3:
+		if err := r.parseComplex(section); err != nil {
+			return nil, err
+		}
+		return r, r.err
+	}
+	r.err = &UnsupportedError{fmt.Sprintf("data representation template 5.%d", r.TemplateNumber)}
+	return r, r.err
+}
+
+// Err returns why the values cannot be unpacked, nil when the template is
+// supported
+func (r *DataRepresentation) Err() error {
+	return r.err
+}
+
+// parseSimple reads the octets shared by every template based on simple
+// packing
+func (r *DataRepresentation) parseSimple(section []byte) {
+	r.Reference = float32At(section, 12)
+	r.BinaryScale = int16At(section, 16)
+	r.DecimalScale = int16At(section, 18)
+	r.Bits = int(section[19])
+	r.OriginalType = int(section[20])
+}
+
+// unpack decodes the packed values of section 7
+func (r *DataRepresentation) unpack(data []byte) ([]float32, error) {
+	switch r.TemplateNumber {
+	case 0:
+		return r.unpackSimple(data)
+	case 2, 3:
+		return r.unpackComplex(data)
+	case 40:
+		return r.unpackJPEG2000(data)
+	case 41:
+		return r.unpackPNG(data)
+	}
+	return nil, fmt.Errorf("grib2: data representation template 5.%d is not supported", r.TemplateNumber)
+}
+
+// scale returns the packing equation for the reference, binary and decimal
+// scale factors. It is evaluated the way wgrib2 does, R * 10^-D + X * 2^E *
+// 10^-D in double precision, so values are identical to its output
+func (r *DataRepresentation) scale() func(x float64) float32 {
+	dscale := intPower(10, -r.DecimalScale)
+	ref := float64(r.Reference) * dscale
+	bscale := intPower(2, r.BinaryScale) * dscale
+	return func(x float64) float32 {
+		return float32(ref + bscale*x)
+	}
+}
+
+// constant fills a field that only has the reference value
+func (r *DataRepresentation) constant() []float32 {
+	values := make([]float32, r.NumValues)
+	c := r.scale()(0)
+	for i := range values {
+		values[i] = c
+	}
+	return values
+}
+
+// intPower raises x to an integer power by repeated squaring, matching the
+// rounding of wgrib2's Int_Power rather than math.Pow
+func intPower(x float64, y int) float64 {
+	if y < 0 {
+		y = -y
+		x = 1 / x
+	}
+	v := 1.0
+	for y != 0 {
+		if y&1 != 0 {
+			v *= x
+		}
+		x *= x
+		y >>= 1
+	}
+	return v
+}
+
+// unpackSimple decodes template 5.0, every value is packed with the same
+// number of bits
+func (r *DataRepresentation) unpackSimple(data []byte) ([]float32, error) {
+	if r.Bits == 0 {
+		return r.constant(), nil
+	}
+
+	values := make([]float32, r.NumValues)
+	scale := r.scale()
+	packed, err := unpackBits(data, uint(r.Bits), r.NumValues)
+	if err != nil {
+		return nil, err
+	}
+	for i, x := range packed {
+		values[i] = scale(float64(x))
+	}
+	return values, nil
+}
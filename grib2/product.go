@@ -0,0 +1,195 @@
+package grib2
+
+import (
+	"fmt"
+	"time"
+)
+
+// Surface is a fixed surface of code table 4.5 with its value
+type Surface struct {
+	Type int
+	// Value is the scaled value of the surface, it is only meaningful when
+	// HasValue is true
+	Value    float64
+	HasValue bool
+}
+
+// ProductDefinition is section 4 of a field
+type ProductDefinition struct {
+	TemplateNumber int
+
+	Category int
+	Number   int
+	// GeneratingProcess is code table 4.3, 0 analysis and 2 forecast
+	GeneratingProcess int
+	// TimeUnit is code table 4.4 and applies to ForecastTime
+	TimeUnit     int
+	ForecastTime int
+
+	FirstSurface  Surface
+	SecondSurface Surface
+
+	// statistically processed products such as accumulations
+	HasInterval bool
+	IntervalEnd time.Time
+	// StatisticalProcess is code table 4.10, 1 is an accumulation
+	StatisticalProcess int
+	IntervalUnit       int
+	IntervalLength     int
+
+	// ensemble products
+	IsEnsemble         bool
+	EnsembleType       int
+	PerturbationNumber int
+	EnsembleSize       int
+
+	// err is set when the template is not supported, only the category and
+	// number are read then
+	err     error
+	section []byte
+}
+
+func parseProductDefinition(section []byte) (*ProductDefinition, error) {
+	if err := checkLength(section, 9, "section 4"); err != nil {
+		return nil, err
+	}
+	p := &ProductDefinition{TemplateNumber: int(uint16At(section, 8)), section: section}
+
+	switch p.TemplateNumber {
+	case 0, 1, 8, 11:
+	default:
+		// every template starts with the parameter category and number
+		if len(section) >= 11 {
+			p.Category = int(section[9])
+			p.Number = int(section[10])
+		}
+		p.FirstSurface.Type = missing1
+		p.SecondSurface.Type = missing1
+		p.err = &UnsupportedError{fmt.Sprintf("product definition template 4.%d", p.TemplateNumber)}
+		return p, p.err
+	}
+
+	if err := checkLength(section, 34, "product definition template"); err != nil {
+		return nil, err
+	}
+	p.Category = int(section[9])
+	p.Number = int(section[10])
+	p.GeneratingProcess = int(section[11])
+	p.TimeUnit = int(section[17])
+	p.ForecastTime = int32At(section, 19)
+	p.FirstSurface = parseSurface(section, 23)
+	p.SecondSurface = parseSurface(section, 29)
+
+	// octet where the time interval starts, after the ensemble octets
+	intervalOctet := 35
+	if p.TemplateNumber == 1 || p.TemplateNumber == 11 {
+		if err := checkLength(section, 37, "product definition template"); err != nil {
+			return nil, err
+		}
+		p.IsEnsemble = true
+		p.EnsembleType = int(section[34])
+		p.PerturbationNumber = int(section[35])
+		p.EnsembleSize = int(section[36])
+		intervalOctet = 38
+	}
+
+	if p.TemplateNumber == 8 || p.TemplateNumber == 11 {
+		if err := checkLength(section, intervalOctet+23, "product definition template"); err != nil {
+			return nil, err
+		}
+		p.HasInterval = true
+		p.IntervalEnd = time.Date(
+			int(uint16At(section, intervalOctet)), time.Month(section[intervalOctet+1]), int(section[intervalOctet+2]),
+			int(section[intervalOctet+3]), int(section[intervalOctet+4]), int(section[intervalOctet+5]), 0, time.UTC)
+		// only the first time range is read, nimbus products have one
+		p.StatisticalProcess = int(section[intervalOctet+11])
+		p.IntervalUnit = int(section[intervalOctet+13])
+		p.IntervalLength = int(uint32At(section, intervalOctet+15))
+	}
+
+	return p, nil
+}
+
+func parseSurface(section []byte, octet int) Surface {
+	s := Surface{Type: int(section[octet-1])}
+	factor := section[octet]
+	value := uint32At(section, octet+2)
+	if factor != missing1 && value != missing4 {
+		s.Value = scaled(int8At(section, octet+1), int32At(section, octet+2))
+		s.HasValue = true
+	}
+	return s
+}
+
+// Duration converts an amount of a code table 4.4 unit to a duration, months
+// and longer have no fixed length and are reported as false
+func Duration(unit, amount int) (time.Duration, bool) {
+	var d time.Duration
+	switch unit {
+	case 0:
+		d = time.Minute
+	case 1:
+		d = time.Hour
+	case 2:
+		d = 24 * time.Hour
+	case 10:
+		d = 3 * time.Hour
+	case 11:
+		d = 6 * time.Hour
+	case 12:
+		d = 12 * time.Hour
+	case 13:
+		d = time.Second
+	default:
+		return 0, false
+	}
+	return time.Duration(amount) * d, true
+}
+
+// addTime adds an amount of a code table 4.4 unit to a time
+func addTime(t time.Time, unit, amount int) (time.Time, error) {
+	if d, ok := Duration(unit, amount); ok {
+		return t.Add(d), nil
+	}
+	switch unit {
+	case 3:
+		return t.AddDate(0, amount, 0), nil
+	case 4:
+		return t.AddDate(amount, 0, 0), nil
+	case 5:
+		return t.AddDate(10*amount, 0, 0), nil
+	case 6:
+		return t.AddDate(30*amount, 0, 0), nil
+	case 7:
+		return t.AddDate(100*amount, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("grib2: unknown time unit %d", unit)
+}
+
+// Err returns why the product cannot be read, nil when its template is
+// supported
+func (p *ProductDefinition) Err() error {
+	return p.err
+}
+
+// ForecastHours is the forecast time in hours, fractional for minute units
+func (p *ProductDefinition) ForecastHours() (float64, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	d, ok := Duration(p.TimeUnit, p.ForecastTime)
+	if !ok {
+		return 0, fmt.Errorf("grib2: forecast time unit %d has no fixed length", p.TimeUnit)
+	}
+	return d.Hours(), nil
+}
+
+func (p *ProductDefinition) verfTime(ref time.Time) (time.Time, error) {
+	if p.err != nil {
+		return time.Time{}, p.err
+	}
+	if p.HasInterval {
+		return p.IntervalEnd, nil
+	}
+	return addTime(ref, p.TimeUnit, p.ForecastTime)
+}
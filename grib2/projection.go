@@ -0,0 +1,248 @@
+package grib2
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const radians = math.Pi / 180
+
+// latLon is the projection of template 3.0, di and dj are signed increments in
+// degrees
+type latLon struct {
+	la1, lo1 float64
+	di, dj   float64
+	ni       int
+}
+
+func newLatLon(la1, lo1, di, dj float64, ni int) *latLon {
+	return &latLon{la1: la1, lo1: lo1, di: di, dj: dj, ni: ni}
+}
+
+func (p *latLon) latLon(i, j float64) (float64, float64) {
+	return p.la1 + j*p.dj, p.lo1 + i*p.di
+}
+
+func (p *latLon) ij(lat, lon float64) (float64, float64) {
+	j := 0.0
+	if p.dj != 0 {
+		j = (lat - p.la1) / p.dj
+	}
+	return lonIndex(lon-p.lo1, p.di, p.ni), j
+}
+
+// lonIndex returns the fractional number of increments di a longitude is away
+// from the first grid point. Longitudes wrap, so of the two candidates the one
+// closer to the ni points of the grid is used
+func lonIndex(offset, di float64, ni int) float64 {
+	if di == 0 {
+		return 0
+	}
+	if di < 0 {
+		offset = -offset
+	}
+	steps := 360 / math.Abs(di)
+	i := normalizeLon(offset) / math.Abs(di)
+	if i > (float64(ni-1)+steps)/2 {
+		i -= steps
+	}
+	return i
+}
+
+// gaussian is the projection of template 3.40, latitudes are the roots of a
+// Legendre polynomial instead of equally spaced
+type gaussian struct {
+	lo1, di float64
+	ni      int
+	// lats holds the latitudes of the global grid from north to south, j0 is
+	// the first grid point and dj is 1 when the grid is scanned northwards
+	lats []float64
+	j0   int
+	dj   int
+}
+
+func newGaussian(la1, lo1, di, dj float64, n, ni, nj int) (*gaussian, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("grib2: Gaussian grid with %d parallels", n)
+	}
+	p := &gaussian{lo1: lo1, di: di, ni: ni, lats: gaussianLatitudes(2 * n), dj: int(dj)}
+
+	// the first latitude is rounded to the octets it is stored in
+	p.j0 = -1
+	for k, lat := range p.lats {
+		if math.Abs(lat-la1) < 0.01 {
+			p.j0 = k
+			break
+		}
+	}
+	last := p.j0 - p.dj*(nj-1)
+	if p.j0 < 0 || last < 0 || last >= len(p.lats) {
+		return nil, fmt.Errorf("grib2: latitude %g and %d rows do not fit a Gaussian grid of %d parallels", la1, nj, n)
+	}
+	return p, nil
+}
+
+func (p *gaussian) latLon(i, j float64) (float64, float64) {
+	k := float64(p.j0) - float64(p.dj)*j
+	return p.latitude(k), p.lo1 + i*p.di
+}
+
+func (p *gaussian) ij(lat, lon float64) (float64, float64) {
+	j := (float64(p.j0) - p.row(lat)) * float64(p.dj)
+	return lonIndex(lon-p.lo1, p.di, p.ni), j
+}
+
+// latitude interpolates the latitudes at a fractional row of the global grid
+func (p *gaussian) latitude(k float64) float64 {
+	last := len(p.lats) - 1
+	k0 := int(math.Floor(k))
+	if k0 < 0 {
+		k0 = 0
+	} else if k0 >= last {
+		k0 = last - 1
+	}
+	return p.lats[k0] + (k-float64(k0))*(p.lats[k0+1]-p.lats[k0])
+}
+
+// row is the inverse of latitude, the half rows beyond the outermost
+// latitudes reach the poles, which round to the outermost rows
+func (p *gaussian) row(lat float64) float64 {
+	last := len(p.lats) - 1
+	if lat >= p.lats[0] {
+		return -0.5 * (lat - p.lats[0]) / (90 - p.lats[0])
+	}
+	if lat <= p.lats[last] {
+		k := float64(last) + 0.5*(p.lats[last]-lat)/(90+p.lats[last])
+		return math.Min(k, math.Nextafter(float64(last)+0.5, 0))
+	}
+	k := sort.Search(len(p.lats), func(k int) bool { return p.lats[k] <= lat })
+	return float64(k-1) + (p.lats[k-1]-lat)/(p.lats[k-1]-p.lats[k])
+}
+
+// gaussianLatitudes returns the n latitudes of a Gaussian grid from north to
+// south, the roots of the Legendre polynomial of degree n found with Newton's
+// method
+func gaussianLatitudes(n int) []float64 {
+	lats := make([]float64, n)
+	for k := 0; k < n/2; k++ {
+		z := math.Cos(math.Pi * (float64(k) + 0.75) / (float64(n) + 0.5))
+		for iter := 0; iter < 100; iter++ {
+			p1, p2 := 1.0, 0.0
+			for m := 1; m <= n; m++ {
+				p1, p2 = ((2*float64(m)-1)*z*p1-(float64(m)-1)*p2)/float64(m), p1
+			}
+			dp := float64(n) * (z*p1 - p2) / (z*z - 1)
+			dz := p1 / dp
+			z -= dz
+			if math.Abs(dz) < 1e-15 {
+				break
+			}
+		}
+		lat := math.Asin(z) / radians
+		lats[k], lats[n-1-k] = lat, -lat
+	}
+	return lats
+}
+
+// lambert is the projection of template 3.30 on a sphere, the same equations
+// as wgrib2
+type lambert struct {
+	n, f, rhoref float64
+	radius, lov  float64
+	// x1, y1 is the first grid point, dx and dy are signed increments in
+	// metres
+	x1, y1, dx, dy float64
+}
+
+func newLambert(la1, lo1, dx, dy, lad, lov, latin1, latin2, radius float64) (*lambert, error) {
+	phi1, phi2 := latin1*radians, latin2*radians
+	p := &lambert{radius: radius, lov: lov, dx: dx, dy: dy}
+	if math.Abs(phi1-phi2) < 1e-9 {
+		p.n = math.Sin(phi1)
+	} else {
+		p.n = math.Log(math.Cos(phi1)/math.Cos(phi2)) /
+			math.Log(math.Tan(math.Pi/4+phi2/2)/math.Tan(math.Pi/4+phi1/2))
+	}
+	if p.n == 0 || math.IsNaN(p.n) {
+		return nil, fmt.Errorf("grib2: Lambert conformal grid with secants %g and %g", latin1, latin2)
+	}
+	p.f = math.Cos(phi1) * math.Pow(math.Tan(math.Pi/4+phi1/2), p.n) / p.n
+	p.rhoref = p.rho(lad)
+	p.x1, p.y1 = p.project(la1, lo1)
+	return p, nil
+}
+
+func (p *lambert) rho(lat float64) float64 {
+	return p.radius * p.f * math.Pow(math.Tan(math.Pi/4+lat*radians/2), -p.n)
+}
+
+// project returns the position of a point on the projection plane in metres
+func (p *lambert) project(lat, lon float64) (x, y float64) {
+	rho := p.rho(lat)
+	theta := p.n * (normalizeLon(lon-p.lov+180) - 180) * radians
+	return rho * math.Sin(theta), p.rhoref - rho*math.Cos(theta)
+}
+
+func (p *lambert) latLon(i, j float64) (float64, float64) {
+	x := p.x1 + i*p.dx
+	y := p.rhoref - (p.y1 + j*p.dy)
+	sign := 1.0
+	if p.n < 0 {
+		sign = -1
+	}
+	theta := math.Atan2(sign*x, sign*y)
+	rho := sign * math.Hypot(x, y)
+	lat := 2*math.Atan(math.Pow(p.radius*p.f/rho, 1/p.n)) - math.Pi/2
+	return lat / radians, p.lov + theta/p.n/radians
+}
+
+func (p *lambert) ij(lat, lon float64) (float64, float64) {
+	x, y := p.project(lat, lon)
+	return (x - p.x1) / p.dx, (y - p.y1) / p.dy
+}
+
+// polarStereographic is the projection of template 3.20 on a sphere, the
+// same equations as wgrib2
+type polarStereographic struct {
+	// h is 1 for the north pole and -1 for the south pole, orient the
+	// longitude pointing down the y axis and de the distance from the pole to
+	// the equator in metres, scaled to be true at LaD
+	h, orient, de  float64
+	x1, y1, dx, dy float64
+}
+
+func newPolarStereographic(la1, lo1, dx, dy, lad, lov float64, south bool, radius float64) *polarStereographic {
+	p := &polarStereographic{h: 1, orient: lov, dx: dx, dy: dy}
+	if south {
+		p.h, p.orient = -1, lov-180
+	}
+	p.de = (1 + math.Sin(math.Abs(lad)*radians)) * radius
+	p.x1, p.y1 = p.project(la1, lo1)
+	return p
+}
+
+// project returns the position of a point on the projection plane in metres
+func (p *polarStereographic) project(lat, lon float64) (x, y float64) {
+	dr := p.de * math.Cos(lat*radians) / (1 + p.h*math.Sin(lat*radians))
+	a := (lon - p.orient) * radians
+	return p.h * dr * math.Sin(a), -dr * math.Cos(a)
+}
+
+func (p *polarStereographic) latLon(i, j float64) (float64, float64) {
+	x := p.x1 + i*p.dx
+	y := p.y1 + j*p.dy
+	dr2 := x*x + y*y
+	de2 := p.de * p.de
+	if dr2 < de2*1e-6 {
+		return p.h * 90, 0
+	}
+	lon := p.orient + p.h*math.Atan2(x, -y)/radians
+	lat := p.h * math.Asin((de2-dr2)/(de2+dr2)) / radians
+	return lat, lon
+}
+
+func (p *polarStereographic) ij(lat, lon float64) (float64, float64) {
+	x, y := p.project(lat, lon)
+	return (x - p.x1) / p.dx, (y - p.y1) / p.dy
+}
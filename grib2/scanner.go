@@ -0,0 +1,166 @@
+package grib2
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// source is where the sections of messages are read from
+type source interface {
+	// read returns n octets at offset, callers may keep the slice
+	read(offset int64, n int) ([]byte, error)
+}
+
+// memory is a file held in memory, base is the offset of its first octet
+type memory struct {
+	data []byte
+	base int64
+}
+
+func (m *memory) read(offset int64, n int) ([]byte, error) {
+	start := offset - m.base
+	if start < 0 || n < 0 || start+int64(n) > int64(len(m.data)) {
+		return nil, fmt.Errorf("grib2: file is truncated at offset %d", offset)
+	}
+	return m.data[start : start+int64(n)], nil
+}
+
+// blockSize is how much is read ahead of the definition sections of a
+// message, enough for all of them in the files NOMADS serves
+const blockSize = 32 << 10
+
+// readerSource reads a file through an io.ReaderAt. Small reads are served
+// from a block read ahead, so every message costs about one read until its
+// values are unpacked
+type readerSource struct {
+	r     io.ReaderAt
+	block []byte
+	start int64
+}
+
+func (s *readerSource) read(offset int64, n int) ([]byte, error) {
+	if offset >= s.start && offset+int64(n) <= s.start+int64(len(s.block)) {
+		return s.block[offset-s.start : offset-s.start+int64(n)], nil
+	}
+	size := n
+	if size < blockSize {
+		size = blockSize
+	}
+	// a new block every time, slices of the previous one may be kept
+	buf := make([]byte, size)
+	got, err := s.r.ReadAt(buf, offset)
+	if got < n {
+		if err == nil || err == io.EOF {
+			err = fmt.Errorf("grib2: file is truncated at offset %d", offset)
+		}
+		return nil, err
+	}
+	if n < blockSize {
+		s.block, s.start = buf[:got], offset
+	}
+	return buf[:n], nil
+}
+
+// find returns the offset of the next message at or after offset, or io.EOF
+func (s *readerSource) find(offset int64) (int64, error) {
+	marker := []byte("GRIB")
+	for {
+		var buf []byte
+		var err error
+		if offset >= s.start && offset+int64(len(marker)) <= s.start+int64(len(s.block)) {
+			buf = s.block[offset-s.start:]
+		} else {
+			buf = make([]byte, blockSize)
+			var got int
+			got, err = s.r.ReadAt(buf, offset)
+			buf = buf[:got]
+			s.block, s.start = buf, offset
+		}
+		if i := bytes.Index(buf, marker); i >= 0 {
+			return offset + int64(i), nil
+		}
+		if err == io.EOF || len(buf) < len(marker) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		// the marker may straddle two reads
+		offset += int64(len(buf) - len(marker) + 1)
+	}
+}
+
+// Scanner reads the messages of a GRIB2 file one at a time through an
+// io.ReaderAt, such as an *os.File or a reader of HTTP ranges. Only the
+// definition sections are read while scanning, bitmaps and packed values
+// are read when Field.Values is called, so skipping a message costs little
+// more than reading its headers:
+//
+//	s := grib2.NewScanner(f)
+//	for s.Scan() {
+//		field := s.Message().Fields[0]
+//		if field.Parameter().Name != "TMP" {
+//			continue
+//		}
+//		values, err := field.Values()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	// Filter, when set, skips the messages it returns false for
+	Filter func(*Message) bool
+
+	src *readerSource
+	// index skips all but sections 0, 1 and 4
+	index   bool
+	offset  int64
+	message *Message
+	err     error
+}
+
+// NewScanner returns a Scanner reading messages from the start of r
+func NewScanner(r io.ReaderAt) *Scanner {
+	return &Scanner{src: &readerSource{r: r}}
+}
+
+// Scan advances to the next message that passes the filter, it returns
+// false at the end of the file or on an error
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	for {
+		offset, err := s.src.find(s.offset)
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			s.message = nil
+			return false
+		}
+		m, err := readMessage(s.src, offset, s.index)
+		if err != nil {
+			s.err = err
+			s.message = nil
+			return false
+		}
+		s.offset = offset + m.Length
+		if s.Filter == nil || s.Filter(m) {
+			s.message = m
+			return true
+		}
+	}
+}
+
+// Message returns the message read by the last call to Scan
+func (s *Scanner) Message() *Message {
+	return s.message
+}
+
+// Err returns the first error met while scanning
+func (s *Scanner) Err() error {
+	return s.err
+}
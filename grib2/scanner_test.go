@@ -0,0 +1,76 @@
+package grib2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	data := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")
+	// a block of padding moves the second message past the read ahead
+	padded := append(append([]byte(nil), data[:17378]...), make([]byte, blockSize+7)...)
+	padded = append(padded, data[17378:]...)
+
+	for _, file := range [][]byte{data, padded} {
+		s := NewScanner(bytes.NewReader(file))
+		var names []string
+		for s.Scan() {
+			m := s.Message()
+			if string(file[m.Offset:m.Offset+4]) != "GRIB" {
+				t.Errorf("message at %d does not start with GRIB", m.Offset)
+			}
+			f := m.Fields[0]
+			name := f.Parameter().Name
+			names = append(names, name)
+			values, err := f.Values()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for n, want := range gfsPoints[name] {
+				if values[n] != want {
+					t.Errorf("%s: value %d = %v, want %v", name, n, values[n], want)
+				}
+			}
+		}
+		if err := s.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 2 || names[0] != "UGRD" || names[1] != "VGRD" {
+			t.Errorf("scanned %v, want UGRD and VGRD", names)
+		}
+	}
+}
+
+func TestScannerFilter(t *testing.T) {
+	data := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")
+	s := NewScanner(bytes.NewReader(data))
+	s.Filter = func(m *Message) bool {
+		return m.Fields[0].Parameter().Name == "VGRD"
+	}
+	n := 0
+	for s.Scan() {
+		if m := s.Message(); m.Offset != 17378 {
+			t.Errorf("message at %d, want the VGRD one at 17378", m.Offset)
+		}
+		n++
+	}
+	if err := s.Err(); err != nil || n != 1 {
+		t.Errorf("%d messages, err %v, want 1", n, err)
+	}
+}
+
+func TestScannerTruncated(t *testing.T) {
+	data := readFixtureBytes(t, "gfs.t00z.pgrb2.0p25.f001")
+	s := NewScanner(bytes.NewReader(data[:len(data)-100]))
+	n := 0
+	for s.Scan() {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("%d messages before the truncated one, want 1", n)
+	}
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("err = %v, want one about the truncated message", err)
+	}
+}
@@ -0,0 +1,249 @@
+package grib2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CentreNCEP is the originating centre of NCEP products, code table C-11
+const CentreNCEP = 7
+
+// Parameter is the short name, description and unit of a product
+type Parameter struct {
+	Name        string
+	Description string
+	Unit        string
+}
+
+type parameterKey struct {
+	discipline int
+	category   int
+	number     int
+}
+
+// isLocal reports whether a code of a WMO table is reserved for local use
+func isLocal(code int) bool {
+	return code >= 192 && code <= 254
+}
+
+// LookupParameter names a product of a centre by its discipline, category
+// and number. Local disciplines, categories and numbers are looked up in the
+// tables of the centre, only NCEP's are known. Unknown products get a stable
+// var<discipline>_<category>_<number> name
+func LookupParameter(centre, discipline, category, number int) Parameter {
+	key := parameterKey{discipline, category, number}
+	table := wmoParameters
+	if isLocal(discipline) || isLocal(category) || isLocal(number) {
+		table = nil
+		if centre == CentreNCEP {
+			table = ncepParameters
+		}
+	}
+	if p, ok := table[key]; ok {
+		return p
+	}
+	return Parameter{
+		Name:        fmt.Sprintf("var%d_%d_%d", discipline, category, number),
+		Description: "unknown",
+		Unit:        "unknown",
+	}
+}
+
+// fixedSurfaces is code table 4.5 in wgrib2's wording, %s is replaced by the
+// value of the surface
+var fixedSurfaces = map[int]string{
+	1:   "surface",
+	2:   "cloud base",
+	3:   "cloud top",
+	4:   "0C isotherm",
+	5:   "level of adiabatic condensation from sfc",
+	6:   "max wind",
+	7:   "tropopause",
+	8:   "top of atmosphere",
+	9:   "sea bottom",
+	10:  "entire atmosphere",
+	11:  "cumulonimbus base",
+	12:  "cumulonimbus top",
+	13:  "lowest level %s% integrated cloud cover",
+	14:  "level of free convection",
+	15:  "convection condensation level",
+	16:  "level of neutral buoyancy",
+	20:  "%s K level",
+	100: "%s mb",
+	101: "mean sea level",
+	102: "%s m above mean sea level",
+	103: "%s m above ground",
+	104: "%s sigma level",
+	105: "%s hybrid level",
+	106: "%s m below ground",
+	107: "%s K isentropic level",
+	108: "%s mb above ground",
+	109: "PV=%s (Km^2/kg/s) surface",
+	111: "%s Eta level",
+	113: "logarithmic hybrid level",
+	114: "snow level",
+	117: "mixed layer depth",
+	118: "hybrid height level",
+	119: "hybrid pressure level",
+	150: "%s generalized vertical height coordinate",
+	151: "soil level %s",
+	160: "%s m below sea level",
+	161: "%s m below water surface",
+	162: "lake or river bottom",
+	163: "bottom of sediment layer",
+	164: "bottom of thermally active sediment layer",
+	165: "bottom of sediment layer penetrated by thermal wave",
+	166: "maxing layer",
+	167: "bottom of root zone",
+	174: "top surface of ice on sea, lake or river",
+	175: "top surface of ice, und snow on sea, lake or river",
+	176: "bottom surface ice on sea, lake or river",
+	177: "deep soil",
+	179: "top surface of glacier ice and inland ice",
+	180: "deep inland or glacier ice",
+	181: "grid tile land fraction as a model surface",
+	182: "grid tile water fraction as a model surface",
+	183: "grid tile ice fraction on sea, lake or river as a model surface",
+	184: "grid tile glacier ice and inland ice fraction as a model surface",
+}
+
+// ncepSurfaces are NCEP's local entries of code table 4.5
+var ncepSurfaces = map[int]string{
+	200: "entire atmosphere (considered as a single layer)",
+	201: "entire ocean (considered as a single layer)",
+	204: "highest tropospheric freezing level",
+	206: "grid scale cloud bottom level",
+	207: "grid scale cloud top level",
+	209: "boundary layer cloud bottom level",
+	210: "boundary layer cloud top level",
+	211: "boundary layer cloud layer",
+	212: "low cloud bottom level",
+	213: "low cloud top level",
+	214: "low cloud layer",
+	215: "cloud ceiling",
+	220: "planetary boundary layer",
+	221: "layer between two hybrid levels",
+	222: "middle cloud bottom level",
+	223: "middle cloud top level",
+	224: "middle cloud layer",
+	232: "high cloud bottom level",
+	233: "high cloud top level",
+	234: "high cloud layer",
+	236: "layer between two depths below ocean surface",
+	237: "bottom of ocean mixed layer",
+	238: "bottom of ocean isothermal layer",
+	239: "layer ocean surface and 26C ocean isothermal level",
+	240: "ocean mixed layer",
+	241: "%s in sequence",
+	242: "convective cloud bottom level",
+	243: "convective cloud top level",
+	244: "convective cloud layer",
+	245: "lowest level of the wet bulb zero",
+	246: "maximum equivalent potential temperature level",
+	247: "equilibrium level",
+	248: "shallow convective cloud bottom level",
+	249: "shallow convective cloud top level",
+	251: "deep convective cloud bottom level",
+	252: "deep convective cloud top level",
+	253: "lowest bottom level of supercooled liquid water layer",
+	254: "highest top level of supercooled liquid water layer",
+}
+
+// layers are the fixed surface types wgrib2 prints as a single layer when
+// both surfaces have the type
+var layers = map[int]string{
+	100: "%s mb",
+	102: "%s m above mean sea level",
+	103: "%s m above ground",
+	104: "%s sigma layer",
+	105: "%s hybrid layer",
+	106: "%s m below ground",
+	107: "%s K isentropic layer",
+	108: "%s mb above ground",
+	160: "%s m below sea level",
+	161: "%s m ocean layer",
+}
+
+// Level describes the fixed surfaces of the field the way wgrib2 prints
+// them, e.g. "2 m above ground" or "0-0.1 m below ground"
+func (f *Field) Level() string {
+	return DescribeLevel(f.Message.Identification.Centre, f.Product.FirstSurface, f.Product.SecondSurface)
+}
+
+// DescribeLevel describes the fixed surfaces of a product of a centre the
+// way wgrib2 prints them, local surface types are only known for NCEP
+func DescribeLevel(centre int, first, second Surface) string {
+	ncep := centre == CentreNCEP
+	if format, ok := layers[first.Type]; ok && second.Type == first.Type {
+		return strings.Replace(format, "%s", surfaceValue(first)+"-"+surfaceValue(second), 1)
+	}
+	switch {
+	case first.Type == 1 && second.Type == 8:
+		return "atmos col"
+	case first.Type == 9 && second.Type == 1:
+		return "ocean column"
+	case ncep && first.Type == 235 && second.Type == 235:
+		return formatLevel(first.Value/10) + "-" + formatLevel(second.Value/10) + "C ocean isotherm layer"
+	case ncep && first.Type == 236 && second.Type == 236:
+		// obsolete, the depths are in units of 10 m
+		return formatLevel(first.Value*10) + "-" + formatLevel(second.Value*10) + " m ocean layer"
+	case first.Type == 255 && second.Type == 255:
+		return "no_level"
+	}
+
+	level := describeSurface(ncep, first)
+	if second.Type != 255 {
+		level += " - " + describeSurface(ncep, second)
+	}
+	return level
+}
+
+// describeSurface describes a single fixed surface
+func describeSurface(ncep bool, s Surface) string {
+	if s.Type == 255 {
+		return ""
+	}
+	format, ok := fixedSurfaces[s.Type]
+	if isLocal(s.Type) {
+		if ncep && s.Type == 235 {
+			return formatLevel(s.Value/10) + "C ocean isotherm"
+		}
+		format, ok = ncepSurfaces[s.Type]
+		if !ncep || !ok {
+			owner := "local"
+			if ncep {
+				owner = "NCEP"
+			}
+			if s.HasValue {
+				return fmt.Sprintf("%s level type %d %s", owner, s.Type, surfaceValue(s))
+			}
+			return fmt.Sprintf("%s level type %d", owner, s.Type)
+		}
+	} else if !ok {
+		return "reserved"
+	}
+
+	return strings.Replace(format, "%s", surfaceValue(s), 1)
+}
+
+// surfaceValue formats the value of a surface, pressures are in Pa and are
+// printed in mb
+func surfaceValue(s Surface) string {
+	v := s.Value
+	if s.Type == 100 || s.Type == 108 {
+		v /= 100
+	}
+	return formatLevel(v)
+}
+
+// formatLevel formats a level value like printf's %g of the single precision
+// value wgrib2 keeps
+func formatLevel(v float64) string {
+	return strconv.FormatFloat(float64(float32(v)), 'g', 6, 64)
+}
+
+// Parameter names the product of the field
+func (f *Field) Parameter() Parameter {
+	return LookupParameter(f.Message.Identification.Centre, f.Discipline(), f.Product.Category, f.Product.Number)
+}
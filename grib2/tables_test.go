@@ -0,0 +1,79 @@
+package grib2
+
+import "testing"
+
+func TestLookupParameter(t *testing.T) {
+	tests := []struct {
+		centre, discipline, category, number int
+		want                                 Parameter
+	}{
+		{CentreNCEP, 0, 0, 0, Parameter{"TMP", "Temperature", "K"}},
+		{CentreNCEP, 0, 2, 2, Parameter{"UGRD", "U-Component of Wind", "m/s"}},
+		{CentreNCEP, 0, 1, 8, Parameter{"APCP", "Total Precipitation", "kg/m^2"}},
+		{CentreNCEP, 10, 2, 0, Parameter{"ICEC", "Ice Cover", "Proportion"}},
+		// WMO entries are the same for every centre
+		{98, 0, 3, 5, Parameter{"HGT", "Geopotential Height", "gpm"}},
+		// local entries are NCEP's
+		{CentreNCEP, 0, 1, 192, Parameter{"CRAIN", "Categorical Rain", "-"}},
+		{CentreNCEP, 2, 0, 192, Parameter{"SOILW", "Volumetric Soil Moisture Content", "Fraction"}},
+		{98, 0, 1, 192, Parameter{"var0_1_192", "unknown", "unknown"}},
+		{CentreNCEP, 0, 0, 250, Parameter{"var0_0_250", "unknown", "unknown"}},
+		{CentreNCEP, 0, 0, 150, Parameter{"var0_0_150", "unknown", "unknown"}},
+	}
+	for _, test := range tests {
+		got := LookupParameter(test.centre, test.discipline, test.category, test.number)
+		if got != test.want {
+			t.Errorf("centre %d parameter %d.%d.%d = %+v, want %+v", test.centre,
+				test.discipline, test.category, test.number, got, test.want)
+		}
+	}
+}
+
+func TestDescribeLevel(t *testing.T) {
+	surface := func(typ int, value float64) Surface {
+		return Surface{Type: typ, Value: value, HasValue: true}
+	}
+	missing := Surface{Type: 255}
+	tests := []struct {
+		centre        int
+		first, second Surface
+		want          string
+	}{
+		{CentreNCEP, surface(103, 2), missing, "2 m above ground"},
+		{CentreNCEP, surface(100, 50000), missing, "500 mb"},
+		{CentreNCEP, Surface{Type: 1}, missing, "surface"},
+		{CentreNCEP, Surface{Type: 101}, missing, "mean sea level"},
+		{CentreNCEP, surface(106, 0), surface(106, 0.1), "0-0.1 m below ground"},
+		{CentreNCEP, surface(108, 18000), surface(108, 0), "180-0 mb above ground"},
+		{CentreNCEP, surface(103, 0), surface(103, 1000), "0-1000 m above ground"},
+		{CentreNCEP, Surface{Type: 1}, Surface{Type: 8}, "atmos col"},
+		{CentreNCEP, Surface{Type: 9}, Surface{Type: 1}, "ocean column"},
+		{CentreNCEP, missing, missing, "no_level"},
+		{CentreNCEP, Surface{Type: 200}, missing, "entire atmosphere (considered as a single layer)"},
+		{CentreNCEP, surface(235, 200), missing, "20C ocean isotherm"},
+		{CentreNCEP, surface(235, 260), surface(235, 200), "26-20C ocean isotherm layer"},
+		{CentreNCEP, Surface{Type: 1}, surface(103, 10), "surface - 10 m above ground"},
+		{CentreNCEP, Surface{Type: 250}, missing, "NCEP level type 250"},
+		{98, Surface{Type: 200}, missing, "local level type 200"},
+		{98, surface(200, 3), missing, "local level type 200 3"},
+		{CentreNCEP, Surface{Type: 190}, missing, "reserved"},
+	}
+	for _, test := range tests {
+		if got := DescribeLevel(test.centre, test.first, test.second); got != test.want {
+			t.Errorf("centre %d surfaces %+v %+v = %q, want %q", test.centre, test.first, test.second, got, test.want)
+		}
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	messages := readFixture(t, "gfs.t00z.pgrb2.0p25.f001")
+	for k, want := range []string{"UGRD", "VGRD"} {
+		f := messages[k].Fields[0]
+		if got := f.Parameter().Name; got != want {
+			t.Errorf("message %d: %s, want %s", k, got, want)
+		}
+		if got := f.Level(); got != "10 m above ground" {
+			t.Errorf("message %d: level %q, want 10 m above ground", k, got)
+		}
+	}
+}
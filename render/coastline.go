@@ -0,0 +1,266 @@
+package render
+
+// coastlines are a rough outline of the continents and larger islands, a
+// polyline of longitude, latitude pairs each, good enough to tell where a
+// map is. Longitudes east of the antimeridian go past 180 so no segment
+// crosses it
+var coastlines = [][]float32{
+	// North America
+	{
+		-166, 68, -156, 71.3, -141, 69.6, -130, 70, -120, 69, -108, 68, -95, 68,
+		-88, 68.5, -82, 66.5, -87, 64, -94, 60, -92, 57, -85, 55, -80, 52, -79, 55,
+		-77, 60, -78, 62, -73, 62, -70, 61, -65, 60, -61, 56, -57, 52, -60, 49.5,
+		-65, 49, -64, 46, -60, 46, -66, 44, -70, 42, -70, 41.5, -74, 40.5, -76, 37,
+		-76, 35, -78, 34, -81, 31, -80, 27, -80, 25, -81, 25.5, -83, 28, -84, 30,
+		-88, 30.3, -90, 29, -94, 29.5, -97, 27.5, -97.5, 24, -97, 21, -96, 19,
+		-94, 18.2, -91, 18.7, -90.5, 21, -87, 21.5, -88, 18, -88.5, 16, -84, 15.5,
+		-83.5, 11, -82, 9, -79.5, 9.5, -77.3, 8.3, -80, 7.5, -83, 8.3, -86, 11,
+		-88, 13.3, -92, 14.5, -95, 16, -98, 16.3, -102, 18, -105.5, 20,
+		-105.7, 22.5, -109.5, 23.2, -112, 26, -114, 28, -115.5, 30, -117, 32.5,
+		-118.5, 34, -120.6, 34.6, -122.5, 37.5, -124, 40.5, -124.5, 43, -124, 46.5,
+		-124.7, 48.4, -127, 50.5, -130, 54.5, -133, 57, -136, 58.5, -140, 59.8,
+		-146, 60.8, -150, 59.5, -154, 57, -158, 56, -162, 55, -164, 54.7,
+		-161, 58.5, -162, 60, -165, 61.5, -165, 63.5, -161, 64.5, -166, 65.5,
+		-166, 68,
+	},
+	// South America
+	{
+		-77.3, 8.3, -75, 10.8, -71.5, 12.3, -68, 10.5, -64, 10.7, -61, 10.5,
+		-58, 7, -55, 6, -52, 5, -50, 1.5, -48, -1, -44, -2.5, -40, -2.9, -35, -5.3,
+		-35, -9, -38.9, -13, -39, -17.5, -40.5, -21.5, -43, -23, -48, -25.5,
+		-48.6, -28.5, -52, -32, -53.5, -34, -58.5, -34.5, -57.5, -38, -62, -39,
+		-62.5, -41, -65, -42, -65.5, -45, -67.5, -46.5, -66, -48, -69, -51,
+		-68.5, -52.5, -68.5, -54.8, -71, -54, -74, -52, -75.5, -48, -74, -44,
+		-73.5, -40, -73, -37, -71.5, -32, -71.5, -28, -70.5, -23, -70.2, -18.5,
+		-75, -15.5, -77, -12, -79.5, -7.5, -81, -6, -80.5, -2.5, -80, 0, -78.5, 2,
+		-77.5, 4, -77.5, 7, -77.3, 8.3,
+	},
+	// Greenland
+	{
+		-73, 78, -60, 82, -40, 83.5, -25, 83, -20, 81.5, -18, 77, -20, 73,
+		-22, 70.5, -27, 68.5, -35, 66, -40, 65, -43, 60, -48, 61, -51, 64,
+		-53.5, 67, -54, 70, -56, 73.5, -62, 76, -66, 77, -73, 78,
+	},
+	// Eurasia
+	{
+		-5.6, 36, -6.5, 36.8, -8.8, 37, -8.9, 38.7, -9.5, 40, -8.9, 42, -9.3, 43,
+		-8, 43.7, -4, 43.4, -1.5, 43.4, -1.2, 46, -2.5, 47.3, -4.7, 48.4,
+		-1.5, 48.7, -1.3, 49.7, 0.2, 49.7, 1.6, 50.9, 3.2, 51.3, 4.5, 52.5,
+		5, 53.3, 7, 53.5, 8.6, 54, 8.6, 55.5, 8.2, 56.8, 10.5, 57.7, 10.5, 56,
+		10, 54.5, 11, 54, 13.5, 54.3, 14.5, 54, 18, 54.8, 21, 55, 21, 57, 24, 57.5,
+		24.5, 59.3, 28, 59.8, 23, 60, 22, 60.5, 21.4, 63.5, 25, 65, 22, 65.8,
+		21, 64.5, 19, 63.3, 17.5, 61, 18.7, 59.5, 16.5, 57, 14.2, 55.4, 12.8, 56,
+		11.2, 58.5, 10.5, 59.4, 8, 58, 6, 58.3, 5, 60, 5, 62, 8, 63.5, 11, 64.8,
+		14, 67, 16, 68.5, 19, 70, 24, 71, 28, 71, 31, 70, 33, 69.3, 36, 69,
+		41, 67.5, 40.5, 66, 44, 66.5, 44, 68.5, 50, 68, 54, 68.5, 58, 68.7,
+		61, 69.8, 68, 68.5, 69, 73, 73, 72.5, 80, 73.5, 87, 75, 100, 77, 105, 77.7,
+		112, 76, 113, 73.7, 127, 73.5, 131, 71, 140, 72.5, 150, 71.5, 160, 69.7,
+		170, 70, 180, 69, 185, 67.5, 189, 66, 186, 65, 180, 65, 177, 62.5,
+		173, 61.5, 170, 60, 163, 59.9, 163, 58, 162, 56, 160, 53, 156.5, 51,
+		156, 57, 160, 61.5, 155, 59.5, 150, 59.5, 142, 59, 137, 54, 141, 52.5,
+		140.5, 48, 138, 45.5, 135, 43.5, 131, 42.6, 129.5, 40.5, 128, 38.5,
+		129.4, 36, 129, 35.1, 126.5, 34.4, 126.5, 37.5, 125, 38, 124.5, 39.8,
+		121.5, 39, 121, 40.8, 118, 39, 119, 37.2, 122.5, 37, 119.5, 35, 121, 32,
+		122, 30, 120, 27, 118, 24.5, 116, 22.8, 113.5, 22.2, 110.5, 21, 108, 21.5,
+		106.5, 20, 106, 18.5, 107, 17, 109, 15, 109.2, 12, 107, 10.5, 105, 8.6,
+		104.8, 10.3, 103, 11, 101, 12.7, 100, 13.5, 99.2, 10.5, 100.3, 8.5,
+		101.3, 6.8, 103.4, 4.5, 104, 1.3, 101.3, 2.8, 100.3, 5.5, 98.3, 8,
+		98.5, 13, 97.6, 16.5, 94.3, 16, 94.5, 19, 92.3, 20.7, 91.8, 22.5, 90, 21.8,
+		88, 21.7, 86.9, 20.5, 85, 19.3, 82.3, 17, 80.3, 15.5, 80.3, 13, 79.8, 10.3,
+		77.5, 8.1, 76.3, 9.5, 74.8, 12.8, 73.5, 16, 72.8, 19, 72.7, 21.3,
+		70.5, 20.7, 69, 22.4, 68.5, 23.5, 67.2, 24.8, 66.5, 25.5, 61.5, 25.2,
+		57.3, 25.8, 56.3, 27.2, 54.5, 26.6, 51.5, 27.9, 50, 30, 48.5, 29.9, 48, 29,
+		49.5, 26.8, 50.8, 24.8, 51.5, 24, 54, 24.2, 56, 26, 56.4, 24.5, 58.5, 23.6,
+		59.8, 22.4, 58.5, 20.4, 57, 18.9, 55.5, 17.5, 52, 16, 49, 14.1, 45, 12.8,
+		43.5, 12.7, 42.7, 15.5, 42.8, 16.5, 41, 19.5, 39, 21.5, 38.5, 23.8,
+		37, 25.8, 35, 28, 34.9, 29.5, 34.2, 31.3, 34.9, 32.8, 35.5, 34.5,
+		35.9, 35.9, 36.2, 36.6, 34, 36.2, 32, 36.5, 30.5, 36.5, 29, 36.7, 27.3, 37,
+		26.3, 38.5, 26.5, 40, 26, 40.7, 24, 40.8, 23, 40.3, 23.5, 38.5, 22, 37,
+		21.5, 37.9, 21, 39.5, 19.5, 40.5, 19.4, 41.8, 16, 43.5, 14, 45, 13.6, 45.7,
+		12.3, 45.3, 12.5, 44, 14, 42.1, 16, 41.4, 18.5, 40.2, 17, 39, 16.1, 38,
+		15.7, 40, 15, 40.2, 13.8, 41.1, 12, 41.9, 10.5, 43, 9, 44.3, 7.5, 43.8,
+		6.2, 43.1, 4.5, 43.5, 3, 43.2, 3.2, 41.9, 1, 41, 0, 39.5, -0.5, 38.3,
+		-0.8, 37.6, -2, 36.7, -4.5, 36.7, -5.6, 36,
+	},
+	// Black Sea
+	{
+		29, 41.2, 31, 41.1, 35, 42, 38, 40.9, 41.5, 41.5, 41.7, 42.5, 40, 43.5,
+		38, 44.6, 37, 45.3, 35.5, 45.1, 33.5, 44.5, 32.5, 45.4, 30.5, 46.5,
+		29.6, 45.3, 28.7, 44, 28, 42.5, 29, 41.2,
+	},
+	// Caspian Sea
+	{
+		47, 45, 49.5, 46.5, 53, 46.8, 53, 45.3, 51, 44.5, 52.7, 42, 54, 40.5,
+		53.9, 37.3, 51, 36.8, 49, 37.6, 49.5, 40.2, 48, 42, 47.5, 43, 47, 45,
+	},
+	// Africa
+	{
+		32.5, 30, 32.3, 31.3, 30, 31.3, 25, 31.8, 23, 32.6, 20, 30.8, 20, 32,
+		15, 32.3, 11.5, 33.2, 10.2, 34.5, 11, 35.2, 10.2, 37.2, 8, 37, 4, 36.9,
+		0, 35.8, -2, 35, -5.5, 35.8, -6.8, 34, -9.6, 30.5, -9.8, 29.5, -12, 28,
+		-13.5, 27, -16.9, 21.5, -16, 19, -16.5, 16, -17.5, 14.7, -16.8, 12.5,
+		-15, 11, -13.3, 9, -11, 7, -7.5, 4.4, -4, 5.2, 0, 5.6, 2.7, 6.3, 4.5, 6.2,
+		6, 4.3, 8.5, 4.5, 9.5, 3.5, 9.8, 1, 9, -1, 11.8, -5, 12.2, -6.5, 13.3, -9,
+		13.8, -11.5, 12, -15, 11.7, -17.3, 13.4, -20.5, 14.5, -22.9, 15.1, -27,
+		16.5, -28.6, 18.1, -32, 18.4, -34.1, 20, -34.8, 22.5, -34, 25.6, -34,
+		28, -32.6, 30, -31, 31.5, -29, 32.8, -26, 35.4, -24, 35.3, -21.5,
+		36.8, -17.8, 40.4, -15, 40.5, -10.5, 39.3, -7, 39.2, -4.7, 41, -2, 43, 0,
+		46, 2.5, 48, 5, 50, 8.5, 51.2, 11.5, 48, 11.2, 45, 10.5, 43.2, 11.5,
+		42.7, 12.8, 41.5, 14, 39, 16, 38.4, 18, 37.2, 21, 36, 24, 34.5, 26.3,
+		33.5, 27.5, 32.5, 30,
+	},
+	// Madagascar
+	{
+		49.3, -12, 50.5, -15.5, 49.5, -17, 48, -22.5, 47, -25, 45, -25.5,
+		43.7, -23.5, 43.3, -21.5, 44.4, -16.5, 46.5, -15.8, 48, -13.5, 49.3, -12,
+	},
+	// Great Britain
+	{
+		-5.7, 50, -3, 50.6, 0.3, 50.8, 1.4, 51.2, 1.7, 52.7, 0.3, 53.4, -0.5, 54.5,
+		-1.5, 55.6, -2.5, 56, -1.8, 57.6, -3.5, 58.6, -5, 58.6, -6, 57.3, -5.6, 56,
+		-5, 55, -3.4, 54.2, -3, 53.4, -4.6, 53.3, -4.2, 52.3, -5.2, 51.7, -3, 51.5,
+		-4.2, 51.2, -5.7, 50,
+	},
+	// Ireland
+	{
+		-6, 52.2, -6.2, 53.8, -5.6, 54.7, -7.5, 55.3, -8.3, 54.6, -10, 54.2,
+		-9.7, 53, -10.3, 51.8, -8, 51.6, -6, 52.2,
+	},
+	// Iceland
+	{
+		-22.5, 63.9, -18, 63.4, -14, 64.4, -13.5, 65.5, -15, 66.4, -18, 66.2,
+		-22, 66.4, -24, 65.5, -22, 64.8, -22.5, 63.9,
+	},
+	// Svalbard
+	{
+		11, 78.5, 12, 79.8, 18, 80.3, 27, 80, 22, 78.3, 18, 77, 15, 77.2, 11, 78.5,
+	},
+	// Novaya Zemlya
+	{
+		52, 71.5, 56, 73, 57, 75.5, 62, 76.5, 69, 77, 65, 75.5, 59, 74, 56, 71,
+		53, 70.8, 52, 71.5,
+	},
+	// Honshu
+	{
+		129.7, 33.2, 131, 31.2, 132, 33.5, 135, 33.5, 136.8, 34.3, 139, 34.9,
+		140.8, 35.7, 141, 38.3, 142, 39.8, 141.3, 41.4, 140, 40.7, 139.8, 39,
+		138.5, 37.8, 136.8, 37.2, 136, 35.7, 133, 35.5, 131, 34.3, 129.7, 33.2,
+	},
+	// Hokkaido
+	{
+		140, 41.5, 141, 43, 141.7, 45.4, 144, 44.1, 145.5, 43.3, 143.5, 42,
+		141, 42.3, 140, 41.5,
+	},
+	// Sakhalin
+	{
+		142, 46, 143.5, 46.5, 143, 49, 143, 52.5, 142.5, 54.3, 142.2, 51,
+		141.8, 48, 142, 46,
+	},
+	// Taiwan
+	{
+		120.3, 22.6, 121, 25.2, 121.9, 24.6, 120.8, 22, 120.3, 22.6,
+	},
+	// Luzon
+	{
+		120, 18.5, 122.3, 18.4, 122, 16, 124, 13, 121.6, 13.9, 120.6, 14.4,
+		120, 16.3, 120, 18.5,
+	},
+	// Mindanao
+	{
+		122, 7, 125.5, 6, 126.5, 7.3, 125.6, 9.6, 123.5, 8.6, 122, 7,
+	},
+	// Borneo
+	{
+		109, 1.8, 110.3, -3, 114.5, -4, 116.5, -2.5, 118, 1, 119, 5.1, 117, 7,
+		116, 6.5, 114, 4.5, 111.5, 2.5, 109.6, 2.1, 109, 1.8,
+	},
+	// Sumatra
+	{
+		95.3, 5.6, 98, 4, 100, 2, 103.5, -0.8, 104.5, -2.3, 106, -3.2, 106, -5.9,
+		104.5, -5.7, 102, -4, 100.4, -1, 98.6, 1.8, 95.3, 5.6,
+	},
+	// Java
+	{
+		105.3, -6.8, 108, -7.8, 111, -8.3, 114.5, -8.7, 114.4, -7.7, 112.7, -6.9,
+		110.5, -6.9, 108.3, -6.2, 106, -5.9, 105.3, -6.8,
+	},
+	// Sulawesi
+	{
+		119.4, -5.5, 119.5, -3.5, 118.8, -2.7, 119.8, 0, 120.3, 0.8, 124.8, 1.4,
+		121.5, 0.5, 120.8, -1.3, 123.3, -1, 121.3, -1.9, 122, -4.5, 121, -4,
+		120.5, -5.6, 119.4, -5.5,
+	},
+	// New Guinea
+	{
+		131, -1.3, 134.2, -0.9, 138, -1.6, 141, -2.6, 145, -4.2, 147.5, -6.2,
+		150.5, -10.6, 147, -10.1, 144, -7.8, 141, -9.1, 138, -8.4, 137.5, -5.5,
+		135, -4.3, 132, -2.8, 131, -1.3,
+	},
+	// Australia
+	{
+		114, -22, 113.5, -26, 115, -30, 115, -34, 118, -35, 123, -34, 126, -32.3,
+		131, -31.5, 135, -33.8, 138, -34.5, 138.5, -35.6, 140, -37.8, 144, -38.4,
+		146, -39, 148, -37.8, 150, -37.3, 151.2, -33.8, 153, -31, 153.5, -28,
+		153, -25, 151, -23, 149.5, -22.3, 146.5, -19, 145.4, -15, 143.5, -14,
+		142.5, -10.7, 141.6, -13, 141.6, -17, 140.8, -17.5, 139.5, -17.5, 137, -16,
+		135.5, -14.8, 136.8, -12.2, 132.5, -11.5, 130, -13, 129.5, -15, 127.5, -14,
+		125, -14.5, 122.5, -17, 121, -19.5, 118, -20.3, 114, -22,
+	},
+	// Tasmania
+	{
+		144.7, -40.7, 148.3, -40.9, 148, -43.2, 146, -43.6, 144.7, -40.7,
+	},
+	// New Zealand North Island
+	{
+		172.7, -34.4, 174.5, -36, 175.5, -37.2, 178.5, -37.7, 177, -39.3,
+		176.9, -40, 175, -41.5, 174.6, -39.5, 173.8, -39.2, 174.6, -37,
+		172.7, -34.4,
+	},
+	// New Zealand South Island
+	{
+		172.7, -40.5, 174.3, -41.3, 173.5, -43, 171.2, -44.5, 169, -46.6,
+		166.5, -46, 167, -44.7, 171, -42, 172.7, -40.5,
+	},
+	// Sri Lanka
+	{
+		80.2, 9.8, 81.8, 7.5, 81.5, 6.2, 80, 6, 79.8, 8, 80.2, 9.8,
+	},
+	// Cuba
+	{
+		-85, 21.9, -82, 22.9, -80, 23.1, -77, 21.8, -74.2, 20.2, -77.7, 19.9,
+		-80, 21.8, -82.5, 21.5, -85, 21.9,
+	},
+	// Hispaniola
+	{
+		-74.4, 18.5, -72.8, 19.9, -69.9, 19.7, -68.4, 18.6, -71.4, 17.6,
+		-74.4, 18.5,
+	},
+	// Newfoundland
+	{
+		-59, 47.6, -56, 49.8, -55.5, 51.6, -53, 49.5, -52.7, 47.6, -54, 46.8,
+		-56, 47.5, -59, 47.6,
+	},
+	// Baffin Island
+	{
+		-86, 73.5, -78, 73.5, -68, 70.5, -62, 67, -64.5, 63, -72, 63.6, -78, 64.6,
+		-74, 67.5, -81, 69.8, -89, 71.5, -86, 73.5,
+	},
+	// Ellesmere Island
+	{
+		-90, 77, -78, 76.5, -75, 79, -65, 81.5, -62, 82.5, -80, 83, -92, 81,
+		-90, 77,
+	},
+	// Victoria Island
+	{
+		-119, 71.5, -114, 73, -105, 73, -101, 70, -107, 68.5, -114, 68.5,
+		-119, 69.5, -119, 71.5,
+	},
+	// Antarctica
+	{
+		-180, -78, -160, -77, -150, -76, -140, -75, -120, -73.5, -100, -73,
+		-80, -73, -70, -70, -62, -64, -57, -63.3, -60, -68, -62, -73, -60, -75,
+		-45, -78, -30, -76, -20, -73, -10, -71, 0, -70, 20, -70, 40, -69, 60, -67,
+		70, -68.5, 75, -69.5, 80, -67.5, 100, -66, 120, -66.5, 140, -66.5,
+		160, -70, 170, -72, 170, -77, 180, -78,
+	},
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// Palette maps values scaled to [0, 1] to colors, interpolating between
+// equally spaced color stops
+type Palette struct {
+	Name  string
+	Stops []color.RGBA
+	// Diverging palettes are centred on zero, the default range of a field
+	// drawn with one is symmetric around it
+	Diverging bool
+}
+
+var palettes = map[string]*Palette{
+	"temperature": {
+		Name: "temperature",
+		Stops: []color.RGBA{
+			rgb(0x5e4fa2), rgb(0x3288bd), rgb(0x66c2a5), rgb(0xabdda4), rgb(0xe6f598),
+			rgb(0xfee08b), rgb(0xfdae61), rgb(0xf46d43), rgb(0xd53e4f), rgb(0x9e0142),
+		},
+	},
+	"precipitation": {
+		Name: "precipitation",
+		Stops: []color.RGBA{
+			rgb(0xffffff), rgb(0xdeebf7), rgb(0x9ecae1), rgb(0x4292c6), rgb(0x08519c), rgb(0x54278f),
+		},
+	},
+	"diverging": {
+		Name: "diverging",
+		Stops: []color.RGBA{
+			rgb(0x053061), rgb(0x2166ac), rgb(0x4393c3), rgb(0x92c5de), rgb(0xd1e5f0), rgb(0xf7f7f7),
+			rgb(0xfddbc7), rgb(0xf4a582), rgb(0xd6604d), rgb(0xb2182b), rgb(0x67001f),
+		},
+		Diverging: true,
+	},
+	"grayscale": {
+		Name:  "grayscale",
+		Stops: []color.RGBA{rgb(0x000000), rgb(0xffffff)},
+	},
+}
+
+// variablePalettes are the palettes variables are drawn with by default,
+// others are drawn with the temperature palette
+var variablePalettes = map[string]string{
+	"TMP":   "temperature",
+	"DPT":   "temperature",
+	"TMAX":  "temperature",
+	"TMIN":  "temperature",
+	"APCP":  "precipitation",
+	"ACPCP": "precipitation",
+	"PRATE": "precipitation",
+	"CPRAT": "precipitation",
+	"PWAT":  "precipitation",
+	"UGRD":  "diverging",
+	"VGRD":  "diverging",
+	"VVEL":  "diverging",
+	"DZDT":  "diverging",
+}
+
+// LookupPalette returns the palette with a name
+func LookupPalette(name string) (*Palette, error) {
+	p, ok := palettes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown palette: %s", name)
+	}
+	return p, nil
+}
+
+// PaletteNames returns the names of the palettes, sorted
+func PaletteNames() []string {
+	var names []string
+	for name := range palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VariablePalette returns the palette a variable is drawn with by default
+func VariablePalette(variable string) *Palette {
+	if name, ok := variablePalettes[strings.ToUpper(variable)]; ok {
+		return palettes[name]
+	}
+	return palettes["temperature"]
+}
+
+// At returns the color of t, clamped to [0, 1]
+func (p *Palette) At(t float64) color.RGBA {
+	if !(t > 0) {
+		return p.Stops[0]
+	}
+	if t >= 1 {
+		return p.Stops[len(p.Stops)-1]
+	}
+	x := t * float64(len(p.Stops)-1)
+	k := int(x)
+	a, b, f := p.Stops[k], p.Stops[k+1], x-float64(k)
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*f + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+}
+
+func rgb(c uint32) color.RGBA {
+	return color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 0xff}
+}
@@ -0,0 +1,384 @@
+/*
+Package render draws decoded GRIB2 fields as images.
+
+A field is drawn a grid point per square of pixels, north up and east to the
+right, with a title above it and a colorbar under it. Only the standard
+library image packages are used, so images can be drawn anywhere nimbus
+runs.
+*/
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// Options change how a field is drawn
+type Options struct {
+	// Palette colors the values, when nil the palette of the variable
+	Palette *Palette
+	// Min and Max, when set, are the values at either end of the palette,
+	// values beyond them get the colors of the ends
+	Min, Max *float64
+	// Coastlines draws the outline of the continents over the field
+	Coastlines bool
+	// Legend draws a colorbar of the palette under the field
+	Legend bool
+	// Scale is the width in pixels of a grid point, when 0 the field is
+	// drawn about 800 pixels wide
+	Scale int
+}
+
+var (
+	background     = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	foreground     = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	missingColor   = color.RGBA{0xc8, 0xc8, 0xc8, 0xff}
+	coastlineColor = color.RGBA{0x10, 0x10, 0x10, 0xff}
+)
+
+// Render draws a field
+func Render(f *gfs.Field, opts Options) (*image.RGBA, error) {
+	g := f.Grid
+	if g == nil || g.Ni == 0 || g.Nj == 0 || len(f.Values) < g.Ni*g.Nj {
+		return nil, errors.New("render: the field has no values")
+	}
+	palette := opts.Palette
+	if palette == nil {
+		palette = VariablePalette(f.Name)
+	}
+	lo, hi := valueRange(f.Values, palette, opts)
+
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 800 / g.Ni
+		if scale < 1 {
+			scale = 1
+		}
+	}
+	// text is drawn twice its size on all but small maps
+	ts := 1
+	if g.Ni*scale >= 480 {
+		ts = 2
+	}
+	pad, lineHeight := 6*ts, 9*ts
+
+	m := newMapping(f, scale)
+	m.x0, m.y0 = pad, pad+2*lineHeight+pad/2
+	width := m.width() + 2*pad
+	height := m.y0 + m.height() + pad
+	barTop, barHeight := height, 8*ts
+	if opts.Legend {
+		height += barHeight + 4*ts + 2*lineHeight + pad/2
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill(img, img.Bounds(), background)
+
+	// the field
+	for j := 0; j < g.Nj; j++ {
+		for i := 0; i < g.Ni; i++ {
+			c := missingColor
+			if v := float64(f.At(i, j)); !math.IsNaN(v) {
+				c = palette.At((v - lo) / (hi - lo))
+			}
+			x, y := m.cell(i, j)
+			fill(img, image.Rect(x, y, x+scale, y+scale), c)
+		}
+	}
+	if opts.Coastlines {
+		drawCoastlines(img, m)
+	}
+	outline(img, m.bounds().Inset(-1), foreground)
+
+	// the title, what is drawn and for when
+	title := f.Name + " " + f.Level
+	fh := int(f.VerfTime.Sub(f.RefTime).Hours())
+	subtitle := fmt.Sprintf("valid %s (f%03d), run %s", f.VerfTime.UTC().Format("2006-01-02 15Z"),
+		fh, f.RefTime.UTC().Format("2006-01-02 15Z"))
+	drawText(img, pad, pad, title, ts, foreground)
+	drawText(img, pad, pad+lineHeight, subtitle, ts, foreground)
+
+	if opts.Legend {
+		drawLegend(img, image.Rect(m.x0, barTop, m.x0+m.width(), barTop+barHeight),
+			palette, lo, hi, f.Unit, ts)
+	}
+	return img, nil
+}
+
+// valueRange returns the values at either end of the palette, those of the
+// options or else the smallest and largest values of the field
+func valueRange(values []float32, palette *Palette, opts Options) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		x := float64(v)
+		if math.IsNaN(x) {
+			continue
+		}
+		lo = math.Min(lo, x)
+		hi = math.Max(hi, x)
+	}
+	if lo > hi {
+		lo, hi = 0, 1
+	}
+	if palette.Diverging && opts.Min == nil && opts.Max == nil {
+		hi = math.Max(math.Abs(lo), math.Abs(hi))
+		lo = -hi
+	}
+	if opts.Min != nil {
+		lo = *opts.Min
+	}
+	if opts.Max != nil {
+		hi = *opts.Max
+	}
+	if !(hi > lo) {
+		lo, hi = lo-0.5, lo+0.5
+	}
+	return lo, hi
+}
+
+// mapping places the grid points of a field in an image, grid axes that
+// run west or north are flipped
+type mapping struct {
+	f            *gfs.Field
+	scale        int
+	flipX, flipY bool
+	x0, y0       int
+}
+
+func newMapping(f *gfs.Field, scale int) *mapping {
+	g := f.Grid
+	m := &mapping{f: f, scale: scale}
+	i, j := g.Ni/2, g.Nj/2
+	lat, lon := g.LatLon(i, j)
+	if i+1 < g.Ni {
+		_, east := g.LatLon(i+1, j)
+		d := math.Mod(east-lon+540, 360) - 180
+		m.flipX = d < 0
+	}
+	if j+1 < g.Nj {
+		north, _ := g.LatLon(i, j+1)
+		m.flipY = north > lat
+	}
+	return m
+}
+
+func (m *mapping) width() int  { return m.f.Grid.Ni * m.scale }
+func (m *mapping) height() int { return m.f.Grid.Nj * m.scale }
+
+func (m *mapping) bounds() image.Rectangle {
+	return image.Rect(m.x0, m.y0, m.x0+m.width(), m.y0+m.height())
+}
+
+// cell returns the top left corner of the pixels of grid point (i, j)
+func (m *mapping) cell(i, j int) (x, y int) {
+	if m.flipX {
+		i = m.f.Grid.Ni - 1 - i
+	}
+	if m.flipY {
+		j = m.f.Grid.Nj - 1 - j
+	}
+	return m.x0 + i*m.scale, m.y0 + j*m.scale
+}
+
+// pixel returns the position in the image of a latitude and longitude
+func (m *mapping) pixel(lat, lon float64) (x, y float64) {
+	i, j := m.f.Grid.IJ(lat, lon)
+	if m.flipX {
+		i = float64(m.f.Grid.Ni-1) - i
+	}
+	if m.flipY {
+		j = float64(m.f.Grid.Nj-1) - j
+	}
+	s := float64(m.scale)
+	return float64(m.x0) + (i+0.5)*s, float64(m.y0) + (j+0.5)*s
+}
+
+// drawCoastlines draws the coastlines over the map. Segments whose middle
+// does not land halfway between their ends are left out, they cross the
+// edge of a global grid and would be drawn across the whole map
+func drawCoastlines(img *image.RGBA, m *mapping) {
+	clip := m.bounds()
+	for _, line := range coastlines {
+		for k := 0; k+3 < len(line); k += 2 {
+			lon0, lat0 := float64(line[k]), float64(line[k+1])
+			lon1, lat1 := float64(line[k+2]), float64(line[k+3])
+			x0, y0 := m.pixel(lat0, lon0)
+			x1, y1 := m.pixel(lat1, lon1)
+			xm, ym := m.pixel((lat0+lat1)/2, (lon0+lon1)/2)
+			length := math.Hypot(x1-x0, y1-y0)
+			if math.IsNaN(length) || math.IsInf(length, 0) {
+				continue
+			}
+			if math.Hypot(xm-(x0+x1)/2, ym-(y0+y1)/2) > 0.25*length+2*float64(m.scale) {
+				continue
+			}
+			drawLine(img, clip, x0, y0, x1, y1, coastlineColor)
+		}
+	}
+}
+
+// drawLegend draws a colorbar from lo to hi with ticks at round values and
+// the unit under them
+func drawLegend(img *image.RGBA, bar image.Rectangle, p *Palette, lo, hi float64, unit string, ts int) {
+	for x := bar.Min.X; x < bar.Max.X; x++ {
+		t := (float64(x-bar.Min.X) + 0.5) / float64(bar.Dx())
+		fill(img, image.Rect(x, bar.Min.Y, x+1, bar.Max.Y), p.At(t))
+	}
+	outline(img, bar.Inset(-1), foreground)
+
+	n := bar.Dx() / (48 * ts)
+	if n < 2 {
+		n = 2
+	}
+	values, decimals := ticks(lo, hi, n)
+	right := math.MinInt32
+	for _, v := range values {
+		x := bar.Min.X + int(math.Round((v-lo)/(hi-lo)*float64(bar.Dx()-1)))
+		fill(img, image.Rect(x, bar.Max.Y, x+1, bar.Max.Y+3*ts), foreground)
+		label := strconv.FormatFloat(v, 'f', decimals, 64)
+		lx := x - textWidth(label, ts)/2
+		if lx < 0 {
+			lx = 0
+		}
+		if lx+textWidth(label, ts) > img.Bounds().Dx() {
+			lx = img.Bounds().Dx() - textWidth(label, ts)
+		}
+		// labels that would overlap the one before are left out
+		if lx < right+3*ts {
+			continue
+		}
+		drawText(img, lx, bar.Max.Y+4*ts, label, ts, foreground)
+		right = lx + textWidth(label, ts)
+	}
+	if unit != "" {
+		x := bar.Min.X + (bar.Dx()-textWidth(unit, ts))/2
+		drawText(img, x, bar.Max.Y+4*ts+9*ts, unit, ts, foreground)
+	}
+}
+
+// ticks returns round values between lo and hi, about n of them, each 1, 2
+// or 5 times a power of ten apart, and the decimals they need
+func ticks(lo, hi float64, n int) (values []float64, decimals int) {
+	raw := (hi - lo) / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * mag
+	switch r := raw / mag; {
+	case r <= 1:
+		step = mag
+	case r <= 2:
+		step = 2 * mag
+	case r <= 5:
+		step = 5 * mag
+	}
+	first := math.Ceil(lo/step) * step
+	for k := 0; ; k++ {
+		v := first + float64(k)*step
+		if v > hi+step*1e-9 {
+			break
+		}
+		if math.Abs(v) < step*1e-6 {
+			v = 0
+		}
+		values = append(values, v)
+	}
+	if d := -int(math.Floor(math.Log10(step))); d > 0 {
+		decimals = d
+	}
+	return values, decimals
+}
+
+// drawLine draws a line between two points with Bresenham's algorithm,
+// leaving out the pixels outside clip
+func drawLine(img *image.RGBA, clip image.Rectangle, fx0, fy0, fx1, fy1 float64, c color.RGBA) {
+	// lines entirely to one side of clip are not drawn at all
+	if math.Max(fx0, fx1) < float64(clip.Min.X) || math.Min(fx0, fx1) >= float64(clip.Max.X) ||
+		math.Max(fy0, fy1) < float64(clip.Min.Y) || math.Min(fy0, fy1) >= float64(clip.Max.Y) {
+		return
+	}
+	if math.Hypot(fx1-fx0, fy1-fy0) > float64(4*(clip.Dx()+clip.Dy())) {
+		return
+	}
+	x0, y0 := int(math.Floor(fx0)), int(math.Floor(fy0))
+	x1, y1 := int(math.Floor(fx1)), int(math.Floor(fy1))
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		if (image.Point{x0, y0}).In(clip) {
+			img.SetRGBA(x0, y0, c)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			x0 += sx
+		} else {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// drawText draws a line of text with its top left corner at x, y, each
+// pixel of a glyph ts pixels wide
+func drawText(img *image.RGBA, x, y int, s string, ts int, c color.RGBA) {
+	for _, r := range s {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		for row, bits := range glyphs[r-' '] {
+			for col := 0; col < 5; col++ {
+				if bits&(0x10>>uint(col)) != 0 {
+					fill(img, image.Rect(x+col*ts, y+row*ts, x+(col+1)*ts, y+(row+1)*ts), c)
+				}
+			}
+		}
+		x += 6 * ts
+	}
+}
+
+// textWidth returns the width in pixels of a line of text
+func textWidth(s string, ts int) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (6*n - 1) * ts
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// outline draws the pixels just inside a rectangle
+func outline(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	fill(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), c)
+	fill(img, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), c)
+	fill(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y), c)
+	fill(img, image.Rect(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y), c)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,238 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/azillion/nimbus/gfs"
+)
+
+// readFields decodes a GRIB2 file in the testdata of the grib2 package
+func readFields(t *testing.T, name string) []gfs.Field {
+	data, err := ioutil.ReadFile(filepath.Join("..", "grib2", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := gfs.ReadFields(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+func TestPalette(t *testing.T) {
+	p, err := LookupPalette("Grayscale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		t    float64
+		want uint8
+	}{
+		{-1, 0}, {math.NaN(), 0}, {0, 0}, {0.5, 0x80}, {1, 0xff}, {2, 0xff},
+	} {
+		if got := p.At(c.t); got != (color.RGBA{c.want, c.want, c.want, 0xff}) {
+			t.Errorf("At(%v) = %v, want gray %#x", c.t, got, c.want)
+		}
+	}
+	// the stops of the other palettes are equally spaced
+	d, _ := LookupPalette("diverging")
+	if got := d.At(0.5); got != rgb(0xf7f7f7) {
+		t.Errorf("the middle of the diverging palette is %v", got)
+	}
+
+	if _, err = LookupPalette("rainbow"); err == nil {
+		t.Error("found an unknown palette")
+	}
+	if got := PaletteNames(); !reflect.DeepEqual(got, []string{"diverging", "grayscale", "precipitation", "temperature"}) {
+		t.Errorf("palettes %v", got)
+	}
+	if VariablePalette("apcp").Name != "precipitation" || VariablePalette("UGRD").Name != "diverging" || VariablePalette("HGT").Name != "temperature" {
+		t.Error("wrong palettes of variables")
+	}
+}
+
+func TestValueRange(t *testing.T) {
+	values := []float32{-2, float32(math.NaN()), 1, 5}
+	temperature, _ := LookupPalette("temperature")
+	diverging, _ := LookupPalette("diverging")
+	one, ten := 1.0, 10.0
+	for _, c := range []struct {
+		values  []float32
+		palette *Palette
+		opts    Options
+		lo, hi  float64
+	}{
+		{values, temperature, Options{}, -2, 5},
+		{values, diverging, Options{}, -5, 5},
+		{values, diverging, Options{Max: &ten}, -2, 10},
+		{values, temperature, Options{Min: &one, Max: &ten}, 1, 10},
+		{[]float32{3, 3}, temperature, Options{}, 2.5, 3.5},
+		{[]float32{float32(math.NaN())}, temperature, Options{}, 0, 1},
+	} {
+		if lo, hi := valueRange(c.values, c.palette, c.opts); lo != c.lo || hi != c.hi {
+			t.Errorf("range of %v with %s = %v, %v, want %v, %v", c.values, c.palette.Name, lo, hi, c.lo, c.hi)
+		}
+	}
+}
+
+func TestTicks(t *testing.T) {
+	for _, c := range []struct {
+		lo, hi   float64
+		n        int
+		want     []float64
+		decimals int
+	}{
+		{0, 10, 5, []float64{0, 2, 4, 6, 8, 10}, 0},
+		{0, 1, 4, []float64{0, 0.5, 1}, 1},
+		{-5.3, 5.3, 3, []float64{-5, 0, 5}, 0},
+		{270.2, 301.7, 4, []float64{280, 290, 300}, 0},
+		{0, 0.03, 3, []float64{0, 0.01, 0.02, 0.03}, 2},
+	} {
+		values, decimals := ticks(c.lo, c.hi, c.n)
+		if len(values) != len(c.want) || decimals != c.decimals {
+			t.Errorf("ticks(%v, %v, %d) = %v, %d, want %v, %d", c.lo, c.hi, c.n, values, decimals, c.want, c.decimals)
+			continue
+		}
+		for k := range values {
+			if math.Abs(values[k]-c.want[k]) > 1e-12 {
+				t.Errorf("ticks(%v, %v, %d) = %v, want %v", c.lo, c.hi, c.n, values, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	f := readFields(t, "gfs.t00z.pgrb2.0p25.f001")[0]
+	img, err := Render(&f, Options{Scale: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// small maps have text of its own size: a padding of 6, two lines of
+	// title of 9 and half a padding above the map
+	const x0, y0 = 6, 27
+	if want := image.Rect(0, 0, 117*2+12, y0+98*2+6); img.Bounds() != want {
+		t.Fatalf("bounds %v, want %v", img.Bounds(), want)
+	}
+	// the title is drawn
+	dark := 0
+	for y := 6; y < 24; y++ {
+		for x := 6; x < img.Bounds().Dx(); x++ {
+			if img.RGBAAt(x, y) == foreground {
+				dark++
+			}
+		}
+	}
+	if dark < 100 {
+		t.Errorf("%d pixels of title", dark)
+	}
+
+	// north up, the grid is scanned northwards. UGRD is drawn with the
+	// diverging palette, symmetric around zero
+	lo, hi := valueRange(f.Values, palettes["diverging"], Options{})
+	if lo != -hi {
+		t.Fatalf("range %v, %v", lo, hi)
+	}
+	for j := 0; j < 98; j++ {
+		for i := 0; i < 117; i++ {
+			want := palettes["diverging"].At((float64(f.At(i, j)) - lo) / (hi - lo))
+			x, y := x0+2*i, y0+2*(97-j)
+			for _, p := range []image.Point{{x, y}, {x + 1, y + 1}} {
+				if got := img.RGBAAt(p.X, p.Y); got != want {
+					t.Fatalf("point %d, %d at %v is %v, want %v", i, j, p, got, want)
+				}
+			}
+		}
+	}
+	// the outline of the map
+	if img.RGBAAt(x0-1, y0-1) != foreground || img.RGBAAt(x0+234, y0+196) != foreground {
+		t.Error("the map is not outlined")
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Errorf("PNG of %v", decoded.Bounds())
+	}
+}
+
+func TestRenderOverlays(t *testing.T) {
+	// the fourth field of complex.grb2 has 690 missing values
+	f := readFields(t, "complex.grb2")[3]
+	min, max := 0.0, 1.0
+	img, err := Render(&f, Options{Scale: 5, Min: &min, Max: &max, Coastlines: true, Legend: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wide maps have text twice its size, the colorbar and its labels
+	// are under the map
+	const pad, line = 12, 18
+	top := pad + 2*line + pad/2
+	bar := top + 98*5 + pad
+	if want := image.Rect(0, 0, 117*5+2*pad, bar+16+8+2*line+pad/2); img.Bounds() != want {
+		t.Fatalf("bounds %v, want %v", img.Bounds(), want)
+	}
+
+	missing, coast := 0, 0
+	for j := 0; j < 98; j++ {
+		for i := 0; i < 117; i++ {
+			x, y := pad+5*i, top+5*(97-j)
+			if math.IsNaN(float64(f.At(i, j))) && img.RGBAAt(x, y) == missingColor {
+				missing++
+			}
+		}
+	}
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.RGBAAt(x, y) != coastlineColor {
+				continue
+			}
+			coast++
+			if !(image.Point{x, y}).In(image.Rect(pad, top, pad+117*5, top+98*5)) {
+				t.Fatalf("coastline at %d, %d outside the map", x, y)
+			}
+		}
+	}
+	// the coastlines of Europe and Africa cross some missing points
+	if missing < 600 || missing > 690 {
+		t.Errorf("%d missing points drawn", missing)
+	}
+	if coast < 1000 {
+		t.Errorf("%d pixels of coastline", coast)
+	}
+
+	// values beyond the range of the options get the colors of the ends
+	p := VariablePalette(f.Name)
+	for x := pad; x < pad+117*5; x++ {
+		c := img.RGBAAt(x, bar+8)
+		if want := p.At((float64(x-pad) + 0.5) / (117 * 5)); c != want {
+			t.Fatalf("colorbar at %d is %v, want %v", x, c, want)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	f := readFields(t, "gfs.t00z.pgrb2.0p25.f001")[0]
+	f.Values = f.Values[:10]
+	if _, err := Render(&f, Options{}); err == nil {
+		t.Error("drew a field without all its values")
+	}
+	f.Grid = nil
+	if _, err := Render(&f, Options{}); err == nil {
+		t.Error("drew a field without a grid")
+	}
+}